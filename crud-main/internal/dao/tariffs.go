@@ -0,0 +1,81 @@
+package dao
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+
+	"git.abanppc.com/farin-project/crud/internal/model"
+	"git.abanppc.com/farin-project/crud/internal/tariff"
+)
+
+var _ TariffsDao = (*tariffsDao)(nil)
+
+// TariffsDao loads the rows the tariff calculator needs for one quote
+type TariffsDao interface {
+	GetRates(ctx context.Context, roadCategoryID string, cityID string, at time.Time) ([]*model.Rates, error)
+	GetBaseRates(ctx context.Context, vehicleCategoryID string) ([]*model.BaseRates, error)
+	GetPeakHourMultipliers(ctx context.Context, from time.Time, to time.Time) ([]*model.PeakHourMultipliers, error)
+	GetExceptions(ctx context.Context, at time.Time) ([]*model.Exceptions, error)
+}
+
+type tariffsDao struct {
+	db *gorm.DB
+}
+
+// NewTariffsDao creating the dao interface
+func NewTariffsDao(db *gorm.DB) TariffsDao {
+	return &tariffsDao{db: db}
+}
+
+// GetRates rates of a road category in a city that are valid on the date of at
+func (d *tariffsDao) GetRates(ctx context.Context, roadCategoryID string, cityID string, at time.Time) ([]*model.Rates, error) {
+	records := []*model.Rates{}
+	err := d.db.WithContext(ctx).
+		Where("road_category_id = ? AND city_id = ?", roadCategoryID, cityID).
+		Where("valid_from <= ? AND (valid_to IS NULL OR valid_to >= ?)", tariff.DateOf(at), tariff.DateOf(at)).
+		Order("valid_from desc").
+		Find(&records).Error
+	if err != nil {
+		return nil, err
+	}
+	return records, nil
+}
+
+// GetBaseRates base rate bands of a vehicle category ordered by FromMinutes
+func (d *tariffsDao) GetBaseRates(ctx context.Context, vehicleCategoryID string) ([]*model.BaseRates, error) {
+	records := []*model.BaseRates{}
+	err := d.db.WithContext(ctx).
+		Where("id_category_vehicle = ?", vehicleCategoryID).
+		Order("from_minutes asc").
+		Find(&records).Error
+	if err != nil {
+		return nil, err
+	}
+	return records, nil
+}
+
+// GetPeakHourMultipliers peak hour multipliers whose validity overlaps the dates from..to
+func (d *tariffsDao) GetPeakHourMultipliers(ctx context.Context, from time.Time, to time.Time) ([]*model.PeakHourMultipliers, error) {
+	records := []*model.PeakHourMultipliers{}
+	err := d.db.WithContext(ctx).
+		Where("from_valid <= ? AND to_valid >= ?", tariff.DateOf(to), tariff.DateOf(from)).
+		Find(&records).Error
+	if err != nil {
+		return nil, err
+	}
+	return records, nil
+}
+
+// GetExceptions exceptions that are valid on the date of at
+func (d *tariffsDao) GetExceptions(ctx context.Context, at time.Time) ([]*model.Exceptions, error) {
+	records := []*model.Exceptions{}
+	err := d.db.WithContext(ctx).
+		Where("start_date <= ? AND (end_date IS NULL OR end_date >= ?)", tariff.DateOf(at), tariff.DateOf(at)).
+		Find(&records).Error
+	if err != nil {
+		return nil, err
+	}
+	return records, nil
+}
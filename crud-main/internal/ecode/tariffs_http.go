@@ -0,0 +1,20 @@
+package ecode
+
+import (
+	"github.com/zhufuyi/sponge/pkg/errcode"
+)
+
+// tariffs business-level http error codes.
+// the tariffsNO value range is 1~100, if the same error code is used, it will cause panic.
+var (
+	tariffsNO       = 1
+	tariffsName     = "tariffs"
+	tariffsBaseCode = errcode.HCode(tariffsNO)
+
+	ErrQuoteTariffs       = errcode.NewError(tariffsBaseCode+1, "failed to quote "+tariffsName)
+	ErrNoRateTariffs      = errcode.NewError(tariffsBaseCode+2, "no rate is valid for the parking interval")
+	ErrNoBaseRateTariffs  = errcode.NewError(tariffsBaseCode+3, "no base rates for the vehicle category")
+	ErrInvalidRateTariffs = errcode.NewError(tariffsBaseCode+4, "rate has no valid time cycle")
+
+	// error codes are globally unique, adding 1 to the previous error code
+)
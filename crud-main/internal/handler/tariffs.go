@@ -0,0 +1,112 @@
+package handler
+
+import (
+	"errors"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/zhufuyi/sponge/pkg/gin/middleware"
+	"github.com/zhufuyi/sponge/pkg/gin/response"
+	"github.com/zhufuyi/sponge/pkg/logger"
+
+	"git.abanppc.com/farin-project/crud/internal/dao"
+	"git.abanppc.com/farin-project/crud/internal/ecode"
+	"git.abanppc.com/farin-project/crud/internal/model"
+	"git.abanppc.com/farin-project/crud/internal/tariff"
+	"git.abanppc.com/farin-project/crud/internal/types"
+)
+
+var _ TariffsHandler = (*tariffsHandler)(nil)
+
+// TariffsHandler defining the handler interface
+type TariffsHandler interface {
+	Quote(c *gin.Context)
+}
+
+type tariffsHandler struct {
+	iDao dao.TariffsDao
+}
+
+// NewTariffsHandler creating the handler interface
+func NewTariffsHandler() TariffsHandler {
+	return &tariffsHandler{
+		iDao: dao.NewTariffsDao(model.GetDB()),
+	}
+}
+
+// Quote calculate the fee of a parking interval
+// @Summary quote parking fee
+// @Description calculate the itemised fee of a parking interval from rates, base rates, peak hour multipliers and exceptions
+// @Tags tariffs
+// @accept json
+// @Produce json
+// @Param data body types.QuoteTariffRequest true "parking interval"
+// @Success 200 {object} types.QuoteTariffReply{}
+// @Router /api/v1/tariffs/quote [post]
+// @Security BearerAuth
+func (h *tariffsHandler) Quote(c *gin.Context) {
+	form := &types.QuoteTariffRequest{}
+	err := c.ShouldBindJSON(form)
+	if err != nil {
+		logger.Warn("ShouldBindJSON error: ", logger.Err(err), middleware.GCtxRequestIDField(c))
+		response.Error(c, ecode.InvalidParams)
+		return
+	}
+	if !form.EndTime.After(form.StartTime) {
+		logger.Warn("invalid parking interval", logger.Any("form", form), middleware.GCtxRequestIDField(c))
+		response.Error(c, ecode.InvalidParams)
+		return
+	}
+
+	ctx := middleware.WrapCtx(c)
+	in := &tariff.Input{
+		Plate: form.Plate,
+		Start: form.StartTime,
+		End:   form.EndTime,
+	}
+
+	in.Rates, err = h.iDao.GetRates(ctx, form.RoadCategoryID, form.CityID, form.StartTime)
+	if err != nil {
+		logger.Error("GetRates error", logger.Err(err), logger.Any("form", form), middleware.GCtxRequestIDField(c))
+		response.Output(c, ecode.InternalServerError.ToHTTPCode())
+		return
+	}
+	in.BaseRates, err = h.iDao.GetBaseRates(ctx, form.VehicleCategoryID)
+	if err != nil {
+		logger.Error("GetBaseRates error", logger.Err(err), logger.Any("form", form), middleware.GCtxRequestIDField(c))
+		response.Output(c, ecode.InternalServerError.ToHTTPCode())
+		return
+	}
+	in.PeakHourMultipliers, err = h.iDao.GetPeakHourMultipliers(ctx, form.StartTime, form.EndTime)
+	if err != nil {
+		logger.Error("GetPeakHourMultipliers error", logger.Err(err), logger.Any("form", form), middleware.GCtxRequestIDField(c))
+		response.Output(c, ecode.InternalServerError.ToHTTPCode())
+		return
+	}
+	in.Exceptions, err = h.iDao.GetExceptions(ctx, form.StartTime)
+	if err != nil {
+		logger.Error("GetExceptions error", logger.Err(err), logger.Any("form", form), middleware.GCtxRequestIDField(c))
+		response.Output(c, ecode.InternalServerError.ToHTTPCode())
+		return
+	}
+
+	quote, err := tariff.Calculate(in)
+	if err != nil {
+		logger.Warn("Calculate error", logger.Err(err), logger.Any("form", form), middleware.GCtxRequestIDField(c))
+		switch {
+		case errors.Is(err, tariff.ErrNoRate):
+			response.Error(c, ecode.ErrNoRateTariffs)
+		case errors.Is(err, tariff.ErrNoBaseRate):
+			response.Error(c, ecode.ErrNoBaseRateTariffs)
+		case errors.Is(err, tariff.ErrInvalidRate):
+			response.Error(c, ecode.ErrInvalidRateTariffs)
+		case errors.Is(err, tariff.ErrInvalidInterval):
+			response.Error(c, ecode.InvalidParams)
+		default:
+			response.Error(c, ecode.ErrQuoteTariffs)
+		}
+		return
+	}
+
+	response.Success(c, gin.H{"quote": quote})
+}
@@ -0,0 +1,22 @@
+package routers
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"git.abanppc.com/farin-project/crud/internal/handler"
+)
+
+func init() {
+	apiV1RouterFns = append(apiV1RouterFns, func(group *gin.RouterGroup) {
+		tariffsRouter(group, handler.NewTariffsHandler())
+	})
+}
+
+func tariffsRouter(group *gin.RouterGroup, h handler.TariffsHandler) {
+	g := group.Group("/tariffs")
+
+	// All the following routes use jwt authentication, you also can use middleware.Auth(middleware.WithVerify(fn))
+	//g.Use(middleware.Auth())
+
+	g.POST("/quote", h.Quote) // [post] /api/v1/tariffs/quote
+}
@@ -0,0 +1,356 @@
+// Package tariff combines rates, base rate bands, peak hour multipliers and
+// exceptions into an itemised parking fee.
+package tariff
+
+import (
+	"errors"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"git.abanppc.com/farin-project/crud/internal/model"
+)
+
+var (
+	// ErrInvalidInterval the parking interval is empty or reversed
+	ErrInvalidInterval = errors.New("tariff: end time must be after start time")
+	// ErrNoRate no rate is valid at the start of the parking interval
+	ErrNoRate = errors.New("tariff: no rate is valid for the parking interval")
+	// ErrNoBaseRate the vehicle category has no base rate bands
+	ErrNoBaseRate = errors.New("tariff: no base rate bands for the vehicle category")
+	// ErrInvalidRate the chosen rate cannot be used for billing
+	ErrInvalidRate = errors.New("tariff: rate has no valid time cycle")
+)
+
+// Input everything needed to price one parking interval. Rates, base rates,
+// peak hour multipliers and exceptions may contain rows that do not apply,
+// the calculator filters them by date, weekday, clock time and plate.
+type Input struct {
+	Rates               []*model.Rates
+	BaseRates           []*model.BaseRates
+	PeakHourMultipliers []*model.PeakHourMultipliers
+	Exceptions          []*model.Exceptions
+
+	Plate string
+	Start time.Time
+	End   time.Time
+}
+
+// Quote itemised fee of a parking interval
+type Quote struct {
+	RateID    uint64     `json:"rateID"`
+	RateCode  string     `json:"rateCode"`
+	ValidFrom *time.Time `json:"validFrom"`
+	ValidTo   *time.Time `json:"validTo"`
+
+	Start            time.Time `json:"start"`
+	End              time.Time `json:"end"`
+	ParkedMinutes    int       `json:"parkedMinutes"`
+	TimeCycleMinutes int       `json:"timeCycleMinutes"`
+	BilledMinutes    int       `json:"billedMinutes"`
+	Cycles           int       `json:"cycles"`
+	FreeCycles       int       `json:"freeCycles"` // cycles starting outside the rate start/end time
+	RateMultiplier   float64   `json:"rateMultiplier"`
+
+	Bands     []BandItem     `json:"bands"`
+	PeakHours []PeakHourItem `json:"peakHours"`
+	Exception *ExceptionItem `json:"exception"`
+
+	BaseAmount          float64 `json:"baseAmount"`
+	PeakSurcharge       float64 `json:"peakSurcharge"`
+	ExceptionAdjustment float64 `json:"exceptionAdjustment"`
+	Total               float64 `json:"total"`
+}
+
+// BandItem cycles billed from one base rate band
+type BandItem struct {
+	BaseRateID  uint64  `json:"baseRateID"`
+	FromMinutes int     `json:"fromMinutes"`
+	ToMinutes   int     `json:"toMinutes"`
+	Cycles      int     `json:"cycles"`
+	UnitPrice   float64 `json:"unitPrice"` // base rate multiplied by the rate multiplier
+	Amount      float64 `json:"amount"`
+}
+
+// PeakHourItem cycles that started inside one peak hour window
+type PeakHourItem struct {
+	PeakHourMultiplierID uint64  `json:"peakHourMultiplierID"`
+	CodeTimePeak         string  `json:"codeTimePeak"`
+	Weekday              string  `json:"weekday"`
+	TimeStart            string  `json:"timeStart"`
+	TimeEnd              string  `json:"timeEnd"`
+	Multiplier           float64 `json:"multiplier"`
+	Cycles               int     `json:"cycles"`
+	Surcharge            float64 `json:"surcharge"`
+}
+
+// ExceptionItem exception applied to the plate
+type ExceptionItem struct {
+	ExceptionID        uint64  `json:"exceptionID"`
+	NotificationNumber string  `json:"notificationNumber"`
+	Multiplier         float64 `json:"multiplier"`
+	Adjustment         float64 `json:"adjustment"`
+}
+
+// Calculate price the parking interval described by in.
+//
+// The interval is split into cycles of the rate TimeCycleMinutes, the last
+// cycle is billed in full. Every cycle is priced by the base rate band that
+// contains its offset from the start of parking, multiplied by the rate
+// multiplier and by the highest peak hour multiplier whose window contains
+// the wall clock start of the cycle. An exception listing the plate scales
+// the subtotal. The rate valid at the start of the interval is used for the
+// whole interval.
+func Calculate(in *Input) (*Quote, error) {
+	if !in.End.After(in.Start) {
+		return nil, ErrInvalidInterval
+	}
+
+	rate := SelectRate(in.Rates, in.Start)
+	if rate == nil {
+		return nil, ErrNoRate
+	}
+	if rate.TimeCycleMinutes <= 0 {
+		return nil, ErrInvalidRate
+	}
+
+	bands := sortedBands(in.BaseRates)
+	if len(bands) == 0 {
+		return nil, ErrNoBaseRate
+	}
+
+	rateMultiplier := parseNumber(rate.RateMultiplier, 1)
+	defaultPeak := parseNumber(rate.PeakHourMultiplier, 1)
+	operating, hasOperating := parseWindow(rate.StartTime, rate.EndTime)
+
+	cycle := rate.TimeCycleMinutes
+	parked := int(math.Ceil(in.End.Sub(in.Start).Minutes()))
+	cycles := (parked + cycle - 1) / cycle
+
+	quote := &Quote{
+		RateID:           rate.ID,
+		RateCode:         rate.Code,
+		ValidFrom:        rate.ValidFrom,
+		ValidTo:          rate.ValidTo,
+		Start:            in.Start,
+		End:              in.End,
+		ParkedMinutes:    parked,
+		TimeCycleMinutes: cycle,
+		BilledMinutes:    cycles * cycle,
+		Cycles:           cycles,
+		RateMultiplier:   rateMultiplier,
+		Bands:            []BandItem{},
+		PeakHours:        []PeakHourItem{},
+	}
+
+	bandIndex := map[uint64]int{}
+	peakIndex := map[uint64]int{}
+	for i := 0; i < cycles; i++ {
+		offset := i * cycle
+		at := in.Start.Add(time.Duration(offset) * time.Minute)
+		if hasOperating && !operating.contains(at) {
+			quote.FreeCycles++
+			continue
+		}
+
+		band := bandFor(bands, offset)
+		unit := parseNumber(band.BaseRate, 0) * rateMultiplier
+
+		idx, ok := bandIndex[band.ID]
+		if !ok {
+			idx = len(quote.Bands)
+			bandIndex[band.ID] = idx
+			quote.Bands = append(quote.Bands, BandItem{
+				BaseRateID:  band.ID,
+				FromMinutes: band.FromMinutes,
+				ToMinutes:   band.ToMinutes,
+				UnitPrice:   round(unit),
+			})
+		}
+		quote.Bands[idx].Cycles++
+		quote.Bands[idx].Amount += unit
+
+		peak, multiplier := peakFor(in.PeakHourMultipliers, at, defaultPeak)
+		if peak == nil {
+			continue
+		}
+		idx, ok = peakIndex[peak.ID]
+		if !ok {
+			idx = len(quote.PeakHours)
+			peakIndex[peak.ID] = idx
+			quote.PeakHours = append(quote.PeakHours, PeakHourItem{
+				PeakHourMultiplierID: peak.ID,
+				CodeTimePeak:         peak.CodeTimePeak,
+				Weekday:              peak.Weekday,
+				TimeStart:            peak.TimeStart,
+				TimeEnd:              peak.TimeEnd,
+				Multiplier:           multiplier,
+			})
+		}
+		quote.PeakHours[idx].Cycles++
+		quote.PeakHours[idx].Surcharge += unit * (multiplier - 1)
+	}
+
+	for i := range quote.Bands {
+		quote.Bands[i].Amount = round(quote.Bands[i].Amount)
+		quote.BaseAmount += quote.Bands[i].Amount
+	}
+	for i := range quote.PeakHours {
+		quote.PeakHours[i].Surcharge = round(quote.PeakHours[i].Surcharge)
+		quote.PeakSurcharge += quote.PeakHours[i].Surcharge
+	}
+	quote.BaseAmount = round(quote.BaseAmount)
+	quote.PeakSurcharge = round(quote.PeakSurcharge)
+	subtotal := round(quote.BaseAmount + quote.PeakSurcharge)
+
+	if exception, multiplier := exceptionFor(in.Exceptions, in.Plate, in.Start); exception != nil {
+		adjustment := round(subtotal*multiplier - subtotal)
+		quote.Exception = &ExceptionItem{
+			ExceptionID:        exception.ID,
+			NotificationNumber: exception.NotificationNumber,
+			Multiplier:         multiplier,
+			Adjustment:         adjustment,
+		}
+		quote.ExceptionAdjustment = adjustment
+	}
+
+	quote.Total = round(subtotal + quote.ExceptionAdjustment)
+
+	return quote, nil
+}
+
+// SelectRate returns the rate valid on the date of at, when several rates
+// are valid the one with the latest ValidFrom wins.
+func SelectRate(rates []*model.Rates, at time.Time) *model.Rates {
+	var selected *model.Rates
+	for _, rate := range rates {
+		if rate == nil || !withinDates(at, rate.ValidFrom, rate.ValidTo) {
+			continue
+		}
+		if selected == nil || civilDate(rate.ValidFrom) > civilDate(selected.ValidFrom) {
+			selected = rate
+		}
+	}
+	return selected
+}
+
+func sortedBands(baseRates []*model.BaseRates) []*model.BaseRates {
+	bands := make([]*model.BaseRates, 0, len(baseRates))
+	for _, band := range baseRates {
+		if band != nil {
+			bands = append(bands, band)
+		}
+	}
+	sort.SliceStable(bands, func(i, j int) bool {
+		return bands[i].FromMinutes < bands[j].FromMinutes
+	})
+	return bands
+}
+
+// bandFor returns the band containing offset minutes. A stay that runs past
+// the last band keeps being billed by the last band.
+func bandFor(bands []*model.BaseRates, offset int) *model.BaseRates {
+	selected := bands[0]
+	for _, band := range bands {
+		if band.FromMinutes > offset {
+			break
+		}
+		selected = band
+	}
+	return selected
+}
+
+// peakFor returns the peak hour window with the highest multiplier that
+// contains at. A window without a multiplier uses the rate peak multiplier.
+func peakFor(peaks []*model.PeakHourMultipliers, at time.Time, defaultMultiplier float64) (*model.PeakHourMultipliers, float64) {
+	var selected *model.PeakHourMultipliers
+	best := 1.0
+	for _, peak := range peaks {
+		if peak == nil || !withinDates(at, peak.FromValid, peak.ToValid) || !matchWeekday(peak.Weekday, at) {
+			continue
+		}
+		window, ok := parseWindow(peak.TimeStart, peak.TimeEnd)
+		if !ok || !window.contains(at) {
+			continue
+		}
+		multiplier := parseNumber(peak.Multiplier, defaultMultiplier)
+		if selected == nil || multiplier > best {
+			selected = peak
+			best = multiplier
+		}
+	}
+	return selected, best
+}
+
+// exceptionFor returns the exception valid at at that lists plate, when the
+// plate is listed several times the lowest multiplier wins.
+func exceptionFor(exceptions []*model.Exceptions, plate string, at time.Time) (*model.Exceptions, float64) {
+	plate = NormalizePlate(plate)
+	if plate == "" {
+		return nil, 1
+	}
+
+	var selected *model.Exceptions
+	best := 1.0
+	for _, exception := range exceptions {
+		if exception == nil || !withinDates(at, exception.StartDate, exception.EndDate) {
+			continue
+		}
+		if !containsPlate(exception.CarLicensePlates, plate) && !containsPlate(exception.MotorcycleLicensePlates, plate) {
+			continue
+		}
+		multiplier := parseNumber(exception.ExceptionMultiplier, 1)
+		if selected == nil || multiplier < best {
+			selected = exception
+			best = multiplier
+		}
+	}
+	return selected, best
+}
+
+// NormalizePlate removes separators and upper-cases the plate so that plates
+// typed by operators compare equal to plates read by the cameras.
+func NormalizePlate(plate string) string {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case ' ', '-', '_', '\t':
+			return -1
+		}
+		return r
+	}, strings.ToUpper(strings.TrimSpace(plate)))
+}
+
+// containsPlate reports whether the postgres text array literal list holds plate
+func containsPlate(list string, plate string) bool {
+	list = strings.TrimSpace(list)
+	list = strings.TrimPrefix(list, "{")
+	list = strings.TrimSuffix(list, "}")
+	if list == "" {
+		return false
+	}
+	for _, item := range strings.Split(list, ",") {
+		if NormalizePlate(strings.Trim(strings.TrimSpace(item), `"`)) == plate {
+			return true
+		}
+	}
+	return false
+}
+
+func parseNumber(s string, def float64) float64 {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return def
+	}
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return def
+	}
+	return v
+}
+
+// round rounds an amount to the two decimals of the DECIMAL(10, 2) columns
+func round(v float64) float64 {
+	return math.Round(v*100) / 100
+}
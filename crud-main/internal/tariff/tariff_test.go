@@ -0,0 +1,219 @@
+package tariff
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"git.abanppc.com/farin-project/crud/internal/model"
+)
+
+func date(year int, month time.Month, day int) *time.Time {
+	t := time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+	return &t
+}
+
+func testInput() *Input {
+	return &Input{
+		Rates: []*model.Rates{
+			{ID: 1, Code: "R-OLD", TimeCycleMinutes: 30, RateMultiplier: "1", ValidFrom: date(2023, 3, 21), ValidTo: date(2024, 3, 19)},
+			{ID: 2, Code: "R-1403", TimeCycleMinutes: 30, RateMultiplier: "1.5", PeakHourMultiplier: "2", ValidFrom: date(2024, 3, 20)},
+		},
+		BaseRates: []*model.BaseRates{
+			{ID: 11, FromMinutes: 60, ToMinutes: 600, BaseRate: "20000"},
+			{ID: 10, FromMinutes: 0, ToMinutes: 60, BaseRate: "10000"},
+		},
+		Plate: "12 ب 345 - 67",
+		// Tuesday
+		Start: time.Date(2024, 6, 4, 9, 0, 0, 0, tehran),
+		End:   time.Date(2024, 6, 4, 10, 10, 0, 0, tehran),
+	}
+}
+
+func TestCalculate(t *testing.T) {
+	tests := []struct {
+		name    string
+		modify  func(in *Input)
+		wantErr error
+		check   func(t *testing.T, q *Quote)
+	}{
+		{
+			name: "base bands and cycle rounding",
+			check: func(t *testing.T, q *Quote) {
+				assert.Equal(t, uint64(2), q.RateID)
+				assert.Equal(t, 70, q.ParkedMinutes)
+				assert.Equal(t, 90, q.BilledMinutes)
+				assert.Equal(t, 3, q.Cycles)
+				assert.Len(t, q.Bands, 2)
+				assert.Equal(t, BandItem{BaseRateID: 10, FromMinutes: 0, ToMinutes: 60, Cycles: 2, UnitPrice: 15000, Amount: 30000}, q.Bands[0])
+				assert.Equal(t, BandItem{BaseRateID: 11, FromMinutes: 60, ToMinutes: 600, Cycles: 1, UnitPrice: 30000, Amount: 30000}, q.Bands[1])
+				assert.Equal(t, 60000.0, q.BaseAmount)
+				assert.Equal(t, 60000.0, q.Total)
+			},
+		},
+		{
+			name: "peak hour window on matching weekday",
+			modify: func(in *Input) {
+				in.PeakHourMultipliers = []*model.PeakHourMultipliers{
+					{ID: 20, CodeTimePeak: "AM", Multiplier: "2", Weekday: "Tuesday", TimeStart: "09:30", TimeEnd: "11:00", FromValid: date(2024, 1, 1), ToValid: date(2024, 12, 31)},
+					{ID: 21, CodeTimePeak: "MON", Multiplier: "3", Weekday: "Monday", TimeStart: "00:00", TimeEnd: "23:59", FromValid: date(2024, 1, 1), ToValid: date(2024, 12, 31)},
+				}
+			},
+			check: func(t *testing.T, q *Quote) {
+				assert.Len(t, q.PeakHours, 1)
+				assert.Equal(t, uint64(20), q.PeakHours[0].PeakHourMultiplierID)
+				assert.Equal(t, 2, q.PeakHours[0].Cycles)
+				// cycles at 09:30 (15000) and 10:00 (30000) are doubled
+				assert.Equal(t, 45000.0, q.PeakSurcharge)
+				assert.Equal(t, 105000.0, q.Total)
+			},
+		},
+		{
+			name: "peak hour outside validity is ignored",
+			modify: func(in *Input) {
+				in.PeakHourMultipliers = []*model.PeakHourMultipliers{
+					{ID: 20, Multiplier: "2", TimeStart: "00:00", TimeEnd: "00:00", FromValid: date(2025, 1, 1), ToValid: date(2025, 12, 31)},
+				}
+			},
+			check: func(t *testing.T, q *Quote) {
+				assert.Empty(t, q.PeakHours)
+				assert.Equal(t, 60000.0, q.Total)
+			},
+		},
+		{
+			name: "peak hour window over midnight uses rate multiplier as default",
+			modify: func(in *Input) {
+				in.Start = time.Date(2024, 6, 4, 23, 30, 0, 0, tehran)
+				in.End = time.Date(2024, 6, 5, 0, 30, 0, 0, tehran)
+				in.PeakHourMultipliers = []*model.PeakHourMultipliers{
+					{ID: 22, TimeStart: "23:00", TimeEnd: "00:15", FromValid: date(2024, 1, 1), ToValid: date(2024, 12, 31)},
+				}
+			},
+			check: func(t *testing.T, q *Quote) {
+				assert.Len(t, q.PeakHours, 1)
+				assert.Equal(t, 2.0, q.PeakHours[0].Multiplier)
+				assert.Equal(t, 2, q.PeakHours[0].Cycles)
+				assert.Equal(t, 60000.0, q.Total)
+			},
+		},
+		{
+			name: "exception for listed plate",
+			modify: func(in *Input) {
+				in.Exceptions = []*model.Exceptions{
+					{ID: 30, CarLicensePlates: `{"11ب11111","12ب34567"}`, ExceptionMultiplier: "0.5", StartDate: date(2024, 1, 1)},
+					{ID: 31, CarLicensePlates: `{99ب99999}`, ExceptionMultiplier: "0", StartDate: date(2024, 1, 1)},
+				}
+			},
+			check: func(t *testing.T, q *Quote) {
+				assert.NotNil(t, q.Exception)
+				assert.Equal(t, uint64(30), q.Exception.ExceptionID)
+				assert.Equal(t, -30000.0, q.ExceptionAdjustment)
+				assert.Equal(t, 30000.0, q.Total)
+			},
+		},
+		{
+			name: "expired exception is ignored",
+			modify: func(in *Input) {
+				in.Exceptions = []*model.Exceptions{
+					{ID: 30, CarLicensePlates: `{12ب34567}`, ExceptionMultiplier: "0", StartDate: date(2023, 1, 1), EndDate: date(2023, 12, 31)},
+				}
+			},
+			check: func(t *testing.T, q *Quote) {
+				assert.Nil(t, q.Exception)
+				assert.Equal(t, 60000.0, q.Total)
+			},
+		},
+		{
+			name: "cycles outside rate hours are free",
+			modify: func(in *Input) {
+				in.Rates[1].StartTime = "07:00"
+				in.Rates[1].EndTime = "10:00"
+			},
+			check: func(t *testing.T, q *Quote) {
+				assert.Equal(t, 1, q.FreeCycles)
+				assert.Len(t, q.Bands, 1)
+				assert.Equal(t, 30000.0, q.Total)
+			},
+		},
+		{
+			name: "rate hours are read on the tehran clock",
+			modify: func(in *Input) {
+				in.Rates[1].StartTime = "07:00"
+				in.Rates[1].EndTime = "10:00"
+				in.Start = time.Date(2024, 6, 4, 5, 30, 0, 0, time.UTC)
+				in.End = time.Date(2024, 6, 4, 6, 40, 0, 0, time.UTC)
+			},
+			check: func(t *testing.T, q *Quote) {
+				assert.Equal(t, 1, q.FreeCycles)
+				assert.Equal(t, 30000.0, q.Total)
+			},
+		},
+		{
+			name: "rate valid at start is chosen",
+			modify: func(in *Input) {
+				in.Start = time.Date(2024, 3, 19, 9, 0, 0, 0, tehran)
+				in.End = time.Date(2024, 3, 19, 9, 30, 0, 0, tehran)
+			},
+			check: func(t *testing.T, q *Quote) {
+				assert.Equal(t, uint64(1), q.RateID)
+				assert.Equal(t, 10000.0, q.Total)
+			},
+		},
+		{
+			name: "no rate",
+			modify: func(in *Input) {
+				in.Start = time.Date(2020, 1, 1, 9, 0, 0, 0, tehran)
+				in.End = time.Date(2020, 1, 1, 9, 30, 0, 0, tehran)
+			},
+			wantErr: ErrNoRate,
+		},
+		{
+			name:    "no base rates",
+			modify:  func(in *Input) { in.BaseRates = nil },
+			wantErr: ErrNoBaseRate,
+		},
+		{
+			name:    "zero time cycle",
+			modify:  func(in *Input) { in.Rates[1].TimeCycleMinutes = 0 },
+			wantErr: ErrInvalidRate,
+		},
+		{
+			name:    "reversed interval",
+			modify:  func(in *Input) { in.End = in.Start },
+			wantErr: ErrInvalidInterval,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			in := testInput()
+			if tt.modify != nil {
+				tt.modify(in)
+			}
+			q, err := Calculate(in)
+			if tt.wantErr != nil {
+				assert.True(t, errors.Is(err, tt.wantErr), "got %v", err)
+				return
+			}
+			if !assert.NoError(t, err) {
+				return
+			}
+			tt.check(t, q)
+		})
+	}
+}
+
+func TestDateOf(t *testing.T) {
+	// 21:00 UTC is 00:30 of the next day in Tehran
+	assert.Equal(t, "2024-06-05", DateOf(time.Date(2024, 6, 4, 21, 0, 0, 0, time.UTC)))
+	assert.Equal(t, "2024-06-05", DateOf(time.Date(2024, 6, 4, 23, 59, 0, 0, time.UTC)))
+	// 20:29 UTC is still 23:59 in Tehran
+	assert.Equal(t, "2024-06-04", DateOf(time.Date(2024, 6, 4, 20, 29, 0, 0, time.UTC)))
+}
+
+func TestNormalizePlate(t *testing.T) {
+	assert.Equal(t, "12ب34567", NormalizePlate(" 12 ب 345-67 "))
+	assert.Equal(t, "", NormalizePlate(""))
+}
@@ -0,0 +1,105 @@
+package tariff
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// tehran the location tariff clock times, weekdays and dates are written in.
+// Iran has no daylight saving since 2022, so a fixed +03:30 zone stands in
+// when the zone database is missing.
+var tehran = loadTehran()
+
+func loadTehran() *time.Location {
+	loc, err := time.LoadLocation("Asia/Tehran")
+	if err != nil {
+		return time.FixedZone("IRST", 3*60*60+30*60)
+	}
+	return loc
+}
+
+// clockWindow a daily time window in minutes after midnight, a window whose
+// end is not after its start runs over midnight.
+type clockWindow struct {
+	start int
+	end   int
+}
+
+// contains reports whether the Tehran wall clock time of t is in the window
+func (w clockWindow) contains(t time.Time) bool {
+	t = t.In(tehran)
+	m := t.Hour()*60 + t.Minute()
+	if w.end > w.start {
+		return m >= w.start && m < w.end
+	}
+	return m >= w.start || m < w.end
+}
+
+// parseWindow parses two clock times such as "07:30" or "07:30:00", ok is
+// false when either side is empty or malformed.
+func parseWindow(start string, end string) (clockWindow, bool) {
+	s, ok := parseClock(start)
+	if !ok {
+		return clockWindow{}, false
+	}
+	e, ok := parseClock(end)
+	if !ok {
+		return clockWindow{}, false
+	}
+	return clockWindow{start: s, end: e}, true
+}
+
+func parseClock(s string) (int, bool) {
+	parts := strings.Split(strings.TrimSpace(s), ":")
+	if len(parts) < 2 || len(parts) > 3 {
+		return 0, false
+	}
+	h, err := strconv.Atoi(parts[0])
+	if err != nil || h < 0 || h > 24 {
+		return 0, false
+	}
+	m, err := strconv.Atoi(parts[1])
+	if err != nil || m < 0 || m > 59 {
+		return 0, false
+	}
+	return (h*60 + m) % (24 * 60), true
+}
+
+// matchWeekday reports whether t falls on weekday in Tehran, an empty weekday
+// matches every day. Weekdays are stored by name, e.g. "Saturday".
+func matchWeekday(weekday string, t time.Time) bool {
+	weekday = strings.TrimSpace(weekday)
+	if weekday == "" {
+		return true
+	}
+	return strings.EqualFold(weekday, t.In(tehran).Weekday().String())
+}
+
+// withinDates reports whether the Tehran date of t is between from and to
+// inclusive, a nil bound is open.
+func withinDates(t time.Time, from *time.Time, to *time.Time) bool {
+	t = t.In(tehran)
+	day := t.Year()*10000 + int(t.Month())*100 + t.Day()
+	if from != nil && day < civilDate(from) {
+		return false
+	}
+	if to != nil && day > civilDate(to) {
+		return false
+	}
+	return true
+}
+
+// DateOf formats the Tehran calendar date of t for comparison with DATE
+// columns, the date validity windows are read on
+func DateOf(t time.Time) string {
+	return t.In(tehran).Format(time.DateOnly)
+}
+
+// civilDate the calendar date of a DATE column as yyyymmdd
+func civilDate(t *time.Time) int {
+	if t == nil {
+		return 0
+	}
+	return t.Year()*10000 + int(t.Month())*100 + t.Day()
+}
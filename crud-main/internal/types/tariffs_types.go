@@ -0,0 +1,26 @@
+package types
+
+import (
+	"time"
+
+	"git.abanppc.com/farin-project/crud/internal/tariff"
+)
+
+// QuoteTariffRequest request params
+type QuoteTariffRequest struct {
+	RoadCategoryID    string    `json:"roadCategoryID" binding:"required"`
+	CityID            string    `json:"cityID" binding:"required"`
+	VehicleCategoryID string    `json:"vehicleCategoryID" binding:"required"`
+	Plate             string    `json:"plate" binding:""`
+	StartTime         time.Time `json:"startTime" binding:"required"`
+	EndTime           time.Time `json:"endTime" binding:"required"`
+}
+
+// QuoteTariffReply only for api docs
+type QuoteTariffReply struct {
+	Code int    `json:"code"` // return code
+	Msg  string `json:"msg"`  // return information description
+	Data struct {
+		Quote tariff.Quote `json:"quote"`
+	} `json:"data"` // return data
+}
@@ -1,11 +1,12 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"git.abanppc.com/farin-project/event/config"
 	"git.abanppc.com/farin-project/event/internal/rabbit/consumers"
-	"git.abanppc.com/farin-project/event/pkg/rabbit"
-
 	"log"
+	"sync"
 )
 
 type Bootstrap struct {
@@ -21,11 +22,6 @@ func NewBootstrap(rbtConfig *config.RabbitMQ, consumers ...consumers.Consumer) *
 }
 
 func (b *Bootstrap) Boot() {
-	rbt := rabbit.NewRabbit(b.rbtConfig)
-	if err := rbt.Setup(); err != nil {
-		log.Fatal(err.Error())
-	}
-
 	for _, consumer := range b.consumers {
 		if err := consumer.Setup(); err != nil {
 			log.Fatal("setup consumer error: " + err.Error())
@@ -33,3 +29,18 @@ func (b *Bootstrap) Boot() {
 		go consumer.Consume()
 	}
 }
+
+// Shutdown drains the consumers at once, until ctx is done
+func (b *Bootstrap) Shutdown(ctx context.Context) error {
+	errs := make([]error, len(b.consumers))
+	var wg sync.WaitGroup
+	for i, consumer := range b.consumers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = consumer.Shutdown(ctx)
+		}()
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
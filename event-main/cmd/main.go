@@ -27,6 +27,9 @@ import (
 
 var ErrorRequestTimeout = errors.New("request take too long to respond")
 
+// defaultShutdownTimeout is used when rabbitMQ.shutdownTimeout is not set
+const defaultShutdownTimeout = 25 * time.Second
+
 func main() {
 	cp := flag.String("config", "", "config file address")
 	flag.Parse()
@@ -65,8 +68,8 @@ func main() {
 		log.Fatal("setup mongodb fail ", err)
 	}
 
+	hz := healthz.NewHealthz(logger, rbt, mongo)
 	go func() {
-		hz := healthz.NewHealthz(logger, rbt, mongo)
 		for {
 			hz.HealthzReadiness()
 			time.Sleep(5 * time.Second)
@@ -80,7 +83,7 @@ func main() {
 	}
 	bootstrap.Boot()
 	// Handle graceful shutdown
-	handleGracefulShutdown(ctx, logger)
+	handleGracefulShutdown(logger, bootstrap, hz, rbt, rbtConfig.ShutdownTimeout)
 }
 
 func initConfig(configPath string) config.Config {
@@ -150,12 +153,28 @@ func initLogger(c config.Config) *slog.Logger {
 	return logger
 }
 
-func handleGracefulShutdown(ctx context.Context, logger *slog.Logger) {
+// handleGracefulShutdown waits for a signal, then fails the readiness, drains
+// the consumers within timeout and closes rabbitMQ
+func handleGracefulShutdown(logger *slog.Logger, bootstrap *Bootstrap, hz *healthz.Healthz, rbt *rabbit.Rabbit,
+	timeout time.Duration) {
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 	sig := <-quit
 
-	logger.Info("app stopping...")
+	if timeout <= 0 {
+		timeout = defaultShutdownTimeout
+	}
+	logger.Info("app stopping...", "timeout", timeout)
+	hz.Drain()
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	if err := bootstrap.Shutdown(ctx); err != nil {
+		logger.Error("failed to drain consumers", "error", err)
+	}
+	if err := rbt.Close(); err != nil {
+		logger.Error("failed to close rabbitMQ", "error", err)
+	}
 
 	logger.Info("app stopped", "signal", sig)
 }
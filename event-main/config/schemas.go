@@ -1,5 +1,7 @@
 package config
 
+import "time"
+
 type (
 	LogingConfig struct {
 		Observability Observability `koanf:"observability"`
@@ -39,6 +41,19 @@ type (
 		Host          string `koanf:"host"`
 		EventExchange string `koanf:"exchange"`
 		EventQueue    string `koanf:"queue"`
+		// DeadLetterExchange is where messages that failed every attempt are published
+		DeadLetterExchange string        `koanf:"deadLetterExchange"`
+		MaxAttempts        int           `koanf:"maxAttempts"`
+		RetryDelay         time.Duration `koanf:"retryDelay"` // first retry delay, doubled on every next one
+		RetryMax           time.Duration `koanf:"retryMax"`
+		// ShutdownTimeout is the time the consumers have to drain on SIGTERM
+		ShutdownTimeout time.Duration `koanf:"shutdownTimeout"`
+		// Consumer settings of the event queue, see consumer.Settings
+		Prefetch   int           `koanf:"prefetch"`
+		Workers    int           `koanf:"workers"`
+		MaxWorkers int           `koanf:"maxWorkers"` // workers are autoscaled up to it
+		Buffer     int           `koanf:"buffer"`
+		Timeout    time.Duration `koanf:"timeout"` // of a handler call
 	}
 
 	MongoDB struct {
@@ -4,6 +4,7 @@ import (
 	"context"
 	v1 "git.abanppc.com/farin-project/event/domain/entity/v1"
 	v12 "git.abanppc.com/farin-project/event/domain/repository/v1/event"
+	"git.abanppc.com/farin-project/vehicle-records/util/plate"
 	"go.opentelemetry.io/otel"
 	"log/slog"
 )
@@ -30,6 +31,16 @@ func (uc *EventBiz) CreateEvent(ctx context.Context, event *v1.Event) error {
 	ctx, span := otel.Tracer("biz").Start(ctx, "CreateEvent")
 	defer span.End()
 
+	// events are looked up by plate, store it as the vehicle records do
+	if event.CitizenPlateNumber != "" {
+		p, err := plate.Parse(event.CitizenPlateNumber)
+		if err != nil {
+			lg.Warn("invalid plate number", "plate", event.CitizenPlateNumber, "err", err)
+		} else {
+			event.CitizenPlateNumber = p.String()
+		}
+	}
+
 	if err := uc.repo.CreateEvent(ctx, event); err != nil {
 		lg.Error("Error in CreateEvent Repo", "err", err)
 		return err
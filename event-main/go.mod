@@ -3,6 +3,8 @@ module git.abanppc.com/farin-project/event
 go 1.22.5
 
 require (
+	git.abanppc.com/farin-project/vehicle-records/util/consumer v0.1.0
+	git.abanppc.com/farin-project/vehicle-records/util/plate v0.1.0
 	github.com/google/uuid v1.6.0
 	github.com/google/wire v0.6.0
 	github.com/knadh/koanf/parsers/yaml v0.1.0
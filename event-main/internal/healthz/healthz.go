@@ -8,6 +8,7 @@ import (
 	"log"
 	"log/slog"
 	"os"
+	"sync/atomic"
 	"time"
 )
 
@@ -16,6 +17,7 @@ type Healthz struct {
 	lastReady time.Time
 	rbt       *rabbit.Rabbit
 	mongo     *mongo.Mongo
+	draining  atomic.Bool
 }
 
 func NewHealthz(logger *slog.Logger, rbt *rabbit.Rabbit, mongo *mongo.Mongo) *Healthz {
@@ -33,6 +35,9 @@ func (s *Healthz) HealthzReadiness() {
 	defer span.End()
 
 	logger := s.logger.With("method", "HealthzReadiness", "ctx", ctx)
+	if s.draining.Load() {
+		return
+	}
 	if s.lastReady.Compare(time.Now().Add(-(time.Minute * 5))) == -1 {
 		logger.Error("healthz readiness timeout,killing the instance...")
 		time.Sleep(3 * time.Second)
@@ -65,3 +70,14 @@ func (s *Healthz) HealthzReadiness() {
 	}
 	s.lastReady = time.Now()
 }
+
+// Drain fails the readiness for the rest of the life of the instance, so no
+// traffic reaches it and rabbitMQ is not set up again while the consumers
+// drain
+func (s *Healthz) Drain() {
+	logger := s.logger.With("method", "Drain")
+	s.draining.Store(true)
+	if err := os.Remove("readiness"); err != nil && !os.IsNotExist(err) {
+		logger.Error("failed to remove readiness file", "error", err)
+	}
+}
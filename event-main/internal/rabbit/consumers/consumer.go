@@ -1,6 +1,7 @@
 package consumers
 
 import (
+	"context"
 	"git.abanppc.com/farin-project/event/config"
 	"git.abanppc.com/farin-project/event/domain/biz/event"
 	"git.abanppc.com/farin-project/event/pkg/rabbit"
@@ -10,6 +11,9 @@ import (
 type Consumer interface {
 	Setup() error
 	Consume()
+	// Shutdown stops consuming and waits for the buffered and in-flight
+	// messages until ctx is done
+	Shutdown(ctx context.Context) error
 }
 
 func Consumers(logger *slog.Logger, rbt *rabbit.Rabbit, rbtConfig *config.RabbitMQ, eventBiz event.EventBizInterface) []Consumer {
@@ -6,37 +6,85 @@ import (
 	"git.abanppc.com/farin-project/event/domain/biz/event"
 	v1 "git.abanppc.com/farin-project/event/domain/entity/v1"
 	"git.abanppc.com/farin-project/event/pkg/rabbit"
+	"git.abanppc.com/farin-project/vehicle-records/util/consumer"
 	"github.com/rabbitmq/amqp091-go"
 	"log/slog"
-	"strings"
 	"time"
 )
 
-const workersCount = 1
-const msgsQueueLength = 5
+// eventRoutingKey matches farin.vehicles.<lpr vehicle id>.drivers.<user id>.event
+const eventRoutingKey = "farin.vehicles.*.drivers.*.event"
 
 type Event struct {
 	rbt       *rabbit.Rabbit
 	delivery  <-chan amqp091.Delivery
 	logger    *slog.Logger
-	msgsQueue chan amqp091.Delivery
 	rbtConfig *config.RabbitMQ
 	eventBiz  event.EventBizInterface
+	runtime   *consumer.Runtime
 }
 
 func NewEvent(l *slog.Logger, rbt *rabbit.Rabbit, rbtConfig *config.RabbitMQ, eventBiz event.EventBizInterface) *Event {
-	return &Event{
+	u := &Event{
 		rbt:       rbt,
 		logger:    l,
-		msgsQueue: make(chan amqp091.Delivery, msgsQueueLength),
 		rbtConfig: rbtConfig,
 		eventBiz:  eventBiz,
+		runtime:   consumer.New(l, runtimeConfig(rbtConfig)),
 	}
+	u.runtime.RegisterHandler(eventRoutingKey, u.createEvent)
+	return u
+}
+
+// runtimeConfig is how the messages of the event queue are handled, retried
+// and dead-lettered, with defaults for the settings left out of the config
+func runtimeConfig(rbtConfig *config.RabbitMQ) consumer.Config {
+	cfg := consumer.Config{
+		Queue:              rbtConfig.EventQueue,
+		DeadLetterExchange: rbtConfig.DeadLetterExchange,
+		MaxAttempts:        rbtConfig.MaxAttempts,
+		RetryDelay:         rbtConfig.RetryDelay,
+		RetryMax:           rbtConfig.RetryMax,
+		Settings: consumer.Settings{
+			Prefetch:   rbtConfig.Prefetch,
+			Workers:    rbtConfig.Workers,
+			MaxWorkers: rbtConfig.MaxWorkers,
+			Buffer:     rbtConfig.Buffer,
+			Timeout:    rbtConfig.Timeout,
+		},
+	}
+	if cfg.DeadLetterExchange == "" {
+		cfg.DeadLetterExchange = "farin.dead-letter"
+	}
+	if cfg.MaxAttempts <= 0 {
+		cfg.MaxAttempts = 5
+	}
+	if cfg.RetryDelay <= 0 {
+		cfg.RetryDelay = 5 * time.Second
+	}
+	if cfg.RetryMax <= 0 {
+		cfg.RetryMax = 5 * time.Minute
+	}
+	if cfg.Prefetch <= 0 {
+		cfg.Prefetch = 5
+	}
+	if cfg.Workers <= 0 {
+		cfg.Workers = 1
+	}
+	if cfg.Buffer <= 0 {
+		cfg.Buffer = 5
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 10 * time.Second
+	}
+	return cfg
 }
 
 func (u *Event) Setup() error {
-	d, err := u.rbt.Ch.Consume(u.rbtConfig.EventQueue, "", false,
-		false, false, false, nil)
+	if err := u.runtime.Declare(u.rbt.Ch); err != nil {
+		return err
+	}
+	d, err := u.runtime.Consume(u.rbt.Ch)
 	if err != nil {
 		return err
 	}
@@ -45,53 +93,28 @@ func (u *Event) Setup() error {
 }
 
 func (u *Event) Consume() {
-	for i := 0; i < workersCount; i++ {
-		go u.worker()
-	}
-	for msg := range u.delivery {
-		u.msgsQueue <- msg
-	}
+	lg := u.logger.With("method", "Consume", "queue", u.rbtConfig.EventQueue)
+	lg.Info("workers started")
+	u.runtime.RunWorkers()
+	u.runtime.Feed(u.delivery)
 }
 
-func (u *Event) worker() {
-	lg := u.logger.With("method", "worker", "queue", u.rbtConfig.EventQueue)
-	lg.Info("worker started")
-	for msg := range u.msgsQueue {
-		u.logger.Info("rabbit message received in msg queue go channel: routingKey:%s,body", msg.RoutingKey)
-		routingKey := msg.RoutingKey
-		// Check if the routing key matches a known dynamic pattern
-		if strings.HasPrefix(routingKey, "farin.vehicles.") && strings.HasSuffix(routingKey, ".event") {
-			// Split the routing key to extract dynamic parts
-			parts := strings.Split(routingKey, ".")
-			if len(parts) == 6 {
-				lprVehicleId := parts[2]
-				userId := parts[4]
-				lg.Info("Processing for event creation", "vehicle id", lprVehicleId, "user id", userId)
-				ctx, _ := context.WithTimeout(context.Background(), time.Second*10)
-				ev := v1.Event{}
-				if err := ev.UnmarshalNonFileFieldsJSON(msg.Body); err != nil {
-					lg.Error("failed to unmarshal event", "error", err)
-					return
-				}
-				if err := u.eventBiz.CreateEvent(ctx, &ev); err != nil {
-					Nack(msg, lg)
-					continue
-				}
-				if err := msg.Ack(false); err != nil {
-					lg.Error("failed to ack message", "error", err)
-				}
-				lg.Info("Acked event creation", "vehicle id", lprVehicleId, "user id", userId)
-				continue
-			}
-		}
-		Nack(msg, lg)
-		lg.Warn("rabbit message nacked", "routingKey", msg.RoutingKey)
-	}
+func (u *Event) Shutdown(ctx context.Context) error {
+	return u.runtime.Shutdown(ctx)
 }
 
-func Nack(msg amqp091.Delivery, lg *slog.Logger) {
-	if err := msg.Nack(false, true); err != nil {
-		lg.Error("failed to nack message", "error", err, "routingKey", msg.RoutingKey)
+// createEvent stores an event, malformed events are dead-lettered at once
+func (u *Event) createEvent(ctx context.Context, body []byte) error {
+	lg := u.logger.With("method", "createEvent")
+	ev := v1.Event{}
+	if err := ev.UnmarshalNonFileFieldsJSON(body); err != nil {
+		lg.Error("failed to unmarshal event", "error", err)
+		return consumer.Permanent(err)
+	}
+	if err := u.eventBiz.CreateEvent(ctx, &ev); err != nil {
+		lg.Error("failed to create event", "error", err)
+		return err
 	}
-	time.Sleep(10 * time.Millisecond) // cool down
+	lg.Info("event created")
+	return nil
 }
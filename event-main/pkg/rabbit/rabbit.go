@@ -2,6 +2,7 @@ package rabbit
 
 import (
 	"errors"
+	"fmt"
 	"git.abanppc.com/farin-project/event/config"
 	"github.com/rabbitmq/amqp091-go"
 )
@@ -35,6 +36,23 @@ func (r *Rabbit) Setup() error {
 	return nil
 }
 
+// Close closes the channel, which returns its unacked deliveries to the
+// queues, then the connection
+func (r *Rabbit) Close() error {
+	var errs []error
+	if r.Ch != nil && !r.Ch.IsClosed() {
+		if err := r.Ch.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("failed to close channel: %w", err))
+		}
+	}
+	if r.Conn != nil && !r.Conn.IsClosed() {
+		if err := r.Conn.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("failed to close connection: %w", err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
 // HealthCheck checks if RabbitMQ is ready by performing a basic ping-like operation
 func (r *Rabbit) HealthCheck() error {
 	// Check if the connection is open
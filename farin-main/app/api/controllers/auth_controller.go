@@ -94,6 +94,7 @@ func (ac AuthController) Login(c *gin.Context) {
 // @Success 200 {object} response.Response[dto.DriverLoginResponse]
 // @failure 422 {object} response.Response[swagger.EmptyObject]
 // @failure 401 {object} response.Response[swagger.EmptyObject]
+// @failure 403 {object} response.Response[swagger.EmptyObject]
 // @Router /auth/driver-login/ [post]
 func (ac AuthController) DriverLogin(c *gin.Context) {
 	// Create a context with a timeout of 5 seconds
@@ -127,13 +128,31 @@ func (ac AuthController) DriverLogin(c *gin.Context) {
 			response.BadRequest(c, "your login credentials is incorrect")
 			return
 		}
+		if errors.Is(err, service.ErrVehicleAssignedToOther) {
+			response.Custom(c, http.StatusForbidden, nil, "vehicle is assigned to another driver")
+			return
+		}
+		if errors.Is(err, service.ErrHoliday) {
+			response.Custom(c, http.StatusForbidden, nil, "login is not allowed on holidays")
+			return
+		}
+		if errors.Is(err, service.ErrOutsideShift) {
+			response.Custom(c, http.StatusForbidden, nil, "login is not allowed outside of your work shift")
+			return
+		}
+		if errors.Is(err, service.ErrCalenderTimeInvalid) {
+			response.Custom(c, http.StatusForbidden, nil, "work shift of your calender is invalid")
+			return
+		}
 		response.InternalError(c)
 		return
 	}
 
 	var loginResult dto.DriverLoginResponse
 	var contractorResponse dto.ContractorResponse
-	contractorResponse.FromEntity(contractor, ac.env)
+	if contractor != nil {
+		contractorResponse.FromEntity(contractor, ac.env)
+	}
 	var calenderResponse dto.CalenderResponse
 	calenderResponse.FromEntity(&da.Calender)
 	var vehicleResponse dto.VehicleResponse
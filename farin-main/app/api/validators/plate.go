@@ -0,0 +1,21 @@
+package validators
+
+import (
+	"git.abanppc.com/farin-project/vehicle-records/util/plate"
+	"github.com/go-playground/validator/v10"
+)
+
+type PlateValidator struct {
+}
+
+func NewPlateValidator() PlateValidator {
+	return PlateValidator{}
+}
+
+// plate validator, accepts the plates plate.Parse can read
+func (pv PlateValidator) Handler() func(fl validator.FieldLevel) bool {
+	return func(fl validator.FieldLevel) bool {
+		_, err := plate.Parse(fl.Field().String())
+		return err == nil
+	}
+}
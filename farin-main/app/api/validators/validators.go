@@ -7,21 +7,25 @@ import (
 	"github.com/google/wire"
 )
 
-var ProviderSet = wire.NewSet(gorm.NewFkValidator, NewTimestampValidator, gorm.NewUniqueValidator, NewFileValidator)
+var ProviderSet = wire.NewSet(gorm.NewFkValidator, NewTimestampValidator, gorm.NewUniqueValidator, NewFileValidator,
+	NewPlateValidator)
 
 type Validators struct {
 	uv   gorm.UniqueValidator
 	fkv  gorm.FkValidator
 	ts   TimestampValidator
 	file FileValidator
+	pl   PlateValidator
 }
 
-func NewValidators(uv gorm.UniqueValidator, fkv gorm.FkValidator, ts TimestampValidator, file FileValidator) Validators {
+func NewValidators(uv gorm.UniqueValidator, fkv gorm.FkValidator, ts TimestampValidator, file FileValidator,
+	pl PlateValidator) Validators {
 	return Validators{
 		uv:   uv,
 		fkv:  fkv,
 		ts:   ts,
 		file: file,
+		pl:   pl,
 	}
 }
 
@@ -32,5 +36,6 @@ func (val Validators) Setup() {
 		v.RegisterValidation("fkGorm", val.fkv.Handler())
 		v.RegisterValidation("timestamp", val.ts.Handler())
 		v.RegisterValidation("fileData", val.file.Handler())
+		v.RegisterValidation("plate", val.pl.Handler())
 	}
 }
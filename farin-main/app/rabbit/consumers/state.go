@@ -0,0 +1,69 @@
+package consumers
+
+import (
+	"context"
+	"farin/infrastructure/godotenv"
+	"git.abanppc.com/farin-project/vehicle-records/util/consumer"
+	"github.com/rabbitmq/amqp091-go"
+	"log/slog"
+)
+
+// StateConsumer consumes LPR vehicle states on its own queue, next to the
+// state service, to follow the patrol of the vehicles
+type StateConsumer struct {
+	q        *amqp091.Queue
+	delivery <-chan amqp091.Delivery
+	logger   *slog.Logger
+	runtime  *consumer.Runtime
+	env      *godotenv.Env
+}
+
+func NewStateConsumer(l *slog.Logger, env *godotenv.Env) *StateConsumer {
+	return &StateConsumer{logger: l.With("layer", "StateConsumer"), env: env,
+		runtime: consumer.New(l, runtimeConfig(env, env.RabbitMQPatrolStateQueue, env.PatrolStateConsumer))}
+}
+
+func (c *StateConsumer) RunInnerWorkers() {
+	c.runtime.RunWorkers()
+}
+
+func (c *StateConsumer) Setup(ch *amqp091.Channel) error {
+	q, err := ch.QueueDeclare(c.env.RabbitMQPatrolStateQueue, true, false,
+		false, false, c.env.PatrolStateConsumer.QueueArgs(amqp091.Table{
+			"x-queue-type": "quorum",
+		}))
+	if err != nil {
+		return err
+	}
+	if err := ch.QueueBind(q.Name, "farin.vehicles.drivers.state", c.env.RabbitMQEventExchange, false, nil); err != nil {
+		return err
+	}
+	if err := c.runtime.Declare(ch); err != nil {
+		return err
+	}
+
+	c.q = &q
+	d, err := c.runtime.Consume(ch)
+	if err != nil {
+		return err
+	}
+	c.delivery = d
+
+	return nil
+}
+
+func (c *StateConsumer) RegisterHandler(routingKey string, handler HandlerFunc) {
+	c.runtime.RegisterHandler(routingKey, handler)
+}
+
+func (c *StateConsumer) Worker() {
+	c.runtime.Feed(c.delivery)
+}
+
+func (c *StateConsumer) Shutdown(ctx context.Context) error {
+	return c.runtime.Shutdown(ctx)
+}
+
+func (c *StateConsumer) Draining() bool {
+	return c.runtime.Draining()
+}
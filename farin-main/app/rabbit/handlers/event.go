@@ -6,6 +6,7 @@ import (
 	"farin/domain/service"
 	"farin/infrastructure/opentelemetry"
 	"git.abanppc.com/farin-project/vehicle-records/domain/entity"
+	"git.abanppc.com/farin-project/vehicle-records/util/consumer"
 	"go.opentelemetry.io/otel/metric"
 	"log/slog"
 	"time"
@@ -48,7 +49,7 @@ func (a *VehicleRecord) VehicleRecord(ctx context.Context, data []byte) error {
 	if err := vr.UnmarshalJSON(data); err != nil {
 		lg.Warn("failed to unmarshal vehicle record", "error", err)
 		a.failCounter.Add(ctx, 1)
-		return nil
+		return consumer.Permanent(err)
 	}
 	vr, err := a.vs.CreateRecord(ctx, vr)
 	if err != nil {
@@ -0,0 +1,46 @@
+package handlers
+
+import (
+	"context"
+	"farin/app/rabbit/consumers"
+	"farin/domain/entity"
+	"farin/domain/service"
+	"git.abanppc.com/farin-project/vehicle-records/util/consumer"
+	"log/slog"
+	"time"
+)
+
+type PatrolState struct {
+	logger *slog.Logger
+	pcs    *service.PatrolCycleService
+	mms    *service.MapMatchService
+}
+
+func NewPatrolState(logger *slog.Logger, pcs *service.PatrolCycleService, mms *service.MapMatchService) *PatrolState {
+	return &PatrolState{logger: logger.With("layer", "RabbitStateHandler"), pcs: pcs, mms: mms}
+}
+
+func (a *PatrolState) State(ctx context.Context, data []byte) error {
+	lg := a.logger.With("method", "State")
+
+	ctx, cancel := context.WithTimeout(ctx, time.Second*30)
+	defer cancel()
+	st := &entity.State{}
+	if err := st.UnmarshalJSON(data); err != nil {
+		lg.Warn("failed to unmarshal state", "error", err)
+		return consumer.Permanent(err)
+	}
+	if err := a.mms.Observe(ctx, st.LPRVehicleID, st.LPRVehicleGPSLongitude, st.LPRVehicleGPSLatitude,
+		st.RecordStoreTime); err != nil {
+		lg.Warn("failed to add trail point", "error", err)
+	}
+	if err := a.pcs.TrackState(ctx, st); err != nil {
+		lg.Warn("failed to track patrol cycle", "error", err)
+		return err
+	}
+	return nil
+}
+
+func (a *PatrolState) RegisterConsumer(c consumers.Consumer) {
+	c.RegisterHandler("farin.vehicles.drivers.state", a.State)
+}
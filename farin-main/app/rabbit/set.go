@@ -6,14 +6,16 @@ import (
 	"github.com/google/wire"
 )
 
-func Consumers(ec *consumers.EventConsumer) []consumers.Consumer {
+func Consumers(ec *consumers.EventConsumer, sc *consumers.StateConsumer) []consumers.Consumer {
 	return []consumers.Consumer{
-		ec,
+		ec, sc,
 	}
 }
 
 var ProviderSet = wire.NewSet(
 	handlers.NewVehicleRecord,
 	consumers.NewEventConsumer,
+	handlers.NewPatrolState,
+	consumers.NewStateConsumer,
 	Consumers,
 )
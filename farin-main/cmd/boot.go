@@ -1,38 +1,56 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"farin/app/api/routes"
 	"farin/app/api/validators"
 	"farin/app/rabbit/consumers"
 	"farin/app/rabbit/handlers"
 	"farin/docs"
 	_ "farin/docs"
+	"farin/domain/service"
 	"farin/infrastructure/godotenv"
 	"farin/infrastructure/rabbit"
 	"github.com/gin-gonic/gin"
 	swaggerfiles "github.com/swaggo/files"
 	ginSwagger "github.com/swaggo/gin-swagger"
 	"log"
+	"net/http"
+	"os/signal"
+	"syscall"
 	"time"
 )
 
+// httpShutdownTimeout is the time the api has to finish its requests after
+// the consumers drained
+const httpShutdownTimeout = 5 * time.Second
+
 type Boot struct {
 	rts           []routes.Router
 	validators    validators.Validators
 	eventConsumer *consumers.EventConsumer
 	event         *handlers.VehicleRecord
+	stateConsumer *consumers.StateConsumer
+	state         *handlers.PatrolState
 	rbt           *rabbit.Rabbit
 	cr            *rabbit.ConsumerRunner
+	gc            *service.GeometryCache
 }
 
 func NewBoot(validators validators.Validators, event *handlers.VehicleRecord, eventConsumer *consumers.EventConsumer,
-	cr *rabbit.ConsumerRunner, rbt *rabbit.Rabbit, rts ...routes.Router) *Boot {
-	return &Boot{rts: rts, validators: validators, event: event, eventConsumer: eventConsumer, cr: cr, rbt: rbt}
+	state *handlers.PatrolState, stateConsumer *consumers.StateConsumer,
+	cr *rabbit.ConsumerRunner, rbt *rabbit.Rabbit, gc *service.GeometryCache, rts ...routes.Router) *Boot {
+	return &Boot{rts: rts, validators: validators, event: event, eventConsumer: eventConsumer,
+		state: state, stateConsumer: stateConsumer, cr: cr, rbt: rbt, gc: gc}
 }
 
 func (b *Boot) Boot() {
 	env := godotenv.NewEnv()
 	env.Load()
+	// cancelled on SIGTERM, which starts the drain
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
 	r := gin.Default()
 
 	b.validators.Setup()
@@ -41,7 +59,11 @@ func (b *Boot) Boot() {
 	}
 	docs.SwaggerInfo.BasePath = "/api"
 
+	go b.gc.RunReloader(ctx)
+	go b.gc.RunListener(ctx)
+
 	b.event.RegisterConsumer(b.eventConsumer)
+	b.state.RegisterConsumer(b.stateConsumer)
 	go func() {
 		if err := b.rbt.Setup(b.cr); err != nil {
 			log.Fatalf("failed to setup rabbitmq:%s", err)
@@ -51,7 +73,34 @@ func (b *Boot) Boot() {
 	b.cr.RunInnerWorkers()
 
 	r.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerfiles.Handler))
-	if err := r.Run(":" + env.HTTPPort); err != nil {
-		log.Fatalf("error running gin grpcServer error:%s", err.Error())
+	srv := &http.Server{Addr: ":" + env.HTTPPort, Handler: r}
+	go func() {
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatalf("error running gin grpcServer error:%s", err.Error())
+		}
+	}()
+
+	<-ctx.Done()
+	b.shutdown(env, srv)
+}
+
+// shutdown drains the consumers while the api keeps answering with a failing
+// readiness, then closes rabbitmq and the api last
+func (b *Boot) shutdown(env *godotenv.Env, srv *http.Server) {
+	log.Printf("shutting down, draining consumers for %s", env.ShutdownTimeout)
+
+	ctx, cancel := context.WithTimeout(context.Background(), env.ShutdownTimeout)
+	defer cancel()
+	if err := b.cr.Shutdown(ctx); err != nil {
+		log.Printf("failed to drain consumers:%s", err)
+	}
+	if err := b.rbt.Close(); err != nil {
+		log.Printf("failed to close rabbitmq:%s", err)
+	}
+
+	httpCtx, httpCancel := context.WithTimeout(context.Background(), httpShutdownTimeout)
+	defer httpCancel()
+	if err := srv.Shutdown(httpCtx); err != nil {
+		log.Printf("failed to shutdown http server:%s", err)
 	}
 }
@@ -36,16 +36,25 @@ func wireApp(logger *slog.Logger, rabbit3 *rabbit.Rabbit, minio2 *minio.Minio, g
 	fkValidator := gorm.NewFkValidator(gorm2)
 	timestampValidator := validators.NewTimestampValidator()
 	fileValidator := validators.NewFileValidator(logger)
-	validatorsValidators := validators.NewValidators(uniqueValidator, fkValidator, timestampValidator, fileValidator)
+	plateValidator := validators.NewPlateValidator()
+	validatorsValidators := validators.NewValidators(uniqueValidator, fkValidator, timestampValidator, fileValidator, plateValidator)
 	vehicleRecordRepository := repository.NewVehicleRecordRepository(gorm2)
 	minIOFileRepository := minio.NewMinIOFileRepository(minio2)
 	env := godotenv.NewEnv()
 	citizenVehiclePhotoRepository := repository.NewCitizenVehiclePhotoRepository(gorm2)
 	eventRecordRabbitMQ := repository.NewEventRecordRabbitMQ(rabbit3, env)
-	vehicleRecordService := service.NewVehicleRecordService(logger, vehicleRecordRepository, minIOFileRepository, env, citizenVehiclePhotoRepository, ot, eventRecordRabbitMQ)
+	patrolCycleRepository := repository.NewPatrolCycleRepository(gorm2)
+	patrolCycleService := service.NewPatrolCycleService(logger, patrolCycleRepository, env)
+	mapMatchRepository := repository.NewMapMatchRepository(gorm2)
+	geometryRepository := repository.NewGeometryRepository(gorm2)
+	geometryCache := service.NewGeometryCache(logger, geometryRepository, env, ot)
+	mapMatchService := service.NewMapMatchService(logger, mapMatchRepository, geometryCache, env)
+	vehicleRecordService := service.NewVehicleRecordService(logger, vehicleRecordRepository, minIOFileRepository, env, citizenVehiclePhotoRepository, ot, eventRecordRabbitMQ, patrolCycleService, mapMatchService)
 	vehicleRecord := handlers.NewVehicleRecord(logger, vehicleRecordService, ot)
 	eventConsumer := consumers.NewEventConsumer(logger, env)
-	v := rabbit2.Consumers(eventConsumer)
+	patrolState := handlers.NewPatrolState(logger, patrolCycleService, mapMatchService)
+	stateConsumer := consumers.NewStateConsumer(logger, env)
+	v := rabbit2.Consumers(eventConsumer, stateConsumer)
 	consumerRunner := rabbit.NewConsumerRunner(logger, v...)
 	userRepository := repository.NewUserRepository(gorm2)
 	driverRepository := repository.NewDriverRepository(gorm2)
@@ -53,7 +62,8 @@ func wireApp(logger *slog.Logger, rabbit3 *rabbit.Rabbit, minio2 *minio.Minio, g
 	deviceRepository := repository.NewDeviceRepository(gorm2)
 	driverAssignmentRepository := repository.NewDriverAssignmentRepository(gorm2)
 	contractorRepository := repository.NewContractorRepository(gorm2)
-	authService := service.NewAuthService(env, logger, userRepository, driverRepository, vehicleRepository, deviceRepository, driverAssignmentRepository, contractorRepository)
+	driverLoginAttemptRepository := repository.NewDriverLoginAttemptRepository(gorm2)
+	authService := service.NewAuthService(env, logger, userRepository, driverRepository, vehicleRepository, deviceRepository, driverAssignmentRepository, contractorRepository, driverLoginAttemptRepository)
 	userService := service.NewUserService(logger, userRepository, minIOFileRepository, env)
 	authController := controller.NewAuthController(env, authService, userService, logger)
 	authMiddleware := middleware.NewAuthMiddleware(logger, env, userRepository)
@@ -80,12 +90,12 @@ func wireApp(logger *slog.Logger, rabbit3 *rabbit.Rabbit, minio2 *minio.Minio, g
 	calenderService := service.NewCalenderService(logger, calenderRepository, minIOFileRepository, env)
 	calenderController := controller.NewCalenderController(logger, calenderService, env)
 	ringRepository := repository.NewRingRepository(gorm2)
-	ringService := service.NewRingService(logger, ringRepository, minIOFileRepository, env)
+	ringService := service.NewRingService(logger, ringRepository, minIOFileRepository, env, geometryCache)
 	ringController := controller.NewRingController(logger, ringService, env)
 	driverAssignmentService := service.NewDriverAssignmentService(logger, driverAssignmentRepository, minIOFileRepository, env)
 	driverAssignmentController := controller.NewDriverAssignmentController(logger, driverAssignmentService, env)
 	userRouter := routes.NewUserRouter(userController, authMiddleware, contractController, contractorController, adminMiddleware, driverController, vehicleController, deviceController, roleController, calenderController, ringController, driverAssignmentController)
 	v2 := routes.CreateRouters(authRouter, healthRouter, userRouter)
-	boot := NewBoot(validatorsValidators, vehicleRecord, eventConsumer, consumerRunner, rabbit3, v2...)
+	boot := NewBoot(validatorsValidators, vehicleRecord, eventConsumer, patrolState, stateConsumer, consumerRunner, rabbit3, geometryCache, v2...)
 	return boot, nil
 }
@@ -8,7 +8,7 @@ type VehicleRequest struct {
 	VehicleID                 int64  `json:"vehicleID" binding:"required,min=1,max=120"`
 	CodeVehicle               string `json:"codeVehicle" binding:"required"`
 	VIN                       string `json:"vin" binding:"required,min=1,max=120"`
-	PlateLicense              string `json:"plateLicense" binding:"required,min=1,max=115"`
+	PlateLicense              string `json:"plateLicense" binding:"required,min=1,max=115,plate"`
 	TypeVehicle               string `json:"typeVehicle,omitempty"`
 	Brand                     string `json:"brand,omitempty"`
 	Model                     string `json:"model,omitempty"`
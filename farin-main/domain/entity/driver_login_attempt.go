@@ -0,0 +1,30 @@
+package entity
+
+type DriverLoginResult string
+
+const (
+	DriverLoginAccepted        DriverLoginResult = "accepted"
+	DriverLoginNoAssignment    DriverLoginResult = "no_assignment"
+	DriverLoginAssignedToOther DriverLoginResult = "assigned_to_other"
+	DriverLoginHoliday         DriverLoginResult = "holiday"
+	DriverLoginOutsideShift    DriverLoginResult = "outside_shift"
+	DriverLoginInvalidCalender DriverLoginResult = "invalid_calender"
+)
+
+// DriverLoginAttempt is a login of a driver into a vehicle and whether it was
+// accepted, for the SLA reports of the contractors. The shift is the one of
+// the assignment of the driver, ShiftStart and ShiftEnd are unix seconds.
+type DriverLoginAttempt struct {
+	Base
+	UserID       string            `gorm:"type:uuid;not null" json:"userId"`
+	DriverID     string            `gorm:"type:uuid;not null;index" json:"driverId"`
+	ContractorID *string           `gorm:"type:uuid;index" json:"contractorId"`
+	CodeVehicle  string            `gorm:"type:varchar(150);not null" json:"codeVehicle"`
+	AssignmentID *string           `gorm:"type:uuid" json:"assignmentId"`
+	CalenderID   *string           `gorm:"type:uuid" json:"calenderId"`
+	WorkShift    WorkShift         `gorm:"type:varchar(17)" json:"workShift"`
+	ShiftStart   int64             `json:"shiftStart"`
+	ShiftEnd     int64             `json:"shiftEnd"`
+	Accepted     bool              `gorm:"not null" json:"accepted"`
+	Result       DriverLoginResult `gorm:"type:varchar(20);not null" json:"result"`
+}
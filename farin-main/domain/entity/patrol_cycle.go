@@ -0,0 +1,26 @@
+package entity
+
+type PatrolCycleStatus string
+
+const (
+	PatrolCycleOpen      PatrolCycleStatus = "open"
+	PatrolCycleCompleted PatrolCycleStatus = "completed"
+	PatrolCycleAbandoned PatrolCycleStatus = "abandoned"
+)
+
+// PatrolCycle is one lap of an LPR vehicle around its assigned ring.
+// Progress is the signed share of the ring outline travelled since the lap
+// started and LastPosition is the last position of the vehicle as a fraction
+// of the ring outline.
+type PatrolCycle struct {
+	Base
+	LPRVehicleID string            `gorm:"type:uuid;not null;index" json:"lprVehicleId"`
+	RingID       int64             `gorm:"type:bigint;not null" json:"ringId"`
+	CycleNumber  int               `gorm:"type:integer;not null" json:"cycleNumber"`
+	Status       PatrolCycleStatus `gorm:"type:varchar(20);not null" json:"status"`
+	Progress     float64           `gorm:"type:double precision;not null" json:"progress"`
+	LastPosition float64           `gorm:"type:double precision;not null" json:"lastPosition"`
+	StartTime    int64             `gorm:"not null" json:"startTime"`
+	LastSeenTime int64             `gorm:"not null" json:"lastSeenTime"`
+	EndTime      int64             `json:"endTime"`
+}
@@ -0,0 +1,29 @@
+package entity
+
+import "github.com/valyala/fastjson"
+
+// State is the GPS position an LPR vehicle reports on the state stream, only
+// the fields needed to follow the vehicle patrol are kept
+type State struct {
+	LPRVehicleID           string
+	LPRVehicleGPSLatitude  float64
+	LPRVehicleGPSLongitude float64
+	LPRVehicleGPSError     int
+	RecordStoreTime        int64
+}
+
+func (s *State) UnmarshalJSON(data []byte) error {
+	var p fastjson.Parser
+	v, err := p.ParseBytes(data)
+	if err != nil {
+		return err
+	}
+
+	s.LPRVehicleID = string(v.GetStringBytes("LPRVehicleId"))
+	s.LPRVehicleGPSLatitude = v.GetFloat64("LPRVehicleGPSLatitude")
+	s.LPRVehicleGPSLongitude = v.GetFloat64("LPRVehicleGPSLongitude")
+	s.LPRVehicleGPSError = v.GetInt("LPRVehicleGPSError")
+	s.RecordStoreTime = v.GetInt64("RecordStoreTime")
+
+	return nil
+}
@@ -92,3 +92,38 @@ func (r *DriverAssignmentRepository) GetByFields(ctx context.Context, driverID,
 	}
 	return &driverAssignment, nil
 }
+
+// GetOfDay finds the assignment of a driver to a vehicle whose calender falls
+// in [from, to), in unix seconds
+func (r *DriverAssignmentRepository) GetOfDay(ctx context.Context, driverID, vehicleCode string, from, to int64) (
+	*entity.DriverAssignment, error) {
+	var driverAssignment entity.DriverAssignment
+	if err := r.DB.DB.WithContext(ctx).Preload("Driver").Preload("Vehicle").Preload("Ring").Preload("Calender").
+		Joins("JOIN calenders ON calenders.id = driver_assignments.calender_id").
+		Where("driver_assignments.driver_id = ?", driverID).
+		Where("driver_assignments.code_vehicle = ?", vehicleCode).
+		Where("calenders.work_date >= ? AND calenders.work_date < ?", from, to).
+		First(&driverAssignment).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrDriverAssignmentNotFound
+		}
+		return nil, err
+	}
+	return &driverAssignment, nil
+}
+
+// OthersOfDay lists the assignments of a vehicle to drivers other than
+// driverID whose calender falls in [from, to), in unix seconds
+func (r *DriverAssignmentRepository) OthersOfDay(ctx context.Context, driverID, vehicleCode string, from, to int64) (
+	[]entity.DriverAssignment, error) {
+	var driverAssignments []entity.DriverAssignment
+	if err := r.DB.DB.WithContext(ctx).Preload("Calender").
+		Joins("JOIN calenders ON calenders.id = driver_assignments.calender_id").
+		Where("driver_assignments.driver_id <> ?", driverID).
+		Where("driver_assignments.code_vehicle = ?", vehicleCode).
+		Where("calenders.work_date >= ? AND calenders.work_date < ?", from, to).
+		Find(&driverAssignments).Error; err != nil {
+		return nil, err
+	}
+	return driverAssignments, nil
+}
@@ -0,0 +1,23 @@
+package repository
+
+import (
+	"context"
+	"farin/domain/entity"
+	gormdb "farin/infrastructure/gorm"
+	"fmt"
+)
+
+type DriverLoginAttemptRepository struct {
+	DB *gormdb.GORMDB
+}
+
+func NewDriverLoginAttemptRepository(db *gormdb.GORMDB) *DriverLoginAttemptRepository {
+	return &DriverLoginAttemptRepository{DB: db}
+}
+
+func (r *DriverLoginAttemptRepository) Create(ctx context.Context, attempt *entity.DriverLoginAttempt) error {
+	if err := r.DB.DB.WithContext(ctx).Create(attempt).Error; err != nil {
+		return fmt.Errorf("failed to create driver login attempt: %w", err)
+	}
+	return nil
+}
@@ -18,15 +18,18 @@ func NewEventRecordRabbitMQ(rbt *rabbit.Rabbit, env *godotenv.Env) *EventRecordR
 	return &EventRecordRabbitMQ{rbt: rbt, env: env}
 }
 
+// Store publishes a record for vehicle-records, it returns once rabbitmq
+// confirmed the record is stored
 func (er EventRecordRabbitMQ) Store(ctx context.Context, record *entity.VehicleRecord) error {
 	b, err := json.Marshal(&record)
 	if err != nil {
 		return err
 	}
-	if err := er.rbt.Ch.PublishWithContext(ctx, er.env.RabbitMQInternalExchange, "farin.vehicles.drivers.event",
-		false, false, amqp091.Publishing{
-			ContentType: "application/json",
-			Body:        b,
+	if err := er.rbt.Publish(ctx, er.env.RabbitMQInternalExchange, "farin.vehicles.drivers.event",
+		amqp091.Publishing{
+			ContentType:  "application/json",
+			DeliveryMode: amqp091.Persistent,
+			Body:         b,
 		}); err != nil {
 		return err
 	}
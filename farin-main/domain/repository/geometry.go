@@ -0,0 +1,116 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	gormdb "farin/infrastructure/gorm"
+	"fmt"
+	"github.com/jackc/pgx/v5/stdlib"
+)
+
+// GeometryLayer is a table of geometries records are located with
+type GeometryLayer string
+
+const (
+	GeometryRings    GeometryLayer = "rings"
+	GeometrySegments GeometryLayer = "segments"
+	GeometryRoads    GeometryLayer = "roads"
+	GeometryParkings GeometryLayer = "parkings"
+)
+
+// GeometryLayers lists every layer
+var GeometryLayers = []GeometryLayer{GeometryRings, GeometrySegments, GeometryRoads, GeometryParkings}
+
+// GeometryRow is a geometry as WKB in its stored coordinates, Junction is only
+// set for segments and RoadCode for roads
+type GeometryRow struct {
+	ID       int64
+	Geom     []byte
+	Junction int8
+	RoadCode int64
+}
+
+type GeometryRepository struct {
+	DB *gormdb.GORMDB
+}
+
+func NewGeometryRepository(db *gormdb.GORMDB) *GeometryRepository {
+	return &GeometryRepository{DB: db}
+}
+
+// Load reads the geometries of a layer that are not deleted
+func (r *GeometryRepository) Load(ctx context.Context, layer GeometryLayer) ([]GeometryRow, error) {
+	columns := "0 AS junction, 0 AS road_code"
+	switch layer {
+	case GeometrySegments:
+		columns = "COALESCE(junction, 0) AS junction, 0 AS road_code"
+	case GeometryRoads:
+		columns = "0 AS junction, road_code"
+	}
+	var rows []GeometryRow
+	err := r.DB.DB.WithContext(ctx).Raw(fmt.Sprintf(
+		`SELECT id, ST_AsBinary(ST_Force2D(geom)) AS geom, %s FROM public.%s
+        WHERE geom IS NOT NULL AND COALESCE(deleted_at, 0) = 0`, columns, layer)).Scan(&rows).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to load %s geometries: %w", layer, err)
+	}
+	return rows, nil
+}
+
+// Checksum hashes the ids and geometries of every layer, it changes whenever
+// a geometry is added, changed or deleted
+func (r *GeometryRepository) Checksum(ctx context.Context) (string, error) {
+	var checksum string
+	err := r.DB.DB.WithContext(ctx).Raw(
+		`SELECT md5(
+            (SELECT COALESCE(string_agg(id || ':' || md5(ST_AsBinary(geom)), ',' ORDER BY id), '')
+                FROM public.rings WHERE COALESCE(deleted_at, 0) = 0) || '|' ||
+            (SELECT COALESCE(string_agg(id || ':' || md5(ST_AsBinary(geom)) || ':' || COALESCE(junction, 0), ',' ORDER BY id), '')
+                FROM public.segments WHERE COALESCE(deleted_at, 0) = 0) || '|' ||
+            (SELECT COALESCE(string_agg(id || ':' || md5(ST_AsBinary(geom)) || ':' || road_code, ',' ORDER BY id), '')
+                FROM public.roads WHERE COALESCE(deleted_at, 0) = 0) || '|' ||
+            (SELECT COALESCE(string_agg(id || ':' || md5(ST_AsBinary(geom)), ',' ORDER BY id), '')
+                FROM public.parkings WHERE COALESCE(deleted_at, 0) = 0)
+        )`).Scan(&checksum).Error
+	if err != nil {
+		return "", fmt.Errorf("failed to checksum geometries: %w", err)
+	}
+	return checksum, nil
+}
+
+// GeometryChannel is notified with the table name by the triggers of every
+// layer, whichever service writes it
+const GeometryChannel = "geometry_changed"
+
+// Listen calls changed with the layer of every notification on
+// GeometryChannel until ctx is done or the connection fails. It holds a
+// connection of the pool meanwhile.
+func (r *GeometryRepository) Listen(ctx context.Context, changed func(layer GeometryLayer)) error {
+	sqlDB, err := r.DB.DB.DB()
+	if err != nil {
+		return fmt.Errorf("failed to get sqlDB from gorm: %w", err)
+	}
+	conn, err := sqlDB.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get a connection: %w", err)
+	}
+	defer conn.Close()
+
+	return conn.Raw(func(driverConn any) error {
+		sc, ok := driverConn.(*stdlib.Conn)
+		if !ok {
+			return errors.New("database driver is not pgx")
+		}
+		pc := sc.Conn()
+		if _, err := pc.Exec(ctx, "LISTEN "+GeometryChannel); err != nil {
+			return fmt.Errorf("failed to listen on %s: %w", GeometryChannel, err)
+		}
+		for {
+			n, err := pc.WaitForNotification(ctx)
+			if err != nil {
+				return fmt.Errorf("failed to wait for geometry notifications: %w", err)
+			}
+			changed(GeometryLayer(n.Payload))
+		}
+	})
+}
@@ -0,0 +1,154 @@
+package repository
+
+import (
+	"context"
+	gormdb "farin/infrastructure/gorm"
+	"farin/util/geo"
+	"fmt"
+)
+
+type MapMatchRepository struct {
+	DB *gormdb.GORMDB
+}
+
+func NewMapMatchRepository(db *gormdb.GORMDB) *MapMatchRepository {
+	return &MapMatchRepository{DB: db}
+}
+
+// MapMatchQuery is a position of an LPR vehicle to match, Radius is in meters
+// and the trail of the vehicle is read from TrailSince to At
+type MapMatchQuery struct {
+	LPRVehicleID string
+	Lon          float64
+	Lat          float64
+	Radius       float64
+	Candidates   int
+	At           int64
+	TrailSince   int64
+}
+
+// MapMatchCandidate is a segment near a position. Distance is in meters,
+// Bearing is the direction of the segment at the closest point in degrees
+// from north and TrailDistance the mean distance of the trail points from the
+// segment, both are nil when unknown. Ring, street and parking lot are the
+// nearest to the closest point of the segment. The candidate with a zero
+// SegmentID holds the ring, street and parking lot of the position itself.
+type MapMatchCandidate struct {
+	SegmentID     int64
+	Junction      int8
+	Distance      float64
+	Bearing       *float64
+	TrailDistance *float64
+	TrailPoints   int
+	RingID        int64
+	StreetID      int64
+	RoadCode      int64
+	ParkingLotID  int64
+}
+
+// candidates lists the segments within @radius of the position nearest first,
+// with a row for the position itself, then snaps the position on every
+// segment to find the ring, road and parking lot around it
+const mapMatchCandidates = `WITH pt AS (SELECT ST_Transform(ST_SetSRID(ST_MakePoint(@lon, @lat), 4326), 32639) AS geom),
+    trail AS (
+        SELECT geom FROM vehicle_trail_points
+        WHERE lpr_vehicle_id = CAST(NULLIF(@vehicle, '') AS uuid) AND recorded_at >= @since AND recorded_at <= @at
+    ),
+    candidates AS (
+        SELECT s.id, COALESCE(s.junction, 0) AS junction, s.geom, ST_Distance(s.geom, pt.geom) AS distance,
+            CASE WHEN ST_Dimension(s.geom) = 1 THEN ST_GeometryN(ST_LineMerge(s.geom), 1) END AS line
+        FROM public.segments s, pt
+        WHERE COALESCE(s.deleted_at, 0) = 0 AND ST_DWithin(s.geom, pt.geom, @radius)
+        ORDER BY s.geom <-> pt.geom
+        LIMIT @candidates
+    ),
+    snapped AS (
+        SELECT c.id, c.junction, c.geom AS shape, c.distance, c.line,
+            ST_LineLocatePoint(c.line, pt.geom) AS loc,
+            LEAST(@span / NULLIF(ST_Length(c.line), 0), 0.5) AS span,
+            ST_ClosestPoint(c.geom, pt.geom) AS geom
+        FROM candidates c, pt
+        UNION ALL
+        SELECT 0, 0, pt.geom, 0, NULL, NULL, NULL, pt.geom FROM pt
+    )
+SELECT sn.id AS segment_id, sn.junction, sn.distance,
+    degrees(ST_Azimuth(ST_LineInterpolatePoint(sn.line, GREATEST(sn.loc - sn.span, 0)),
+        ST_LineInterpolatePoint(sn.line, LEAST(sn.loc + sn.span, 1)))) AS bearing,
+    (SELECT avg(ST_Distance(sn.shape, t.geom)) FROM trail t) AS trail_distance,
+    (SELECT count(*) FROM trail) AS trail_points,
+    COALESCE(ring.id, 0) AS ring_id,
+    COALESCE(road.id, 0) AS street_id, COALESCE(road.road_code, 0) AS road_code,
+    COALESCE(parking.id, 0) AS parking_lot_id
+FROM snapped sn
+LEFT JOIN LATERAL (
+    SELECT r.id FROM public.rings r
+    WHERE COALESCE(r.deleted_at, 0) = 0 AND ST_DWithin(r.geom, sn.geom, @radius)
+    ORDER BY r.geom <-> sn.geom LIMIT 1
+) ring ON true
+LEFT JOIN LATERAL (
+    SELECT rd.id, rd.road_code FROM public.roads rd
+    WHERE COALESCE(rd.deleted_at, 0) = 0 AND ST_DWithin(rd.geom, sn.geom, @radius)
+    ORDER BY rd.geom <-> sn.geom LIMIT 1
+) road ON true
+LEFT JOIN LATERAL (
+    SELECT p.id FROM public.parkings p
+    WHERE COALESCE(p.deleted_at, 0) = 0 AND ST_DWithin(p.geom, sn.geom, @radius)
+    ORDER BY p.geom <-> sn.geom LIMIT 1
+) parking ON true
+ORDER BY sn.distance`
+
+// BearingSpan is the length in meters of the piece of a segment its bearing
+// is measured on
+const BearingSpan = 5
+
+// Candidates finds the segments a position may be on in one query, nearest
+// first, followed by the candidate of the position itself
+func (r *MapMatchRepository) Candidates(ctx context.Context, q MapMatchQuery) ([]MapMatchCandidate, error) {
+	var candidates []MapMatchCandidate
+	err := r.DB.DB.WithContext(ctx).Raw(mapMatchCandidates, map[string]interface{}{
+		"lon":        q.Lon,
+		"lat":        q.Lat,
+		"radius":     q.Radius,
+		"candidates": q.Candidates,
+		"span":       BearingSpan,
+		"vehicle":    q.LPRVehicleID,
+		"since":      q.TrailSince,
+		"at":         q.At,
+	}).Scan(&candidates).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to find map match candidates: %w", err)
+	}
+	return candidates, nil
+}
+
+// AddTrailPoint adds a position to the trail of a vehicle and drops the
+// points of the vehicle older than before
+func (r *MapMatchRepository) AddTrailPoint(ctx context.Context, lprVehicleID string, lon, lat float64, at,
+	before int64) error {
+	err := r.DB.DB.WithContext(ctx).Exec(
+		`WITH expired AS (
+            DELETE FROM vehicle_trail_points WHERE lpr_vehicle_id = @vehicle AND recorded_at < @before
+        )
+        INSERT INTO vehicle_trail_points (lpr_vehicle_id, recorded_at, geom)
+        VALUES (@vehicle, @at, ST_Transform(ST_SetSRID(ST_MakePoint(@lon, @lat), 4326), 32639))`,
+		map[string]interface{}{"vehicle": lprVehicleID, "lon": lon, "lat": lat, "at": at, "before": before}).Error
+	if err != nil {
+		return fmt.Errorf("failed to add trail point: %w", err)
+	}
+	return nil
+}
+
+// Trail returns the positions of a vehicle from since to at, oldest first, in
+// EPSG:32639
+func (r *MapMatchRepository) Trail(ctx context.Context, lprVehicleID string, since, at int64) ([]geo.Point, error) {
+	var points []geo.Point
+	err := r.DB.DB.WithContext(ctx).Raw(
+		`SELECT ST_X(geom) AS x, ST_Y(geom) AS y FROM vehicle_trail_points
+        WHERE lpr_vehicle_id = ? AND recorded_at >= ? AND recorded_at <= ?
+        ORDER BY recorded_at`,
+		lprVehicleID, since, at).Scan(&points).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch trail: %w", err)
+	}
+	return points, nil
+}
@@ -0,0 +1,145 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"farin/domain/entity"
+	gormdb "farin/infrastructure/gorm"
+	"fmt"
+	"gorm.io/gorm"
+)
+
+var ErrPatrolCycleNotFound = errors.New("patrol cycle not found")
+var ErrPatrolRingNotFound = errors.New("patrol ring not found")
+
+type PatrolCycleRepository struct {
+	DB *gormdb.GORMDB
+}
+
+func NewPatrolCycleRepository(db *gormdb.GORMDB) *PatrolCycleRepository {
+	return &PatrolCycleRepository{DB: db}
+}
+
+// Transaction runs fn in a transaction that holds a lock on the patrol cycles
+// of the vehicle, so positions of the state and record streams are applied
+// one at a time
+func (r *PatrolCycleRepository) Transaction(ctx context.Context, lprVehicleID string,
+	fn func(tx *PatrolCycleRepository) error) error {
+	return r.DB.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("SELECT pg_advisory_xact_lock(hashtext(?))", lprVehicleID).Error; err != nil {
+			return fmt.Errorf("failed to lock patrol cycles: %w", err)
+		}
+		return fn(&PatrolCycleRepository{DB: &gormdb.GORMDB{DB: tx}})
+	})
+}
+
+func (r *PatrolCycleRepository) Create(ctx context.Context, cycle *entity.PatrolCycle) (*entity.PatrolCycle, error) {
+	if err := r.DB.DB.WithContext(ctx).Create(cycle).Error; err != nil {
+		return nil, fmt.Errorf("failed to create patrol cycle: %w", err)
+	}
+	return cycle, nil
+}
+
+func (r *PatrolCycleRepository) Update(ctx context.Context, cycle *entity.PatrolCycle) (*entity.PatrolCycle, error) {
+	if err := r.DB.DB.WithContext(ctx).Save(cycle).Error; err != nil {
+		return nil, fmt.Errorf("failed to update patrol cycle: %w", err)
+	}
+	return cycle, nil
+}
+
+// FindOpen finds the lap the vehicle is currently driving
+func (r *PatrolCycleRepository) FindOpen(ctx context.Context, lprVehicleID string) (*entity.PatrolCycle, error) {
+	var cycle entity.PatrolCycle
+	err := r.DB.DB.WithContext(ctx).
+		Where("lpr_vehicle_id = ? AND status = ?", lprVehicleID, entity.PatrolCycleOpen).
+		First(&cycle).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrPatrolCycleNotFound
+		}
+		return nil, fmt.Errorf("failed to find open patrol cycle: %w", err)
+	}
+	return &cycle, nil
+}
+
+// LastNumber returns the highest cycle number of the vehicle, zero when the
+// vehicle has no cycle yet
+func (r *PatrolCycleRepository) LastNumber(ctx context.Context, lprVehicleID string) (int, error) {
+	var number int
+	err := r.DB.DB.WithContext(ctx).Model(&entity.PatrolCycle{}).
+		Where("lpr_vehicle_id = ?", lprVehicleID).
+		Select("COALESCE(MAX(cycle_number), 0)").Scan(&number).Error
+	if err != nil {
+		return 0, fmt.Errorf("failed to find last patrol cycle number: %w", err)
+	}
+	return number, nil
+}
+
+// NumberAt returns the number of the cycle the vehicle was driving at the
+// given time, zero when no cycle had started yet
+func (r *PatrolCycleRepository) NumberAt(ctx context.Context, lprVehicleID string, at int64) (int, error) {
+	var cycles []entity.PatrolCycle
+	err := r.DB.DB.WithContext(ctx).
+		Where("lpr_vehicle_id = ? AND start_time <= ?", lprVehicleID, at).
+		Order("start_time desc, cycle_number desc").Limit(1).Find(&cycles).Error
+	if err != nil {
+		return 0, fmt.Errorf("failed to find patrol cycle: %w", err)
+	}
+	if len(cycles) < 1 {
+		return 0, nil
+	}
+	return cycles[0].CycleNumber, nil
+}
+
+// AssignedRing finds the ring the LPR vehicle is assigned to on the calender
+// day [from, to), in unix seconds. With several assignments that day the one
+// whose shift started last by at wins, before any shift started the earliest.
+func (r *PatrolCycleRepository) AssignedRing(ctx context.Context, lprVehicleID string, from, to, at int64) (
+	int64, error) {
+	var ringIDs []int64
+	err := r.DB.DB.WithContext(ctx).Raw(
+		`SELECT da.ring_id FROM driver_assignments da
+            JOIN vehicles v ON v.code_vehicle = da.code_vehicle
+            JOIN calenders c ON c.id = da.calender_id
+            WHERE v.id = ? AND c.work_date >= ? AND c.work_date < ?
+            AND COALESCE(da.deleted_at, 0) = 0 AND COALESCE(v.deleted_at, 0) = 0 AND COALESCE(c.deleted_at, 0) = 0
+            ORDER BY COALESCE(c.work_shift_start, 0) <= ? DESC, abs(COALESCE(c.work_shift_start, 0) - ?), da.id
+            LIMIT 1`,
+		lprVehicleID, from, to, at, at).Scan(&ringIDs).Error
+	if err != nil {
+		return 0, fmt.Errorf("failed to find assigned ring: %w", err)
+	}
+	if len(ringIDs) < 1 {
+		return 0, ErrPatrolRingNotFound
+	}
+	return ringIDs[0], nil
+}
+
+// RingPosition locates a GPS position on the outline of a ring. position is
+// the fraction of the outline length from its start point to the closest
+// point of the outline and distance is the distance of the GPS position from
+// the ring in meters, zero inside a polygon ring
+func (r *PatrolCycleRepository) RingPosition(ctx context.Context, ringID int64, lon, lat float64) (
+	position float64, distance float64, err error) {
+	var rows []struct {
+		Position float64
+		Distance float64
+	}
+	err = r.DB.DB.WithContext(ctx).Raw(
+		`WITH pt AS (SELECT ST_Transform(ST_SetSRID(ST_MakePoint(?, ?), 4326), 32639) AS geom),
+            ring AS (
+                SELECT geom, CASE WHEN ST_Dimension(geom) = 2 THEN ST_ExteriorRing(ST_GeometryN(geom, 1))
+                    ELSE ST_GeometryN(ST_LineMerge(geom), 1) END AS outline
+                FROM public.rings WHERE id = ?
+            )
+        SELECT ST_LineLocatePoint(ring.outline, pt.geom) AS position, ST_Distance(ring.geom, pt.geom) AS distance
+        FROM ring, pt`,
+		lon, lat, ringID).Scan(&rows).Error
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to locate position on ring: %w", err)
+	}
+	if len(rows) < 1 {
+		return 0, 0, ErrPatrolRingNotFound
+	}
+	return rows[0].Position, rows[0].Distance, nil
+}
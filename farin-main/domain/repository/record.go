@@ -3,7 +3,6 @@ package repository
 import (
 	"context"
 	"errors"
-	"farin/domain/entity"
 	gormdb "farin/infrastructure/gorm"
 	"fmt"
 	vent "git.abanppc.com/farin-project/vehicle-records/domain/entity"
@@ -136,59 +135,3 @@ func (r *VehicleRecordRepository) BulkCreate(ctx context.Context, records []*ven
 	}
 	return nil
 }
-
-func (r *VehicleRecordRepository) FindRing(ctx context.Context, record *vent.VehicleRecord) ([]entity.Ring, error) {
-	var rings []entity.Ring
-	err := r.DB.DB.WithContext(ctx).Raw(
-		`SELECT * FROM public.rings pgone WHERE ST_intersects(
-            ST_Buffer(pgone.geom, 5),
-            ST_Buffer(ST_Transform(ST_SetSRID(ST_MakePoint(?, ?), 4326), 32639), ?)
-        )`,
-		record.LPRVehicleGPSLongitude, record.LPRVehicleGPSLatitude, record.LPRVehicleGPSError).Scan(&rings).Error
-	if err != nil {
-		return nil, err
-	}
-	return rings, nil
-}
-
-func (r *VehicleRecordRepository) FindSegment(ctx context.Context, record *vent.VehicleRecord) ([]entity.Segment, error) {
-	var segments []entity.Segment
-	err := r.DB.DB.WithContext(ctx).Raw(
-		`SELECT * FROM public.segments pgone WHERE ST_intersects(
-            ST_Buffer(pgone.geom, 10),
-            ST_Transform(ST_SetSRID(ST_MakePoint(?, ?), 4326), 32639)
-        )`,
-		record.LPRVehicleGPSLongitude, record.LPRVehicleGPSLatitude).Scan(&segments).Error
-	if err != nil {
-		return nil, err
-	}
-	return segments, nil
-}
-
-func (r *VehicleRecordRepository) FindRoad(ctx context.Context, record *vent.VehicleRecord) ([]entity.Road, error) {
-	var roads []entity.Road
-	err := r.DB.DB.WithContext(ctx).Raw(
-		`SELECT * FROM public.roads pgone WHERE ST_intersects(
-            ST_Buffer(pgone.geom, 5),
-            ST_Buffer(ST_Transform(ST_SetSRID(ST_MakePoint(?, ?), 4326), 32639), ?)
-        )`,
-		record.LPRVehicleGPSLongitude, record.LPRVehicleGPSLatitude, record.LPRVehicleGPSError).Scan(&roads).Error
-	if err != nil {
-		return nil, err
-	}
-	return roads, nil
-}
-
-func (r *VehicleRecordRepository) FindParking(ctx context.Context, record *vent.VehicleRecord) ([]entity.Road, error) {
-	var roads []entity.Road
-	err := r.DB.DB.WithContext(ctx).Raw(
-		`SELECT * FROM public.parkings pgone WHERE ST_intersects(
-            ST_Buffer(pgone.geom, 5),
-            ST_Buffer(ST_Transform(ST_SetSRID(ST_MakePoint(?, ?), 4326), 32639), ?)
-        )`,
-		record.LPRVehicleGPSLongitude, record.LPRVehicleGPSLatitude, record.LPRVehicleGPSError).Scan(&roads).Error
-	if err != nil {
-		return nil, err
-	}
-	return roads, nil
-}
@@ -17,4 +17,6 @@ var ProviderSet = wire.NewSet(
 	NewDeviceRepository, NewEventRecordRabbitMQ,
 	NewCalenderRepository, NewVehicleRecordRepository, NewCitizenVehiclePhotoRepository,
 	NewRingRepository, NewDriverAssignmentRepository,
+	NewPatrolCycleRepository, NewDriverLoginAttemptRepository,
+	NewMapMatchRepository, NewGeometryRepository,
 )
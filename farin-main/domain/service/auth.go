@@ -15,7 +15,12 @@ import (
 	"time"
 )
 
-var ErrCalenderTimeInvalid = errors.New("calender time invalid")
+var (
+	ErrCalenderTimeInvalid    = errors.New("calender time invalid")
+	ErrOutsideShift           = errors.New("login outside of the work shift")
+	ErrHoliday                = errors.New("login on a holiday")
+	ErrVehicleAssignedToOther = errors.New("vehicle is assigned to another driver")
+)
 
 type AuthService struct {
 	env            *godotenv.Env
@@ -27,11 +32,13 @@ type AuthService struct {
 	vehicleRepo    *repository.VehicleRepository
 	deviceRepo     *repository.DeviceRepository
 	assignmentRepo *repository.DriverAssignmentRepository
+	attemptRepo    *repository.DriverLoginAttemptRepository
 }
 
 func NewAuthService(env *godotenv.Env, logger *slog.Logger, userRepository *repository.UserRepository,
 	driverRepo *repository.DriverRepository, vehicleRepo *repository.VehicleRepository, deviceRepo *repository.DeviceRepository,
-	assignmentRepo *repository.DriverAssignmentRepository, contractorRepo *repository.ContractorRepository) *AuthService {
+	assignmentRepo *repository.DriverAssignmentRepository, contractorRepo *repository.ContractorRepository,
+	attemptRepo *repository.DriverLoginAttemptRepository) *AuthService {
 	return &AuthService{
 		env:            env,
 		logger:         logger.With("layer", "AuthService"),
@@ -41,6 +48,7 @@ func NewAuthService(env *godotenv.Env, logger *slog.Logger, userRepository *repo
 		deviceRepo:     deviceRepo,
 		assignmentRepo: assignmentRepo,
 		contractorRepo: contractorRepo,
+		attemptRepo:    attemptRepo,
 	}
 }
 
@@ -140,7 +148,7 @@ func (s AuthService) Login(ctx context.Context, email, enteredPassword string, r
 
 func (s AuthService) LoginDriver(ctx context.Context, user *entity.User, vehicleID string) (*entity.DriverAssignment,
 	*entity.Contractor, []*entity.Device, error) {
-	lg := s.logger.With("method", "Login")
+	lg := s.logger.With("method", "LoginDriver")
 	driver, err := s.driverRepo.GetByField(ctx, "user_id", user.ID)
 	if err != nil {
 		if errors.Is(err, repository.ErrDriverNotFound) {
@@ -149,31 +157,155 @@ func (s AuthService) LoginDriver(ctx context.Context, user *entity.User, vehicle
 		lg.Error("Error finding driver", slog.Any("error", err))
 		return nil, nil, nil, err
 	}
-	var da *entity.DriverAssignment
 
-	if driver != nil {
-		da, err = s.assignmentRepo.GetByFields(ctx, driver.ID, vehicleID)
-		if err != nil {
-			if !errors.Is(err, repository.ErrDriverAssignmentNotFound) {
-				lg.Error("Error finding driver", slog.Any("error", err))
-				return nil, nil, nil, err
-			}
+	attempt := &entity.DriverLoginAttempt{
+		UserID:       user.ID,
+		DriverID:     driver.ID,
+		ContractorID: driver.ContractorID,
+		CodeVehicle:  vehicleID,
+	}
+	now := time.Now()
+	from, to := tehranDay(now)
+	others, err := s.assignmentRepo.OthersOfDay(ctx, driver.ID, vehicleID, from, to)
+	if err != nil {
+		lg.Error("Error finding vehicle assignments", slog.Any("error", err))
+		return nil, nil, nil, err
+	}
+	for _, other := range others {
+		// drivers may share a vehicle on a day in different shifts
+		if s.checkShift(other.Calender, now) == nil {
+			attempt.Result = entity.DriverLoginAssignedToOther
+			return nil, nil, nil, s.recordAttempt(ctx, attempt, ErrVehicleAssignedToOther)
+		}
+	}
+
+	da, err := s.assignmentRepo.GetOfDay(ctx, driver.ID, vehicleID, from, to)
+	if err != nil {
+		if !errors.Is(err, repository.ErrDriverAssignmentNotFound) {
+			lg.Error("Error finding driver assignment", slog.Any("error", err))
+			return nil, nil, nil, err
+		}
+		attempt.Result = entity.DriverLoginNoAssignment
+		return nil, nil, nil, s.recordAttempt(ctx, attempt, repository.ErrDriverAssignmentNotFound)
+	}
+
+	attempt.AssignmentID = &da.ID
+	attempt.CalenderID = &da.CalenderID
+	attempt.WorkShift = da.Calender.WorkShift
+	attempt.ShiftStart = da.Calender.WorkShiftStart
+	attempt.ShiftEnd = da.Calender.WorkShiftEnd
+	if err := s.checkShift(da.Calender, now); err != nil {
+		switch {
+		case errors.Is(err, ErrHoliday):
+			attempt.Result = entity.DriverLoginHoliday
+		case errors.Is(err, ErrCalenderTimeInvalid):
+			attempt.Result = entity.DriverLoginInvalidCalender
+		default:
+			attempt.Result = entity.DriverLoginOutsideShift
 		}
+		return nil, nil, nil, s.recordAttempt(ctx, attempt, err)
 	}
 
 	var contractor *entity.Contractor
-	if driver != nil {
+	if driver.ContractorID != nil {
 		contractor, err = s.contractorRepo.GetByField(ctx, "id", *driver.ContractorID)
+		if err != nil {
+			lg.Error("Error finding contractor", slog.Any("error", err))
+			return nil, nil, nil, err
+		}
 	}
 
-	var devices []*entity.Device
-	if da != nil {
-		devices, err = s.deviceRepo.GetMultipleByField(ctx, "vehicle_id", da.Vehicle.ID)
+	devices, err := s.deviceRepo.GetMultipleByField(ctx, "vehicle_id", da.Vehicle.ID)
+	if err != nil {
+		lg.Error("Error finding devices", slog.Any("error", err))
+		return nil, nil, nil, err
 	}
 
+	attempt.Result = entity.DriverLoginAccepted
+	if err := s.recordAttempt(ctx, attempt, nil); err != nil {
+		return nil, nil, nil, err
+	}
 	return da, contractor, devices, nil
 }
 
+// checkShift reports whether a driver may log in at now with the calender of
+// their assignment. Morning shifts end and afternoon shifts start at noon in
+// Tehran, both span the whole calender window, which is widened by
+// DriverShiftGrace on both ends.
+func (s AuthService) checkShift(calender entity.Calender, now time.Time) error {
+	if calender.IsHoliday {
+		return ErrHoliday
+	}
+	start, end, err := shiftWindow(calender)
+	if err != nil {
+		return err
+	}
+	if now.Before(start.Add(-s.env.DriverShiftGrace)) || now.After(end.Add(s.env.DriverShiftGrace)) {
+		return ErrOutsideShift
+	}
+	return nil
+}
+
+// shiftWindow returns when the work shift of a calender starts and ends
+func shiftWindow(calender entity.Calender) (time.Time, time.Time, error) {
+	if calender.WorkShiftStart <= 0 || calender.WorkShiftEnd <= calender.WorkShiftStart {
+		return time.Time{}, time.Time{}, ErrCalenderTimeInvalid
+	}
+	start := time.Unix(calender.WorkShiftStart, 0).In(tehran)
+	end := time.Unix(calender.WorkShiftEnd, 0).In(tehran)
+	noon := time.Date(start.Year(), start.Month(), start.Day(), 12, 0, 0, 0, tehran)
+	switch calender.WorkShift {
+	case entity.Both:
+	case entity.Morning:
+		if !start.Before(noon) {
+			return time.Time{}, time.Time{}, ErrCalenderTimeInvalid
+		}
+		if end.After(noon) {
+			end = noon
+		}
+	case entity.Afternoon:
+		if !end.After(noon) {
+			return time.Time{}, time.Time{}, ErrCalenderTimeInvalid
+		}
+		if start.Before(noon) {
+			start = noon
+		}
+	default:
+		return time.Time{}, time.Time{}, ErrCalenderTimeInvalid
+	}
+	return start, end, nil
+}
+
+// tehranDay returns the unix seconds the day of t in Tehran starts and the
+// next one starts, the work dates of calenders fall in between
+func tehranDay(t time.Time) (int64, int64) {
+	t = t.In(tehran)
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, tehran)
+	return day.Unix(), day.AddDate(0, 0, 1).Unix()
+}
+
+// tehran is the zone shifts are planned in, a fixed offset stands in where
+// the zone database is missing
+var tehran = func() *time.Location {
+	loc, err := time.LoadLocation("Asia/Tehran")
+	if err != nil {
+		return time.FixedZone("IRST", 3*60*60+30*60)
+	}
+	return loc
+}()
+
+// recordAttempt stores the login attempt and returns the error the login
+// failed with, or the error of storing it.
+func (s AuthService) recordAttempt(ctx context.Context, attempt *entity.DriverLoginAttempt, loginErr error) error {
+	attempt.Accepted = loginErr == nil
+	if err := s.attemptRepo.Create(ctx, attempt); err != nil {
+		s.logger.With("method", "recordAttempt").Error("Error recording driver login attempt",
+			slog.Any("error", err), slog.String("result", string(attempt.Result)))
+		return err
+	}
+	return loginErr
+}
+
 func (s AuthService) RenewToken(ctx context.Context, refreshToken string) (accessToken string, expAccessToken int64, err error) {
 	lg := s.logger.With("method", "RenewToken")
 	var valid bool
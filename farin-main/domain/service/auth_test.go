@@ -0,0 +1,69 @@
+package service
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"farin/domain/entity"
+	"farin/infrastructure/godotenv"
+)
+
+func TestCheckShift(t *testing.T) {
+	s := AuthService{env: &godotenv.Env{DriverShiftGrace: 30 * time.Minute}}
+	at := func(hour, min int) time.Time {
+		return time.Date(2024, 6, 4, hour, min, 0, 0, tehran)
+	}
+	calender := func(shift entity.WorkShift, start, end time.Time) entity.Calender {
+		return entity.Calender{WorkShift: shift, WorkShiftStart: start.Unix(), WorkShiftEnd: end.Unix()}
+	}
+	day := calender(entity.Both, at(8, 0), at(16, 0))
+	holiday := day
+	holiday.IsHoliday = true
+
+	tests := []struct {
+		name     string
+		calender entity.Calender
+		now      time.Time
+		want     error
+	}{
+		{"inside the shift", day, at(10, 0), nil},
+		{"at the start", day, at(8, 0), nil},
+		{"before the grace", day, at(7, 29), ErrOutsideShift},
+		{"inside the grace before", day, at(7, 31), nil},
+		{"inside the grace after", day, at(16, 29), nil},
+		{"after the grace", day, at(16, 31), ErrOutsideShift},
+		{"holiday", holiday, at(10, 0), ErrHoliday},
+		{"no start", calender(entity.Both, time.Unix(0, 0), at(16, 0)), at(10, 0), ErrCalenderTimeInvalid},
+		{"end before start", calender(entity.Both, at(16, 0), at(8, 0)), at(10, 0), ErrCalenderTimeInvalid},
+		{"unknown work shift", calender("", at(8, 0), at(16, 0)), at(10, 0), ErrCalenderTimeInvalid},
+		{"morning before noon", calender(entity.Morning, at(8, 0), at(16, 0)), at(11, 0), nil},
+		{"morning after the grace of noon", calender(entity.Morning, at(8, 0), at(16, 0)), at(12, 31),
+			ErrOutsideShift},
+		{"morning ending before noon", calender(entity.Morning, at(8, 0), at(11, 0)), at(11, 45),
+			ErrOutsideShift},
+		{"morning starting after noon", calender(entity.Morning, at(13, 0), at(16, 0)), at(14, 0),
+			ErrCalenderTimeInvalid},
+		{"afternoon before the grace of noon", calender(entity.Afternoon, at(8, 0), at(16, 0)), at(11, 29),
+			ErrOutsideShift},
+		{"afternoon after noon", calender(entity.Afternoon, at(8, 0), at(16, 0)), at(15, 0), nil},
+		{"afternoon ending before noon", calender(entity.Afternoon, at(8, 0), at(11, 0)), at(10, 0),
+			ErrCalenderTimeInvalid},
+	}
+	for _, tt := range tests {
+		if err := s.checkShift(tt.calender, tt.now); !errors.Is(err, tt.want) {
+			t.Errorf("%s: checkShift() = %v, want %v", tt.name, err, tt.want)
+		}
+	}
+}
+
+func TestTehranDay(t *testing.T) {
+	from, to := tehranDay(time.Date(2024, 6, 4, 22, 0, 0, 0, time.UTC))
+	// 22:00 UTC is 01:30 of the next day in Tehran
+	if want := time.Date(2024, 6, 5, 0, 0, 0, 0, tehran).Unix(); from != want {
+		t.Errorf("tehranDay() from = %d, want %d", from, want)
+	}
+	if to-from != 24*60*60 {
+		t.Errorf("tehranDay() spans %ds, want a day", to-from)
+	}
+}
@@ -0,0 +1,228 @@
+package service
+
+import (
+	"context"
+	"farin/domain/repository"
+	"farin/infrastructure/godotenv"
+	"farin/infrastructure/opentelemetry"
+	"farin/util/geo"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"log/slog"
+	"sync/atomic"
+	"time"
+)
+
+// geometryFeature is what a record takes from the geometry it is located on
+type geometryFeature struct {
+	ID       int64
+	Junction int8
+	RoadCode int64
+}
+
+// geometryLayers holds an R-tree of every layer, loaded at checksum
+type geometryLayers struct {
+	checksum string
+	rings    *geo.RTree[geometryFeature]
+	segments *geo.RTree[geometryFeature]
+	roads    *geo.RTree[geometryFeature]
+	parkings *geo.RTree[geometryFeature]
+}
+
+// GeometryCache keeps the ring, segment, road and parking geometries in
+// in-process R-trees so records are located without querying PostGIS. The
+// layers are reloaded when the checksum of the tables changes, a reload
+// replaces them at once so lookups never see half of a reload.
+type GeometryCache struct {
+	logger        *slog.Logger
+	geometryRepo  *repository.GeometryRepository
+	env           *godotenv.Env
+	layers        atomic.Pointer[geometryLayers]
+	reload        chan struct{}
+	lookupCounter metric.Int64Counter
+}
+
+func NewGeometryCache(logger *slog.Logger, geometryRepo *repository.GeometryRepository, env *godotenv.Env,
+	telemetry *opentelemetry.OpenTelemetry) *GeometryCache {
+	meter := telemetry.Meter.Meter("farin.backend.GeometryCache")
+	lookupCounter, err := meter.Int64Counter("geometry_cache.lookup.counter",
+		metric.WithDescription("number of geometry cache lookups by result"))
+	if err != nil {
+		panic(err)
+	}
+	return &GeometryCache{
+		logger:        logger.With("layer", "GeometryCache"),
+		geometryRepo:  geometryRepo,
+		env:           env,
+		reload:        make(chan struct{}, 1),
+		lookupCounter: lookupCounter,
+	}
+}
+
+// RunReloader loads the layers, then checks the checksum of the tables every
+// GeometryCacheInterval and reloads them when it changed, until ctx is done
+func (c *GeometryCache) RunReloader(ctx context.Context) {
+	ticker := time.NewTicker(c.env.GeometryCacheInterval)
+	defer ticker.Stop()
+
+	force := true
+	for {
+		if err := c.Refresh(ctx, force); err != nil {
+			c.logger.Error("failed to refresh geometry cache", "error", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			force = false
+		case <-c.reload:
+			force = true
+		}
+	}
+}
+
+// geometryListenRetry is the wait before listening again after the
+// connection failed
+const geometryListenRetry = 10 * time.Second
+
+// RunListener invalidates the layers whenever a geometry table is written,
+// by this or any other service, until ctx is done. Changes made while the
+// connection is down are found by the checksum.
+func (c *GeometryCache) RunListener(ctx context.Context) {
+	for ctx.Err() == nil {
+		err := c.geometryRepo.Listen(ctx, func(layer repository.GeometryLayer) {
+			c.logger.Debug("geometry changed", "layer", layer)
+			c.Invalidate()
+		})
+		if ctx.Err() != nil {
+			return
+		}
+		c.logger.Error("failed to listen for geometry changes", "error", err)
+		select {
+		case <-ctx.Done():
+		case <-time.After(geometryListenRetry):
+		}
+	}
+}
+
+// Invalidate makes the reloader load the layers again, for geometries
+// changed by this instance
+func (c *GeometryCache) Invalidate() {
+	select {
+	case c.reload <- struct{}{}:
+	default:
+	}
+}
+
+// Refresh loads the layers when their checksum changed since the last load,
+// or always with force
+func (c *GeometryCache) Refresh(ctx context.Context, force bool) error {
+	lg := c.logger.With("method", "Refresh")
+	checksum, err := c.geometryRepo.Checksum(ctx)
+	if err != nil {
+		return err
+	}
+	if cur := c.layers.Load(); !force && cur != nil && cur.checksum == checksum {
+		return nil
+	}
+
+	start := time.Now()
+	layers := &geometryLayers{checksum: checksum}
+	for _, layer := range repository.GeometryLayers {
+		rows, err := c.geometryRepo.Load(ctx, layer)
+		if err != nil {
+			return err
+		}
+		entries := make([]geo.Entry[geometryFeature], 0, len(rows))
+		for _, row := range rows {
+			g, err := geo.ParseWKB(row.Geom)
+			if err != nil {
+				lg.Warn("skipped invalid geometry", "error", err, "layer", layer, "id", row.ID)
+				continue
+			}
+			entries = append(entries, geo.Entry[geometryFeature]{Geom: g,
+				Value: geometryFeature{ID: row.ID, Junction: row.Junction, RoadCode: row.RoadCode}})
+		}
+		tree := geo.NewRTree(entries)
+		switch layer {
+		case repository.GeometryRings:
+			layers.rings = tree
+		case repository.GeometrySegments:
+			layers.segments = tree
+		case repository.GeometryRoads:
+			layers.roads = tree
+		case repository.GeometryParkings:
+			layers.parkings = tree
+		}
+	}
+	c.layers.Store(layers)
+	lg.Info("geometry cache loaded", "duration", time.Since(start), "rings", layers.rings.Len(),
+		"segments", layers.segments.Len(), "roads", layers.roads.Len(), "parkings", layers.parkings.Len())
+	return nil
+}
+
+// Candidates answers a map-matching query from the cache as
+// MapMatchRepository.Candidates does, trail is read for every hit as the
+// query reads it. It returns false on a miss, while the layers are not
+// loaded or when no segment is near the position, for the query to be run
+// in SQL.
+func (c *GeometryCache) Candidates(ctx context.Context, q repository.MapMatchQuery,
+	trail func() ([]geo.Point, error)) ([]repository.MapMatchCandidate, bool, error) {
+	layers := c.layers.Load()
+	if layers == nil {
+		c.lookupCounter.Add(ctx, 1, metric.WithAttributes(attribute.String("result", "unloaded")))
+		return nil, false, nil
+	}
+	pt := geo.Project(q.Lon, q.Lat)
+	segments := layers.segments.Nearest(pt, q.Radius, q.Candidates)
+	if len(segments) == 0 {
+		c.lookupCounter.Add(ctx, 1, metric.WithAttributes(attribute.String("result", "miss")))
+		return nil, false, nil
+	}
+	c.lookupCounter.Add(ctx, 1, metric.WithAttributes(attribute.String("result", "hit")))
+
+	points, err := trail()
+	if err != nil {
+		return nil, false, err
+	}
+
+	candidates := make([]repository.MapMatchCandidate, 0, len(segments)+1)
+	for _, s := range segments {
+		g := &s.Entry.Geom
+		cand := layers.around(g.ClosestPoint(pt), q.Radius)
+		cand.SegmentID = s.Entry.Value.ID
+		cand.Junction = s.Entry.Value.Junction
+		cand.Distance = s.Distance
+		if b, ok := g.Bearing(pt, repository.BearingSpan); ok {
+			cand.Bearing = &b
+		}
+		if len(points) > 0 {
+			var sum float64
+			for _, p := range points {
+				sum += g.Distance(p)
+			}
+			td := sum / float64(len(points))
+			cand.TrailDistance = &td
+			cand.TrailPoints = len(points)
+		}
+		candidates = append(candidates, cand)
+	}
+	return append(candidates, layers.around(pt, q.Radius)), true, nil
+}
+
+// around finds the nearest ring, road and parking lot within radius of p
+func (l *geometryLayers) around(p geo.Point, radius float64) repository.MapMatchCandidate {
+	var cand repository.MapMatchCandidate
+	if n := l.rings.Nearest(p, radius, 1); len(n) > 0 {
+		cand.RingID = n[0].Entry.Value.ID
+	}
+	if n := l.roads.Nearest(p, radius, 1); len(n) > 0 {
+		cand.StreetID = n[0].Entry.Value.ID
+		cand.RoadCode = n[0].Entry.Value.RoadCode
+	}
+	if n := l.parkings.Nearest(p, radius, 1); len(n) > 0 {
+		cand.ParkingLotID = n[0].Entry.Value.ID
+	}
+	return cand
+}
@@ -0,0 +1,181 @@
+package service
+
+import (
+	"context"
+	"farin/domain/repository"
+	"farin/infrastructure/godotenv"
+	"farin/util/geo"
+	vehicleRecordEnt "git.abanppc.com/farin-project/vehicle-records/domain/entity"
+	"github.com/google/uuid"
+	"log/slog"
+	"math"
+)
+
+type MapMatchService struct {
+	logger       *slog.Logger
+	mapMatchRepo *repository.MapMatchRepository
+	cache        *GeometryCache
+	env          *godotenv.Env
+}
+
+func NewMapMatchService(logger *slog.Logger, mapMatchRepo *repository.MapMatchRepository,
+	cache *GeometryCache, env *godotenv.Env) *MapMatchService {
+	return &MapMatchService{
+		logger:       logger.With("layer", "MapMatchService"),
+		mapMatchRepo: mapMatchRepo,
+		cache:        cache,
+		env:          env,
+	}
+}
+
+// MapMatch is where a record was taken, Confidence goes from 0 when no
+// segment was matched to 1 for a single segment right under the position
+type MapMatch struct {
+	SegmentID    int64
+	IsJunction   bool
+	RingID       int64
+	StreetID     int64
+	RoadCode     int64
+	ParkingLotID int64
+	Confidence   float64
+}
+
+// Match finds the segment a record was taken on. The segments around the
+// position are scored by their distance, by how well their direction fits
+// the heading of the vehicle and by how close the recent trail of the LPR
+// vehicle runs to them. The RTK position is used when its error is lower
+// than the GPS error. The geometry cache is looked up first, the query runs
+// in SQL on a miss.
+func (s *MapMatchService) Match(ctx context.Context, record *vehicleRecordEnt.VehicleRecord) (*MapMatch, error) {
+	lg := s.logger.With("method", "Match")
+	lon, lat, accuracy := recordPosition(record)
+
+	q := repository.MapMatchQuery{
+		LPRVehicleID: trailVehicle(record.LPRVehicleID),
+		Lon:          lon,
+		Lat:          lat,
+		Radius:       s.env.MapMatchRadius + accuracy,
+		Candidates:   s.env.MapMatchCandidates,
+		At:           record.RecordStoreTime,
+		TrailSince:   record.RecordStoreTime - s.env.MapMatchTrailWindow.Milliseconds(),
+	}
+	candidates, hit, err := s.cache.Candidates(ctx, q, func() ([]geo.Point, error) {
+		if q.LPRVehicleID == "" {
+			return nil, nil
+		}
+		return s.mapMatchRepo.Trail(ctx, q.LPRVehicleID, q.TrailSince, q.At)
+	})
+	if err == nil && !hit {
+		candidates, err = s.mapMatchRepo.Candidates(ctx, q)
+	}
+	if err != nil {
+		lg.Error("failed to find map match candidates", "error", err, "recordID", record.RecordID)
+		return nil, err
+	}
+
+	heading := math.NaN()
+	if record.CitizenVehicleDegree >= 0 && record.CitizenVehicleDegree < 360 {
+		heading = float64(record.CitizenVehicleDegree)
+	}
+	sigma := math.Max(accuracy, s.env.MapMatchSigma)
+	best, confidence := bestCandidate(candidates, heading, sigma)
+	if best == nil {
+		return &MapMatch{}, nil
+	}
+	return &MapMatch{
+		SegmentID:    best.SegmentID,
+		IsJunction:   best.Junction == 1,
+		RingID:       best.RingID,
+		StreetID:     best.StreetID,
+		RoadCode:     best.RoadCode,
+		ParkingLotID: best.ParkingLotID,
+		Confidence:   confidence,
+	}, nil
+}
+
+// Observe adds a position of an LPR vehicle to its trail, points older than
+// MapMatchTrailWindow are dropped
+func (s *MapMatchService) Observe(ctx context.Context, lprVehicleID string, lon, lat float64, at int64) error {
+	lprVehicleID = trailVehicle(lprVehicleID)
+	if lprVehicleID == "" || (lon == 0 && lat == 0) {
+		return nil
+	}
+	return s.mapMatchRepo.AddTrailPoint(ctx, lprVehicleID, lon, lat, at,
+		at-s.env.MapMatchTrailWindow.Milliseconds())
+}
+
+// recordPosition returns the position of a record and its error in meters,
+// the RTK position is taken when it is set and more accurate than GPS
+func recordPosition(record *vehicleRecordEnt.VehicleRecord) (float64, float64, float64) {
+	if (record.LPRVehicleRTKLongitude != 0 || record.LPRVehicleRTKLatitude != 0) &&
+		record.LPRVehicleRTKError >= 0 && record.LPRVehicleRTKError < record.LPRVehicleGPSError {
+		return record.LPRVehicleRTKLongitude, record.LPRVehicleRTKLatitude, float64(record.LPRVehicleRTKError)
+	}
+	return record.LPRVehicleGPSLongitude, record.LPRVehicleGPSLatitude, math.Max(float64(record.LPRVehicleGPSError), 0)
+}
+
+// trailVehicle returns the id of the vehicle a trail is kept for, empty for
+// ids that are not UUIDs
+func trailVehicle(lprVehicleID string) string {
+	if _, err := uuid.Parse(lprVehicleID); err != nil {
+		return ""
+	}
+	return lprVehicleID
+}
+
+// bestCandidate picks the segment with the highest score and returns it with
+// its confidence, the share of the scores of all segments it takes times its
+// own score. Without segments the candidate of the position itself is
+// returned with a zero confidence. heading is NaN when unknown.
+func bestCandidate(candidates []repository.MapMatchCandidate, heading, sigma float64) (
+	*repository.MapMatchCandidate, float64) {
+	var best, fallback *repository.MapMatchCandidate
+	var bestScore, total float64
+	for i := range candidates {
+		c := &candidates[i]
+		if c.SegmentID == 0 {
+			fallback = c
+			continue
+		}
+		score := candidateScore(c, heading, sigma)
+		total += score
+		if best == nil || score > bestScore {
+			best, bestScore = c, score
+		}
+	}
+	if best == nil {
+		return fallback, 0
+	}
+	if total == 0 {
+		return best, 0
+	}
+	return best, bestScore * bestScore / total
+}
+
+// candidateScore multiplies a gaussian of the distance of the position, the
+// fit of the heading, which ignores the way the segment is driven, and a
+// gaussian of the distance of the trail when the vehicle has one
+func candidateScore(c *repository.MapMatchCandidate, heading, sigma float64) float64 {
+	score := gaussian(c.Distance, sigma)
+	if !math.IsNaN(heading) && c.Bearing != nil {
+		d := math.Mod(math.Abs(heading-*c.Bearing), 180)
+		if d > 90 {
+			d = 180 - d
+		}
+		// a perpendicular segment is unlikely but kept, the heading may be off
+		score *= math.Max(math.Cos(d*math.Pi/180), minHeadingFit)
+	}
+	if c.TrailDistance != nil && c.TrailPoints >= minTrailPoints {
+		score *= gaussian(*c.TrailDistance, 2*sigma)
+	}
+	return score
+}
+
+const (
+	minHeadingFit  = 0.1
+	minTrailPoints = 2
+)
+
+func gaussian(x, sigma float64) float64 {
+	return math.Exp(-0.5 * (x / sigma) * (x / sigma))
+}
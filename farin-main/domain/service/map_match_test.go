@@ -0,0 +1,174 @@
+package service
+
+import (
+	"math"
+	"testing"
+
+	"farin/domain/repository"
+	vehicleRecordEnt "git.abanppc.com/farin-project/vehicle-records/domain/entity"
+)
+
+func ptr(f float64) *float64 {
+	return &f
+}
+
+func near(a, b float64) bool {
+	return math.Abs(a-b) < 1e-9
+}
+
+func TestCandidateScore(t *testing.T) {
+	tests := []struct {
+		name    string
+		c       repository.MapMatchCandidate
+		heading float64
+		want    float64
+	}{
+		{"on the segment", repository.MapMatchCandidate{Distance: 0}, math.NaN(), 1},
+		{"one sigma away", repository.MapMatchCandidate{Distance: 10}, math.NaN(), math.Exp(-0.5)},
+		{"heading along", repository.MapMatchCandidate{Bearing: ptr(90)}, 90, 1},
+		{"heading against the way", repository.MapMatchCandidate{Bearing: ptr(90)}, 270, 1},
+		{"heading at 60 degrees", repository.MapMatchCandidate{Bearing: ptr(0)}, 60, 0.5},
+		{"heading across", repository.MapMatchCandidate{Bearing: ptr(0)}, 90, minHeadingFit},
+		{"heading over north", repository.MapMatchCandidate{Bearing: ptr(350)}, 10, math.Cos(20 * math.Pi / 180)},
+		{"unknown heading", repository.MapMatchCandidate{Bearing: ptr(0)}, math.NaN(), 1},
+		{"segment without bearing", repository.MapMatchCandidate{}, 90, 1},
+		{"trail on the segment", repository.MapMatchCandidate{TrailDistance: ptr(0), TrailPoints: 5}, math.NaN(), 1},
+		{"trail two sigma away", repository.MapMatchCandidate{TrailDistance: ptr(20), TrailPoints: 5}, math.NaN(),
+			math.Exp(-0.5)},
+		{"trail of one point", repository.MapMatchCandidate{TrailDistance: ptr(20), TrailPoints: 1}, math.NaN(), 1},
+		{"all factors", repository.MapMatchCandidate{Distance: 10, Bearing: ptr(0), TrailDistance: ptr(20),
+			TrailPoints: 2}, 60, math.Exp(-0.5) * 0.5 * math.Exp(-0.5)},
+	}
+	for _, tt := range tests {
+		if got := candidateScore(&tt.c, tt.heading, 10); !near(got, tt.want) {
+			t.Errorf("%s: candidateScore() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestBestCandidate(t *testing.T) {
+	tests := []struct {
+		name           string
+		candidates     []repository.MapMatchCandidate
+		heading        float64
+		wantSegment    int64
+		wantRing       int64
+		wantConfidence float64
+	}{
+		{
+			name:           "no candidates",
+			heading:        math.NaN(),
+			wantConfidence: 0,
+		},
+		{
+			name:           "position only",
+			candidates:     []repository.MapMatchCandidate{{RingID: 7}},
+			heading:        math.NaN(),
+			wantRing:       7,
+			wantConfidence: 0,
+		},
+		{
+			name: "single segment under the position",
+			candidates: []repository.MapMatchCandidate{
+				{RingID: 7},
+				{SegmentID: 1, RingID: 3},
+			},
+			heading:        math.NaN(),
+			wantSegment:    1,
+			wantRing:       3,
+			wantConfidence: 1,
+		},
+		{
+			name: "nearest segment wins without a heading",
+			candidates: []repository.MapMatchCandidate{
+				{SegmentID: 1, Distance: 10, Bearing: ptr(0)},
+				{SegmentID: 2, Distance: 0, Bearing: ptr(90)},
+			},
+			heading:        math.NaN(),
+			wantSegment:    2,
+			wantConfidence: 1 / (1 + math.Exp(-0.5)),
+		},
+		{
+			name: "heading picks the farther segment",
+			candidates: []repository.MapMatchCandidate{
+				{SegmentID: 1, Distance: 10, Bearing: ptr(0)},
+				{SegmentID: 2, Distance: 0, Bearing: ptr(90)},
+			},
+			heading:     0,
+			wantSegment: 1,
+			wantConfidence: math.Exp(-0.5) * math.Exp(-0.5) /
+				(math.Exp(-0.5) + minHeadingFit),
+		},
+		{
+			name: "trail picks the segment the vehicle drove",
+			candidates: []repository.MapMatchCandidate{
+				{SegmentID: 1, Distance: 0, TrailDistance: ptr(40), TrailPoints: 5},
+				{SegmentID: 2, Distance: 5, TrailDistance: ptr(0), TrailPoints: 5},
+			},
+			heading:     math.NaN(),
+			wantSegment: 2,
+			wantConfidence: math.Exp(-0.125) * math.Exp(-0.125) /
+				(math.Exp(-2) + math.Exp(-0.125)),
+		},
+		{
+			name: "zero total",
+			candidates: []repository.MapMatchCandidate{
+				{SegmentID: 1, Distance: 1e6},
+				{SegmentID: 2, Distance: 2e6},
+			},
+			heading:        math.NaN(),
+			wantSegment:    1,
+			wantConfidence: 0,
+		},
+	}
+	for _, tt := range tests {
+		best, confidence := bestCandidate(tt.candidates, tt.heading, 10)
+		var segment, ring int64
+		if best != nil {
+			segment, ring = best.SegmentID, best.RingID
+		}
+		if segment != tt.wantSegment || ring != tt.wantRing {
+			t.Errorf("%s: bestCandidate() = segment %d ring %d, want segment %d ring %d", tt.name, segment, ring,
+				tt.wantSegment, tt.wantRing)
+		}
+		if !near(confidence, tt.wantConfidence) {
+			t.Errorf("%s: bestCandidate() confidence = %v, want %v", tt.name, confidence, tt.wantConfidence)
+		}
+	}
+}
+
+func TestRecordPosition(t *testing.T) {
+	gps := vehicleRecordEnt.VehicleRecord{
+		LPRVehicleGPSLongitude: 51.4,
+		LPRVehicleGPSLatitude:  35.7,
+		LPRVehicleGPSError:     8,
+	}
+	rtk := gps
+	rtk.LPRVehicleRTKLongitude, rtk.LPRVehicleRTKLatitude, rtk.LPRVehicleRTKError = 51.41, 35.71, 1
+	worseRTK := rtk
+	worseRTK.LPRVehicleRTKError = 12
+	noRTKError := rtk
+	noRTKError.LPRVehicleRTKError = -1
+	noGPSError := gps
+	noGPSError.LPRVehicleGPSError = -1
+
+	tests := []struct {
+		name     string
+		record   vehicleRecordEnt.VehicleRecord
+		lon, lat float64
+		accuracy float64
+	}{
+		{"gps only", gps, 51.4, 35.7, 8},
+		{"more accurate rtk", rtk, 51.41, 35.71, 1},
+		{"less accurate rtk", worseRTK, 51.4, 35.7, 8},
+		{"rtk without error", noRTKError, 51.4, 35.7, 8},
+		{"negative gps error", noGPSError, 51.4, 35.7, 0},
+	}
+	for _, tt := range tests {
+		lon, lat, accuracy := recordPosition(&tt.record)
+		if lon != tt.lon || lat != tt.lat || accuracy != tt.accuracy {
+			t.Errorf("%s: recordPosition() = %v, %v, %v, want %v, %v, %v", tt.name, lon, lat, accuracy,
+				tt.lon, tt.lat, tt.accuracy)
+		}
+	}
+}
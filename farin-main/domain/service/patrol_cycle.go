@@ -0,0 +1,155 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"farin/domain/entity"
+	"farin/domain/repository"
+	"farin/infrastructure/godotenv"
+	"log/slog"
+	"math"
+	"time"
+)
+
+type PatrolCycleService struct {
+	logger    *slog.Logger
+	cycleRepo *repository.PatrolCycleRepository
+	env       *godotenv.Env
+}
+
+func NewPatrolCycleService(logger *slog.Logger, cycleRepo *repository.PatrolCycleRepository,
+	env *godotenv.Env) *PatrolCycleService {
+	return &PatrolCycleService{
+		logger:    logger.With("layer", "PatrolCycleService"),
+		cycleRepo: cycleRepo,
+		env:       env,
+	}
+}
+
+// TrackState moves the lap of the vehicle forward with a position of the state stream
+func (s *PatrolCycleService) TrackState(ctx context.Context, state *entity.State) error {
+	_, err := s.Observe(ctx, state.LPRVehicleID, state.LPRVehicleGPSLongitude, state.LPRVehicleGPSLatitude,
+		state.RecordStoreTime)
+	return err
+}
+
+// Observe applies a GPS position of an LPR vehicle to its current lap and
+// returns the number of the cycle the vehicle was driving at that time. A lap
+// is finished once the position of the vehicle, projected on the outline of
+// its assigned ring, has gone PatrolLapCoverage of the way round, the next
+// lap starts right away with the next number. Zero is returned for vehicles
+// without a ring assigned on the day of the position.
+func (s *PatrolCycleService) Observe(ctx context.Context, lprVehicleID string, lon, lat float64, at int64) (int, error) {
+	lg := s.logger.With("method", "Observe")
+	if lprVehicleID == "" {
+		return 0, nil
+	}
+
+	from, to := tehranDay(time.UnixMilli(at))
+	ringID, err := s.cycleRepo.AssignedRing(ctx, lprVehicleID, from, to, time.UnixMilli(at).Unix())
+	if err != nil {
+		if errors.Is(err, repository.ErrPatrolRingNotFound) {
+			return 0, nil
+		}
+		lg.Error("failed to find assigned ring", "error", err, "lprVehicleID", lprVehicleID)
+		return 0, err
+	}
+	position, distance, err := s.cycleRepo.RingPosition(ctx, ringID, lon, lat)
+	if err != nil {
+		if errors.Is(err, repository.ErrPatrolRingNotFound) {
+			return 0, nil
+		}
+		lg.Error("failed to locate position on ring", "error", err, "ringID", ringID)
+		return 0, err
+	}
+
+	var number int
+	err = s.cycleRepo.Transaction(ctx, lprVehicleID, func(tx *repository.PatrolCycleRepository) error {
+		cycle, err := tx.FindOpen(ctx, lprVehicleID)
+		if err != nil && !errors.Is(err, repository.ErrPatrolCycleNotFound) {
+			return err
+		}
+
+		if cycle != nil && at < cycle.LastSeenTime {
+			// late position, it does not move the lap but still belongs to a cycle
+			number, err = tx.NumberAt(ctx, lprVehicleID, at)
+			return err
+		}
+
+		if cycle != nil && (cycle.RingID != ringID ||
+			at-cycle.LastSeenTime > s.env.PatrolCycleMaxIdle.Milliseconds()) {
+			cycle.Status = entity.PatrolCycleAbandoned
+			cycle.EndTime = cycle.LastSeenTime
+			if _, err := tx.Update(ctx, cycle); err != nil {
+				return err
+			}
+			lg.Info("patrol cycle abandoned", "lprVehicleID", lprVehicleID, "cycle", cycle.CycleNumber)
+			cycle = nil
+		}
+
+		if cycle == nil {
+			cycle, err = s.startCycle(ctx, tx, lprVehicleID, ringID, position, at)
+			if err != nil {
+				return err
+			}
+			number = cycle.CycleNumber
+			return nil
+		}
+
+		number = cycle.CycleNumber
+		cycle.LastSeenTime = at
+		if distance <= s.env.PatrolRingMaxDistance {
+			cycle.Progress += ringProgress(cycle.LastPosition, position)
+			cycle.LastPosition = position
+		}
+		if math.Abs(cycle.Progress) < s.env.PatrolLapCoverage {
+			_, err := tx.Update(ctx, cycle)
+			return err
+		}
+
+		cycle.Status = entity.PatrolCycleCompleted
+		cycle.EndTime = at
+		if _, err := tx.Update(ctx, cycle); err != nil {
+			return err
+		}
+		lg.Info("patrol cycle completed", "lprVehicleID", lprVehicleID, "cycle", cycle.CycleNumber,
+			"ringID", ringID)
+		_, err = s.startCycle(ctx, tx, lprVehicleID, ringID, position, at)
+		return err
+	})
+	if err != nil {
+		lg.Error("failed to track patrol cycle", "error", err, "lprVehicleID", lprVehicleID)
+		return 0, err
+	}
+	return number, nil
+}
+
+func (s *PatrolCycleService) startCycle(ctx context.Context, tx *repository.PatrolCycleRepository,
+	lprVehicleID string, ringID int64, position float64, at int64) (*entity.PatrolCycle, error) {
+	last, err := tx.LastNumber(ctx, lprVehicleID)
+	if err != nil {
+		return nil, err
+	}
+	return tx.Create(ctx, &entity.PatrolCycle{
+		LPRVehicleID: lprVehicleID,
+		RingID:       ringID,
+		CycleNumber:  last + 1,
+		Status:       entity.PatrolCycleOpen,
+		LastPosition: position,
+		StartTime:    at,
+		LastSeenTime: at,
+	})
+}
+
+// ringProgress returns the signed share of a closed outline travelled from
+// prev to cur, both given as fractions of the outline. The shorter way round
+// is taken, so crossing the start point of the outline is not a jump.
+func ringProgress(prev, cur float64) float64 {
+	d := cur - prev
+	if d > 0.5 {
+		d -= 1
+	} else if d < -0.5 {
+		d += 1
+	}
+	return d
+}
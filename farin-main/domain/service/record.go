@@ -6,6 +6,7 @@ import (
 	"farin/infrastructure/godotenv"
 	"farin/infrastructure/opentelemetry"
 	vehicleRecordEnt "git.abanppc.com/farin-project/vehicle-records/domain/entity"
+	"git.abanppc.com/farin-project/vehicle-records/util/plate"
 	"github.com/mahdimehrabi/uploader"
 	"go.opentelemetry.io/otel/metric"
 	"log/slog"
@@ -17,6 +18,8 @@ type VehicleRecordService struct {
 	recordRepo         *repository.VehicleRecordRepository
 	photoRepo          *repository.CitizenVehiclePhotoRepository
 	eventRecordRbtRepo *repository.EventRecordRabbitMQ
+	patrolCycle        *PatrolCycleService
+	mapMatch           *MapMatchService
 	fr                 uploader.FileRepository
 	env                *godotenv.Env
 	durationCounter    metric.Int64Histogram
@@ -24,7 +27,8 @@ type VehicleRecordService struct {
 
 func NewVehicleRecordService(logger *slog.Logger, ringRepo *repository.VehicleRecordRepository, fr uploader.FileRepository,
 	env *godotenv.Env, photoRepo *repository.CitizenVehiclePhotoRepository, telemetry *opentelemetry.OpenTelemetry,
-	eventRecordRbtRepo *repository.EventRecordRabbitMQ) *VehicleRecordService {
+	eventRecordRbtRepo *repository.EventRecordRabbitMQ, patrolCycle *PatrolCycleService,
+	mapMatch *MapMatchService) *VehicleRecordService {
 	meter := telemetry.Meter.Meter("farin.backend.VehicleRecordService")
 
 	durationCounter, err := meter.Int64Histogram("vehicle_record.consume.duration",
@@ -43,6 +47,8 @@ func NewVehicleRecordService(logger *slog.Logger, ringRepo *repository.VehicleRe
 		env:                env,
 		photoRepo:          photoRepo,
 		eventRecordRbtRepo: eventRecordRbtRepo,
+		patrolCycle:        patrolCycle,
+		mapMatch:           mapMatch,
 		durationCounter:    durationCounter,
 	}
 }
@@ -51,53 +57,39 @@ func (s *VehicleRecordService) CreateRecord(ctx context.Context, record *vehicle
 	lg := s.logger.With("method", "CreateRecord")
 	startTime := time.Now().UnixMicro()
 
-	rings, err := s.recordRepo.FindRing(ctx, record)
+	match, err := s.mapMatch.Match(ctx, record)
 	if err != nil {
-		lg.Error("failed to find record", "error", err)
+		lg.Error("failed to map-match record", "error", err)
 		return nil, err
 	}
-	if len(rings) < 1 {
-		record.RingID = 0
-	} else {
-		record.RingID = rings[0].ID
-	}
+	record.SegmentID = match.SegmentID
+	record.IsJunction = match.IsJunction
+	record.RingID = match.RingID
+	record.StreetID = match.StreetID
+	record.RoadCode = match.RoadCode
+	record.ParkingLotID = match.ParkingLotID
+	record.MatchConfidence = match.Confidence
 
-	segments, err := s.recordRepo.FindSegment(ctx, record)
-	if err != nil {
-		lg.Error("failed to find record", "error", err)
-		return nil, err
-	}
-	if len(segments) < 1 {
-		record.SegmentID = 0
+	// plates are stored in the camera format along with their numeric encoding,
+	// unreadable plates are left for vehicle-records to reject
+	if p, err := plate.Parse(record.CitizenPlateNumber); err != nil {
+		lg.Warn("failed to parse plate", "error", err, "recordID", record.RecordID)
 	} else {
-		segment := segments[0]
-		record.SegmentID = segment.ID
-		record.IsJunction = segment.Junction == 1
+		record.CitizenPlateNumber = p.String()
+		record.CitizenPlateNumberNumeric = p.Numeric()
 	}
 
-	roads, err := s.recordRepo.FindRoad(ctx, record)
-	if err != nil {
-		lg.Error("failed to find record", "error", err)
-		return nil, err
-	}
-	if len(roads) < 1 {
-		record.StreetID = 0
-	} else {
-		road := roads[0]
-		record.StreetID = road.ID
-		record.RoadCode = road.RoadCode
+	lon, lat, _ := recordPosition(record)
+	if err := s.mapMatch.Observe(ctx, record.LPRVehicleID, lon, lat, record.RecordStoreTime); err != nil {
+		lg.Warn("failed to add trail point", "error", err)
 	}
 
-	parkings, err := s.recordRepo.FindParking(ctx, record)
+	record.CycleID, err = s.patrolCycle.Observe(ctx, record.LPRVehicleID, record.LPRVehicleGPSLongitude,
+		record.LPRVehicleGPSLatitude, record.RecordStoreTime)
 	if err != nil {
-		lg.Error("failed to find record", "error", err)
+		lg.Error("failed to find patrol cycle", "error", err)
 		return nil, err
 	}
-	if len(parkings) < 1 {
-		record.ParkingLotID = 0
-	} else {
-		record.ParkingLotID = parkings[0].ID
-	}
 
 	if err := s.eventRecordRbtRepo.Store(ctx, record); err != nil {
 		lg.Error("failed to store record", "error", err)
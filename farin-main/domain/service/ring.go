@@ -15,15 +15,17 @@ type RingService struct {
 	ringRepo *repository.RingRepository
 	fr       uploader.FileRepository
 	env      *godotenv.Env
+	cache    *GeometryCache
 }
 
 func NewRingService(logger *slog.Logger, ringRepo *repository.RingRepository, fr uploader.FileRepository,
-	env *godotenv.Env) *RingService {
+	env *godotenv.Env, cache *GeometryCache) *RingService {
 	return &RingService{
 		logger:   logger.With("layer", "RingService"),
 		ringRepo: ringRepo,
 		fr:       fr,
 		env:      env,
+		cache:    cache,
 	}
 }
 
@@ -34,6 +36,7 @@ func (s *RingService) CreateRing(ctx context.Context, ring *entity.Ring) (*entit
 		logger.Error("failed to create ring", "error", err.Error())
 		return nil, err
 	}
+	s.cache.Invalidate()
 	logger.Info("ring created", "ringID", ring.ID)
 	return createdRing, nil
 }
@@ -67,6 +70,7 @@ func (s *RingService) UpdateRing(ctx context.Context, ring *entity.Ring) (*entit
 		logger.Error("failed to update ring", "error", err.Error())
 		return nil, err
 	}
+	s.cache.Invalidate()
 	logger.Info("ring updated", "ringID", updatedRing.ID)
 	return updatedRing, nil
 }
@@ -89,6 +93,7 @@ func (s *RingService) DeleteRing(ctx context.Context, id string) error {
 		logger.Error("failed to delete ring", "error", err.Error())
 		return err
 	}
+	s.cache.Invalidate()
 	logger.Info("ring deleted", "ringID", existingRing.ID)
 	return nil
 }
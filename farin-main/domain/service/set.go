@@ -14,4 +14,6 @@ var ProviderSet = wire.NewSet(
 	NewDeviceService,
 	NewVehicleRecordService, NewRoleService,
 	NewCalenderService, NewRingService, NewDriverAssignmentService,
+	NewPatrolCycleService,
+	NewMapMatchService, NewGeometryCache,
 )
@@ -3,13 +3,16 @@ module farin
 go 1.23.2
 
 require (
-	git.abanppc.com/farin-project/vehicle-records v0.0.0-20250121124609-092f3f1b6735
+	git.abanppc.com/farin-project/vehicle-records v0.1.0
+	git.abanppc.com/farin-project/vehicle-records/util/consumer v0.1.0
+	git.abanppc.com/farin-project/vehicle-records/util/plate v0.1.0
 	github.com/bxcodec/faker/v4 v4.0.0-beta.3
 	github.com/gin-gonic/gin v1.10.0
 	github.com/go-playground/validator/v10 v10.23.0
 	github.com/golang-jwt/jwt v3.2.2+incompatible
 	github.com/google/uuid v1.6.0
 	github.com/google/wire v0.6.0
+	github.com/jackc/pgx/v5 v5.7.1
 	github.com/joho/godotenv v1.5.1
 	github.com/mahdimehrabi/uploader v1.1.1-0.20250120110548-34b506b92ba5
 	github.com/rabbitmq/amqp091-go v1.10.0
@@ -59,7 +62,6 @@ require (
 	github.com/grpc-ecosystem/grpc-gateway/v2 v2.25.1 // indirect
 	github.com/jackc/pgpassfile v1.0.0 // indirect
 	github.com/jackc/pgservicefile v0.0.0-20240606120523-5a60cdf6a761 // indirect
-	github.com/jackc/pgx/v5 v5.7.1 // indirect
 	github.com/jackc/puddle/v2 v2.2.2 // indirect
 	github.com/jinzhu/inflection v1.0.0 // indirect
 	github.com/jinzhu/now v1.1.5 // indirect
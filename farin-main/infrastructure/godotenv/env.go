@@ -1,8 +1,11 @@
 package godotenv
 
 import (
+	"git.abanppc.com/farin-project/vehicle-records/util/consumer"
 	"github.com/joho/godotenv"
 	"os"
+	"strconv"
+	"time"
 )
 
 type Env struct {
@@ -16,6 +19,7 @@ type Env struct {
 	RabbitMQEventExchange       string
 	RabbitMQInternalExchange    string
 	RabbitMQGeodataQueue        string
+	RabbitMQPatrolStateQueue    string
 	MinioHost                   string
 	MinioAccessToken            string
 	MinioSecret                 string
@@ -23,6 +27,29 @@ type Env struct {
 	MinioDoctorBucket           string
 	OpenTelemetryMetricExporter string
 	OpenTelemetryLogExporter    string
+
+	PatrolLapCoverage     float64       // share of the ring outline a vehicle must travel to finish a lap
+	PatrolRingMaxDistance float64       // meters, positions farther from the ring do not advance the lap
+	PatrolCycleMaxIdle    time.Duration // a lap without positions for longer is abandoned
+
+	MapMatchRadius      float64       // meters around a position, on top of its error, segments are looked for in
+	MapMatchSigma       float64       // meters, the least spread of positions around the segment they were taken on
+	MapMatchCandidates  int           // nearest segments scored for a position
+	MapMatchTrailWindow time.Duration // positions of a vehicle this recent are its trail
+
+	GeometryCacheInterval time.Duration // how often the geometry cache checks the tables for changes
+
+	RabbitMQDeadLetterExchange string        // exchange messages that failed every attempt are published on
+	ConsumerMaxAttempts        int           // attempts of a message before it is dead-lettered
+	ConsumerRetryDelay         time.Duration // delay of the first retry of a message, doubled on every next one
+	ConsumerRetryMax           time.Duration
+
+	ShutdownTimeout time.Duration // time the consumers have to drain on SIGTERM, within the grace period of the pod
+
+	GeodataConsumer     consumer.Settings // GEODATA_CONSUMER_*, see getSettings
+	PatrolStateConsumer consumer.Settings // PATROL_STATE_CONSUMER_*
+
+	DriverShiftGrace time.Duration // drivers may log in this long before their shift starts and after it ends
 }
 
 func NewEnv() *Env {
@@ -50,7 +77,74 @@ func (e *Env) Load() {
 	e.MinioProfilePictureBucket = os.Getenv("MINIO_PROFILE_PICTURE_BUCKET")
 	e.MinioDoctorBucket = os.Getenv("MINIO_DOCTOR_BUCKET")
 	e.RabbitMQGeodataQueue = os.Getenv("RABBITMQ_GEODATA_QUEUE")
+	e.RabbitMQPatrolStateQueue = os.Getenv("RABBITMQ_PATROL_STATE_QUEUE")
 
 	e.OpenTelemetryMetricExporter = os.Getenv("OPEN_TELEMETRY_METRIC_EXPORTER")
 	e.OpenTelemetryLogExporter = os.Getenv("OPEN_TELEMETRY_LOG_EXPORTER")
+
+	e.PatrolLapCoverage = getFloat("PATROL_LAP_COVERAGE", 0.95)
+	e.PatrolRingMaxDistance = getFloat("PATROL_RING_MAX_DISTANCE", 50)
+	e.PatrolCycleMaxIdle = getDuration("PATROL_CYCLE_MAX_IDLE", 2*time.Hour)
+
+	e.MapMatchRadius = getFloat("MAP_MATCH_RADIUS", 25)
+	e.MapMatchSigma = getFloat("MAP_MATCH_SIGMA", 10)
+	e.MapMatchCandidates = getInt("MAP_MATCH_CANDIDATES", 5)
+	e.MapMatchTrailWindow = getDuration("MAP_MATCH_TRAIL_WINDOW", time.Minute)
+
+	e.GeometryCacheInterval = getDuration("GEOMETRY_CACHE_INTERVAL", 5*time.Minute)
+
+	e.RabbitMQDeadLetterExchange = os.Getenv("RABBITMQ_DEAD_LETTER_EXCHANGE")
+	if e.RabbitMQDeadLetterExchange == "" {
+		e.RabbitMQDeadLetterExchange = "farin.dead-letter"
+	}
+	e.ConsumerMaxAttempts = getInt("CONSUMER_MAX_ATTEMPTS", 5)
+	e.ConsumerRetryDelay = getDuration("CONSUMER_RETRY_DELAY", 5*time.Second)
+	e.ConsumerRetryMax = getDuration("CONSUMER_RETRY_MAX", 5*time.Minute)
+	e.ShutdownTimeout = getDuration("SHUTDOWN_TIMEOUT", 25*time.Second)
+	// a handler has 65s, over the 60s of the tehran api calls
+	e.GeodataConsumer = getSettings("GEODATA_CONSUMER", consumer.Settings{
+		Prefetch: 10, Workers: 3, MaxWorkers: 10, Buffer: 10, Timeout: 65 * time.Second,
+	})
+	e.PatrolStateConsumer = getSettings("PATROL_STATE_CONSUMER", consumer.Settings{
+		Prefetch: 10, Workers: 3, MaxWorkers: 6, Buffer: 10, Timeout: 65 * time.Second,
+	})
+	e.DriverShiftGrace = getDuration("DRIVER_SHIFT_GRACE", 30*time.Minute)
+}
+
+func getInt(key string, def int) int {
+	i, err := strconv.Atoi(os.Getenv(key))
+	if err != nil || i <= 0 {
+		return def
+	}
+	return i
+}
+
+func getFloat(key string, def float64) float64 {
+	f, err := strconv.ParseFloat(os.Getenv(key), 64)
+	if err != nil || f <= 0 {
+		return def
+	}
+	return f
+}
+
+func getDuration(key string, def time.Duration) time.Duration {
+	d, err := time.ParseDuration(os.Getenv(key))
+	if err != nil || d <= 0 {
+		return def
+	}
+	return d
+}
+
+// getSettings reads the settings of a consumer from the variables starting
+// with prefix, such as GEODATA_CONSUMER_WORKERS, def holds the defaults
+func getSettings(prefix string, def consumer.Settings) consumer.Settings {
+	return consumer.Settings{
+		Prefetch:       getInt(prefix+"_PREFETCH", def.Prefetch),
+		Workers:        getInt(prefix+"_WORKERS", def.Workers),
+		MaxWorkers:     getInt(prefix+"_MAX_WORKERS", def.MaxWorkers),
+		Buffer:         getInt(prefix+"_BUFFER", def.Buffer),
+		Timeout:        getDuration(prefix+"_TIMEOUT", def.Timeout),
+		QueueTTL:       getDuration(prefix+"_QUEUE_TTL", def.QueueTTL),
+		QueueMaxLength: getInt(prefix+"_QUEUE_MAX_LENGTH", def.QueueMaxLength),
+	}
 }
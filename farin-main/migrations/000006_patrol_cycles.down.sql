@@ -0,0 +1 @@
+DROP TABLE IF EXISTS patrol_cycles;
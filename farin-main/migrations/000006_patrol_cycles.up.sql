@@ -0,0 +1,21 @@
+CREATE TABLE patrol_cycles
+(
+    id             UUID DEFAULT uuid_generate_v4() PRIMARY KEY,
+    lpr_vehicle_id UUID             NOT NULL,
+    ring_id        BIGINT           NOT NULL,
+    cycle_number   INTEGER          NOT NULL,
+    status         VARCHAR(20)      NOT NULL,
+    progress       DOUBLE PRECISION NOT NULL DEFAULT 0,
+    last_position  DOUBLE PRECISION NOT NULL DEFAULT 0,
+    start_time     BIGINT           NOT NULL,
+    last_seen_time BIGINT           NOT NULL,
+    end_time       BIGINT,
+
+    created_at     BIGINT           NOT NULL,
+    updated_at     BIGINT           NOT NULL,
+    deleted_at     BIGINT           NOT NULL DEFAULT 0
+);
+
+CREATE UNIQUE INDEX idx_patrol_cycles_vehicle_number ON patrol_cycles (lpr_vehicle_id, cycle_number);
+CREATE UNIQUE INDEX idx_patrol_cycles_open ON patrol_cycles (lpr_vehicle_id) WHERE status = 'open' AND deleted_at = 0;
+CREATE INDEX idx_patrol_cycles_vehicle_start_time ON patrol_cycles (lpr_vehicle_id, start_time);
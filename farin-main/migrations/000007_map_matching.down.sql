@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_parkings_geom;
+DROP INDEX IF EXISTS idx_roads_geom;
+DROP INDEX IF EXISTS idx_rings_geom;
+DROP INDEX IF EXISTS idx_segments_geom;
+DROP TABLE IF EXISTS vehicle_trail_points;
@@ -0,0 +1,15 @@
+-- recent positions of LPR vehicles, records are map-matched against the trail of their vehicle
+CREATE TABLE vehicle_trail_points
+(
+    lpr_vehicle_id UUID                  NOT NULL,
+    recorded_at    BIGINT                NOT NULL,
+    geom           GEOMETRY(Point, 32639) NOT NULL
+);
+
+CREATE INDEX idx_vehicle_trail_points_vehicle_time ON vehicle_trail_points (lpr_vehicle_id, recorded_at);
+
+-- nearest-neighbour ordering of the map-matching query
+CREATE INDEX IF NOT EXISTS idx_segments_geom ON segments USING GIST (geom);
+CREATE INDEX IF NOT EXISTS idx_rings_geom ON rings USING GIST (geom);
+CREATE INDEX IF NOT EXISTS idx_roads_geom ON roads USING GIST (geom);
+CREATE INDEX IF NOT EXISTS idx_parkings_geom ON parkings USING GIST (geom);
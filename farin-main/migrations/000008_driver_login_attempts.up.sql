@@ -0,0 +1,22 @@
+CREATE TABLE driver_login_attempts
+(
+    id            UUID DEFAULT uuid_generate_v4() PRIMARY KEY,
+    user_id       UUID         NOT NULL,
+    driver_id     UUID         NOT NULL REFERENCES drivers (id) ON DELETE CASCADE,
+    contractor_id UUID,
+    code_vehicle  VARCHAR(150) NOT NULL,
+    assignment_id UUID,
+    calender_id   UUID,
+    work_shift    VARCHAR(17),
+    shift_start   BIGINT,
+    shift_end     BIGINT,
+    accepted      BOOLEAN      NOT NULL,
+    result        VARCHAR(20)  NOT NULL,
+
+    created_at    BIGINT       NOT NULL,
+    updated_at    BIGINT       NOT NULL,
+    deleted_at    BIGINT       NOT NULL DEFAULT 0
+);
+
+CREATE INDEX idx_driver_login_attempts_contractor_created_at ON driver_login_attempts (contractor_id, created_at);
+CREATE INDEX idx_driver_login_attempts_driver_created_at ON driver_login_attempts (driver_id, created_at);
@@ -0,0 +1,5 @@
+DROP TRIGGER IF EXISTS trg_parkings_geometry_changed ON parkings;
+DROP TRIGGER IF EXISTS trg_roads_geometry_changed ON roads;
+DROP TRIGGER IF EXISTS trg_segments_geometry_changed ON segments;
+DROP TRIGGER IF EXISTS trg_rings_geometry_changed ON rings;
+DROP FUNCTION IF EXISTS notify_geometry_changed();
@@ -0,0 +1,21 @@
+-- the geometry cache of every farin instance reloads once a layer is written, whichever service writes it
+CREATE OR REPLACE FUNCTION notify_geometry_changed() RETURNS trigger AS
+$$
+BEGIN
+    PERFORM pg_notify('geometry_changed', TG_TABLE_NAME);
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER trg_rings_geometry_changed
+    AFTER INSERT OR UPDATE OR DELETE OR TRUNCATE ON rings
+    FOR EACH STATEMENT EXECUTE FUNCTION notify_geometry_changed();
+CREATE TRIGGER trg_segments_geometry_changed
+    AFTER INSERT OR UPDATE OR DELETE OR TRUNCATE ON segments
+    FOR EACH STATEMENT EXECUTE FUNCTION notify_geometry_changed();
+CREATE TRIGGER trg_roads_geometry_changed
+    AFTER INSERT OR UPDATE OR DELETE OR TRUNCATE ON roads
+    FOR EACH STATEMENT EXECUTE FUNCTION notify_geometry_changed();
+CREATE TRIGGER trg_parkings_geometry_changed
+    AFTER INSERT OR UPDATE OR DELETE OR TRUNCATE ON parkings
+    FOR EACH STATEMENT EXECUTE FUNCTION notify_geometry_changed();
@@ -0,0 +1,216 @@
+// Package geo holds the ring, segment, road and parking geometries in memory.
+// Geometries are kept in the projected EPSG:32639 coordinates they are stored
+// in, positions are projected with Project the way the SQL queries do it with
+// ST_Transform, so distances are in meters.
+package geo
+
+import "math"
+
+type Point struct {
+	X float64
+	Y float64
+}
+
+// Rect is a bounding box
+type Rect struct {
+	Min Point
+	Max Point
+}
+
+// Distance returns the distance of p from the box, zero inside it
+func (r Rect) Distance(p Point) float64 {
+	dx := math.Max(math.Max(r.Min.X-p.X, 0), p.X-r.Max.X)
+	dy := math.Max(math.Max(r.Min.Y-p.Y, 0), p.Y-r.Max.Y)
+	return math.Hypot(dx, dy)
+}
+
+func (r Rect) union(o Rect) Rect {
+	return Rect{
+		Min: Point{X: math.Min(r.Min.X, o.Min.X), Y: math.Min(r.Min.Y, o.Min.Y)},
+		Max: Point{X: math.Max(r.Max.X, o.Max.X), Y: math.Max(r.Max.Y, o.Max.Y)},
+	}
+}
+
+func (r Rect) center() Point {
+	return Point{X: (r.Min.X + r.Max.X) / 2, Y: (r.Min.Y + r.Max.Y) / 2}
+}
+
+// Geometry is a point, line or polygon or a collection of them. Polygons are
+// lists of rings, the first ring is the shell and the others are holes.
+type Geometry struct {
+	Points   []Point
+	Lines    [][]Point
+	Polygons [][][]Point
+}
+
+// Empty tells if the geometry has no coordinates
+func (g *Geometry) Empty() bool {
+	return len(g.Points) == 0 && len(g.Lines) == 0 && len(g.Polygons) == 0
+}
+
+// Dimension returns 2 for geometries with polygons, 1 for geometries with
+// lines and 0 for points, as ST_Dimension
+func (g *Geometry) Dimension() int {
+	switch {
+	case len(g.Polygons) > 0:
+		return 2
+	case len(g.Lines) > 0:
+		return 1
+	}
+	return 0
+}
+
+// Bounds returns the bounding box of the geometry
+func (g *Geometry) Bounds() Rect {
+	r := Rect{Min: Point{X: math.Inf(1), Y: math.Inf(1)}, Max: Point{X: math.Inf(-1), Y: math.Inf(-1)}}
+	add := func(pts []Point) {
+		for _, p := range pts {
+			r = r.union(Rect{Min: p, Max: p})
+		}
+	}
+	add(g.Points)
+	for _, l := range g.Lines {
+		add(l)
+	}
+	for _, poly := range g.Polygons {
+		if len(poly) > 0 {
+			add(poly[0])
+		}
+	}
+	return r
+}
+
+// Distance returns the distance of p from the geometry, zero inside a
+// polygon, as ST_Distance
+func (g *Geometry) Distance(p Point) float64 {
+	_, d := g.closest(p)
+	return d
+}
+
+// ClosestPoint returns the point of the geometry closest to p, p itself
+// inside a polygon, as ST_ClosestPoint
+func (g *Geometry) ClosestPoint(p Point) Point {
+	c, _ := g.closest(p)
+	return c
+}
+
+func (g *Geometry) closest(p Point) (Point, float64) {
+	best, bestD := p, math.Inf(1)
+	try := func(c Point) {
+		if d := math.Hypot(c.X-p.X, c.Y-p.Y); d < bestD {
+			best, bestD = c, d
+		}
+	}
+	for _, q := range g.Points {
+		try(q)
+	}
+	for _, l := range g.Lines {
+		c, _, _ := closestOnLine(l, p)
+		try(c)
+	}
+	for _, poly := range g.Polygons {
+		if polygonContains(poly, p) {
+			return p, 0
+		}
+		for _, ring := range poly {
+			c, _, _ := closestOnLine(ring, p)
+			try(c)
+		}
+	}
+	return best, bestD
+}
+
+// Bearing returns the direction in degrees from north of the line closest to
+// p, measured between the points span meters before and after the closest
+// point. Only line geometries have a bearing.
+func (g *Geometry) Bearing(p Point, span float64) (float64, bool) {
+	if g.Dimension() != 1 {
+		return 0, false
+	}
+	var line []Point
+	var at, bestD float64
+	bestD = math.Inf(1)
+	for _, l := range g.Lines {
+		c, pos, _ := closestOnLine(l, p)
+		if d := math.Hypot(c.X-p.X, c.Y-p.Y); d < bestD {
+			line, at, bestD = l, pos, d
+		}
+	}
+	length := lineLength(line)
+	if length == 0 {
+		return 0, false
+	}
+	span = math.Min(span, length/2)
+	from := interpolate(line, math.Max(at-span, 0))
+	to := interpolate(line, math.Min(at+span, length))
+	if from == to {
+		return 0, false
+	}
+	b := math.Atan2(to.X-from.X, to.Y-from.Y) * 180 / math.Pi
+	if b < 0 {
+		b += 360
+	}
+	return b, true
+}
+
+// closestOnLine returns the point of a line closest to p, its distance along
+// the line from the start and its distance from p
+func closestOnLine(line []Point, p Point) (Point, float64, float64) {
+	if len(line) == 1 {
+		return line[0], 0, math.Hypot(line[0].X-p.X, line[0].Y-p.Y)
+	}
+	best, bestPos, bestD := p, 0.0, math.Inf(1)
+	walked := 0.0
+	for i := 1; i < len(line); i++ {
+		a, b := line[i-1], line[i]
+		dx, dy := b.X-a.X, b.Y-a.Y
+		segLen := math.Hypot(dx, dy)
+		t := 0.0
+		if segLen > 0 {
+			t = math.Max(0, math.Min(1, ((p.X-a.X)*dx+(p.Y-a.Y)*dy)/(segLen*segLen)))
+		}
+		c := Point{X: a.X + t*dx, Y: a.Y + t*dy}
+		if d := math.Hypot(c.X-p.X, c.Y-p.Y); d < bestD {
+			best, bestPos, bestD = c, walked+t*segLen, d
+		}
+		walked += segLen
+	}
+	return best, bestPos, bestD
+}
+
+func lineLength(line []Point) float64 {
+	var l float64
+	for i := 1; i < len(line); i++ {
+		l += math.Hypot(line[i].X-line[i-1].X, line[i].Y-line[i-1].Y)
+	}
+	return l
+}
+
+// interpolate returns the point at a distance along a line
+func interpolate(line []Point, at float64) Point {
+	for i := 1; i < len(line); i++ {
+		a, b := line[i-1], line[i]
+		segLen := math.Hypot(b.X-a.X, b.Y-a.Y)
+		if at <= segLen && segLen > 0 {
+			t := at / segLen
+			return Point{X: a.X + t*(b.X-a.X), Y: a.Y + t*(b.Y-a.Y)}
+		}
+		at -= segLen
+	}
+	return line[len(line)-1]
+}
+
+// polygonContains tells if p is inside a polygon, holes are left out by the
+// even-odd rule
+func polygonContains(poly [][]Point, p Point) bool {
+	in := false
+	for _, ring := range poly {
+		for i, j := 0, len(ring)-1; i < len(ring); j, i = i, i+1 {
+			a, b := ring[i], ring[j]
+			if (a.Y > p.Y) != (b.Y > p.Y) && p.X < (b.X-a.X)*(p.Y-a.Y)/(b.Y-a.Y)+a.X {
+				in = !in
+			}
+		}
+	}
+	return in
+}
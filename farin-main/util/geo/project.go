@@ -0,0 +1,46 @@
+package geo
+
+import "math"
+
+// UTM zone 39N on WGS 84, EPSG:32639
+const (
+	wgs84A          = 6378137.0
+	wgs84F          = 1 / 298.257223563
+	utmScale        = 0.9996
+	utmFalseEasting = 500000.0
+	utm39Meridian   = 51.0
+)
+
+var (
+	tmN = wgs84F / (2 - wgs84F)
+	// tmA is the radius of the rectifying sphere
+	tmA     = wgs84A / (1 + tmN) * (1 + tmN*tmN/4 + tmN*tmN*tmN*tmN/64)
+	tmAlpha = [4]float64{
+		tmN/2 - 2*tmN*tmN/3 + 5*tmN*tmN*tmN/16 + 41*tmN*tmN*tmN*tmN/180,
+		13*tmN*tmN/48 - 3*tmN*tmN*tmN/5 + 557*tmN*tmN*tmN*tmN/1440,
+		61*tmN*tmN*tmN/240 - 103*tmN*tmN*tmN*tmN/140,
+		49561 * tmN * tmN * tmN * tmN / 161280,
+	}
+	tmE = 2 * math.Sqrt(tmN) / (1 + tmN)
+)
+
+// Project turns a WGS 84 longitude and latitude, EPSG:4326, into EPSG:32639
+// with the Krüger series of the transverse Mercator projection, as PROJ does
+// for ST_Transform. It is accurate to a millimeter within the zone.
+func Project(lon, lat float64) Point {
+	phi := lat * math.Pi / 180
+	lambda := (lon - utm39Meridian) * math.Pi / 180
+
+	sinPhi := math.Sin(phi)
+	t := math.Sinh(math.Atanh(sinPhi) - tmE*math.Atanh(tmE*sinPhi))
+	xi := math.Atan2(t, math.Cos(lambda))
+	eta := math.Atanh(math.Sin(lambda) / math.Sqrt(1+t*t))
+
+	x, y := eta, xi
+	for j, a := range tmAlpha {
+		k := float64(2 * (j + 1))
+		y += a * math.Sin(k*xi) * math.Cosh(k*eta)
+		x += a * math.Cos(k*xi) * math.Sinh(k*eta)
+	}
+	return Point{X: utmFalseEasting + utmScale*tmA*x, Y: utmScale * tmA * y}
+}
@@ -0,0 +1,29 @@
+package geo
+
+import (
+	"math"
+	"testing"
+)
+
+func TestProject(t *testing.T) {
+	// EPSG:4326 to EPSG:32639 as ST_Transform gives them, to the millimeter
+	tests := []struct {
+		lon, lat float64
+		x, y     float64
+	}{
+		{51, 0, 500000, 0},
+		{51, 45, 500000, 4982950.400},
+		{51.389, 35.6892, 535196.782, 3949546.789},
+		{51.338, 35.6997, 530578.267, 3950694.260},
+		{48, 30, 210590.347, 3322575.904},
+		{54, 38, 763421.376, 4210063.034},
+		{50.5, 25.3, 449667.938, 2798260.848},
+		{53.9, 39.7, 748642.760, 4398482.334},
+	}
+	for _, tt := range tests {
+		p := Project(tt.lon, tt.lat)
+		if math.Abs(p.X-tt.x) > 0.001 || math.Abs(p.Y-tt.y) > 0.001 {
+			t.Errorf("Project(%v, %v) = %.3f, %.3f, want %.3f, %.3f", tt.lon, tt.lat, p.X, p.Y, tt.x, tt.y)
+		}
+	}
+}
@@ -0,0 +1,155 @@
+package geo
+
+import (
+	"container/heap"
+	"math"
+	"sort"
+)
+
+// rtreeNodeSize is the number of children of a node
+const rtreeNodeSize = 16
+
+// Entry is a geometry of an RTree with its value
+type Entry[T any] struct {
+	Geom   Geometry
+	Value  T
+	bounds Rect
+}
+
+// Neighbor is an entry found near a point with its distance
+type Neighbor[T any] struct {
+	Entry    *Entry[T]
+	Distance float64
+}
+
+// RTree is a read-only R-tree packed with the sort-tile-recursive algorithm,
+// it is safe for concurrent use
+type RTree[T any] struct {
+	entries []Entry[T]
+	root    *rtreeNode
+}
+
+type rtreeNode struct {
+	bounds   Rect
+	children []*rtreeNode
+	// entries holds the indexes of the entries of a leaf
+	entries []int
+}
+
+// NewRTree indexes entries, empty geometries are left out
+func NewRTree[T any](entries []Entry[T]) *RTree[T] {
+	t := &RTree[T]{}
+	for _, e := range entries {
+		if e.Geom.Empty() {
+			continue
+		}
+		e.bounds = e.Geom.Bounds()
+		t.entries = append(t.entries, e)
+	}
+	if len(t.entries) == 0 {
+		return t
+	}
+
+	nodes := make([]*rtreeNode, len(t.entries))
+	for i := range t.entries {
+		nodes[i] = &rtreeNode{bounds: t.entries[i].bounds, entries: []int{i}}
+	}
+	// the first level packs entries into leaves, the next ones nodes into nodes
+	leaves := true
+	for len(nodes) > 1 || leaves {
+		nodes = pack(nodes, leaves)
+		leaves = false
+	}
+	t.root = nodes[0]
+	return t
+}
+
+// Len returns the number of entries
+func (t *RTree[T]) Len() int {
+	return len(t.entries)
+}
+
+// pack groups nodes by rtreeNodeSize in tiles of vertical slices, leaves
+// merges the entries of the nodes instead of keeping them as children
+func pack(nodes []*rtreeNode, leaves bool) []*rtreeNode {
+	groups := int(math.Ceil(float64(len(nodes)) / rtreeNodeSize))
+	slices := int(math.Ceil(math.Sqrt(float64(groups))))
+	sliceSize := slices * rtreeNodeSize
+
+	sort.Slice(nodes, func(i, j int) bool { return nodes[i].bounds.center().X < nodes[j].bounds.center().X })
+	var packed []*rtreeNode
+	for s := 0; s < len(nodes); s += sliceSize {
+		slice := nodes[s:min(s+sliceSize, len(nodes))]
+		sort.Slice(slice, func(i, j int) bool { return slice[i].bounds.center().Y < slice[j].bounds.center().Y })
+		for g := 0; g < len(slice); g += rtreeNodeSize {
+			group := slice[g:min(g+rtreeNodeSize, len(slice))]
+			n := &rtreeNode{bounds: group[0].bounds}
+			for _, c := range group {
+				n.bounds = n.bounds.union(c.bounds)
+				if leaves {
+					n.entries = append(n.entries, c.entries...)
+				} else {
+					n.children = append(n.children, c)
+				}
+			}
+			packed = append(packed, n)
+		}
+	}
+	return packed
+}
+
+// Nearest returns up to k entries within maxDist of p, nearest first, as an
+// ORDER BY geom <-> p LIMIT k on ST_DWithin
+func (t *RTree[T]) Nearest(p Point, maxDist float64, k int) []Neighbor[T] {
+	if t.root == nil || k <= 0 {
+		return nil
+	}
+	var found []Neighbor[T]
+	q := &rtreeQueue{{node: t.root, dist: t.root.bounds.Distance(p)}}
+	for q.Len() > 0 && len(found) < k {
+		item := heap.Pop(q).(rtreeQueueItem)
+		if item.dist > maxDist {
+			break
+		}
+		if item.node == nil {
+			found = append(found, Neighbor[T]{Entry: &t.entries[item.entry], Distance: item.dist})
+			continue
+		}
+		for _, c := range item.node.children {
+			if d := c.bounds.Distance(p); d <= maxDist {
+				heap.Push(q, rtreeQueueItem{node: c, dist: d})
+			}
+		}
+		for _, i := range item.node.entries {
+			e := &t.entries[i]
+			if e.bounds.Distance(p) > maxDist {
+				continue
+			}
+			if d := e.Geom.Distance(p); d <= maxDist {
+				heap.Push(q, rtreeQueueItem{entry: i, dist: d})
+			}
+		}
+	}
+	return found
+}
+
+// rtreeQueueItem is a node to visit, by the distance of its bounds, or an
+// entry, by its exact distance
+type rtreeQueueItem struct {
+	node  *rtreeNode
+	entry int
+	dist  float64
+}
+
+type rtreeQueue []rtreeQueueItem
+
+func (q rtreeQueue) Len() int           { return len(q) }
+func (q rtreeQueue) Less(i, j int) bool { return q[i].dist < q[j].dist }
+func (q rtreeQueue) Swap(i, j int)      { q[i], q[j] = q[j], q[i] }
+func (q *rtreeQueue) Push(x any)        { *q = append(*q, x.(rtreeQueueItem)) }
+func (q *rtreeQueue) Pop() any {
+	old := *q
+	item := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return item
+}
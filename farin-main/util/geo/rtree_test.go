@@ -0,0 +1,93 @@
+package geo
+
+import (
+	"math/rand"
+	"sort"
+	"testing"
+)
+
+// randomGeometries returns points, lines and polygons spread over a few
+// kilometers, some of them overlapping
+func randomGeometries(r *rand.Rand, n int) []Entry[int] {
+	pt := func() Point {
+		return Point{X: 530000 + r.Float64()*5000, Y: 3950000 + r.Float64()*5000}
+	}
+	near := func(p Point) Point {
+		return Point{X: p.X + r.Float64()*200 - 100, Y: p.Y + r.Float64()*200 - 100}
+	}
+	entries := make([]Entry[int], n)
+	for i := range entries {
+		var g Geometry
+		switch i % 4 {
+		case 0:
+			g.Points = []Point{pt()}
+		case 1, 2:
+			line := []Point{pt()}
+			for j := 0; j < 1+r.Intn(4); j++ {
+				line = append(line, near(line[len(line)-1]))
+			}
+			g.Lines = [][]Point{line}
+		case 3:
+			c := pt()
+			w, h := 10+r.Float64()*100, 10+r.Float64()*100
+			g.Polygons = [][][]Point{{{c, {X: c.X + w, Y: c.Y}, {X: c.X + w, Y: c.Y + h}, {X: c.X, Y: c.Y + h}, c}}}
+		}
+		entries[i] = Entry[int]{Geom: g, Value: i}
+	}
+	return entries
+}
+
+func TestNearest(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	entries := randomGeometries(r, 2000)
+	// an empty geometry is never found
+	entries = append(entries, Entry[int]{Value: -1})
+	tree := NewRTree(entries)
+	if tree.Len() != 2000 {
+		t.Fatalf("Len() = %d, want 2000", tree.Len())
+	}
+
+	for i := 0; i < 200; i++ {
+		p := Point{X: 529500 + r.Float64()*6000, Y: 3949500 + r.Float64()*6000}
+		maxDist := []float64{0, 20, 100, 500}[i%4]
+		k := 1 + r.Intn(10)
+
+		var want []float64
+		for _, e := range entries[:2000] {
+			if d := e.Geom.Distance(p); d <= maxDist {
+				want = append(want, d)
+			}
+		}
+		sort.Float64s(want)
+		if len(want) > k {
+			want = want[:k]
+		}
+
+		got := tree.Nearest(p, maxDist, k)
+		if len(got) != len(want) {
+			t.Errorf("Nearest(%v, %v, %d) found %d, brute force %d", p, maxDist, k, len(got), len(want))
+			continue
+		}
+		for j, n := range got {
+			// ties may come in any order, their distances may not
+			if n.Distance != want[j] {
+				t.Errorf("Nearest(%v, %v, %d)[%d] = %v, brute force %v", p, maxDist, k, j, n.Distance, want[j])
+			}
+			if d := n.Entry.Geom.Distance(p); d != n.Distance {
+				t.Errorf("Nearest(%v, %v, %d)[%d] distance = %v, entry is at %v", p, maxDist, k, j,
+					n.Distance, d)
+			}
+		}
+	}
+}
+
+func TestNearestEmpty(t *testing.T) {
+	tree := NewRTree[int](nil)
+	if got := tree.Nearest(Point{}, 100, 5); got != nil {
+		t.Errorf("Nearest() on an empty tree = %v, want nil", got)
+	}
+	tree = NewRTree([]Entry[int]{{Geom: Geometry{Points: []Point{{X: 1, Y: 1}}}}})
+	if got := tree.Nearest(Point{}, 100, 0); got != nil {
+		t.Errorf("Nearest(k = 0) = %v, want nil", got)
+	}
+}
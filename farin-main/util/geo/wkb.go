@@ -0,0 +1,169 @@
+package geo
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+)
+
+var ErrInvalidWKB = errors.New("invalid wkb")
+
+// WKB geometry types
+const (
+	wkbPoint              = 1
+	wkbLineString         = 2
+	wkbPolygon            = 3
+	wkbMultiPoint         = 4
+	wkbMultiLineString    = 5
+	wkbMultiPolygon       = 6
+	wkbGeometryCollection = 7
+)
+
+// EWKB flags of PostGIS
+const (
+	ewkbZ    = 0x80000000
+	ewkbM    = 0x40000000
+	ewkbSRID = 0x20000000
+)
+
+// ParseWKB reads a geometry as returned by ST_AsBinary or ST_AsEWKB, Z and M
+// ordinates are dropped
+func ParseWKB(b []byte) (Geometry, error) {
+	r := &wkbReader{b: b}
+	var g Geometry
+	if err := r.geometry(&g); err != nil {
+		return Geometry{}, err
+	}
+	return g, nil
+}
+
+type wkbReader struct {
+	b     []byte
+	order binary.ByteOrder
+}
+
+func (r *wkbReader) geometry(g *Geometry) error {
+	if len(r.b) < 5 {
+		return fmt.Errorf("%w: truncated header", ErrInvalidWKB)
+	}
+	switch r.b[0] {
+	case 0:
+		r.order = binary.BigEndian
+	case 1:
+		r.order = binary.LittleEndian
+	default:
+		return fmt.Errorf("%w: byte order %d", ErrInvalidWKB, r.b[0])
+	}
+	typ := r.order.Uint32(r.b[1:])
+	r.b = r.b[5:]
+
+	dims := 2
+	if typ&ewkbZ != 0 {
+		dims++
+	}
+	if typ&ewkbM != 0 {
+		dims++
+	}
+	if typ&ewkbSRID != 0 {
+		if _, err := r.uint32(); err != nil {
+			return err
+		}
+	}
+	typ &= 0xffff
+	// ISO WKB adds 1000 for Z, 2000 for M and 3000 for both
+	switch typ / 1000 {
+	case 1, 2:
+		dims++
+	case 3:
+		dims += 2
+	}
+	typ %= 1000
+
+	switch typ {
+	case wkbPoint:
+		p, err := r.point(dims)
+		if err != nil {
+			return err
+		}
+		// an empty point has NaN ordinates
+		if !math.IsNaN(p.X) && !math.IsNaN(p.Y) {
+			g.Points = append(g.Points, p)
+		}
+	case wkbLineString:
+		l, err := r.line(dims)
+		if err != nil {
+			return err
+		}
+		if len(l) > 0 {
+			g.Lines = append(g.Lines, l)
+		}
+	case wkbPolygon:
+		n, err := r.uint32()
+		if err != nil {
+			return err
+		}
+		poly := make([][]Point, 0, n)
+		for i := uint32(0); i < n; i++ {
+			ring, err := r.line(dims)
+			if err != nil {
+				return err
+			}
+			poly = append(poly, ring)
+		}
+		if len(poly) > 0 {
+			g.Polygons = append(g.Polygons, poly)
+		}
+	case wkbMultiPoint, wkbMultiLineString, wkbMultiPolygon, wkbGeometryCollection:
+		n, err := r.uint32()
+		if err != nil {
+			return err
+		}
+		for i := uint32(0); i < n; i++ {
+			if err := r.geometry(g); err != nil {
+				return err
+			}
+		}
+	default:
+		return fmt.Errorf("%w: geometry type %d", ErrInvalidWKB, typ)
+	}
+	return nil
+}
+
+func (r *wkbReader) uint32() (uint32, error) {
+	if len(r.b) < 4 {
+		return 0, fmt.Errorf("%w: truncated", ErrInvalidWKB)
+	}
+	v := r.order.Uint32(r.b)
+	r.b = r.b[4:]
+	return v, nil
+}
+
+func (r *wkbReader) point(dims int) (Point, error) {
+	if len(r.b) < 8*dims {
+		return Point{}, fmt.Errorf("%w: truncated point", ErrInvalidWKB)
+	}
+	p := Point{
+		X: math.Float64frombits(r.order.Uint64(r.b)),
+		Y: math.Float64frombits(r.order.Uint64(r.b[8:])),
+	}
+	r.b = r.b[8*dims:]
+	return p, nil
+}
+
+func (r *wkbReader) line(dims int) ([]Point, error) {
+	n, err := r.uint32()
+	if err != nil {
+		return nil, err
+	}
+	if uint64(len(r.b)) < uint64(n)*uint64(8*dims) {
+		return nil, fmt.Errorf("%w: truncated line", ErrInvalidWKB)
+	}
+	line := make([]Point, n)
+	for i := range line {
+		if line[i], err = r.point(dims); err != nil {
+			return nil, err
+		}
+	}
+	return line, nil
+}
@@ -2,4 +2,4 @@ module seeder
 
 go 1.22
 
-require github.com/rabbitmq/amqp091-go v1.10.0 // indirect
+require github.com/rabbitmq/amqp091-go v1.10.0
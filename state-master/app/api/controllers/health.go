@@ -1,7 +1,6 @@
 package controller
 
 import (
-	"context"
 	"git.abanppc.com/farin-project/state/app/api/response"
 	"git.abanppc.com/farin-project/state/app/rabbit/consumers"
 	gormdb "git.abanppc.com/farin-project/state/infrastructure/gorm"
@@ -44,14 +43,24 @@ func (hc *HealthController) Liveness(c *gin.Context) {
 func (hc *HealthController) Readiness(c *gin.Context) {
 	lg := hc.logger.With("method", "Readiness")
 
+	// a draining pod takes no new traffic, liveness keeps passing so it is
+	// not restarted before the drain ends
+	if hc.eventConsumer.Draining() {
+		lg.Info("consumers are draining")
+		response.Custom(c, http.StatusServiceUnavailable, nil, "")
+		return
+	}
+
 	if err := hc.rbt.HealthCheck(); err != nil {
 		lg.Error("rabbitMQ health check failed", "error", err)
 		response.Custom(c, http.StatusServiceUnavailable, nil, "")
+		return
 	}
 
-	if err := hc.gorm.HealthCheck(context.Background()); err != nil {
+	if err := hc.gorm.HealthCheck(c.Request.Context()); err != nil {
 		lg.Error("mongodb health check failed", "error", err)
 		response.Custom(c, http.StatusServiceUnavailable, nil, "")
+		return
 	}
 
 	hc.lastReady = time.Now()
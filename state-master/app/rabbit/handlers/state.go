@@ -5,6 +5,7 @@ import (
 	"git.abanppc.com/farin-project/state/app/rabbit/consumers"
 	"git.abanppc.com/farin-project/state/domain/entity"
 	"git.abanppc.com/farin-project/state/domain/service"
+	"git.abanppc.com/farin-project/vehicle-records/util/consumer"
 	"log/slog"
 	"time"
 )
@@ -23,7 +24,7 @@ func (a *State) State(ctx context.Context, data []byte) error {
 	state := &entity.State{}
 	if err := state.UnmarshalJSON(data); err != nil {
 		lg.Warn("failed to unmarshal vehicle record", "error", err)
-		return nil
+		return consumer.Permanent(err)
 	}
 	state, err := a.ss.CreateState(ctx, state)
 	if err != nil {
@@ -1,6 +1,8 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"git.abanppc.com/farin-project/state/app/api/routes"
 	"git.abanppc.com/farin-project/state/app/rabbit/consumers"
 	"git.abanppc.com/farin-project/state/app/rabbit/handlers"
@@ -8,8 +10,16 @@ import (
 	"git.abanppc.com/farin-project/state/infrastructure/rabbit"
 	"github.com/gin-gonic/gin"
 	"log"
+	"net/http"
+	"os/signal"
+	"syscall"
+	"time"
 )
 
+// httpShutdownTimeout is the time the api has to finish its requests after
+// the consumers drained
+const httpShutdownTimeout = 5 * time.Second
+
 type Boot struct {
 	rts           []routes.Router
 	stateConsumer *consumers.StateConsumer
@@ -27,6 +37,9 @@ func NewBoot(event *handlers.State, eventConsumer *consumers.StateConsumer, rbt
 func (b *Boot) Boot() {
 	env := godotenv.NewEnv()
 	env.Load()
+	// cancelled on SIGTERM, which starts the drain
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
 	r := gin.Default()
 	for _, router := range b.rts {
 		router.SetupRoutes(r)
@@ -40,7 +53,34 @@ func (b *Boot) Boot() {
 		}
 	}()
 
-	if err := r.Run(":" + env.HTTPPort); err != nil {
-		log.Fatalf("error running gin grpcServer error:%s", err.Error())
+	srv := &http.Server{Addr: ":" + env.HTTPPort, Handler: r}
+	go func() {
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatalf("error running gin grpcServer error:%s", err.Error())
+		}
+	}()
+
+	<-ctx.Done()
+	b.shutdown(env, srv)
+}
+
+// shutdown drains the consumers while the api keeps answering with a failing
+// readiness, then closes rabbitmq and the api last
+func (b *Boot) shutdown(env *godotenv.Env, srv *http.Server) {
+	log.Printf("shutting down, draining consumers for %s", env.ShutdownTimeout)
+
+	ctx, cancel := context.WithTimeout(context.Background(), env.ShutdownTimeout)
+	defer cancel()
+	if err := b.cr.Shutdown(ctx); err != nil {
+		log.Printf("failed to drain consumers:%s", err)
+	}
+	if err := b.rbt.Close(); err != nil {
+		log.Printf("failed to close rabbitmq:%s", err)
+	}
+
+	httpCtx, httpCancel := context.WithTimeout(context.Background(), httpShutdownTimeout)
+	defer httpCancel()
+	if err := srv.Shutdown(httpCtx); err != nil {
+		log.Printf("failed to shutdown http server:%s", err)
 	}
 }
@@ -3,6 +3,7 @@ module git.abanppc.com/farin-project/state
 go 1.23.2
 
 require (
+	git.abanppc.com/farin-project/vehicle-records/util/consumer v0.1.0
 	github.com/gin-gonic/gin v1.10.0
 	github.com/golang-jwt/jwt v3.2.2+incompatible
 	github.com/google/uuid v1.6.0
@@ -1,8 +1,11 @@
 package godotenv
 
 import (
+	"git.abanppc.com/farin-project/vehicle-records/util/consumer"
 	"github.com/joho/godotenv"
 	"os"
+	"strconv"
+	"time"
 )
 
 type Env struct {
@@ -15,6 +18,15 @@ type Env struct {
 	RabbitMQHost          string
 	RabbitMQEventExchange string
 	RabbitMQStateQueue    string
+
+	RabbitMQDeadLetterExchange string        //exchange messages that failed every attempt are published on
+	ConsumerMaxAttempts        int           //attempts of a message before it is dead-lettered
+	ConsumerRetryDelay         time.Duration //delay of the first retry of a message, doubled on every next one
+	ConsumerRetryMax           time.Duration
+
+	ShutdownTimeout time.Duration //time the consumers have to drain on SIGTERM, within the grace period of the pod
+
+	StateConsumer consumer.Settings //STATE_CONSUMER_*, see getSettings
 }
 
 func NewEnv() *Env {
@@ -34,4 +46,49 @@ func (e *Env) Load() {
 	e.RabbitMQHost = os.Getenv("RABBITMQ_HOST")
 	e.RabbitMQEventExchange = os.Getenv("RABBITMQ_EVENT_EXCHANGE")
 	e.RabbitMQStateQueue = os.Getenv("RABBITMQ_STATE_QUEUE")
+	e.RabbitMQDeadLetterExchange = os.Getenv("RABBITMQ_DEAD_LETTER_EXCHANGE")
+	if e.RabbitMQDeadLetterExchange == "" {
+		e.RabbitMQDeadLetterExchange = "farin.dead-letter"
+	}
+	e.ConsumerMaxAttempts = getInt("CONSUMER_MAX_ATTEMPTS", 5)
+	e.ConsumerRetryDelay = getDuration("CONSUMER_RETRY_DELAY", 5*time.Second)
+	e.ConsumerRetryMax = getDuration("CONSUMER_RETRY_MAX", 5*time.Minute)
+	e.ShutdownTimeout = getDuration("SHUTDOWN_TIMEOUT", 25*time.Second)
+	e.StateConsumer = getSettings("STATE_CONSUMER", consumer.Settings{
+		Prefetch: 4, Workers: 10, MaxWorkers: 10, Buffer: 30, Timeout: 55 * time.Second, QueueMaxLength: 1000,
+	})
+}
+
+// getInt reads a positive number from the environment, def is used when the
+// variable is missing or malformed
+func getInt(key string, def int) int {
+	i, err := strconv.Atoi(os.Getenv(key))
+	if err != nil || i <= 0 {
+		return def
+	}
+	return i
+}
+
+// getDuration reads a duration such as "10m" from the environment, def is
+// used when the variable is missing or malformed
+func getDuration(key string, def time.Duration) time.Duration {
+	d, err := time.ParseDuration(os.Getenv(key))
+	if err != nil || d <= 0 {
+		return def
+	}
+	return d
+}
+
+// getSettings reads the settings of a consumer from the variables starting
+// with prefix, such as STATE_CONSUMER_WORKERS, def holds the defaults
+func getSettings(prefix string, def consumer.Settings) consumer.Settings {
+	return consumer.Settings{
+		Prefetch:       getInt(prefix+"_PREFETCH", def.Prefetch),
+		Workers:        getInt(prefix+"_WORKERS", def.Workers),
+		MaxWorkers:     getInt(prefix+"_MAX_WORKERS", def.MaxWorkers),
+		Buffer:         getInt(prefix+"_BUFFER", def.Buffer),
+		Timeout:        getDuration(prefix+"_TIMEOUT", def.Timeout),
+		QueueTTL:       getDuration(prefix+"_QUEUE_TTL", def.QueueTTL),
+		QueueMaxLength: getInt(prefix+"_QUEUE_MAX_LENGTH", def.QueueMaxLength),
+	}
 }
@@ -1,7 +1,9 @@
 package rabbit
 
 import (
+	"context"
 	"errors"
+	"fmt"
 	"git.abanppc.com/farin-project/state/app/rabbit/consumers"
 	"git.abanppc.com/farin-project/state/infrastructure/godotenv"
 	"log/slog"
@@ -30,6 +32,21 @@ func (cr *ConsumerRunner) RunInnerWorkers() {
 	}
 }
 
+// Shutdown drains the consumers at once, until ctx is done
+func (cr *ConsumerRunner) Shutdown(ctx context.Context) error {
+	errs := make([]error, len(cr.consumers))
+	var wg sync.WaitGroup
+	for i, c := range cr.consumers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = c.Shutdown(ctx)
+		}()
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
+
 func (cr *ConsumerRunner) Setup(ch *amqp091.Channel) error {
 	cr.channel = ch
 
@@ -59,6 +76,7 @@ type Rabbit struct {
 
 	lg        *slog.Logger
 	connected bool
+	closing   bool // set by Close, the connection is not reopened
 	Mutex     sync.RWMutex
 }
 
@@ -74,6 +92,9 @@ func (r *Rabbit) Setup(consumerRunner *ConsumerRunner) error {
 	r.Mutex.Lock()
 	defer r.Mutex.Unlock()
 
+	if r.closing {
+		return errors.New("rabbitmq is closed")
+	}
 	if err := r.cleanup(); err != nil {
 		return err
 	}
@@ -91,22 +112,25 @@ func (r *Rabbit) Setup(consumerRunner *ConsumerRunner) error {
 	}
 
 	r.connected = true
-	go r.handleDisconnect(consumerRunner)
+	go r.handleDisconnect(consumerRunner,
+		r.conn.NotifyClose(make(chan *amqp091.Error, 1)),
+		r.ch.NotifyClose(make(chan *amqp091.Error, 1)))
 
 	return nil
 }
 
 func (r *Rabbit) cleanup() error {
-	if r.conn != nil {
-		if err := r.conn.Close(); err != nil {
-			r.lg.Error("failed to close connection", "err", err)
-		}
-	}
-	if r.ch != nil {
+	r.connected = false
+	if r.ch != nil && !r.ch.IsClosed() {
 		if err := r.ch.Close(); err != nil {
 			r.lg.Error("failed to close channel", "err", err)
 		}
 	}
+	if r.conn != nil && !r.conn.IsClosed() {
+		if err := r.conn.Close(); err != nil {
+			r.lg.Error("failed to close connection", "err", err)
+		}
+	}
 	return nil
 }
 
@@ -126,27 +150,40 @@ func (r *Rabbit) setupChannel() error {
 	}
 	r.ch = ch
 
-	if err := r.ch.Qos(4, 0, false); err != nil {
+	// the prefetch is set by every consumer, retries and dead letters are
+	// acked once the broker stored their copy
+	if err := r.ch.Confirm(false); err != nil {
 		return err
 	}
 
 	return nil
 }
 
-func (r *Rabbit) handleDisconnect(consumerRunner *ConsumerRunner) {
-	closeChan := make(chan *amqp091.Error)
-	r.conn.NotifyClose(closeChan)
-
-	err := <-closeChan
-	r.connected = false
+// handleDisconnect waits for the connection or the channel to close with an
+// error, such as on a broker restart, then sets up the connection, the
+// exchanges, the queues and the consumers again, backing off between failed
+// attempts. Closes without an error are made by Setup or Close.
+func (r *Rabbit) handleDisconnect(consumerRunner *ConsumerRunner, connClosed, chClosed <-chan *amqp091.Error) {
+	var err *amqp091.Error
+	select {
+	case err = <-connClosed:
+	case err = <-chClosed:
+	}
+	if err == nil || r.isClosing() {
+		r.lg.Info("RabbitMQ connection closed")
+		return
+	}
 	r.lg.Error("RabbitMQ connection closed", "err", err)
 
 	backoff := 1 * time.Second
 	maxBackoff := 30 * time.Second
 
-	for !r.connected {
+	for {
 		r.lg.Info("attempting to reconnect to RabbitMQ", "backoff", backoff)
 		time.Sleep(backoff)
+		if r.isClosing() {
+			return
+		}
 
 		if err := r.Setup(consumerRunner); err != nil {
 			r.lg.Error("failed to reconnect", "err", err)
@@ -162,6 +199,33 @@ func (r *Rabbit) handleDisconnect(consumerRunner *ConsumerRunner) {
 	}
 }
 
+func (r *Rabbit) isClosing() bool {
+	r.Mutex.RLock()
+	defer r.Mutex.RUnlock()
+	return r.closing
+}
+
+// Close closes the channel, which returns its unacked deliveries to the
+// queue, then the connection. The consumers are drained before it.
+func (r *Rabbit) Close() error {
+	r.Mutex.Lock()
+	defer r.Mutex.Unlock()
+
+	r.closing = true
+	var errs []error
+	if r.ch != nil && !r.ch.IsClosed() {
+		if err := r.ch.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("failed to close channel: %w", err))
+		}
+	}
+	if r.conn != nil && !r.conn.IsClosed() {
+		if err := r.conn.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("failed to close connection: %w", err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
 func (r *Rabbit) HealthCheck() error {
 	r.Mutex.RLock()
 	defer r.Mutex.RUnlock()
@@ -0,0 +1,152 @@
+package controller
+
+import (
+	"errors"
+	"strconv"
+
+	"git.abanppc.com/farin-project/vehicle-records/app/api/response"
+	"git.abanppc.com/farin-project/vehicle-records/domain/service"
+	"git.abanppc.com/farin-project/vehicle-records/util/consumer"
+	"github.com/gin-gonic/gin"
+)
+
+// maxDeadLetters is the most dead letters listed or replayed by a request
+const maxDeadLetters = 100
+
+type DeadLetterController struct {
+	service *service.DeadLetterService
+}
+
+func NewDeadLetterController(service *service.DeadLetterService) *DeadLetterController {
+	return &DeadLetterController{service: service}
+}
+
+type DeadLetterList struct {
+	Items any `json:"items"`
+	Total int `json:"total"`
+}
+
+// ReplayDeadLettersRequest selects dead letters by id, or the first limit
+// of them without ids
+type ReplayDeadLettersRequest struct {
+	IDs   []string `json:"ids"`
+	Limit int      `json:"limit" binding:"min=0,max=100"`
+}
+
+type PurgeDeadLettersRequest struct {
+	IDs []string `json:"ids"`
+}
+
+type DeadLetterCount struct {
+	Count int `json:"count"`
+}
+
+// Queues godoc
+// @Summary      List the queues with dead letters
+// @Description  List the queues of this service whose messages are dead-lettered after their last attempt
+// @Tags         dead-letters
+// @Param        x-api-key  header  string  true  "API key with the deadletters:manage scope"
+// @Success      200   {object}  response.Response[[]string]
+// @Failure      401   {object}  response.Response[swagger.EmptyObject]
+// @Failure      403   {object}  response.Response[swagger.EmptyObject]
+// @Router       /v1/dead-letters [get]
+func (d DeadLetterController) Queues(c *gin.Context) {
+	response.Ok(c, d.service.Queues(), "")
+}
+
+// List godoc
+// @Summary      Inspect dead letters
+// @Description  List the dead letters of a queue, oldest first, with the error of their last attempt
+// @Tags         dead-letters
+// @Param        queue      path    string  true   "Queue"
+// @Param        limit      query   int     false  "Dead letters listed, at most 100"
+// @Param        x-api-key  header  string  true   "API key with the deadletters:manage scope"
+// @Success      200   {object}  response.Response[DeadLetterList]
+// @Failure      400   {object}  response.Response[swagger.EmptyObject]
+// @Failure      401   {object}  response.Response[swagger.EmptyObject]
+// @Failure      403   {object}  response.Response[swagger.EmptyObject]
+// @Failure      404   {object}  response.Response[swagger.EmptyObject]
+// @Failure      500   {object}  response.Response[swagger.EmptyObject]
+// @Router       /v1/dead-letters/{queue} [get]
+func (d DeadLetterController) List(c *gin.Context) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if err != nil || limit < 1 || limit > maxDeadLetters {
+		response.BadRequest(c, "Invalid limit parameter")
+		return
+	}
+	letters, total, err := d.service.List(c, c.Param("queue"), limit)
+	if err != nil {
+		deadLetterError(c, err)
+		return
+	}
+	response.Ok(c, DeadLetterList{Items: letters, Total: total}, "")
+}
+
+// Replay godoc
+// @Summary      Replay dead letters
+// @Description  Send dead letters back to their queue with a fresh attempt count, by id or the oldest ones up to limit
+// @Tags         dead-letters
+// @Param        queue      path    string                    true  "Queue"
+// @Param        request    body    ReplayDeadLettersRequest  true  "Dead letters"
+// @Param        x-api-key  header  string                    true  "API key with the deadletters:manage scope"
+// @Success      200   {object}  response.Response[DeadLetterCount]
+// @Failure      400   {object}  response.Response[swagger.EmptyObject]
+// @Failure      401   {object}  response.Response[swagger.EmptyObject]
+// @Failure      403   {object}  response.Response[swagger.EmptyObject]
+// @Failure      404   {object}  response.Response[swagger.EmptyObject]
+// @Failure      500   {object}  response.Response[swagger.EmptyObject]
+// @Router       /v1/dead-letters/{queue}/replay [post]
+func (d DeadLetterController) Replay(c *gin.Context) {
+	var req ReplayDeadLettersRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "Invalid request body")
+		return
+	}
+	if len(req.IDs) == 0 && req.Limit == 0 {
+		response.BadRequest(c, "ids or limit is required")
+		return
+	}
+	n, err := d.service.Replay(c, c.Param("queue"), req.IDs, req.Limit, reviewer(c))
+	if err != nil {
+		deadLetterError(c, err)
+		return
+	}
+	response.Ok(c, DeadLetterCount{Count: n}, "")
+}
+
+// Purge godoc
+// @Summary      Purge dead letters
+// @Description  Delete dead letters by id, or every dead letter of the queue without ids
+// @Tags         dead-letters
+// @Param        queue      path    string                   true  "Queue"
+// @Param        request    body    PurgeDeadLettersRequest  true  "Dead letters"
+// @Param        x-api-key  header  string                   true  "API key with the deadletters:manage scope"
+// @Success      200   {object}  response.Response[DeadLetterCount]
+// @Failure      400   {object}  response.Response[swagger.EmptyObject]
+// @Failure      401   {object}  response.Response[swagger.EmptyObject]
+// @Failure      403   {object}  response.Response[swagger.EmptyObject]
+// @Failure      404   {object}  response.Response[swagger.EmptyObject]
+// @Failure      500   {object}  response.Response[swagger.EmptyObject]
+// @Router       /v1/dead-letters/{queue}/purge [post]
+func (d DeadLetterController) Purge(c *gin.Context) {
+	var req PurgeDeadLettersRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "Invalid request body")
+		return
+	}
+	n, err := d.service.Purge(c, c.Param("queue"), req.IDs, reviewer(c))
+	if err != nil {
+		deadLetterError(c, err)
+		return
+	}
+	response.Ok(c, DeadLetterCount{Count: n}, "")
+}
+
+func deadLetterError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrUnknownQueue), errors.Is(err, consumer.ErrDeadQueueNotFound):
+		response.NotFound(c)
+	default:
+		response.InternalError(c)
+	}
+}
@@ -0,0 +1,88 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"git.abanppc.com/farin-project/vehicle-records/app/api/response"
+	"git.abanppc.com/farin-project/vehicle-records/domain/repository"
+	"git.abanppc.com/farin-project/vehicle-records/domain/service"
+	"git.abanppc.com/farin-project/vehicle-records/util/evidence"
+	"github.com/gin-gonic/gin"
+)
+
+type EvidenceController struct {
+	service *service.EvidenceService
+}
+
+func NewEvidenceController(service *service.EvidenceService) *EvidenceController {
+	return &EvidenceController{service: service}
+}
+
+type EvidencePublicKey struct {
+	KeyID     string `json:"keyId"`
+	PublicKey string `json:"publicKey"`
+}
+
+// Export godoc
+// @Summary      Export the evidence package of a record
+// @Description  Build a ZIP of the original photos, plate crops, metadata and a map of the GPS point over the segment, with a SHA-256 manifest signed by the service key
+// @Tags         evidence
+// @Produce      application/zip
+// @Param        id         path    string  true  "VehicleRecord ID"
+// @Param        x-api-key  header  string  true  "API key with the records:evidence scope"
+// @Success      200
+// @Failure      401   {object}  response.Response[swagger.EmptyObject]
+// @Failure      403   {object}  response.Response[swagger.EmptyObject]
+// @Failure      404   {object}  response.Response[swagger.EmptyObject]
+// @Failure      500   {object}  response.Response[swagger.EmptyObject]
+// @Failure      503   {object}  response.Response[swagger.EmptyObject]
+// @Router       /v1/vehicle-records/{id}/evidence [get]
+func (e EvidenceController) Export(c *gin.Context) {
+	id := c.Param("id")
+	pkg, err := e.service.Export(c, id, reviewer(c))
+	if err != nil {
+		evidenceError(c, err)
+		return
+	}
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=\"evidence_%s.zip\"", id))
+	c.Header("Cache-Control", "no-store")
+	c.Data(http.StatusOK, "application/zip", pkg)
+}
+
+// PublicKey godoc
+// @Summary      Get the evidence verification key
+// @Description  Get the PEM public key the manifests of evidence packages are signed with
+// @Tags         evidence
+// @Param        x-api-key  header  string  true  "API key with the records:evidence scope"
+// @Success      200   {object}  response.Response[EvidencePublicKey]
+// @Failure      401   {object}  response.Response[swagger.EmptyObject]
+// @Failure      403   {object}  response.Response[swagger.EmptyObject]
+// @Failure      500   {object}  response.Response[swagger.EmptyObject]
+// @Failure      503   {object}  response.Response[swagger.EmptyObject]
+// @Router       /v1/evidence/public-key [get]
+func (e EvidenceController) PublicKey(c *gin.Context) {
+	pub, keyID, err := e.service.PublicKey()
+	if err != nil {
+		evidenceError(c, err)
+		return
+	}
+	pb, err := evidence.PublicKeyPEM(pub)
+	if err != nil {
+		response.InternalError(c)
+		return
+	}
+	response.Ok(c, EvidencePublicKey{KeyID: keyID, PublicKey: string(pb)}, "")
+}
+
+func evidenceError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, repository.ErrVehicleRecordNotFound):
+		response.NotFound(c)
+	case errors.Is(err, service.ErrEvidenceUnsigned):
+		response.Custom(c, http.StatusServiceUnavailable, struct{}{}, "Evidence export is not configured")
+	default:
+		response.InternalError(c)
+	}
+}
@@ -0,0 +1,139 @@
+package controller
+
+import (
+	"errors"
+	"strconv"
+	"time"
+
+	"git.abanppc.com/farin-project/vehicle-records/app/api/response"
+	"git.abanppc.com/farin-project/vehicle-records/domain/repository"
+	"git.abanppc.com/farin-project/vehicle-records/domain/service"
+	"github.com/gin-gonic/gin"
+)
+
+type ForwardingController struct {
+	service *service.ForwardingAttemptService
+}
+
+func NewForwardingController(service *service.ForwardingAttemptService) *ForwardingController {
+	return &ForwardingController{service: service}
+}
+
+type ForwardingAttemptList struct {
+	Items any   `json:"items"`
+	Total int64 `json:"total"`
+}
+
+// Attempts godoc
+// @Summary      List forwarding attempts of a vehicle record
+// @Description  List every attempt of sending the record to its municipality, newest first
+// @Tags         forwarding
+// @Param        id        path   string  true   "VehicleRecord ID"
+// @Param        page      query  int     false  "Page, starts from 1"
+// @Param        pageSize  query  int     false  "Page size, at most 100"
+// @Success      200   {object}  response.Response[ForwardingAttemptList]
+// @Failure      400   {object}  response.Response[swagger.EmptyObject]
+// @Failure      404   {object}  response.Response[swagger.EmptyObject]
+// @Failure      500   {object}  response.Response[swagger.EmptyObject]
+// @Router       /v1/vehicle-records/{id}/attempts [get]
+func (f ForwardingController) Attempts(c *gin.Context) {
+	id := c.Param("id")
+	if id == "" {
+		response.NotFound(c)
+		return
+	}
+	page, pageSize, ok := pagination(c)
+	if !ok {
+		return
+	}
+
+	attempts, total, err := f.service.Attempts(c, id, page, pageSize)
+	if err != nil {
+		if errors.Is(err, repository.ErrVehicleRecordNotFound) {
+			response.NotFound(c)
+			return
+		}
+		response.InternalError(c)
+		return
+	}
+	response.Ok(c, ForwardingAttemptList{Items: attempts, Total: total}, "")
+}
+
+// Failures godoc
+// @Summary      List failed forwarding attempts
+// @Description  List failed attempts of sending records to municipalities, newest first
+// @Tags         forwarding
+// @Param        forwarder   query  string  false  "Forwarder name"
+// @Param        errorClass  query  string  false  "auth, request, timeout, transport, status, response or unknown"
+// @Param        statusCode  query  int     false  "HTTP status code"
+// @Param        from        query  string  false  "Attempts made at or after, RFC3339"
+// @Param        to          query  string  false  "Attempts made before, RFC3339"
+// @Param        page        query  int     false  "Page, starts from 1"
+// @Param        pageSize    query  int     false  "Page size, at most 100"
+// @Success      200   {object}  response.Response[ForwardingAttemptList]
+// @Failure      400   {object}  response.Response[swagger.EmptyObject]
+// @Failure      500   {object}  response.Response[swagger.EmptyObject]
+// @Router       /v1/forwarding/failures [get]
+func (f ForwardingController) Failures(c *gin.Context) {
+	filters := map[string]interface{}{}
+	if forwarder := c.Query("forwarder"); forwarder != "" {
+		filters["forwarder"] = forwarder
+	}
+	if errorClass := c.Query("errorClass"); errorClass != "" {
+		filters["error_class"] = errorClass
+	}
+	if v := c.Query("statusCode"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			response.BadRequest(c, "Invalid statusCode parameter")
+			return
+		}
+		filters["status_code"] = n
+	}
+
+	var from, to int64
+	if v := c.Query("from"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			response.BadRequest(c, "Invalid from time format")
+			return
+		}
+		from = t.UnixMilli()
+	}
+	if v := c.Query("to"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			response.BadRequest(c, "Invalid to time format")
+			return
+		}
+		to = t.UnixMilli()
+	}
+
+	page, pageSize, ok := pagination(c)
+	if !ok {
+		return
+	}
+
+	attempts, total, err := f.service.Failures(c, filters, from, to, page, pageSize)
+	if err != nil {
+		response.InternalError(c)
+		return
+	}
+	response.Ok(c, ForwardingAttemptList{Items: attempts, Total: total}, "")
+}
+
+// pagination reads the page and pageSize query parameters, it responds with
+// a bad request and returns false when they are invalid
+func pagination(c *gin.Context) (int, int, bool) {
+	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if err != nil || page < 1 {
+		response.BadRequest(c, "Invalid page parameter")
+		return 0, 0, false
+	}
+	pageSize, err := strconv.Atoi(c.DefaultQuery("pageSize", "20"))
+	if err != nil || pageSize < 1 || pageSize > maxPageSize {
+		response.BadRequest(c, "Invalid pageSize parameter")
+		return 0, 0, false
+	}
+	return page, pageSize, true
+}
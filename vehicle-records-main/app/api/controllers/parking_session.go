@@ -0,0 +1,130 @@
+package controller
+
+import (
+	"errors"
+	"strconv"
+	"time"
+
+	"git.abanppc.com/farin-project/vehicle-records/app/api/response"
+	_ "git.abanppc.com/farin-project/vehicle-records/domain/entity"
+	"git.abanppc.com/farin-project/vehicle-records/domain/repository"
+	"git.abanppc.com/farin-project/vehicle-records/domain/service"
+	"github.com/gin-gonic/gin"
+)
+
+const maxPageSize = 100
+
+type ParkingSessionController struct {
+	service *service.ParkingSessionService
+}
+
+func NewParkingSessionController(service *service.ParkingSessionService) *ParkingSessionController {
+	return &ParkingSessionController{service: service}
+}
+
+type ParkingSessionList struct {
+	Items any   `json:"items"`
+	Total int64 `json:"total"`
+}
+
+// List godoc
+// @Summary      List parking sessions
+// @Description  List parking sessions inferred from repeated sightings, newest first
+// @Tags         parkingSessions
+// @Param        plate         query  string  false  "Numeric plate number"
+// @Param        segmentId     query  int     false  "Segment ID"
+// @Param        parkingLotId  query  int     false  "Parking lot ID"
+// @Param        status        query  string  false  "open, closed or expired"
+// @Param        from          query  string  false  "Sessions started at or after, RFC3339"
+// @Param        to            query  string  false  "Sessions started before, RFC3339"
+// @Param        page          query  int     false  "Page, starts from 1"
+// @Param        pageSize      query  int     false  "Page size, at most 100"
+// @Success      200   {object}  response.Response[ParkingSessionList]
+// @Failure      400   {object}  response.Response[swagger.EmptyObject]
+// @Failure      500   {object}  response.Response[swagger.EmptyObject]
+// @Router       /v1/parking-sessions [get]
+func (p ParkingSessionController) List(c *gin.Context) {
+	filters := map[string]interface{}{}
+	for param, column := range map[string]string{
+		"plate":        "citizen_plate_number_numeric",
+		"segmentId":    "segment_id",
+		"parkingLotId": "parking_lot_id",
+	} {
+		value := c.Query(param)
+		if value == "" {
+			continue
+		}
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			response.BadRequest(c, "Invalid "+param+" parameter")
+			return
+		}
+		filters[column] = n
+	}
+	if status := c.Query("status"); status != "" {
+		filters["status"] = status
+	}
+
+	var from, to int64
+	if v := c.Query("from"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			response.BadRequest(c, "Invalid from time format")
+			return
+		}
+		from = t.UnixMilli()
+	}
+	if v := c.Query("to"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			response.BadRequest(c, "Invalid to time format")
+			return
+		}
+		to = t.UnixMilli()
+	}
+
+	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if err != nil || page < 1 {
+		response.BadRequest(c, "Invalid page parameter")
+		return
+	}
+	pageSize, err := strconv.Atoi(c.DefaultQuery("pageSize", "20"))
+	if err != nil || pageSize < 1 || pageSize > maxPageSize {
+		response.BadRequest(c, "Invalid pageSize parameter")
+		return
+	}
+
+	sessions, total, err := p.service.List(c, filters, from, to, page, pageSize)
+	if err != nil {
+		response.InternalError(c)
+		return
+	}
+	response.Ok(c, ParkingSessionList{Items: sessions, Total: total}, "")
+}
+
+// Detail godoc
+// @Summary      Get parking session details
+// @Description  Retrieve a parking session by ID
+// @Tags         parkingSessions
+// @Param        id   path      string  true  "Parking session ID"
+// @Success      200   {object}  response.Response[entity.ParkingSession]
+// @Failure      500   {object}  response.Response[swagger.EmptyObject]
+// @Failure      404   {object}  response.Response[swagger.EmptyObject]
+// @Router       /v1/parking-sessions/{id} [get]
+func (p ParkingSessionController) Detail(c *gin.Context) {
+	id := c.Param("id")
+	if id == "" {
+		response.NotFound(c)
+		return
+	}
+	session, err := p.service.Detail(c, id)
+	if err != nil {
+		if errors.Is(err, repository.ErrParkingSessionNotFound) {
+			response.NotFound(c)
+			return
+		}
+		response.InternalError(c)
+		return
+	}
+	response.Ok(c, session, "")
+}
@@ -0,0 +1,88 @@
+package controller
+
+import (
+	"errors"
+	"strconv"
+	"time"
+
+	"git.abanppc.com/farin-project/vehicle-records/app/api/response"
+	"git.abanppc.com/farin-project/vehicle-records/domain/service"
+	"git.abanppc.com/farin-project/vehicle-records/util/plate"
+	"github.com/gin-gonic/gin"
+)
+
+type PlateHistoryController struct {
+	service *service.PlateHistoryService
+}
+
+func NewPlateHistoryController(service *service.PlateHistoryService) *PlateHistoryController {
+	return &PlateHistoryController{service: service}
+}
+
+// History godoc
+// @Summary      Get the history of a plate
+// @Description  List the sightings of a plate newest first with cursor pagination, the first page also counts the sightings per day and per segment. Fuzzy adds plates that differ by one character the cameras misread.
+// @Tags         plates
+// @Param        plate      path    string  true   "Plate, such as 12ب34567 or 12 BE 345 IR 67"
+// @Param        fuzzy      query   bool    false  "Match confusable plates too"
+// @Param        from       query   string  false  "Records stored at or after, RFC3339"
+// @Param        to         query   string  false  "Records stored before, RFC3339"
+// @Param        ringId     query   int     false  "Ring ID"
+// @Param        segmentId  query   int     false  "Segment ID"
+// @Param        streetId   query   int     false  "Street ID"
+// @Param        cursor     query   string  false  "nextCursor of the previous page"
+// @Param        limit      query   int     false  "Page size, 20 by default and at most 100"
+// @Param        x-api-key  header  string  true   "API key with the records:read scope"
+// @Success      200   {object}  response.Response[service.PlateHistory]
+// @Failure      400   {object}  response.Response[swagger.EmptyObject]
+// @Failure      401   {object}  response.Response[swagger.EmptyObject]
+// @Failure      403   {object}  response.Response[swagger.EmptyObject]
+// @Failure      500   {object}  response.Response[swagger.EmptyObject]
+// @Router       /v1/plates/{plate}/history [get]
+func (p PlateHistoryController) History(c *gin.Context) {
+	q := service.PlateHistoryQuery{Plate: c.Param("plate"), Cursor: c.Query("cursor")}
+
+	var err error
+	if q.Fuzzy, err = strconv.ParseBool(c.DefaultQuery("fuzzy", "false")); err != nil {
+		response.BadRequest(c, "Invalid fuzzy parameter")
+		return
+	}
+	if q.Limit, err = strconv.Atoi(c.DefaultQuery("limit", "20")); err != nil || q.Limit < 1 || q.Limit > maxPageSize {
+		response.BadRequest(c, "Invalid limit parameter")
+		return
+	}
+	for name, dst := range map[string]*int64{"from": &q.From, "to": &q.To} {
+		if v := c.Query(name); v != "" {
+			t, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				response.BadRequest(c, "Invalid "+name+" time format")
+				return
+			}
+			*dst = t.UnixMilli()
+		}
+	}
+	for name, dst := range map[string]*int64{"ringId": &q.RingID, "segmentId": &q.SegmentID, "streetId": &q.StreetID} {
+		if v := c.Query(name); v != "" {
+			n, err := strconv.ParseInt(v, 10, 64)
+			if err != nil || n < 1 {
+				response.BadRequest(c, "Invalid "+name+" parameter")
+				return
+			}
+			*dst = n
+		}
+	}
+
+	history, err := p.service.History(c, q)
+	if err != nil {
+		switch {
+		case errors.Is(err, plate.ErrInvalidPlate):
+			response.BadRequest(c, "Invalid plate")
+		case errors.Is(err, service.ErrInvalidCursor):
+			response.BadRequest(c, "Invalid cursor parameter")
+		default:
+			response.InternalError(c)
+		}
+		return
+	}
+	response.Ok(c, history, "")
+}
@@ -0,0 +1,180 @@
+package controller
+
+import (
+	"errors"
+	"time"
+
+	"git.abanppc.com/farin-project/vehicle-records/app/api/response"
+	"git.abanppc.com/farin-project/vehicle-records/domain/repository"
+	"git.abanppc.com/farin-project/vehicle-records/domain/service"
+	"github.com/gin-gonic/gin"
+)
+
+type RetentionController struct {
+	service *service.RetentionService
+}
+
+func NewRetentionController(service *service.RetentionService) *RetentionController {
+	return &RetentionController{service: service}
+}
+
+type UpdateRetentionPolicyRequest struct {
+	RetentionHours *int `json:"retentionHours" binding:"required,min=0"`
+}
+
+type LegalHoldRequest struct {
+	Reason string `json:"reason" binding:"required"`
+}
+
+type RetentionReport struct {
+	repository.RetentionReport
+	Items any `json:"items"`
+}
+
+// Policies godoc
+// @Summary      List photo retention policies
+// @Description  List how many hours the photos of records are kept in every status, zero keeps them forever
+// @Tags         retention
+// @Param        x-api-key  header  string  true  "API key with the records:retention scope"
+// @Success      200   {object}  response.Response[[]entity.RetentionPolicy]
+// @Failure      401   {object}  response.Response[swagger.EmptyObject]
+// @Failure      403   {object}  response.Response[swagger.EmptyObject]
+// @Failure      500   {object}  response.Response[swagger.EmptyObject]
+// @Router       /v1/retention/policies [get]
+func (r RetentionController) Policies(c *gin.Context) {
+	policies, err := r.service.Policies(c)
+	if err != nil {
+		response.InternalError(c)
+		return
+	}
+	response.Ok(c, policies, "")
+}
+
+// UpdatePolicy godoc
+// @Summary      Update a photo retention policy
+// @Description  Change how many hours the photos of records in a status are kept, zero keeps them forever
+// @Tags         retention
+// @Param        status     path    string                        true  "forwarded, unforwarded, rejected, under_review or evidence_hold"
+// @Param        request    body    UpdateRetentionPolicyRequest  true  "Retention"
+// @Param        x-api-key  header  string                        true  "API key with the records:retention scope"
+// @Success      200   {object}  response.Response[swagger.EmptyObject]
+// @Failure      400   {object}  response.Response[swagger.EmptyObject]
+// @Failure      401   {object}  response.Response[swagger.EmptyObject]
+// @Failure      403   {object}  response.Response[swagger.EmptyObject]
+// @Failure      404   {object}  response.Response[swagger.EmptyObject]
+// @Failure      500   {object}  response.Response[swagger.EmptyObject]
+// @Router       /v1/retention/policies/{status} [put]
+func (r RetentionController) UpdatePolicy(c *gin.Context) {
+	var req UpdateRetentionPolicyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "Invalid request body")
+		return
+	}
+	if err := r.service.UpdatePolicy(c, c.Param("status"), *req.RetentionHours, reviewer(c)); err != nil {
+		retentionError(c, err)
+		return
+	}
+	response.Ok(c, struct{}{}, "")
+}
+
+// SetLegalHold godoc
+// @Summary      Put a record on legal hold
+// @Description  Keep the photos of a record under the evidence_hold policy until the hold is released
+// @Tags         retention
+// @Param        id         path    string            true  "Record ID"
+// @Param        request    body    LegalHoldRequest  true  "Reason of the hold"
+// @Param        x-api-key  header  string            true  "API key with the records:retention scope"
+// @Success      200   {object}  response.Response[swagger.EmptyObject]
+// @Failure      400   {object}  response.Response[swagger.EmptyObject]
+// @Failure      401   {object}  response.Response[swagger.EmptyObject]
+// @Failure      403   {object}  response.Response[swagger.EmptyObject]
+// @Failure      404   {object}  response.Response[swagger.EmptyObject]
+// @Failure      500   {object}  response.Response[swagger.EmptyObject]
+// @Router       /v1/vehicle-records/{id}/legal-hold [put]
+func (r RetentionController) SetLegalHold(c *gin.Context) {
+	var req LegalHoldRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "Invalid request body")
+		return
+	}
+	if err := r.service.Hold(c, c.Param("id"), req.Reason, reviewer(c)); err != nil {
+		retentionError(c, err)
+		return
+	}
+	response.Ok(c, struct{}{}, "")
+}
+
+// ReleaseLegalHold godoc
+// @Summary      Release the legal hold of a record
+// @Description  Return the photos of a record to the policy of its status
+// @Tags         retention
+// @Param        id         path    string  true  "Record ID"
+// @Param        x-api-key  header  string  true  "API key with the records:retention scope"
+// @Success      200   {object}  response.Response[swagger.EmptyObject]
+// @Failure      401   {object}  response.Response[swagger.EmptyObject]
+// @Failure      403   {object}  response.Response[swagger.EmptyObject]
+// @Failure      404   {object}  response.Response[swagger.EmptyObject]
+// @Failure      500   {object}  response.Response[swagger.EmptyObject]
+// @Router       /v1/vehicle-records/{id}/legal-hold [delete]
+func (r RetentionController) ReleaseLegalHold(c *gin.Context) {
+	if err := r.service.Release(c, c.Param("id"), "", reviewer(c)); err != nil {
+		retentionError(c, err)
+		return
+	}
+	response.Ok(c, struct{}{}, "")
+}
+
+// Report godoc
+// @Summary      Report reclaimed storage
+// @Description  Sum the photos, objects and bytes deleted by the reaper passes in a time range and list the passes, newest first
+// @Tags         retention
+// @Param        from       query   string  false  "Passes started at or after, RFC3339"
+// @Param        to         query   string  false  "Passes started before, RFC3339"
+// @Param        page       query   int     false  "Page, starts from 1"
+// @Param        pageSize   query   int     false  "Page size, at most 100"
+// @Param        x-api-key  header  string  true   "API key with the records:retention scope"
+// @Success      200   {object}  response.Response[RetentionReport]
+// @Failure      400   {object}  response.Response[swagger.EmptyObject]
+// @Failure      401   {object}  response.Response[swagger.EmptyObject]
+// @Failure      403   {object}  response.Response[swagger.EmptyObject]
+// @Failure      500   {object}  response.Response[swagger.EmptyObject]
+// @Router       /v1/retention/report [get]
+func (r RetentionController) Report(c *gin.Context) {
+	var from, to int64
+	if v := c.Query("from"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			response.BadRequest(c, "Invalid from time format")
+			return
+		}
+		from = t.UnixMilli()
+	}
+	if v := c.Query("to"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			response.BadRequest(c, "Invalid to time format")
+			return
+		}
+		to = t.UnixMilli()
+	}
+	page, pageSize, ok := pagination(c)
+	if !ok {
+		return
+	}
+
+	report, runs, err := r.service.Report(c, from, to, page, pageSize)
+	if err != nil {
+		response.InternalError(c)
+		return
+	}
+	response.Ok(c, RetentionReport{RetentionReport: *report, Items: runs}, "")
+}
+
+func retentionError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, repository.ErrRetentionPolicyNotFound), errors.Is(err, repository.ErrVehicleRecordNotFound):
+		response.NotFound(c)
+	default:
+		response.InternalError(c)
+	}
+}
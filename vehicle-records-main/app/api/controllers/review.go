@@ -0,0 +1,252 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"git.abanppc.com/farin-project/vehicle-records/app/api/middleware"
+	"git.abanppc.com/farin-project/vehicle-records/app/api/response"
+	"git.abanppc.com/farin-project/vehicle-records/domain/entity"
+	"git.abanppc.com/farin-project/vehicle-records/domain/repository"
+	"git.abanppc.com/farin-project/vehicle-records/domain/service"
+	"git.abanppc.com/farin-project/vehicle-records/util/plate"
+	"github.com/gin-gonic/gin"
+)
+
+type ReviewController struct {
+	service *service.RecordReviewService
+}
+
+func NewReviewController(service *service.RecordReviewService) *ReviewController {
+	return &ReviewController{service: service}
+}
+
+type ReviewList struct {
+	Items any   `json:"items"`
+	Total int64 `json:"total"`
+}
+
+type CorrectPlateRequest struct {
+	Plate string `json:"plate" binding:"required"`
+	Note  string `json:"note"`
+}
+
+type ReviewDecisionRequest struct {
+	Note string `json:"note"`
+}
+
+// List godoc
+// @Summary      List record reviews
+// @Description  List records held for review with temporary photo URLs, oldest first
+// @Tags         reviews
+// @Param        status     query   string  false  "pending, approved or rejected, pending by default"
+// @Param        page       query   int     false  "Page, starts from 1"
+// @Param        pageSize   query   int     false  "Page size, at most 100"
+// @Param        x-api-key  header  string  true   "API key with the records:review scope"
+// @Success      200   {object}  response.Response[ReviewList]
+// @Failure      400   {object}  response.Response[swagger.EmptyObject]
+// @Failure      401   {object}  response.Response[swagger.EmptyObject]
+// @Failure      403   {object}  response.Response[swagger.EmptyObject]
+// @Failure      500   {object}  response.Response[swagger.EmptyObject]
+// @Router       /v1/reviews [get]
+func (r ReviewController) List(c *gin.Context) {
+	status := entity.RecordReviewStatus(c.DefaultQuery("status", string(entity.RecordReviewPending)))
+	switch status {
+	case entity.RecordReviewPending, entity.RecordReviewApproved, entity.RecordReviewRejected:
+	default:
+		response.BadRequest(c, "Invalid status parameter")
+		return
+	}
+	page, pageSize, ok := pagination(c)
+	if !ok {
+		return
+	}
+
+	reviews, total, err := r.service.List(c, status, page, pageSize)
+	if err != nil {
+		response.InternalError(c)
+		return
+	}
+	response.Ok(c, ReviewList{Items: reviews, Total: total}, "")
+}
+
+// Detail godoc
+// @Summary      Get a record review
+// @Description  Get a record review with its record, temporary photo URLs and audit trail
+// @Tags         reviews
+// @Param        id         path    string  true  "Review ID"
+// @Param        x-api-key  header  string  true  "API key with the records:review scope"
+// @Success      200   {object}  response.Response[entity.RecordReview]
+// @Failure      401   {object}  response.Response[swagger.EmptyObject]
+// @Failure      403   {object}  response.Response[swagger.EmptyObject]
+// @Failure      404   {object}  response.Response[swagger.EmptyObject]
+// @Failure      500   {object}  response.Response[swagger.EmptyObject]
+// @Router       /v1/reviews/{id} [get]
+func (r ReviewController) Detail(c *gin.Context) {
+	review, err := r.service.Get(c, c.Param("id"))
+	if err != nil {
+		reviewError(c, err)
+		return
+	}
+	response.Ok(c, review, "")
+}
+
+// CorrectPlate godoc
+// @Summary      Correct the plate of a record under review
+// @Description  Replace the plate of a pending record, the old and new plate are audited with the reviewer
+// @Tags         reviews
+// @Param        id         path    string               true  "Review ID"
+// @Param        request    body    CorrectPlateRequest  true  "Corrected plate"
+// @Param        x-api-key  header  string               true  "API key with the records:review scope"
+// @Param        X-Reviewer header  string               false "Operator, unless the auth proxy sets X-Forwarded-Email or X-Forwarded-User"
+// @Success      200   {object}  response.Response[swagger.EmptyObject]
+// @Failure      400   {object}  response.Response[swagger.EmptyObject]
+// @Failure      401   {object}  response.Response[swagger.EmptyObject]
+// @Failure      403   {object}  response.Response[swagger.EmptyObject]
+// @Failure      404   {object}  response.Response[swagger.EmptyObject]
+// @Failure      409   {object}  response.Response[swagger.EmptyObject]
+// @Failure      500   {object}  response.Response[swagger.EmptyObject]
+// @Router       /v1/reviews/{id}/plate [put]
+func (r ReviewController) CorrectPlate(c *gin.Context) {
+	var req CorrectPlateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "Invalid request body")
+		return
+	}
+	rv, ok := reviewOperator(c)
+	if !ok {
+		return
+	}
+	if err := r.service.Correct(c, c.Param("id"), rv, req.Plate, req.Note); err != nil {
+		reviewError(c, err)
+		return
+	}
+	response.Ok(c, struct{}{}, "")
+}
+
+// Approve godoc
+// @Summary      Approve a record under review
+// @Description  Release a pending record into the forwarding path
+// @Tags         reviews
+// @Param        id         path    string                 true   "Review ID"
+// @Param        request    body    ReviewDecisionRequest  false  "Note of the reviewer"
+// @Param        x-api-key  header  string                 true   "API key with the records:review scope"
+// @Param        X-Reviewer header  string                 false  "Operator, unless the auth proxy sets X-Forwarded-Email or X-Forwarded-User"
+// @Success      200   {object}  response.Response[swagger.EmptyObject]
+// @Failure      400   {object}  response.Response[swagger.EmptyObject]
+// @Failure      401   {object}  response.Response[swagger.EmptyObject]
+// @Failure      403   {object}  response.Response[swagger.EmptyObject]
+// @Failure      404   {object}  response.Response[swagger.EmptyObject]
+// @Failure      409   {object}  response.Response[swagger.EmptyObject]
+// @Failure      500   {object}  response.Response[swagger.EmptyObject]
+// @Router       /v1/reviews/{id}/approve [post]
+func (r ReviewController) Approve(c *gin.Context) {
+	req, ok := decisionRequest(c)
+	if !ok {
+		return
+	}
+	rv, ok := reviewOperator(c)
+	if !ok {
+		return
+	}
+	if err := r.service.Approve(c, c.Param("id"), rv, req.Note); err != nil {
+		reviewError(c, err)
+		return
+	}
+	response.Ok(c, struct{}{}, "")
+}
+
+// Reject godoc
+// @Summary      Reject a record under review
+// @Description  Drop a pending record, it is never forwarded
+// @Tags         reviews
+// @Param        id         path    string                 true   "Review ID"
+// @Param        request    body    ReviewDecisionRequest  false  "Note of the reviewer"
+// @Param        x-api-key  header  string                 true   "API key with the records:review scope"
+// @Param        X-Reviewer header  string                 false  "Operator, unless the auth proxy sets X-Forwarded-Email or X-Forwarded-User"
+// @Success      200   {object}  response.Response[swagger.EmptyObject]
+// @Failure      400   {object}  response.Response[swagger.EmptyObject]
+// @Failure      401   {object}  response.Response[swagger.EmptyObject]
+// @Failure      403   {object}  response.Response[swagger.EmptyObject]
+// @Failure      404   {object}  response.Response[swagger.EmptyObject]
+// @Failure      409   {object}  response.Response[swagger.EmptyObject]
+// @Failure      500   {object}  response.Response[swagger.EmptyObject]
+// @Router       /v1/reviews/{id}/reject [post]
+func (r ReviewController) Reject(c *gin.Context) {
+	req, ok := decisionRequest(c)
+	if !ok {
+		return
+	}
+	rv, ok := reviewOperator(c)
+	if !ok {
+		return
+	}
+	if err := r.service.Reject(c, c.Param("id"), rv, req.Note); err != nil {
+		reviewError(c, err)
+		return
+	}
+	response.Ok(c, struct{}{}, "")
+}
+
+// decisionRequest reads the optional body of a decision, it responds with a
+// bad request and returns false when the body is invalid
+func decisionRequest(c *gin.Context) (ReviewDecisionRequest, bool) {
+	var req ReviewDecisionRequest
+	if c.Request.ContentLength == 0 {
+		return req, true
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "Invalid request body")
+		return req, false
+	}
+	return req, true
+}
+
+// reviewer is the identity audited for a review action, the name and prefix
+// of the api key of the request
+func reviewer(c *gin.Context) string {
+	key := middleware.ApiKey(c)
+	return fmt.Sprintf("%s (%s)", key.Name, key.Prefix)
+}
+
+// reviewerHeaders name the operator of a request in order of preference, the
+// first two are set by oauth2-proxy in front of the review UI
+var reviewerHeaders = []string{"X-Forwarded-Email", "X-Forwarded-User", "X-Reviewer"}
+
+// maxReviewerLength is the size of the reviewer columns
+const maxReviewerLength = 100
+
+// reviewOperator is the identity audited for a review decision, the operator
+// named by the request headers and the api key of the request. A bad request
+// is answered when no operator is named.
+func reviewOperator(c *gin.Context) (entity.Reviewer, bool) {
+	for _, h := range reviewerHeaders {
+		name := strings.TrimSpace(c.GetHeader(h))
+		if name == "" {
+			continue
+		}
+		if len(name) > maxReviewerLength {
+			response.BadRequest(c, "Reviewer is too long")
+			return entity.Reviewer{}, false
+		}
+		return entity.Reviewer{Name: name, ApiKeyID: middleware.ApiKey(c).ID}, true
+	}
+	response.BadRequest(c, "Reviewer is required, set the X-Reviewer header")
+	return entity.Reviewer{}, false
+}
+
+func reviewError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, repository.ErrRecordReviewNotFound):
+		response.NotFound(c)
+	case errors.Is(err, repository.ErrRecordReviewDecided):
+		response.Conflict(c, "Review is already decided")
+	case errors.Is(err, plate.ErrInvalidPlate):
+		response.BadRequest(c, "Invalid plate")
+	case errors.Is(err, service.ErrReviewPlateInvalid):
+		response.BadRequest(c, "Plate of the record is invalid, correct it before approving")
+	default:
+		response.InternalError(c)
+	}
+}
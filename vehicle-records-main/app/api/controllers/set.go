@@ -2,4 +2,6 @@ package controller
 
 import "github.com/google/wire"
 
-var ProviderSet = wire.NewSet(NewHealthController, NewVehicleRecordController)
+var ProviderSet = wire.NewSet(NewHealthController, NewVehicleRecordController, NewParkingSessionController,
+	NewForwardingController, NewReviewController, NewRetentionController,
+	NewEvidenceController, NewPlateHistoryController, NewWatchlistController, NewDeadLetterController)
@@ -1,27 +1,41 @@
 package controller
 
 import (
+	"errors"
+	"net/http"
 	"strconv"
 	"time"
 
 	"git.abanppc.com/farin-project/vehicle-records/app/api/response"
 	_ "git.abanppc.com/farin-project/vehicle-records/domain/entity"
+	"git.abanppc.com/farin-project/vehicle-records/domain/repository"
 	"git.abanppc.com/farin-project/vehicle-records/domain/service"
 	"github.com/gin-gonic/gin"
 )
 
 type VehicleRecordController struct {
 	service *service.VehicleRecordService
+	photos  *service.PhotoService
 }
 
+const (
+	defaultThumbnailWidth = 320
+	maxThumbnailWidth     = 1280
+)
+
 // GetVehicleRecordDetail godoc
 // @Summary      Get vehicleRecord details
-// @Description  Retrieve vehicleRecord details by ID
+// @Description  Retrieve vehicleRecord details by ID, photos are base64 contents unless temporary URLs are asked for
 // @Tags         vehicleRecords
 // @Param        id   path      string  true  "VehicleRecord ID"
+// @Param        photos     query      string  false  "base64 or url, base64 by default"
+// @Param        x-api-key  header     string  true  "API key with the records:read scope"
 // @Success      200   {object}  response.Response[entity.VehicleRecord]
+// @Failure      400   {object}  response.Response[swagger.EmptyObject]
 // @Failure      500   {object}  response.Response[swagger.EmptyObject]
 // @Failure      404   {object}  response.Response[swagger.EmptyObject]
+// @Failure      401   {object}  response.Response[swagger.EmptyObject]
+// @Failure      403   {object}  response.Response[swagger.EmptyObject]
 // @Router       /v1/vehicle-records/{id} [get]
 func (v VehicleRecordController) Detail(c *gin.Context) {
 	id := c.Param("id")
@@ -29,16 +43,26 @@ func (v VehicleRecordController) Detail(c *gin.Context) {
 		response.NotFound(c)
 		return
 	}
-	record, err := v.service.Detail(c, id)
+	mode := service.PhotoMode(c.DefaultQuery("photos", string(service.PhotoModeBase64)))
+	if mode != service.PhotoModeURL && mode != service.PhotoModeBase64 {
+		response.BadRequest(c, "Invalid photos parameter")
+		return
+	}
+	record, err := v.service.Detail(c, id, mode)
 	if err != nil {
+		if errors.Is(err, repository.ErrVehicleRecordNotFound) {
+			response.NotFound(c)
+			return
+		}
 		response.InternalError(c)
 		return
 	}
 	response.Ok(c, record, "")
 }
 
-func NewVehicleRecordController(service *service.VehicleRecordService) *VehicleRecordController {
-	return &VehicleRecordController{service: service}
+func NewVehicleRecordController(service *service.VehicleRecordService,
+	photos *service.PhotoService) *VehicleRecordController {
+	return &VehicleRecordController{service: service, photos: photos}
 }
 
 // Retry godoc
@@ -47,20 +71,11 @@ func NewVehicleRecordController(service *service.VehicleRecordService) *VehicleR
 // @Tags         vehicleRecords
 // @Param        from   	query      string  true  "From time in RFC3339 format"
 // @Param        limit  	query      int     true  "Limit"
-// @Param        x-api-key  header     string  true  "Authorization token"
+// @Param        x-api-key  header     string  true  "API key with the records:retry scope"
 // @Success      200   {object}  response.Response[swagger.EmptyObject]
+// @Failure      401   {object}  response.Response[swagger.EmptyObject]
+// @Failure      403   {object}  response.Response[swagger.EmptyObject]
 func (v VehicleRecordController) Retry(c *gin.Context) {
-
-	XApiKey := c.GetHeader("x-api-key")
-	if XApiKey == "" {
-		response.BadRequest(c, "Missing x-api-key header")
-		return
-	}
-	if XApiKey != "ettRJD9ai1W17EiZ" {
-		response.BadRequest(c, "Invalid x-api-key")
-		return
-	}
-
 	from := c.Query("from")
 	parsedTime, err := time.Parse(time.RFC3339, from)
 	if err != nil {
@@ -84,3 +99,128 @@ func (v VehicleRecordController) Retry(c *gin.Context) {
 
 	response.Ok(c, struct{}{}, "")
 }
+
+type VehicleRecordList struct {
+	Items any   `json:"items"`
+	Total int64 `json:"total"`
+}
+
+// Duplicates godoc
+// @Summary      List the duplicates of a vehicleRecord
+// @Description  List the sightings of the same vehicle that were merged into the record
+// @Tags         vehicleRecords
+// @Param        id         path    string  true   "VehicleRecord ID"
+// @Param        page       query   int     false  "Page, starts from 1"
+// @Param        pageSize   query   int     false  "Page size, at most 100"
+// @Param        x-api-key  header  string  true   "API key with the records:read scope"
+// @Success      200   {object}  response.Response[VehicleRecordList]
+// @Failure      400   {object}  response.Response[swagger.EmptyObject]
+// @Failure      401   {object}  response.Response[swagger.EmptyObject]
+// @Failure      403   {object}  response.Response[swagger.EmptyObject]
+// @Failure      404   {object}  response.Response[swagger.EmptyObject]
+// @Failure      500   {object}  response.Response[swagger.EmptyObject]
+// @Router       /v1/vehicle-records/{id}/duplicates [get]
+func (v VehicleRecordController) Duplicates(c *gin.Context) {
+	id := c.Param("id")
+	if id == "" {
+		response.NotFound(c)
+		return
+	}
+	page, pageSize, ok := pagination(c)
+	if !ok {
+		return
+	}
+
+	records, total, err := v.service.Duplicates(c, id, page, pageSize)
+	if err != nil {
+		if errors.Is(err, repository.ErrVehicleRecordNotFound) {
+			response.NotFound(c)
+			return
+		}
+		response.InternalError(c)
+		return
+	}
+	response.Ok(c, VehicleRecordList{Items: records, Total: total}, "")
+}
+
+// DedupStats godoc
+// @Summary      Count merged vehicleRecords
+// @Description  Count the records created in a time range and how many were merged into an earlier sighting
+// @Tags         vehicleRecords
+// @Param        from       query   string  false  "Records created at or after, RFC3339"
+// @Param        to         query   string  false  "Records created before, RFC3339"
+// @Param        x-api-key  header  string  true   "API key with the records:read scope"
+// @Success      200   {object}  response.Response[repository.DedupStats]
+// @Failure      400   {object}  response.Response[swagger.EmptyObject]
+// @Failure      401   {object}  response.Response[swagger.EmptyObject]
+// @Failure      403   {object}  response.Response[swagger.EmptyObject]
+// @Failure      500   {object}  response.Response[swagger.EmptyObject]
+// @Router       /v1/dedup/stats [get]
+func (v VehicleRecordController) DedupStats(c *gin.Context) {
+	var from, to int64
+	if q := c.Query("from"); q != "" {
+		t, err := time.Parse(time.RFC3339, q)
+		if err != nil {
+			response.BadRequest(c, "Invalid from time format")
+			return
+		}
+		from = t.UnixMilli()
+	}
+	if q := c.Query("to"); q != "" {
+		t, err := time.Parse(time.RFC3339, q)
+		if err != nil {
+			response.BadRequest(c, "Invalid to time format")
+			return
+		}
+		to = t.UnixMilli()
+	}
+
+	stats, err := v.service.DedupStats(c, from, to)
+	if err != nil {
+		response.InternalError(c)
+		return
+	}
+	response.Ok(c, stats, "")
+}
+
+// Thumbnail godoc
+// @Summary      Get a thumbnail of a vehicleRecord photo
+// @Description  Scale a photo, or its plate crop, down to a JPEG of at most width pixels
+// @Tags         vehicleRecords
+// @Produce      jpeg
+// @Param        id         path    string  true   "VehicleRecord ID"
+// @Param        photoId    path    string  true   "Photo ID"
+// @Param        crop       query   bool    false  "Plate crop instead of the photo"
+// @Param        width      query   int     false  "Width in pixels, 320 by default and at most 1280"
+// @Param        x-api-key  header  string  true   "API key with the records:read scope"
+// @Success      200
+// @Failure      400   {object}  response.Response[swagger.EmptyObject]
+// @Failure      401   {object}  response.Response[swagger.EmptyObject]
+// @Failure      403   {object}  response.Response[swagger.EmptyObject]
+// @Failure      404   {object}  response.Response[swagger.EmptyObject]
+// @Failure      500   {object}  response.Response[swagger.EmptyObject]
+// @Router       /v1/vehicle-records/{id}/photos/{photoId}/thumbnail [get]
+func (v VehicleRecordController) Thumbnail(c *gin.Context) {
+	width, err := strconv.Atoi(c.DefaultQuery("width", strconv.Itoa(defaultThumbnailWidth)))
+	if err != nil || width < 1 || width > maxThumbnailWidth {
+		response.BadRequest(c, "Invalid width parameter")
+		return
+	}
+	crop, err := strconv.ParseBool(c.DefaultQuery("crop", "false"))
+	if err != nil {
+		response.BadRequest(c, "Invalid crop parameter")
+		return
+	}
+
+	thumb, err := v.photos.Thumbnail(c, c.Param("id"), c.Param("photoId"), crop, width)
+	if err != nil {
+		if errors.Is(err, repository.ErrCitizenVehiclePhotoNotFound) {
+			response.NotFound(c)
+			return
+		}
+		response.InternalError(c)
+		return
+	}
+	c.Header("Cache-Control", "private, max-age=3600")
+	c.Data(http.StatusOK, "image/jpeg", thumb)
+}
@@ -0,0 +1,375 @@
+package controller
+
+import (
+	"errors"
+	"time"
+
+	"git.abanppc.com/farin-project/vehicle-records/app/api/response"
+	"git.abanppc.com/farin-project/vehicle-records/domain/entity"
+	"git.abanppc.com/farin-project/vehicle-records/domain/repository"
+	"git.abanppc.com/farin-project/vehicle-records/domain/service"
+	"git.abanppc.com/farin-project/vehicle-records/util/plate"
+	"github.com/gin-gonic/gin"
+)
+
+type WatchlistController struct {
+	service *service.WatchlistService
+}
+
+func NewWatchlistController(service *service.WatchlistService) *WatchlistController {
+	return &WatchlistController{service: service}
+}
+
+type WatchlistRequest struct {
+	Name        string               `json:"name" binding:"required,max=100"`
+	Kind        entity.WatchlistKind `json:"kind" binding:"required"`
+	Description string               `json:"description"`
+	Active      *bool                `json:"active"`
+}
+
+// WatchlistEntryRequest is an entry of a watchlist, it is valid from
+// validFrom, or from now, until validUntil, or forever
+type WatchlistEntryRequest struct {
+	Pattern    string     `json:"pattern" binding:"required"`
+	Priority   int        `json:"priority" binding:"min=0"`
+	ValidFrom  *time.Time `json:"validFrom"`
+	ValidUntil *time.Time `json:"validUntil"`
+	Note       string     `json:"note"`
+}
+
+type WatchlistEntryList struct {
+	Items any   `json:"items"`
+	Total int64 `json:"total"`
+}
+
+type WatchlistMatchList struct {
+	Items any   `json:"items"`
+	Total int64 `json:"total"`
+}
+
+// Lists godoc
+// @Summary      List watchlists
+// @Description  List the watchlists by name
+// @Tags         watchlists
+// @Param        x-api-key  header  string  true  "API key with the watchlists:manage scope"
+// @Success      200   {object}  response.Response[[]entity.Watchlist]
+// @Failure      401   {object}  response.Response[swagger.EmptyObject]
+// @Failure      403   {object}  response.Response[swagger.EmptyObject]
+// @Failure      500   {object}  response.Response[swagger.EmptyObject]
+// @Router       /v1/watchlists [get]
+func (w WatchlistController) Lists(c *gin.Context) {
+	lists, err := w.service.Lists(c)
+	if err != nil {
+		response.InternalError(c)
+		return
+	}
+	response.Ok(c, lists, "")
+}
+
+// Detail godoc
+// @Summary      Get a watchlist
+// @Tags         watchlists
+// @Param        id         path    string  true  "Watchlist ID"
+// @Param        x-api-key  header  string  true  "API key with the watchlists:manage scope"
+// @Success      200   {object}  response.Response[entity.Watchlist]
+// @Failure      401   {object}  response.Response[swagger.EmptyObject]
+// @Failure      403   {object}  response.Response[swagger.EmptyObject]
+// @Failure      404   {object}  response.Response[swagger.EmptyObject]
+// @Failure      500   {object}  response.Response[swagger.EmptyObject]
+// @Router       /v1/watchlists/{id} [get]
+func (w WatchlistController) Detail(c *gin.Context) {
+	list, err := w.service.GetList(c, c.Param("id"))
+	if err != nil {
+		watchlistError(c, err)
+		return
+	}
+	response.Ok(c, list, "")
+}
+
+// Create godoc
+// @Summary      Create a watchlist
+// @Description  Create a watchlist of stolen, unpaid_fines, police or other kind, it is active unless active is false
+// @Tags         watchlists
+// @Param        request    body    WatchlistRequest  true  "Watchlist"
+// @Param        x-api-key  header  string            true  "API key with the watchlists:manage scope"
+// @Success      201   {object}  response.Response[entity.Watchlist]
+// @Failure      400   {object}  response.Response[swagger.EmptyObject]
+// @Failure      401   {object}  response.Response[swagger.EmptyObject]
+// @Failure      403   {object}  response.Response[swagger.EmptyObject]
+// @Failure      500   {object}  response.Response[swagger.EmptyObject]
+// @Router       /v1/watchlists [post]
+func (w WatchlistController) Create(c *gin.Context) {
+	list, ok := watchlistRequest(c)
+	if !ok {
+		return
+	}
+	if err := w.service.CreateList(c, list); err != nil {
+		watchlistError(c, err)
+		return
+	}
+	response.Created(c, list)
+}
+
+// Update godoc
+// @Summary      Update a watchlist
+// @Description  Change a watchlist, the entries of an inactive watchlist are not matched
+// @Tags         watchlists
+// @Param        id         path    string            true  "Watchlist ID"
+// @Param        request    body    WatchlistRequest  true  "Watchlist"
+// @Param        x-api-key  header  string            true  "API key with the watchlists:manage scope"
+// @Success      200   {object}  response.Response[swagger.EmptyObject]
+// @Failure      400   {object}  response.Response[swagger.EmptyObject]
+// @Failure      401   {object}  response.Response[swagger.EmptyObject]
+// @Failure      403   {object}  response.Response[swagger.EmptyObject]
+// @Failure      404   {object}  response.Response[swagger.EmptyObject]
+// @Failure      500   {object}  response.Response[swagger.EmptyObject]
+// @Router       /v1/watchlists/{id} [put]
+func (w WatchlistController) Update(c *gin.Context) {
+	list, ok := watchlistRequest(c)
+	if !ok {
+		return
+	}
+	list.ID = c.Param("id")
+	if err := w.service.UpdateList(c, list); err != nil {
+		watchlistError(c, err)
+		return
+	}
+	response.Ok(c, struct{}{}, "")
+}
+
+// Delete godoc
+// @Summary      Delete a watchlist
+// @Description  Delete a watchlist with its entries, its matches are kept
+// @Tags         watchlists
+// @Param        id         path    string  true  "Watchlist ID"
+// @Param        x-api-key  header  string  true  "API key with the watchlists:manage scope"
+// @Success      200   {object}  response.Response[swagger.EmptyObject]
+// @Failure      401   {object}  response.Response[swagger.EmptyObject]
+// @Failure      403   {object}  response.Response[swagger.EmptyObject]
+// @Failure      404   {object}  response.Response[swagger.EmptyObject]
+// @Failure      500   {object}  response.Response[swagger.EmptyObject]
+// @Router       /v1/watchlists/{id} [delete]
+func (w WatchlistController) Delete(c *gin.Context) {
+	if err := w.service.DeleteList(c, c.Param("id")); err != nil {
+		watchlistError(c, err)
+		return
+	}
+	response.Ok(c, struct{}{}, "")
+}
+
+// Entries godoc
+// @Summary      List the entries of a watchlist
+// @Description  List the entries of a watchlist, newest first
+// @Tags         watchlists
+// @Param        id         path    string  true   "Watchlist ID"
+// @Param        page       query   int     false  "Page, starts from 1"
+// @Param        pageSize   query   int     false  "Page size, at most 100"
+// @Param        x-api-key  header  string  true   "API key with the watchlists:manage scope"
+// @Success      200   {object}  response.Response[WatchlistEntryList]
+// @Failure      400   {object}  response.Response[swagger.EmptyObject]
+// @Failure      401   {object}  response.Response[swagger.EmptyObject]
+// @Failure      403   {object}  response.Response[swagger.EmptyObject]
+// @Failure      404   {object}  response.Response[swagger.EmptyObject]
+// @Failure      500   {object}  response.Response[swagger.EmptyObject]
+// @Router       /v1/watchlists/{id}/entries [get]
+func (w WatchlistController) Entries(c *gin.Context) {
+	page, pageSize, ok := pagination(c)
+	if !ok {
+		return
+	}
+	entries, total, err := w.service.Entries(c, c.Param("id"), page, pageSize)
+	if err != nil {
+		watchlistError(c, err)
+		return
+	}
+	response.Ok(c, WatchlistEntryList{Items: entries, Total: total}, "")
+}
+
+// CreateEntry godoc
+// @Summary      Add an entry to a watchlist
+// @Description  Add a plate, such as 12ب34567, or a pattern with * for any run of characters and ? for one character, such as 12ب345*
+// @Tags         watchlists
+// @Param        id         path    string                 true  "Watchlist ID"
+// @Param        request    body    WatchlistEntryRequest  true  "Entry"
+// @Param        x-api-key  header  string                 true  "API key with the watchlists:manage scope"
+// @Success      201   {object}  response.Response[entity.WatchlistEntry]
+// @Failure      400   {object}  response.Response[swagger.EmptyObject]
+// @Failure      401   {object}  response.Response[swagger.EmptyObject]
+// @Failure      403   {object}  response.Response[swagger.EmptyObject]
+// @Failure      404   {object}  response.Response[swagger.EmptyObject]
+// @Failure      500   {object}  response.Response[swagger.EmptyObject]
+// @Router       /v1/watchlists/{id}/entries [post]
+func (w WatchlistController) CreateEntry(c *gin.Context) {
+	entry, ok := watchlistEntryRequest(c)
+	if !ok {
+		return
+	}
+	entry.CreatedBy = reviewer(c)
+	if err := w.service.CreateEntry(c, entry); err != nil {
+		watchlistError(c, err)
+		return
+	}
+	response.Created(c, entry)
+}
+
+// UpdateEntry godoc
+// @Summary      Update an entry of a watchlist
+// @Tags         watchlists
+// @Param        id         path    string                 true  "Watchlist ID"
+// @Param        entryId    path    string                 true  "Entry ID"
+// @Param        request    body    WatchlistEntryRequest  true  "Entry"
+// @Param        x-api-key  header  string                 true  "API key with the watchlists:manage scope"
+// @Success      200   {object}  response.Response[swagger.EmptyObject]
+// @Failure      400   {object}  response.Response[swagger.EmptyObject]
+// @Failure      401   {object}  response.Response[swagger.EmptyObject]
+// @Failure      403   {object}  response.Response[swagger.EmptyObject]
+// @Failure      404   {object}  response.Response[swagger.EmptyObject]
+// @Failure      500   {object}  response.Response[swagger.EmptyObject]
+// @Router       /v1/watchlists/{id}/entries/{entryId} [put]
+func (w WatchlistController) UpdateEntry(c *gin.Context) {
+	entry, ok := watchlistEntryRequest(c)
+	if !ok {
+		return
+	}
+	entry.ID = c.Param("entryId")
+	if err := w.service.UpdateEntry(c, entry); err != nil {
+		watchlistError(c, err)
+		return
+	}
+	response.Ok(c, struct{}{}, "")
+}
+
+// DeleteEntry godoc
+// @Summary      Delete an entry of a watchlist
+// @Tags         watchlists
+// @Param        id         path    string  true  "Watchlist ID"
+// @Param        entryId    path    string  true  "Entry ID"
+// @Param        x-api-key  header  string  true  "API key with the watchlists:manage scope"
+// @Success      200   {object}  response.Response[swagger.EmptyObject]
+// @Failure      401   {object}  response.Response[swagger.EmptyObject]
+// @Failure      403   {object}  response.Response[swagger.EmptyObject]
+// @Failure      404   {object}  response.Response[swagger.EmptyObject]
+// @Failure      500   {object}  response.Response[swagger.EmptyObject]
+// @Router       /v1/watchlists/{id}/entries/{entryId} [delete]
+func (w WatchlistController) DeleteEntry(c *gin.Context) {
+	if err := w.service.DeleteEntry(c, c.Param("id"), c.Param("entryId")); err != nil {
+		watchlistError(c, err)
+		return
+	}
+	response.Ok(c, struct{}{}, "")
+}
+
+// Matches godoc
+// @Summary      List watchlist matches
+// @Description  List the records that matched a watchlist with temporary photo URLs, highest priority first then oldest first
+// @Tags         watchlists
+// @Param        status     query   string  false  "pending or acknowledged, pending by default"
+// @Param        page       query   int     false  "Page, starts from 1"
+// @Param        pageSize   query   int     false  "Page size, at most 100"
+// @Param        x-api-key  header  string  true   "API key with the watchlists:ack scope"
+// @Success      200   {object}  response.Response[WatchlistMatchList]
+// @Failure      400   {object}  response.Response[swagger.EmptyObject]
+// @Failure      401   {object}  response.Response[swagger.EmptyObject]
+// @Failure      403   {object}  response.Response[swagger.EmptyObject]
+// @Failure      500   {object}  response.Response[swagger.EmptyObject]
+// @Router       /v1/watchlist-matches [get]
+func (w WatchlistController) Matches(c *gin.Context) {
+	status := entity.WatchlistMatchStatus(c.DefaultQuery("status", string(entity.WatchlistMatchPending)))
+	switch status {
+	case entity.WatchlistMatchPending, entity.WatchlistMatchAcknowledged:
+	default:
+		response.BadRequest(c, "Invalid status parameter")
+		return
+	}
+	page, pageSize, ok := pagination(c)
+	if !ok {
+		return
+	}
+
+	matches, total, err := w.service.ListMatches(c, status, page, pageSize)
+	if err != nil {
+		response.InternalError(c)
+		return
+	}
+	response.Ok(c, WatchlistMatchList{Items: matches, Total: total}, "")
+}
+
+// Acknowledge godoc
+// @Summary      Acknowledge a watchlist match
+// @Description  Record that an operator handled the alert of a match, the operator is audited with the match
+// @Tags         watchlists
+// @Param        id         path    string                 true   "Match ID"
+// @Param        request    body    ReviewDecisionRequest  false  "Note of the operator"
+// @Param        x-api-key  header  string                 true   "API key with the watchlists:ack scope"
+// @Success      200   {object}  response.Response[swagger.EmptyObject]
+// @Failure      400   {object}  response.Response[swagger.EmptyObject]
+// @Failure      401   {object}  response.Response[swagger.EmptyObject]
+// @Failure      403   {object}  response.Response[swagger.EmptyObject]
+// @Failure      404   {object}  response.Response[swagger.EmptyObject]
+// @Failure      409   {object}  response.Response[swagger.EmptyObject]
+// @Failure      500   {object}  response.Response[swagger.EmptyObject]
+// @Router       /v1/watchlist-matches/{id}/ack [post]
+func (w WatchlistController) Acknowledge(c *gin.Context) {
+	req, ok := decisionRequest(c)
+	if !ok {
+		return
+	}
+	if err := w.service.Acknowledge(c, c.Param("id"), reviewer(c), req.Note); err != nil {
+		watchlistError(c, err)
+		return
+	}
+	response.Ok(c, struct{}{}, "")
+}
+
+func watchlistRequest(c *gin.Context) (*entity.Watchlist, bool) {
+	var req WatchlistRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "Invalid request body")
+		return nil, false
+	}
+	list := &entity.Watchlist{Name: req.Name, Kind: req.Kind, Description: req.Description, Active: true}
+	if req.Active != nil {
+		list.Active = *req.Active
+	}
+	return list, true
+}
+
+func watchlistEntryRequest(c *gin.Context) (*entity.WatchlistEntry, bool) {
+	var req WatchlistEntryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "Invalid request body")
+		return nil, false
+	}
+	entry := &entity.WatchlistEntry{
+		WatchlistID: c.Param("id"),
+		Pattern:     req.Pattern,
+		Priority:    req.Priority,
+		ValidFrom:   time.Now().UnixMilli(),
+		Note:        req.Note,
+	}
+	if req.ValidFrom != nil {
+		entry.ValidFrom = req.ValidFrom.UnixMilli()
+	}
+	if req.ValidUntil != nil {
+		entry.ValidUntil = req.ValidUntil.UnixMilli()
+	}
+	return entry, true
+}
+
+func watchlistError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, repository.ErrWatchlistNotFound), errors.Is(err, repository.ErrWatchlistEntryNotFound),
+		errors.Is(err, repository.ErrWatchlistMatchNotFound):
+		response.NotFound(c)
+	case errors.Is(err, repository.ErrWatchlistMatchAcknowledged):
+		response.Conflict(c, "Match is already acknowledged")
+	case errors.Is(err, plate.ErrInvalidPlate):
+		response.BadRequest(c, "Invalid pattern")
+	case errors.Is(err, service.ErrInvalidWatchlistKind):
+		response.BadRequest(c, "Invalid kind")
+	case errors.Is(err, service.ErrInvalidValidity):
+		response.BadRequest(c, "validUntil must be after validFrom")
+	default:
+		response.InternalError(c)
+	}
+}
@@ -0,0 +1,55 @@
+package middleware
+
+import (
+	"errors"
+
+	"git.abanppc.com/farin-project/vehicle-records/app/api/response"
+	"git.abanppc.com/farin-project/vehicle-records/domain/entity"
+	"git.abanppc.com/farin-project/vehicle-records/domain/service"
+	"github.com/gin-gonic/gin"
+)
+
+// ApiKeyContextKey holds the authenticated *entity.ApiKey in the gin context
+const ApiKeyContextKey = "apiKey"
+
+type ApiKeyMiddleware struct {
+	service *service.ApiKeyService
+}
+
+func NewApiKeyMiddleware(service *service.ApiKeyService) *ApiKeyMiddleware {
+	return &ApiKeyMiddleware{service: service}
+}
+
+// Require lets a request through only when its x-api-key header holds an
+// active key granted scope
+func (m *ApiKeyMiddleware) Require(scope string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		plain := c.GetHeader("x-api-key")
+		if plain == "" {
+			response.Unauthorized(c, "Missing x-api-key header")
+			c.Abort()
+			return
+		}
+
+		key, err := m.service.Authenticate(c, plain, scope)
+		if err != nil {
+			switch {
+			case errors.Is(err, service.ErrInvalidApiKey):
+				response.Unauthorized(c, "Invalid x-api-key")
+			case errors.Is(err, service.ErrApiKeyScope):
+				response.Forbidden(c, "x-api-key lacks the "+scope+" scope")
+			default:
+				response.InternalError(c)
+			}
+			c.Abort()
+			return
+		}
+		c.Set(ApiKeyContextKey, key)
+		c.Next()
+	}
+}
+
+// ApiKey returns the key a request was authenticated with by Require
+func ApiKey(c *gin.Context) *entity.ApiKey {
+	return c.MustGet(ApiKeyContextKey).(*entity.ApiKey)
+}
@@ -48,3 +48,15 @@ func BadRequest(c *gin.Context, message string) {
 func Pure(c *gin.Context, statusCode int, data any) {
 	c.JSON(statusCode, data)
 }
+
+func Unauthorized(c *gin.Context, message string) {
+	Custom(c, http.StatusUnauthorized, nil, message)
+}
+
+func Forbidden(c *gin.Context, message string) {
+	Custom(c, http.StatusForbidden, nil, message)
+}
+
+func Conflict(c *gin.Context, message string) {
+	Custom(c, http.StatusConflict, nil, message)
+}
@@ -0,0 +1,26 @@
+package routes
+
+import (
+	controller "git.abanppc.com/farin-project/vehicle-records/app/api/controllers"
+	"git.abanppc.com/farin-project/vehicle-records/app/api/middleware"
+	"git.abanppc.com/farin-project/vehicle-records/domain/entity"
+	"github.com/gin-gonic/gin"
+)
+
+type DeadLetterRouter struct {
+	deadLetterController *controller.DeadLetterController
+	apiKey               *middleware.ApiKeyMiddleware
+}
+
+func NewDeadLetterRouter(deadLetterController *controller.DeadLetterController,
+	apiKey *middleware.ApiKeyMiddleware) *DeadLetterRouter {
+	return &DeadLetterRouter{deadLetterController: deadLetterController, apiKey: apiKey}
+}
+
+func (dh *DeadLetterRouter) SetupRoutes(router *gin.Engine) {
+	deadLetters := router.Group("api/v1/dead-letters", dh.apiKey.Require(entity.ScopeDeadLettersManage))
+	deadLetters.GET("", dh.deadLetterController.Queues)
+	deadLetters.GET("/:queue", dh.deadLetterController.List)
+	deadLetters.POST("/:queue/replay", dh.deadLetterController.Replay)
+	deadLetters.POST("/:queue/purge", dh.deadLetterController.Purge)
+}
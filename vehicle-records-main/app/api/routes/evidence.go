@@ -0,0 +1,24 @@
+package routes
+
+import (
+	controller "git.abanppc.com/farin-project/vehicle-records/app/api/controllers"
+	"git.abanppc.com/farin-project/vehicle-records/app/api/middleware"
+	"git.abanppc.com/farin-project/vehicle-records/domain/entity"
+	"github.com/gin-gonic/gin"
+)
+
+type EvidenceRouter struct {
+	evidenceController *controller.EvidenceController
+	apiKey             *middleware.ApiKeyMiddleware
+}
+
+func NewEvidenceRouter(evidenceController *controller.EvidenceController,
+	apiKey *middleware.ApiKeyMiddleware) *EvidenceRouter {
+	return &EvidenceRouter{evidenceController: evidenceController, apiKey: apiKey}
+}
+
+func (rh *EvidenceRouter) SetupRoutes(router *gin.Engine) {
+	require := rh.apiKey.Require(entity.ScopeRecordsEvidence)
+	router.GET("api/v1/vehicle-records/:id/evidence", require, rh.evidenceController.Export)
+	router.GET("api/v1/evidence/public-key", require, rh.evidenceController.PublicKey)
+}
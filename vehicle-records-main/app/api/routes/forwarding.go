@@ -0,0 +1,25 @@
+package routes
+
+import (
+	controller "git.abanppc.com/farin-project/vehicle-records/app/api/controllers"
+	"git.abanppc.com/farin-project/vehicle-records/app/api/middleware"
+	"git.abanppc.com/farin-project/vehicle-records/domain/entity"
+	"github.com/gin-gonic/gin"
+)
+
+type ForwardingRouter struct {
+	forwardingController *controller.ForwardingController
+	apiKey               *middleware.ApiKeyMiddleware
+}
+
+func NewForwardingRouter(forwardingController *controller.ForwardingController,
+	apiKey *middleware.ApiKeyMiddleware) *ForwardingRouter {
+	return &ForwardingRouter{forwardingController: forwardingController, apiKey: apiKey}
+}
+
+func (rh *ForwardingRouter) SetupRoutes(router *gin.Engine) {
+	router.GET("api/v1/vehicle-records/:id/attempts", rh.apiKey.Require(entity.ScopeRecordsRead),
+		rh.forwardingController.Attempts)
+	router.GET("api/v1/forwarding/failures", rh.apiKey.Require(entity.ScopeRecordsRead),
+		rh.forwardingController.Failures)
+}
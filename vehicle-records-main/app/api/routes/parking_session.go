@@ -0,0 +1,25 @@
+package routes
+
+import (
+	controller "git.abanppc.com/farin-project/vehicle-records/app/api/controllers"
+	"git.abanppc.com/farin-project/vehicle-records/app/api/middleware"
+	"git.abanppc.com/farin-project/vehicle-records/domain/entity"
+	"github.com/gin-gonic/gin"
+)
+
+type ParkingSessionRouter struct {
+	parkingSessionController *controller.ParkingSessionController
+	apiKey                   *middleware.ApiKeyMiddleware
+}
+
+func NewParkingSessionRouter(parkingSessionController *controller.ParkingSessionController,
+	apiKey *middleware.ApiKeyMiddleware) *ParkingSessionRouter {
+	return &ParkingSessionRouter{parkingSessionController: parkingSessionController, apiKey: apiKey}
+}
+
+func (rh *ParkingSessionRouter) SetupRoutes(router *gin.Engine) {
+	router.GET("api/v1/parking-sessions", rh.apiKey.Require(entity.ScopeRecordsRead),
+		rh.parkingSessionController.List)
+	router.GET("api/v1/parking-sessions/:id", rh.apiKey.Require(entity.ScopeRecordsRead),
+		rh.parkingSessionController.Detail)
+}
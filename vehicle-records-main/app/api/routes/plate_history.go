@@ -0,0 +1,23 @@
+package routes
+
+import (
+	controller "git.abanppc.com/farin-project/vehicle-records/app/api/controllers"
+	"git.abanppc.com/farin-project/vehicle-records/app/api/middleware"
+	"git.abanppc.com/farin-project/vehicle-records/domain/entity"
+	"github.com/gin-gonic/gin"
+)
+
+type PlateHistoryRouter struct {
+	plateHistoryController *controller.PlateHistoryController
+	apiKey                 *middleware.ApiKeyMiddleware
+}
+
+func NewPlateHistoryRouter(plateHistoryController *controller.PlateHistoryController,
+	apiKey *middleware.ApiKeyMiddleware) *PlateHistoryRouter {
+	return &PlateHistoryRouter{plateHistoryController: plateHistoryController, apiKey: apiKey}
+}
+
+func (rh *PlateHistoryRouter) SetupRoutes(router *gin.Engine) {
+	router.GET("api/v1/plates/:plate/history", rh.apiKey.Require(entity.ScopeRecordsRead),
+		rh.plateHistoryController.History)
+}
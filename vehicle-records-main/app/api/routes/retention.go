@@ -0,0 +1,29 @@
+package routes
+
+import (
+	controller "git.abanppc.com/farin-project/vehicle-records/app/api/controllers"
+	"git.abanppc.com/farin-project/vehicle-records/app/api/middleware"
+	"git.abanppc.com/farin-project/vehicle-records/domain/entity"
+	"github.com/gin-gonic/gin"
+)
+
+type RetentionRouter struct {
+	retentionController *controller.RetentionController
+	apiKey              *middleware.ApiKeyMiddleware
+}
+
+func NewRetentionRouter(retentionController *controller.RetentionController,
+	apiKey *middleware.ApiKeyMiddleware) *RetentionRouter {
+	return &RetentionRouter{retentionController: retentionController, apiKey: apiKey}
+}
+
+func (rh *RetentionRouter) SetupRoutes(router *gin.Engine) {
+	require := rh.apiKey.Require(entity.ScopeRecordsRetention)
+	retention := router.Group("api/v1/retention", require)
+	retention.GET("/policies", rh.retentionController.Policies)
+	retention.PUT("/policies/:status", rh.retentionController.UpdatePolicy)
+	retention.GET("/report", rh.retentionController.Report)
+
+	router.PUT("api/v1/vehicle-records/:id/legal-hold", require, rh.retentionController.SetLegalHold)
+	router.DELETE("api/v1/vehicle-records/:id/legal-hold", require, rh.retentionController.ReleaseLegalHold)
+}
@@ -0,0 +1,26 @@
+package routes
+
+import (
+	controller "git.abanppc.com/farin-project/vehicle-records/app/api/controllers"
+	"git.abanppc.com/farin-project/vehicle-records/app/api/middleware"
+	"git.abanppc.com/farin-project/vehicle-records/domain/entity"
+	"github.com/gin-gonic/gin"
+)
+
+type ReviewRouter struct {
+	reviewController *controller.ReviewController
+	apiKey           *middleware.ApiKeyMiddleware
+}
+
+func NewReviewRouter(reviewController *controller.ReviewController, apiKey *middleware.ApiKeyMiddleware) *ReviewRouter {
+	return &ReviewRouter{reviewController: reviewController, apiKey: apiKey}
+}
+
+func (rh *ReviewRouter) SetupRoutes(router *gin.Engine) {
+	reviews := router.Group("api/v1/reviews", rh.apiKey.Require(entity.ScopeRecordsReview))
+	reviews.GET("", rh.reviewController.List)
+	reviews.GET("/:id", rh.reviewController.Detail)
+	reviews.PUT("/:id/plate", rh.reviewController.CorrectPlate)
+	reviews.POST("/:id/approve", rh.reviewController.Approve)
+	reviews.POST("/:id/reject", rh.reviewController.Reject)
+}
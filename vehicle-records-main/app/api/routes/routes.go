@@ -8,8 +8,12 @@ type Router interface {
 	SetupRoutes(engine *gin.Engine)
 }
 
-func CreateRouters(healthRouter *HealthRouter, vrRouter *VehicleRecordRouter) []Router {
+func CreateRouters(healthRouter *HealthRouter, vrRouter *VehicleRecordRouter, psRouter *ParkingSessionRouter,
+	fwRouter *ForwardingRouter, reviewRouter *ReviewRouter, retentionRouter *RetentionRouter,
+	evidenceRouter *EvidenceRouter, plateHistoryRouter *PlateHistoryRouter, watchlistRouter *WatchlistRouter,
+	deadLetterRouter *DeadLetterRouter) []Router {
 	return []Router{
-		healthRouter, vrRouter,
+		healthRouter, vrRouter, psRouter, fwRouter, reviewRouter, retentionRouter, evidenceRouter, plateHistoryRouter,
+		watchlistRouter, deadLetterRouter,
 	}
 }
@@ -2,4 +2,6 @@ package routes
 
 import "github.com/google/wire"
 
-var ProviderSet = wire.NewSet(NewHealthRouter, CreateRouters, NewVehicleRecordRouter)
+var ProviderSet = wire.NewSet(NewHealthRouter, CreateRouters, NewVehicleRecordRouter, NewParkingSessionRouter,
+	NewForwardingRouter, NewReviewRouter, NewRetentionRouter,
+	NewEvidenceRouter, NewPlateHistoryRouter, NewWatchlistRouter, NewDeadLetterRouter)
@@ -2,18 +2,30 @@ package routes
 
 import (
 	controller "git.abanppc.com/farin-project/vehicle-records/app/api/controllers"
+	"git.abanppc.com/farin-project/vehicle-records/app/api/middleware"
+	"git.abanppc.com/farin-project/vehicle-records/domain/entity"
 	"github.com/gin-gonic/gin"
 )
 
 type VehicleRecordRouter struct {
 	vehicleRecordController *controller.VehicleRecordController
+	apiKey                  *middleware.ApiKeyMiddleware
 }
 
-func NewVehicleRecordRouter(vehicleRecordController *controller.VehicleRecordController) *VehicleRecordRouter {
-	return &VehicleRecordRouter{vehicleRecordController: vehicleRecordController}
+func NewVehicleRecordRouter(vehicleRecordController *controller.VehicleRecordController,
+	apiKey *middleware.ApiKeyMiddleware) *VehicleRecordRouter {
+	return &VehicleRecordRouter{vehicleRecordController: vehicleRecordController, apiKey: apiKey}
 }
 
 func (rh *VehicleRecordRouter) SetupRoutes(router *gin.Engine) {
-	router.GET("api/v1/vehicle-records/:id", rh.vehicleRecordController.Detail)
-	router.GET("api/v1/vehicle-records/retry", rh.vehicleRecordController.Retry)
+	router.GET("api/v1/vehicle-records/:id", rh.apiKey.Require(entity.ScopeRecordsRead),
+		rh.vehicleRecordController.Detail)
+	router.GET("api/v1/vehicle-records/retry", rh.apiKey.Require(entity.ScopeRecordsRetry),
+		rh.vehicleRecordController.Retry)
+	router.GET("api/v1/vehicle-records/:id/duplicates", rh.apiKey.Require(entity.ScopeRecordsRead),
+		rh.vehicleRecordController.Duplicates)
+	router.GET("api/v1/vehicle-records/:id/photos/:photoId/thumbnail", rh.apiKey.Require(entity.ScopeRecordsRead),
+		rh.vehicleRecordController.Thumbnail)
+	router.GET("api/v1/dedup/stats", rh.apiKey.Require(entity.ScopeRecordsRead),
+		rh.vehicleRecordController.DedupStats)
 }
@@ -0,0 +1,35 @@
+package routes
+
+import (
+	controller "git.abanppc.com/farin-project/vehicle-records/app/api/controllers"
+	"git.abanppc.com/farin-project/vehicle-records/app/api/middleware"
+	"git.abanppc.com/farin-project/vehicle-records/domain/entity"
+	"github.com/gin-gonic/gin"
+)
+
+type WatchlistRouter struct {
+	watchlistController *controller.WatchlistController
+	apiKey              *middleware.ApiKeyMiddleware
+}
+
+func NewWatchlistRouter(watchlistController *controller.WatchlistController,
+	apiKey *middleware.ApiKeyMiddleware) *WatchlistRouter {
+	return &WatchlistRouter{watchlistController: watchlistController, apiKey: apiKey}
+}
+
+func (wh *WatchlistRouter) SetupRoutes(router *gin.Engine) {
+	watchlists := router.Group("api/v1/watchlists", wh.apiKey.Require(entity.ScopeWatchlistsManage))
+	watchlists.GET("", wh.watchlistController.Lists)
+	watchlists.POST("", wh.watchlistController.Create)
+	watchlists.GET("/:id", wh.watchlistController.Detail)
+	watchlists.PUT("/:id", wh.watchlistController.Update)
+	watchlists.DELETE("/:id", wh.watchlistController.Delete)
+	watchlists.GET("/:id/entries", wh.watchlistController.Entries)
+	watchlists.POST("/:id/entries", wh.watchlistController.CreateEntry)
+	watchlists.PUT("/:id/entries/:entryId", wh.watchlistController.UpdateEntry)
+	watchlists.DELETE("/:id/entries/:entryId", wh.watchlistController.DeleteEntry)
+
+	matches := router.Group("api/v1/watchlist-matches", wh.apiKey.Require(entity.ScopeWatchlistsAck))
+	matches.GET("", wh.watchlistController.Matches)
+	matches.POST("/:id/ack", wh.watchlistController.Acknowledge)
+}
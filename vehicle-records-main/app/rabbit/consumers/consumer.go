@@ -2,14 +2,35 @@ package consumers
 
 import (
 	"context"
+
+	"git.abanppc.com/farin-project/vehicle-records/infrastructure/godotenv"
+	"git.abanppc.com/farin-project/vehicle-records/util/consumer"
 	"github.com/rabbitmq/amqp091-go"
 )
 
-type HandlerFunc func(context.Context, []byte) error
+type HandlerFunc = consumer.HandlerFunc
 
 type Consumer interface {
 	Setup(ch *amqp091.Channel, rch *amqp091.Channel) error
 	Worker()
 	RegisterHandler(routingKey string, handler HandlerFunc)
 	RunInnerWorkers()
+	// Shutdown stops consuming and waits for the buffered and in-flight
+	// messages until ctx is done
+	Shutdown(ctx context.Context) error
+	// Draining tells if Shutdown started
+	Draining() bool
+}
+
+// runtimeConfig is how the messages of queue are handled, retried and
+// dead-lettered
+func runtimeConfig(env *godotenv.Env, queue string, settings consumer.Settings) consumer.Config {
+	return consumer.Config{
+		Queue:              queue,
+		DeadLetterExchange: env.RabbitMQDeadLetterExchange,
+		MaxAttempts:        env.ConsumerMaxAttempts,
+		RetryDelay:         env.ConsumerRetryDelay,
+		RetryMax:           env.ConsumerRetryMax,
+		Settings:           settings,
+	}
 }
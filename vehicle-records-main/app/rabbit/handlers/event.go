@@ -11,18 +11,22 @@ import (
 	"git.abanppc.com/farin-project/vehicle-records/domain/opentelemetry"
 	"git.abanppc.com/farin-project/vehicle-records/domain/repository"
 	"git.abanppc.com/farin-project/vehicle-records/domain/service"
+	"git.abanppc.com/farin-project/vehicle-records/util/consumer"
 	"go.opentelemetry.io/otel/metric"
 )
 
 type EventVehicleRecord struct {
 	logger         *slog.Logger
 	vs             *service.VehicleRecordService
+	ps             *service.ParkingSessionService
+	ws             *service.WatchlistService
 	consumeCounter metric.Int64Counter
 	successCounter metric.Int64Counter
 	failCounter    metric.Int64Counter
 }
 
-func NewEventVehicleRecord(logger *slog.Logger, vs *service.VehicleRecordService, telemetry *opentelemetry.OpenTelemetry) *EventVehicleRecord {
+func NewEventVehicleRecord(logger *slog.Logger, vs *service.VehicleRecordService, ps *service.ParkingSessionService,
+	ws *service.WatchlistService, telemetry *opentelemetry.OpenTelemetry) *EventVehicleRecord {
 	meter := telemetry.Meter.Meter("farin,vehicleRecord.RabbitMQVehicleRecordHandler")
 	consumeCounter, err := meter.Int64Counter("vehicle_record.consume.total.counter", metric.WithDescription("number of vehicle record handler consumes"))
 	if err != nil {
@@ -36,7 +40,7 @@ func NewEventVehicleRecord(logger *slog.Logger, vs *service.VehicleRecordService
 	if err != nil {
 		panic(err)
 	}
-	return &EventVehicleRecord{logger: logger.With("layer", "RabbitEventHandler"), vs: vs,
+	return &EventVehicleRecord{logger: logger.With("layer", "RabbitEventHandler"), vs: vs, ps: ps, ws: ws,
 		consumeCounter: consumeCounter, successCounter: successCounter, failCounter: failCounter}
 }
 
@@ -45,7 +49,7 @@ func (a *EventVehicleRecord) VehicleRecord(ctx context.Context, data []byte) err
 	dt := time.Now()
 	a.consumeCounter.Add(ctx, 1)
 
-	ctx, cancel := context.WithTimeout(context.Background(), time.Second*100)
+	ctx, cancel := context.WithTimeout(ctx, time.Second*100)
 	defer func() {
 		lg.Info("vehicle record handler finished", "duration", time.Since(dt).Seconds())
 		cancel()
@@ -55,8 +59,10 @@ func (a *EventVehicleRecord) VehicleRecord(ctx context.Context, data []byte) err
 	if err := vr.UnmarshalJSON(data); err != nil {
 		lg.Warn("failed to unmarshal vehicle record", "error", err)
 		a.failCounter.Add(ctx, 1)
-		return nil
+		return consumer.Permanent(err)
 	}
+	// CreateRecord moves the photos to the outbox
+	photos := vr.VehiclePhotos
 	cr, err := a.vs.CreateRecord(ctx, vr)
 	if err != nil {
 		if errors.Is(err, repository.ErrDuplicateRecord) {
@@ -69,6 +75,12 @@ func (a *EventVehicleRecord) VehicleRecord(ctx context.Context, data []byte) err
 		return err
 	}
 	lg.Info("vehicle record created successfully", "record_id", cr.RecordID)
+	if err := a.ps.Track(ctx, cr); err != nil {
+		lg.Warn("failed to track parking session", "error", err, "record_id", cr.RecordID)
+	}
+	if err := a.ws.Check(ctx, cr, photos); err != nil {
+		lg.Warn("failed to check watchlists", "error", err, "record_id", cr.RecordID)
+	}
 	a.successCounter.Add(ctx, 1)
 	return nil
 }
@@ -9,6 +9,7 @@ import (
 	"git.abanppc.com/farin-project/vehicle-records/domain/entity"
 	"git.abanppc.com/farin-project/vehicle-records/domain/opentelemetry"
 	"git.abanppc.com/farin-project/vehicle-records/domain/service"
+	"git.abanppc.com/farin-project/vehicle-records/util/consumer"
 	"go.opentelemetry.io/otel/metric"
 )
 
@@ -46,21 +47,17 @@ func (a *Retry) Retry(ctx context.Context, data []byte) error {
 	lg := a.logger.With("method", "Retry")
 
 	dt := time.Now()
-	defer func() {
-		lg.Info("vehicle record retry handler finished", "duration", time.Since(dt).Seconds())
-	}()
-
 	a.consumeCounter.Add(ctx, 1)
-	ctx, cancell := context.WithTimeout(context.Background(), time.Second*100)
+	ctx, cancel := context.WithTimeout(ctx, time.Second*100)
 	defer func() {
 		lg.Info("vehicle record retry handler finished", "duration", time.Since(dt).Seconds())
-		cancell()
+		cancel()
 	}()
 	vr := &entity.VehicleRecord{}
 	if err := vr.UnmarshalJSON(data); err != nil {
 		lg.Warn("failed to unmarshal vehicle record", "error", err)
 		a.failCounter.Add(ctx, 1)
-		return nil
+		return consumer.Permanent(err)
 	}
 	if err := a.vs.Retry(ctx, vr); err != nil {
 		lg.Warn("failed to retry create record", "error", err)
@@ -0,0 +1,111 @@
+package apikey
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"git.abanppc.com/farin-project/vehicle-records/domain/repository"
+	"git.abanppc.com/farin-project/vehicle-records/domain/service"
+	"git.abanppc.com/farin-project/vehicle-records/infrastructure/godotenv"
+	gormdb "git.abanppc.com/farin-project/vehicle-records/infrastructure/gorm"
+)
+
+const usage = `usage:
+  apikey mint -name NAME -scopes records:read,records:retry [-expires 720h]
+  apikey revoke -id ID
+  apikey list`
+
+// Run handles the apikey subcommand, args are the arguments after "apikey"
+func Run(args []string, logger *slog.Logger) error {
+	if len(args) == 0 {
+		return errors.New(usage)
+	}
+
+	env := godotenv.NewEnv()
+	env.Load()
+	ctx := context.Background()
+	gorm := gormdb.NewGORMDB(env)
+	if err := gorm.Setup(ctx); err != nil {
+		return fmt.Errorf("failed to setup gorm:%w", err)
+	}
+	s := service.NewApiKeyService(logger, repository.NewApiKeyRepository(gorm))
+
+	switch args[0] {
+	case "mint":
+		return mint(ctx, s, args[1:], os.Stdout)
+	case "revoke":
+		return revoke(ctx, s, args[1:], os.Stdout)
+	case "list":
+		return list(ctx, s, os.Stdout)
+	}
+	return errors.New(usage)
+}
+
+func mint(ctx context.Context, s *service.ApiKeyService, args []string, out io.Writer) error {
+	fs := flag.NewFlagSet("apikey mint", flag.ContinueOnError)
+	name := fs.String("name", "", "name of the key owner")
+	scopes := fs.String("scopes", "", "comma separated scopes")
+	expires := fs.Duration("expires", 0, "lifetime of the key, 0 for no expiry")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	var expiresAt time.Time
+	if *expires > 0 {
+		expiresAt = time.Now().Add(*expires)
+	}
+	plain, key, err := s.Mint(ctx, *name, strings.Split(*scopes, ","), expiresAt)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "id:     %s\nscopes: %s\nkey:    %s\n", key.ID, key.Scopes, plain)
+	fmt.Fprintln(out, "the key is not stored, keep it now")
+	return nil
+}
+
+func revoke(ctx context.Context, s *service.ApiKeyService, args []string, out io.Writer) error {
+	fs := flag.NewFlagSet("apikey revoke", flag.ContinueOnError)
+	id := fs.String("id", "", "id of the key")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *id == "" {
+		return errors.New("apikey revoke needs -id")
+	}
+
+	if err := s.Revoke(ctx, *id); err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "revoked %s\n", *id)
+	return nil
+}
+
+func list(ctx context.Context, s *service.ApiKeyService, out io.Writer) error {
+	keys, err := s.List(ctx)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tNAME\tPREFIX\tSCOPES\tEXPIRES\tREVOKED\tLAST USED")
+	for _, k := range keys {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n", k.ID, k.Name, k.Prefix, k.Scopes,
+			formatMilli(k.ExpiresAt), formatMilli(k.RevokedAt), formatMilli(k.LastUsedAt))
+	}
+	return w.Flush()
+}
+
+func formatMilli(ms int64) string {
+	if ms == 0 {
+		return "-"
+	}
+	return time.UnixMilli(ms).Format(time.RFC3339)
+}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"errors"
 	"git.abanppc.com/farin-project/vehicle-records/app/api/routes"
 	"git.abanppc.com/farin-project/vehicle-records/app/rabbit/consumers"
 	"git.abanppc.com/farin-project/vehicle-records/app/rabbit/handlers"
@@ -15,9 +16,16 @@ import (
 	ginSwagger "github.com/swaggo/gin-swagger"
 	"log"
 	"log/slog"
+	"net/http"
+	"os/signal"
+	"syscall"
 	"time"
 )
 
+// httpShutdownTimeout is the time the api has to finish its requests after
+// the consumers drained
+const httpShutdownTimeout = 5 * time.Second
+
 type Boot struct {
 	rts           []routes.Router
 	eventConsumer *consumers.EventConsumer
@@ -27,23 +35,30 @@ type Boot struct {
 	retryConsumer *consumers.RetryConsumer
 	retry         *handlers.Retry
 	vs            *service.VehicleRecordService
+	ps            *service.ParkingSessionService
+	rs            *service.RetentionService
+	ws            *service.WatchlistService
 	lg            *slog.Logger
 	env           *godotenv.Env
 }
 
 func NewBoot(event *handlers.EventVehicleRecord, eventConsumer *consumers.EventConsumer, rbt *rabbit.Rabbit,
 	cr *rabbit.ConsumerRunner, retryConsumer *consumers.RetryConsumer, retry *handlers.Retry, vs *service.VehicleRecordService,
-	env *godotenv.Env, lg *slog.Logger, rts ...routes.Router) *Boot {
+	ps *service.ParkingSessionService, rs *service.RetentionService, ws *service.WatchlistService, env *godotenv.Env,
+	lg *slog.Logger, rts ...routes.Router) *Boot {
 	return &Boot{event: event, eventConsumer: eventConsumer, retryConsumer: retryConsumer,
-		rts: rts, rbt: rbt, cr: cr, retry: retry, vs: vs, lg: lg, env: env}
+		rts: rts, rbt: rbt, cr: cr, retry: retry, vs: vs, ps: ps, rs: rs, ws: ws, lg: lg, env: env}
 }
 
 func (b *Boot) Boot(retry bool, retryFrom int64, retryLimit int) {
 	env := godotenv.NewEnv()
 	env.Load()
+	// cancelled on SIGTERM, which starts the drain
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
 	if retry && retryLimit > 0 {
 		manual := manual_retry.NewManualRetry(b.lg, b.env, b.vs)
-		manual.FindResend(context.Background(), retryFrom, retryLimit)
+		manual.FindResend(ctx, retryFrom, retryLimit)
 	}
 	r := gin.Default()
 	for _, router := range b.rts {
@@ -53,6 +68,12 @@ func (b *Boot) Boot(retry bool, retryFrom int64, retryLimit int) {
 
 	b.event.RegisterConsumer(b.eventConsumer)
 	b.retry.RegisterConsumer(b.retryConsumer)
+	// records are matched against the watchlists, they are loaded before any
+	// consumer starts
+	if err := b.ws.Refresh(ctx); err != nil {
+		log.Fatalf("failed to load watchlists:%s", err)
+	}
+	go b.ws.RunRefresher(ctx)
 	go func() {
 		if err := b.rbt.Setup(b.cr); err != nil {
 			log.Fatalf("failed to setup rabbitmq:%s", err)
@@ -60,10 +81,43 @@ func (b *Boot) Boot(retry bool, retryFrom int64, retryLimit int) {
 	}()
 	time.Sleep(5 * time.Second) //todo: clean
 	b.cr.RunInnerWorkers()
+	go b.ps.RunCloser(ctx)
+	b.vs.RunOutbox(ctx)
+	go b.vs.RunResend(ctx)
+	go b.rs.RunReaper(ctx)
 
 	r.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerfiles.Handler))
 
-	if err := r.Run(":" + env.HTTPPort); err != nil {
-		log.Fatalf("error running gin grpcServer error:%s", err.Error())
+	srv := &http.Server{Addr: ":" + env.HTTPPort, Handler: r}
+	go func() {
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatalf("error running gin grpcServer error:%s", err.Error())
+		}
+	}()
+
+	<-ctx.Done()
+	b.shutdown(srv)
+}
+
+// shutdown drains the consumers while the api keeps answering with a failing
+// readiness, then closes rabbitmq and the api last
+func (b *Boot) shutdown(srv *http.Server) {
+	lg := b.lg.With("method", "shutdown")
+	lg.Info("shutting down", "timeout", b.env.ShutdownTimeout)
+
+	ctx, cancel := context.WithTimeout(context.Background(), b.env.ShutdownTimeout)
+	defer cancel()
+	if err := b.cr.Shutdown(ctx); err != nil {
+		lg.Error("failed to drain consumers", "error", err)
+	}
+	if err := b.rbt.Close(); err != nil {
+		lg.Error("failed to close rabbitmq", "error", err)
+	}
+
+	httpCtx, httpCancel := context.WithTimeout(context.Background(), httpShutdownTimeout)
+	defer httpCancel()
+	if err := srv.Shutdown(httpCtx); err != nil {
+		lg.Error("failed to shutdown http server", "error", err)
 	}
+	lg.Info("shut down")
 }
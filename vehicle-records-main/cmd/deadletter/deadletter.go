@@ -0,0 +1,132 @@
+package deadletter
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"git.abanppc.com/farin-project/vehicle-records/infrastructure/godotenv"
+	"git.abanppc.com/farin-project/vehicle-records/util/consumer"
+	"github.com/rabbitmq/amqp091-go"
+)
+
+const usage = `usage:
+  deadletter list -queue QUEUE [-limit 20] [-body]
+  deadletter replay -queue QUEUE (-ids ID,ID | -limit N)
+  deadletter purge -queue QUEUE (-ids ID,ID | -all)
+QUEUE is the queue a consumer reads, of any service on the broker`
+
+// Run handles the deadletter subcommand, args are the arguments after
+// "deadletter"
+func Run(args []string) error {
+	if len(args) == 0 {
+		return errors.New(usage)
+	}
+
+	env := godotenv.NewEnv()
+	env.Load()
+	conn, err := amqp091.Dial(env.RabbitMQHost)
+	if err != nil {
+		return fmt.Errorf("failed to connect to rabbitmq:%w", err)
+	}
+	defer conn.Close()
+	admin := consumer.NewAdmin(conn.Channel)
+
+	switch args[0] {
+	case "list":
+		return list(admin, args[1:], os.Stdout)
+	case "replay":
+		return replay(admin, args[1:], os.Stdout)
+	case "purge":
+		return purge(admin, args[1:], os.Stdout)
+	}
+	return errors.New(usage)
+}
+
+func list(admin *consumer.Admin, args []string, out io.Writer) error {
+	fs := flag.NewFlagSet("deadletter list", flag.ContinueOnError)
+	queue := fs.String("queue", "", "queue of the dead letters")
+	limit := fs.Int("limit", 20, "dead letters listed")
+	body := fs.Bool("body", false, "print the bodies of the dead letters")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *queue == "" || *limit < 1 {
+		return errors.New("deadletter list needs -queue and a positive -limit")
+	}
+
+	letters, total, err := admin.Inspect(*queue, *limit)
+	if err != nil {
+		return err
+	}
+	w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tROUTING KEY\tATTEMPTS\tDEAD AT\tERROR")
+	for _, l := range letters {
+		deadAt := "-"
+		if l.DeadAt != 0 {
+			deadAt = time.UnixMilli(l.DeadAt).Format(time.RFC3339)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%d\t%s\t%s\n", l.ID, l.RoutingKey, l.Attempts, deadAt, l.Error)
+		if *body {
+			fmt.Fprintf(w, "\t%s\n", l.Body)
+		}
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "%d of %d dead letters\n", len(letters), total)
+	return nil
+}
+
+func replay(admin *consumer.Admin, args []string, out io.Writer) error {
+	fs := flag.NewFlagSet("deadletter replay", flag.ContinueOnError)
+	queue := fs.String("queue", "", "queue of the dead letters")
+	ids := fs.String("ids", "", "comma separated ids of the dead letters")
+	limit := fs.Int("limit", 0, "oldest dead letters replayed, without -ids")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *queue == "" || (*ids == "" && *limit < 1) {
+		return errors.New("deadletter replay needs -queue and -ids or -limit")
+	}
+
+	n, err := admin.Replay(context.Background(), *queue, splitIDs(*ids), *limit)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "replayed %d dead letters to %s\n", n, *queue)
+	return nil
+}
+
+func purge(admin *consumer.Admin, args []string, out io.Writer) error {
+	fs := flag.NewFlagSet("deadletter purge", flag.ContinueOnError)
+	queue := fs.String("queue", "", "queue of the dead letters")
+	ids := fs.String("ids", "", "comma separated ids of the dead letters")
+	all := fs.Bool("all", false, "purge every dead letter of the queue")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *queue == "" || (*ids == "") == !*all {
+		return errors.New("deadletter purge needs -queue and either -ids or -all")
+	}
+
+	n, err := admin.Purge(*queue, splitIDs(*ids))
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "purged %d dead letters of %s\n", n, *queue)
+	return nil
+}
+
+func splitIDs(ids string) []string {
+	if ids == "" {
+		return nil
+	}
+	return strings.Split(ids, ",")
+}
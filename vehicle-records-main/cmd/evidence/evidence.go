@@ -0,0 +1,87 @@
+package evidence
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"git.abanppc.com/farin-project/vehicle-records/util/evidence"
+)
+
+const usage = `usage:
+  evidence keygen -private evidence.key -public evidence.pub
+  evidence verify -public evidence.pub PACKAGE.zip`
+
+// Run handles the evidence subcommand, args are the arguments after "evidence"
+func Run(args []string) error {
+	if len(args) == 0 {
+		return errors.New(usage)
+	}
+	switch args[0] {
+	case "keygen":
+		return keygen(args[1:], os.Stdout)
+	case "verify":
+		return verify(args[1:], os.Stdout)
+	}
+	return errors.New(usage)
+}
+
+func keygen(args []string, out io.Writer) error {
+	fs := flag.NewFlagSet("evidence keygen", flag.ContinueOnError)
+	private := fs.String("private", "", "file to write the private key to")
+	public := fs.String("public", "", "file to write the public key to")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *private == "" || *public == "" {
+		return errors.New(usage)
+	}
+
+	kb, pb, err := evidence.GenerateKey()
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(*private, kb, 0o600); err != nil {
+		return fmt.Errorf("failed to write private key: %w", err)
+	}
+	if err := os.WriteFile(*public, pb, 0o644); err != nil {
+		return fmt.Errorf("failed to write public key: %w", err)
+	}
+	pub, err := evidence.LoadPublicKey(*public)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "key %s written, set EVIDENCE_SIGNING_KEY=%s\n", evidence.KeyID(pub), *private)
+	return nil
+}
+
+func verify(args []string, out io.Writer) error {
+	fs := flag.NewFlagSet("evidence verify", flag.ContinueOnError)
+	public := fs.String("public", "", "public key file")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *public == "" || fs.NArg() != 1 {
+		return errors.New(usage)
+	}
+
+	pub, err := evidence.LoadPublicKey(*public)
+	if err != nil {
+		return err
+	}
+	b, err := os.ReadFile(fs.Arg(0))
+	if err != nil {
+		return fmt.Errorf("failed to read package: %w", err)
+	}
+	manifest, err := evidence.Verify(bytes.NewReader(b), int64(len(b)), pub)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "package of record %s created at %s is intact, %d files signed by key %s\n",
+		manifest.RecordID, manifest.CreatedAt.Format(time.RFC3339), len(manifest.Files), manifest.KeyID)
+	return nil
+}
@@ -3,6 +3,9 @@ package main
 import (
 	"context"
 	"flag"
+	"git.abanppc.com/farin-project/vehicle-records/cmd/apikey"
+	"git.abanppc.com/farin-project/vehicle-records/cmd/deadletter"
+	"git.abanppc.com/farin-project/vehicle-records/cmd/evidence"
 	"git.abanppc.com/farin-project/vehicle-records/cmd/seeder"
 	"git.abanppc.com/farin-project/vehicle-records/domain/opentelemetry"
 	"git.abanppc.com/farin-project/vehicle-records/infrastructure/godotenv"
@@ -21,6 +24,25 @@ import (
 // @securityDefinitions.apikey ApiKeyAuth
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "apikey" {
+		if err := apikey.Run(os.Args[2:], initSlogLogger()); err != nil {
+			log.Fatal(err.Error())
+		}
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "deadletter" {
+		if err := deadletter.Run(os.Args[2:]); err != nil {
+			log.Fatal(err.Error())
+		}
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "evidence" {
+		if err := evidence.Run(os.Args[2:]); err != nil {
+			log.Fatal(err.Error())
+		}
+		return
+	}
+
 	seed := flag.Bool("seed", false, "Seed the database with initial data")
 	retry := flag.Bool("retry", false, "retry from database")
 	fromTime := flag.Int64("from", 0, "retry from time")
@@ -56,6 +78,12 @@ func main() {
 	if err := minio.Setup(ctx); err != nil {
 		log.Fatalf("failed to setup minio:%s", err)
 	}
+	// photos were once readable through a public bucket policy, MinIO has no
+	// object ACLs so dropping the policy leaves presigned URLs as the only
+	// way to read the photos already stored
+	if err := minio.M.SetBucketPolicy(ctx, env.MinioVehicleRecordsBucket, ""); err != nil {
+		log.Fatalf("failed to remove the policy of the vehicle records bucket:%s", err)
+	}
 
 	ot := opentelemetry.NewOpenTelemetry(env)
 	if err := ot.Setup(ctx); err != nil {
@@ -72,7 +100,12 @@ func main() {
 		log.Fatalf("failed to setup gorm:%s", err)
 	}
 
-	boot, err := wireApp(logger, rbt, minio, gorm, ot)
+	farinGorm := gormdb.NewFarinGORMDB(env)
+	if err := farinGorm.Setup(ctx); err != nil {
+		log.Fatalf("failed to setup farin gorm:%s", err)
+	}
+
+	boot, err := wireApp(logger, rbt, minio, gorm, farinGorm, ot)
 	if err != nil {
 		log.Fatalf("failed to setup app:%s", err)
 	}
@@ -7,6 +7,7 @@ package main
 
 import (
 	controller "git.abanppc.com/farin-project/vehicle-records/app/api/controllers"
+	"git.abanppc.com/farin-project/vehicle-records/app/api/middleware"
 	"git.abanppc.com/farin-project/vehicle-records/app/api/routes"
 	rabbitApp "git.abanppc.com/farin-project/vehicle-records/app/rabbit"
 	"git.abanppc.com/farin-project/vehicle-records/domain/opentelemetry"
@@ -25,6 +26,7 @@ func wireApp(
 	rabbit *rabbit.Rabbit,
 	minio *minio.Minio,
 	gorm *gormdb.GORMDB,
+	farinGorm *gormdb.FarinGORMDB,
 	ot *opentelemetry.OpenTelemetry,
 ) (*Boot, error) {
 	panic(wire.Build(
@@ -34,6 +36,7 @@ func wireApp(
 		rabbitApp.ProviderSet,
 		routes.ProviderSet,
 		controller.ProviderSet,
+		middleware.ProviderSet,
 		wire.NewSet(NewBoot),
 	))
 }
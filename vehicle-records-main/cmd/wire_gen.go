@@ -8,6 +8,7 @@ package main
 
 import (
 	"git.abanppc.com/farin-project/vehicle-records/app/api/controllers"
+	"git.abanppc.com/farin-project/vehicle-records/app/api/middleware"
 	"git.abanppc.com/farin-project/vehicle-records/app/api/routes"
 	rabbit2 "git.abanppc.com/farin-project/vehicle-records/app/rabbit"
 	"git.abanppc.com/farin-project/vehicle-records/app/rabbit/consumers"
@@ -28,24 +29,68 @@ import (
 
 // Injectors from wire.go:
 
-func wireApp(logger *slog.Logger, rabbit3 *rabbit.Rabbit, minio2 *minio.Minio, gorm *gormdb.GORMDB, ot *opentelemetry.OpenTelemetry) (*Boot, error) {
+func wireApp(logger *slog.Logger, rabbit3 *rabbit.Rabbit, minio2 *minio.Minio, gorm *gormdb.GORMDB, farinGorm *gormdb.FarinGORMDB, ot *opentelemetry.OpenTelemetry) (*Boot, error) {
 	vehicleRecordRepository := repository.NewVehicleRecordRepository(gorm)
-	minIOFileRepository := minio.NewMinIOFileRepository(minio2)
 	env := godotenv.NewEnv()
 	citizenVehiclePhotoRepository := repository.NewCitizenVehiclePhotoRepository(gorm)
 	tehranSiteRecordRepository := repository.NewTehranSiteRecordRepository(env, logger)
-	vehicleRecordService := service.NewVehicleRecordService(logger, vehicleRecordRepository, minIOFileRepository, env, citizenVehiclePhotoRepository, tehranSiteRecordRepository, rabbit3, ot, minio2)
-	eventVehicleRecord := handlers.NewEventVehicleRecord(logger, vehicleRecordService, ot)
+	forwarderRegistry, err := repository.NewForwarderRegistry(env, logger, tehranSiteRecordRepository)
+	if err != nil {
+		return nil, err
+	}
+	recordOutboxRepository := repository.NewRecordOutboxRepository(gorm)
+	forwardingAttemptRepository := repository.NewForwardingAttemptRepository(gorm)
+	resendCheckpointRepository := repository.NewResendCheckpointRepository(gorm)
+	photoService := service.NewPhotoService(logger, env, citizenVehiclePhotoRepository, minio2)
+	vehicleRecordService := service.NewVehicleRecordService(logger, vehicleRecordRepository, env, citizenVehiclePhotoRepository, forwarderRegistry, recordOutboxRepository, forwardingAttemptRepository, resendCheckpointRepository, ot, minio2, photoService)
+	parkingSessionRepository := repository.NewParkingSessionRepository(gorm)
+	parkingSessionService := service.NewParkingSessionService(logger, parkingSessionRepository, env)
+	watchlistRepository := repository.NewWatchlistRepository(gorm)
+	hotlistAlertRabbitMQ := repository.NewHotlistAlertRabbitMQ(rabbit3, env)
+	watchlistService := service.NewWatchlistService(logger, env, watchlistRepository, hotlistAlertRabbitMQ, photoService)
+	eventVehicleRecord := handlers.NewEventVehicleRecord(logger, vehicleRecordService, parkingSessionService, watchlistService, ot)
 	eventConsumer := consumers.NewEventConsumer(logger, env)
 	retryConsumer := consumers.NewRetryConsumer(logger, env)
 	v := rabbit2.Consumers(eventConsumer, retryConsumer)
 	consumerRunner := rabbit.NewConsumerRunner(logger, v...)
 	retry := handlers.NewRetry(logger, vehicleRecordService, ot)
+	retentionRepository := repository.NewRetentionRepository(gorm)
+	retentionService := service.NewRetentionService(logger, env, retentionRepository, vehicleRecordRepository, minio2)
 	healthController := controller.NewHealthController(logger, rabbit3, minio2, gorm, eventConsumer)
 	healthRouter := routes.NewHealthRouter(healthController)
-	vehicleRecordController := controller.NewVehicleRecordController(vehicleRecordService)
-	vehicleRecordRouter := routes.NewVehicleRecordRouter(vehicleRecordController)
-	v2 := routes.CreateRouters(healthRouter, vehicleRecordRouter)
-	boot := NewBoot(eventVehicleRecord, eventConsumer, rabbit3, consumerRunner, retryConsumer, retry, vehicleRecordService, env, logger, v2...)
+	vehicleRecordController := controller.NewVehicleRecordController(vehicleRecordService, photoService)
+	apiKeyRepository := repository.NewApiKeyRepository(gorm)
+	apiKeyService := service.NewApiKeyService(logger, apiKeyRepository)
+	apiKeyMiddleware := middleware.NewApiKeyMiddleware(apiKeyService)
+	vehicleRecordRouter := routes.NewVehicleRecordRouter(vehicleRecordController, apiKeyMiddleware)
+	parkingSessionController := controller.NewParkingSessionController(parkingSessionService)
+	parkingSessionRouter := routes.NewParkingSessionRouter(parkingSessionController, apiKeyMiddleware)
+	forwardingAttemptService := service.NewForwardingAttemptService(logger, forwardingAttemptRepository, vehicleRecordRepository)
+	forwardingController := controller.NewForwardingController(forwardingAttemptService)
+	forwardingRouter := routes.NewForwardingRouter(forwardingController, apiKeyMiddleware)
+	recordReviewRepository := repository.NewRecordReviewRepository(gorm)
+	recordReviewService := service.NewRecordReviewService(logger, recordReviewRepository, vehicleRecordService, photoService)
+	reviewController := controller.NewReviewController(recordReviewService)
+	reviewRouter := routes.NewReviewRouter(reviewController, apiKeyMiddleware)
+	retentionController := controller.NewRetentionController(retentionService)
+	retentionRouter := routes.NewRetentionRouter(retentionController, apiKeyMiddleware)
+	farinRepository := repository.NewFarinRepository(farinGorm)
+	evidenceService, err := service.NewEvidenceService(logger, env, vehicleRecordRepository, farinRepository, minio2)
+	if err != nil {
+		return nil, err
+	}
+	evidenceController := controller.NewEvidenceController(evidenceService)
+	evidenceRouter := routes.NewEvidenceRouter(evidenceController, apiKeyMiddleware)
+	plateHistoryService := service.NewPlateHistoryService(logger, vehicleRecordRepository)
+	plateHistoryController := controller.NewPlateHistoryController(plateHistoryService)
+	plateHistoryRouter := routes.NewPlateHistoryRouter(plateHistoryController, apiKeyMiddleware)
+	watchlistController := controller.NewWatchlistController(watchlistService)
+	watchlistRouter := routes.NewWatchlistRouter(watchlistController, apiKeyMiddleware)
+	deadLetterRabbitMQ := repository.NewDeadLetterRabbitMQ(rabbit3)
+	deadLetterService := service.NewDeadLetterService(logger, env, deadLetterRabbitMQ)
+	deadLetterController := controller.NewDeadLetterController(deadLetterService)
+	deadLetterRouter := routes.NewDeadLetterRouter(deadLetterController, apiKeyMiddleware)
+	v2 := routes.CreateRouters(healthRouter, vehicleRecordRouter, parkingSessionRouter, forwardingRouter, reviewRouter, retentionRouter, evidenceRouter, plateHistoryRouter, watchlistRouter, deadLetterRouter)
+	boot := NewBoot(eventVehicleRecord, eventConsumer, rabbit3, consumerRunner, retryConsumer, retry, vehicleRecordService, parkingSessionService, retentionService, watchlistService, env, logger, v2...)
 	return boot, nil
 }
@@ -0,0 +1,54 @@
+package entity
+
+import "strings"
+
+const (
+	ScopeRecordsRead  = "records:read"
+	ScopeRecordsRetry = "records:retry"
+	// ScopeRecordsReview lets a key correct, approve and reject held records
+	ScopeRecordsReview = "records:review"
+	// ScopeRecordsRetention lets a key change retention policies and legal holds
+	ScopeRecordsRetention = "records:retention"
+	// ScopeRecordsEvidence lets a key export the evidence package of a record
+	ScopeRecordsEvidence = "records:evidence"
+	// ScopeWatchlistsManage lets a key change watchlists and their entries
+	ScopeWatchlistsManage = "watchlists:manage"
+	// ScopeWatchlistsAck lets a key list and acknowledge watchlist matches
+	ScopeWatchlistsAck = "watchlists:ack"
+	// ScopeDeadLettersManage lets a key inspect, replay and purge dead-lettered messages
+	ScopeDeadLettersManage = "deadletters:manage"
+)
+
+// Scopes lists every scope a key can be granted
+var Scopes = []string{ScopeRecordsRead, ScopeRecordsRetry, ScopeRecordsReview, ScopeRecordsRetention,
+	ScopeRecordsEvidence, ScopeWatchlistsManage, ScopeWatchlistsAck, ScopeDeadLettersManage}
+
+// ApiKey is an operator key of the HTTP API. Only the SHA-256 hash of the key
+// is stored, Prefix is the start of the key so operators can tell keys apart.
+// Scopes is a comma separated list, ExpiresAt and RevokedAt are zero while
+// they do not apply.
+type ApiKey struct {
+	Base
+	Name       string `gorm:"type:varchar(100);not null" json:"name"`
+	Prefix     string `gorm:"type:varchar(20);not null" json:"prefix"`
+	KeyHash    string `gorm:"type:char(64);not null" json:"-"`
+	Scopes     string `gorm:"not null" json:"scopes"`
+	ExpiresAt  int64  `gorm:"not null" json:"expiresAt"`
+	RevokedAt  int64  `gorm:"not null" json:"revokedAt"`
+	LastUsedAt int64  `gorm:"not null" json:"lastUsedAt"`
+}
+
+// HasScope tells if the key was granted scope
+func (k *ApiKey) HasScope(scope string) bool {
+	for _, s := range strings.Split(k.Scopes, ",") {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// Active tells if the key is neither revoked nor expired at now, in milliseconds
+func (k *ApiKey) Active(now int64) bool {
+	return k.RevokedAt == 0 && (k.ExpiresAt == 0 || now < k.ExpiresAt)
+}
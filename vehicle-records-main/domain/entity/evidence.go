@@ -0,0 +1,29 @@
+package entity
+
+// EvidenceSegment is the segment of a record as kept in the farin database,
+// Geometry is GeoJSON in WGS 84
+type EvidenceSegment struct {
+	ID       int64  `json:"id"`
+	SegCode  string `json:"segCode"`
+	SegName  string `json:"segName"`
+	Geometry string `json:"geometry"`
+}
+
+// EvidenceRing is the ring of a record as kept in the farin database
+type EvidenceRing struct {
+	ID       int64  `json:"id"`
+	RingCode string `json:"ringCode"`
+	RingName string `json:"ringName"`
+}
+
+// EvidenceDriver is the user who drove the LPR vehicle of a record, DriverID
+// is empty when the user has no driver profile
+type EvidenceDriver struct {
+	UserID     string `json:"userId"`
+	Username   string `json:"username"`
+	FirstName  string `json:"firstName"`
+	LastName   string `json:"lastName"`
+	DriverID   string `json:"driverId,omitempty"`
+	DriverType string `json:"driverType,omitempty"`
+	ShiftType  string `json:"shiftType,omitempty"`
+}
@@ -0,0 +1,29 @@
+package entity
+
+// ForwardingAttempt is one try of sending a vehicle record to a municipality.
+// StatusCode is zero when no response was received, ResponseBody holds the
+// start of the response and ErrorClass tells why the attempt failed.
+type ForwardingAttempt struct {
+	Base
+	RecordID         string `gorm:"type:uuid;column:record_id;not null" json:"recordId"`
+	Forwarder        string `gorm:"type:varchar(50);not null" json:"forwarder"`
+	AttemptedAt      int64  `gorm:"not null" json:"attemptedAt"`
+	StatusCode       int    `json:"statusCode"`
+	ResponseBody     string `json:"responseBody"`
+	LatencyMs        int64  `gorm:"column:latency_ms;not null" json:"latencyMs"`
+	Success          bool   `gorm:"not null" json:"success"`
+	ErrorClass       string `gorm:"type:varchar(20)" json:"errorClass"`
+	Error            string `json:"error"`
+	RequestID        string `gorm:"column:request_id" json:"requestId"`
+	PlateDetectionID int    `gorm:"column:plate_detection_id" json:"plateDetectionId"`
+}
+
+const (
+	ForwardingErrorAuth      = "auth"
+	ForwardingErrorRequest   = "request"
+	ForwardingErrorTimeout   = "timeout"
+	ForwardingErrorTransport = "transport"
+	ForwardingErrorStatus    = "status"
+	ForwardingErrorResponse  = "response"
+	ForwardingErrorUnknown   = "unknown"
+)
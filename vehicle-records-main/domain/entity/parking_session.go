@@ -0,0 +1,28 @@
+package entity
+
+const (
+	ParkingSessionOpen    = "open"
+	ParkingSessionClosed  = "closed"  // the plate was missing on a later pass over the segment
+	ParkingSessionExpired = "expired" // the segment was not passed again before the session got too old
+)
+
+// ParkingSession groups repeated sightings of one plate in one segment/parking lot
+type ParkingSession struct {
+	Base
+	CitizenPlateNumber        string `gorm:"column:citizen_plate_number;not null" json:"CitizenPlateNumber"`
+	CitizenPlateNumberNumeric int    `gorm:"column:citizen_plate_number_numeric;not null" json:"CitizenPlateNumberNumeric"`
+
+	RingID       int64 `gorm:"column:ring_id" json:"RingId"`
+	StreetID     int64 `gorm:"column:street_id" json:"StreetId"`
+	SegmentID    int64 `gorm:"column:segment_id;not null" json:"SegmentId"`
+	ParkingLotID int64 `gorm:"column:parking_lot_id;not null" json:"ParkingLotId"`
+
+	FirstRecordID string `gorm:"type:uuid;column:first_record_id;not null" json:"FirstRecordId"`
+	LastRecordID  string `gorm:"type:uuid;column:last_record_id;not null" json:"LastRecordId"`
+	Sightings     int    `gorm:"column:sightings;not null" json:"Sightings"`
+
+	StartTime    int64  `gorm:"column:start_time;not null" json:"StartTime"`        //unix milli, first sighting
+	LastSeenTime int64  `gorm:"column:last_seen_time;not null" json:"LastSeenTime"` //unix milli, latest sighting
+	EndTime      int64  `gorm:"column:end_time" json:"EndTime"`                     //unix milli, first pass that missed the plate
+	Status       string `gorm:"column:status;not null" json:"Status"`
+}
@@ -36,6 +36,9 @@ type VehicleRecord struct {
 	ParkingLotID int64 `gorm:"column:parking_lot_id" json:"ParkingLotId"`
 	RoadCode     int64 `gorm:"column:road_code" json:"RoadCode"` //streetCode
 	IsJunction   bool  `gorm:"column:is_junction;not null" json:"IsJunction"`
+	// MatchConfidence is how sure farin is of the segment the record was
+	// map-matched to, from 0 for no segment to 1
+	MatchConfidence float64 `gorm:"column:match_confidence;not null;default:0" json:"MatchConfidence"`
 
 	UserID              string `gorm:"type:uuid;column:user_id;not null" json:"UserId"`
 	LPRVehicleID        string `gorm:"type:uuid;column:lpr_vehicle_id;not null" json:"LPRVehicleId"`
@@ -58,6 +61,18 @@ type VehicleRecord struct {
 	CitizenPlateNumberNumeric int     `json:"CitizenPlateNumberNumeric"`
 	ShamsiTime                string  `json:"ShamsiTime"`
 
+	// DuplicateOf is the record a suppressed sighting of the same vehicle was
+	// merged into, Duplicates the number of sightings merged into this one
+	DuplicateOf *string `gorm:"type:uuid;column:duplicate_of" json:"DuplicateOf,omitempty"`
+	Duplicates  int     `gorm:"column:duplicates;not null;default:0" json:"Duplicates"`
+
+	// LegalHold keeps the photos of the record as evidence regardless of the
+	// retention policy of its status
+	LegalHold       bool   `gorm:"column:legal_hold;not null;default:false" json:"LegalHold"`
+	LegalHoldAt     int64  `gorm:"column:legal_hold_at;not null;default:0" json:"LegalHoldAt"`
+	LegalHoldReason string `gorm:"column:legal_hold_reason" json:"LegalHoldReason,omitempty"`
+	LegalHoldBy     string `gorm:"column:legal_hold_by" json:"LegalHoldBy,omitempty"`
+
 	VehiclePhotos []*CitizenVehiclePhoto `gorm:"foreignKey:RecordID" json:"VehiclePhotos,omitempty"`
 	CreatedAt     int64                  `gorm:"autoCreateTime:milli" json:"CreatedAt"`
 	UpdatedAt     int64                  `gorm:"autoUpdateTime:milli" json:"UpdatedAt"`
@@ -74,6 +89,7 @@ type CitizenVehiclePhoto struct {
 	CitizenVehiclePhotoArea        string  `gorm:"column:citizen_vehicle_photo_area" json:"CitizenVehiclePhotoArea"`
 	CitizenVehiclePlateCropPhoto   string  `gorm:"column:citizen_vehicle_plate_crop_photo" json:"CitizenVehiclePlateCropPhoto"`
 	CitizenVehiclePhotoCaptureTime int64   `gorm:"column:citizen_vehicle_photo_capture_time;not null" json:"CitizenVehiclePhotoCaptureTime"`
+	ReapedAt                       int64   `gorm:"column:reaped_at;not null;default:0" json:"ReapedAt"` //photos were deleted by retention at
 
 	VehicleRecord VehicleRecord `gorm:"foreignKey:RecordID" json:"-"`
 }
@@ -119,6 +135,7 @@ func (vr *VehicleRecord) UnmarshalJSON(data []byte) error {
 	vr.RoadCode = v.GetInt64("RoadCode")
 	vr.IsJunction = v.GetBool("IsJunction")
 	vr.ParkingLotID = v.GetInt64("ParkingLotId")
+	vr.MatchConfidence = v.GetFloat64("MatchConfidence")
 
 	vr.UserID = string(v.GetStringBytes("UserId"))
 	vr.LPRVehicleID = string(v.GetStringBytes("LPRVehicleId"))
@@ -135,6 +152,7 @@ func (vr *VehicleRecord) UnmarshalJSON(data []byte) error {
 	vr.LPRVehicleRTKLatitude = v.GetFloat64("LPRVehicleRTKLatitude")
 	vr.LPRVehicleRTKLongitude = v.GetFloat64("LPRVehicleRTKLongitude")
 	vr.LPRVehicleRTKError = v.GetInt("LPRVehicleRTKError")
+	vr.CycleID = v.GetInt("CycleID")
 
 	if photosArray := v.GetArray("VehiclePhotos"); len(photosArray) > 0 {
 		vr.VehiclePhotos = make([]*CitizenVehiclePhoto, len(photosArray))
@@ -0,0 +1,36 @@
+package entity
+
+type RecordOutboxStatus string
+
+const (
+	RecordOutboxPendingUpload RecordOutboxStatus = "pending_upload"
+	RecordOutboxPendingSend   RecordOutboxStatus = "pending_send"
+	RecordOutboxSent          RecordOutboxStatus = "sent"
+	RecordOutboxFailed        RecordOutboxStatus = "failed"
+	RecordOutboxPendingReview RecordOutboxStatus = "pending_review"
+	RecordOutboxRejected      RecordOutboxStatus = "rejected"
+	RecordOutboxSuppressed    RecordOutboxStatus = "suppressed"
+)
+
+// RecordOutbox is the delivery state of a vehicle record. It is written in
+// the same transaction as the record and moves from pending_upload to
+// pending_send once the photos are stored, then to sent once tehran.ir
+// accepted the record. Photos holds the JSON encoded photos of the record
+// until they are uploaded. Held entries wait in pending_review after the
+// upload until the review of the record is approved, or end in rejected.
+// Entries of suppressed duplicates end in suppressed without being sent.
+type RecordOutbox struct {
+	Base
+	RecordID      string             `gorm:"type:uuid;column:record_id;not null" json:"recordId"`
+	Status        RecordOutboxStatus `gorm:"type:varchar(20);not null" json:"status"`
+	Photos        []byte             `gorm:"type:bytea" json:"-"`
+	Attempts      int                `gorm:"not null" json:"attempts"`
+	NextAttemptAt int64              `gorm:"not null" json:"nextAttemptAt"`
+	LockedUntil   int64              `gorm:"not null" json:"lockedUntil"`
+	LastError     string             `json:"lastError"`
+	Held          bool               `gorm:"not null" json:"held"`
+}
+
+func (RecordOutbox) TableName() string {
+	return "record_outbox"
+}
@@ -0,0 +1,73 @@
+package entity
+
+import "strings"
+
+type RecordReviewStatus string
+
+const (
+	RecordReviewPending  RecordReviewStatus = "pending"
+	RecordReviewApproved RecordReviewStatus = "approved"
+	RecordReviewRejected RecordReviewStatus = "rejected"
+)
+
+// Reasons a record is held for review
+const (
+	ReviewReasonLowOCRAccuracy  = "low_ocr_accuracy"
+	ReviewReasonDistorted       = "distorted"
+	ReviewReasonPlateNotVisible = "plate_not_visible"
+	ReviewReasonInvalidPlate    = "invalid_plate"
+)
+
+// RecordReview holds a record of doubtful quality back from its municipality
+// until an operator approved it. Reasons is a comma separated list of the
+// quality rules the record failed, OriginalPlate the plate as it was read.
+type RecordReview struct {
+	Base
+	RecordID      string             `gorm:"type:uuid;column:record_id;not null" json:"recordId"`
+	Status        RecordReviewStatus `gorm:"type:varchar(20);not null" json:"status"`
+	Reasons       string             `gorm:"not null" json:"reasons"`
+	OriginalPlate string             `gorm:"not null" json:"originalPlate"`
+	Reviewer      string             `gorm:"type:varchar(100)" json:"reviewer"`
+	ReviewedAt    int64              `gorm:"not null" json:"reviewedAt"`
+	Note          string             `json:"note"`
+
+	VehicleRecord *VehicleRecord      `gorm:"foreignKey:RecordID;references:RecordID" json:"record,omitempty"`
+	Audits        []RecordReviewAudit `gorm:"foreignKey:ReviewID" json:"audits,omitempty"`
+}
+
+// HasReason tells if the record failed the quality rule reason
+func (r *RecordReview) HasReason(reason string) bool {
+	for _, s := range strings.Split(r.Reasons, ",") {
+		if s == reason {
+			return true
+		}
+	}
+	return false
+}
+
+const (
+	ReviewActionCorrect = "correct"
+	ReviewActionApprove = "approve"
+	ReviewActionReject  = "reject"
+)
+
+// RecordReviewAudit is one action of an operator on a review, OldPlate and
+// NewPlate are set by corrections. ApiKeyID is the key the operator acted with.
+type RecordReviewAudit struct {
+	Base
+	ReviewID string `gorm:"type:uuid;column:review_id;not null" json:"reviewId"`
+	RecordID string `gorm:"type:uuid;column:record_id;not null" json:"recordId"`
+	Action   string `gorm:"type:varchar(20);not null" json:"action"`
+	Reviewer string `gorm:"type:varchar(100);not null" json:"reviewer"`
+	ApiKeyID string `gorm:"type:uuid;column:api_key_id" json:"apiKeyId"`
+	OldPlate string `json:"oldPlate"`
+	NewPlate string `json:"newPlate"`
+	Note     string `json:"note"`
+}
+
+// Reviewer is the operator behind a review action, Name identifies the person
+// and ApiKeyID the api key of the request
+type Reviewer struct {
+	Name     string
+	ApiKeyID string
+}
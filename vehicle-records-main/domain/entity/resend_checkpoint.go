@@ -0,0 +1,14 @@
+package entity
+
+// ResendCheckpoint is the progress of the scheduled resend of unsent records.
+// The scan resumes after the (CursorCreatedAt, CursorRecordID) record, an
+// empty CursorRecordID starts a new pass. The scheduler that holds the lease,
+// until LockedUntil, is the only one scanning.
+type ResendCheckpoint struct {
+	Name            string `gorm:"type:varchar(50);primaryKey" json:"name"`
+	CursorCreatedAt int64  `gorm:"not null" json:"cursorCreatedAt"`
+	CursorRecordID  string `gorm:"type:varchar(36);not null" json:"cursorRecordId"`
+	PassStartedAt   int64  `gorm:"not null" json:"passStartedAt"`
+	LockedUntil     int64  `gorm:"not null" json:"lockedUntil"`
+	UpdatedAt       int64  `gorm:"autoUpdateTime:milli" json:"updatedAt"`
+}
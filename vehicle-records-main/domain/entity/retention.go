@@ -0,0 +1,52 @@
+package entity
+
+// Record statuses photo retention policies are keyed by
+const (
+	RetentionForwarded    = "forwarded"
+	RetentionRejected     = "rejected"
+	RetentionUnderReview  = "under_review"
+	RetentionEvidenceHold = "evidence_hold"
+	// RetentionUnforwarded is every other record, as one whose sending failed
+	RetentionUnforwarded = "unforwarded"
+)
+
+// RetentionStatuses lists every status a policy exists for
+var RetentionStatuses = []string{RetentionForwarded, RetentionRejected, RetentionUnderReview, RetentionEvidenceHold,
+	RetentionUnforwarded}
+
+// RetentionPolicy is how long the photos of records in Status are kept.
+// Forwarded, unforwarded and under review records count from their creation, rejected
+// records from their review and records on legal hold from the hold. A zero
+// RetentionHours keeps the photos forever.
+type RetentionPolicy struct {
+	Status         string `gorm:"type:varchar(20);primaryKey" json:"status"`
+	RetentionHours int    `gorm:"not null" json:"retentionHours"`
+	UpdatedBy      string `gorm:"type:varchar(100)" json:"updatedBy"`
+	UpdatedAt      int64  `gorm:"autoUpdateTime:milli" json:"updatedAt"`
+}
+
+func (RetentionPolicy) TableName() string {
+	return "photo_retention_policies"
+}
+
+// RetentionRun is one pass of the photo reaper, Bytes is the size of the
+// deleted objects
+type RetentionRun struct {
+	Base
+	StartedAt  int64 `gorm:"not null" json:"startedAt"`
+	FinishedAt int64 `gorm:"not null" json:"finishedAt"`
+	Photos     int   `gorm:"not null" json:"photos"`
+	Objects    int   `gorm:"not null" json:"objects"`
+	Bytes      int64 `gorm:"not null" json:"bytes"`
+	Errors     int   `gorm:"not null" json:"errors"`
+}
+
+// ReapedObject is a photo object whose path the reaper cleared, it is deleted
+// from object storage after the pass that reaped it committed. LockedUntil is
+// set while a pass deletes it.
+type ReapedObject struct {
+	Base
+	Path        string `gorm:"not null" json:"path"`
+	ReapedAt    int64  `gorm:"not null" json:"reapedAt"`
+	LockedUntil int64  `gorm:"not null;default:0" json:"lockedUntil"`
+}
@@ -0,0 +1,113 @@
+package entity
+
+type WatchlistKind string
+
+const (
+	WatchlistStolen      WatchlistKind = "stolen"
+	WatchlistUnpaidFines WatchlistKind = "unpaid_fines"
+	WatchlistPolice      WatchlistKind = "police"
+	WatchlistOther       WatchlistKind = "other"
+)
+
+// Watchlist is a list of plates to raise alerts for, entries of an inactive
+// list are not matched
+type Watchlist struct {
+	Base
+	Name        string        `gorm:"type:varchar(100);not null" json:"name"`
+	Kind        WatchlistKind `gorm:"type:varchar(20);not null" json:"kind"`
+	Description string        `json:"description"`
+	Active      bool          `gorm:"not null" json:"active"`
+}
+
+// WatchlistEntry is a plate of a watchlist. Pattern is a plate or a plate
+// with * and ? wildcards, it matches records stored in [ValidFrom,
+// ValidUntil), a zero ValidUntil never expires.
+type WatchlistEntry struct {
+	Base
+	WatchlistID string `gorm:"type:uuid;column:watchlist_id;not null" json:"watchlistId"`
+	Pattern     string `gorm:"type:varchar(50);not null" json:"pattern"`
+	Priority    int    `gorm:"not null" json:"priority"`
+	ValidFrom   int64  `gorm:"not null" json:"validFrom"`
+	ValidUntil  int64  `gorm:"not null" json:"validUntil"`
+	Note        string `json:"note"`
+	CreatedBy   string `gorm:"type:varchar(100);not null" json:"createdBy"`
+
+	Watchlist *Watchlist `gorm:"foreignKey:WatchlistID" json:"watchlist,omitempty"`
+}
+
+// Active tells if the entry matches records stored at t, in milliseconds
+func (e *WatchlistEntry) Active(t int64) bool {
+	return t >= e.ValidFrom && (e.ValidUntil == 0 || t < e.ValidUntil)
+}
+
+type WatchlistMatchStatus string
+
+const (
+	WatchlistMatchPending      WatchlistMatchStatus = "pending"
+	WatchlistMatchAcknowledged WatchlistMatchStatus = "acknowledged"
+)
+
+// WatchlistMatch is a record that matched an entry, an alert is published
+// for it and waits for an operator to acknowledge it. PublishedAt is zero
+// until the alert was published.
+type WatchlistMatch struct {
+	Base
+	WatchlistID    string               `gorm:"type:uuid;column:watchlist_id;not null" json:"watchlistId"`
+	EntryID        string               `gorm:"type:uuid;column:entry_id;not null" json:"entryId"`
+	RecordID       string               `gorm:"type:uuid;column:record_id;not null" json:"recordId"`
+	Plate          string               `gorm:"not null" json:"plate"`
+	Priority       int                  `gorm:"not null" json:"priority"`
+	Status         WatchlistMatchStatus `gorm:"type:varchar(20);not null" json:"status"`
+	PublishedAt    int64                `gorm:"not null" json:"publishedAt"`
+	AcknowledgedBy string               `gorm:"type:varchar(100)" json:"acknowledgedBy"`
+	AcknowledgedAt int64                `gorm:"not null" json:"acknowledgedAt"`
+	Note           string               `json:"note"`
+
+	Watchlist     *Watchlist      `gorm:"foreignKey:WatchlistID" json:"watchlist,omitempty"`
+	Entry         *WatchlistEntry `gorm:"foreignKey:EntryID" json:"entry,omitempty"`
+	VehicleRecord *VehicleRecord  `gorm:"foreignKey:RecordID;references:RecordID" json:"record,omitempty"`
+}
+
+// HotlistAlert is the farin.alerts.hotlist event published for every match.
+// Photos are named by their bucket/object paths, since alerts outlive any
+// presigned URL.
+type HotlistAlert struct {
+	MatchID   string            `json:"matchId"`
+	MatchedAt int64             `json:"matchedAt"`
+	Watchlist HotlistAlertList  `json:"watchlist"`
+	Entry     HotlistAlertEntry `json:"entry"`
+	Plate     string            `json:"plate"`
+	Location  HotlistLocation   `json:"location"`
+	Photos    []HotlistPhoto    `json:"photos"`
+	Record    *VehicleRecord    `json:"record"`
+}
+
+type HotlistAlertList struct {
+	ID   string        `json:"id"`
+	Name string        `json:"name"`
+	Kind WatchlistKind `json:"kind"`
+}
+
+type HotlistAlertEntry struct {
+	ID       string `json:"id"`
+	Pattern  string `json:"pattern"`
+	Priority int    `json:"priority"`
+	Note     string `json:"note"`
+}
+
+type HotlistLocation struct {
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+	RingID    int64   `json:"ringId"`
+	SegmentID int64   `json:"segmentId"`
+	StreetID  int64   `json:"streetId"`
+}
+
+// HotlistPhoto names the stored objects of a photo, signed URLs of them are
+// returned by GET /v1/vehicle-records/{id}. A path is empty once the photo
+// was reaped.
+type HotlistPhoto struct {
+	ID              string `json:"id"`
+	Object          string `json:"object"`
+	PlateCropObject string `json:"plateCropObject"`
+}
@@ -0,0 +1,80 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"git.abanppc.com/farin-project/vehicle-records/domain/entity"
+	gormdb "git.abanppc.com/farin-project/vehicle-records/infrastructure/gorm"
+	"gorm.io/gorm"
+)
+
+var ErrApiKeyNotFound = errors.New("api key not found")
+
+type ApiKeyRepository struct {
+	DB *gormdb.GORMDB
+}
+
+func NewApiKeyRepository(db *gormdb.GORMDB) *ApiKeyRepository {
+	return &ApiKeyRepository{DB: db}
+}
+
+// Create a new api key
+func (r *ApiKeyRepository) Create(ctx context.Context, key *entity.ApiKey) (*entity.ApiKey, error) {
+	if err := r.DB.DB.WithContext(ctx).Create(key).Error; err != nil {
+		return nil, fmt.Errorf("failed to create api key: %w", err)
+	}
+	return key, nil
+}
+
+// GetByHash gets an api key by the hash of the key
+func (r *ApiKeyRepository) GetByHash(ctx context.Context, hash string) (*entity.ApiKey, error) {
+	var key entity.ApiKey
+
+	if err := r.DB.DB.WithContext(ctx).Where("key_hash = ?", hash).First(&key).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrApiKeyNotFound
+		}
+		return nil, fmt.Errorf("failed to retrieve api key: %w", err)
+	}
+
+	return &key, nil
+}
+
+// List all api keys, newest first
+func (r *ApiKeyRepository) List(ctx context.Context) ([]entity.ApiKey, error) {
+	var keys []entity.ApiKey
+
+	if err := r.DB.DB.WithContext(ctx).Order("created_at desc").Find(&keys).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch api keys: %w", err)
+	}
+
+	return keys, nil
+}
+
+// Revoke marks a key as revoked at revokedAt, a key that is already revoked
+// keeps its first revocation time
+func (r *ApiKeyRepository) Revoke(ctx context.Context, id string, revokedAt int64) error {
+	result := r.DB.DB.WithContext(ctx).Model(&entity.ApiKey{}).
+		Where("id = ?", id).
+		Update("revoked_at", gorm.Expr("CASE WHEN revoked_at = 0 THEN ? ELSE revoked_at END", revokedAt))
+	if result.Error != nil {
+		return fmt.Errorf("failed to revoke api key: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrApiKeyNotFound
+	}
+	return nil
+}
+
+// Used sets the last use time of a key
+func (r *ApiKeyRepository) Used(ctx context.Context, id string, usedAt int64) error {
+	err := r.DB.DB.WithContext(ctx).Model(&entity.ApiKey{}).
+		Where("id = ?", id).
+		UpdateColumn("last_used_at", usedAt).Error
+	if err != nil {
+		return fmt.Errorf("failed to update api key: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,16 @@
+package repository
+
+import (
+	"git.abanppc.com/farin-project/vehicle-records/infrastructure/rabbit"
+	"git.abanppc.com/farin-project/vehicle-records/util/consumer"
+)
+
+// DeadLetterRabbitMQ reads the dead-letter queues of the consumers, on
+// channels of its own
+type DeadLetterRabbitMQ struct {
+	*consumer.Admin
+}
+
+func NewDeadLetterRabbitMQ(rbt *rabbit.Rabbit) *DeadLetterRabbitMQ {
+	return &DeadLetterRabbitMQ{Admin: consumer.NewAdmin(rbt.Channel)}
+}
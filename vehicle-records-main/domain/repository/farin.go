@@ -0,0 +1,77 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"git.abanppc.com/farin-project/vehicle-records/domain/entity"
+	gormdb "git.abanppc.com/farin-project/vehicle-records/infrastructure/gorm"
+)
+
+// ErrFarinUnavailable is returned while no farin database is configured
+var ErrFarinUnavailable = errors.New("farin database is not configured")
+
+// FarinRepository reads the segments, rings and drivers of records from the
+// farin database. Deleted rows are read as well, since a record keeps
+// pointing at the row it was made with. Lookups of unknown ids return nil.
+type FarinRepository struct {
+	DB *gormdb.FarinGORMDB
+}
+
+func NewFarinRepository(db *gormdb.FarinGORMDB) *FarinRepository {
+	return &FarinRepository{DB: db}
+}
+
+func (r *FarinRepository) Segment(ctx context.Context, id int64) (*entity.EvidenceSegment, error) {
+	if r.DB.DB == nil {
+		return nil, ErrFarinUnavailable
+	}
+	var segments []entity.EvidenceSegment
+	err := r.DB.DB.WithContext(ctx).Raw(`SELECT id, seg_code, seg_name, ST_AsGeoJSON(ST_Transform(geom, 4326)) AS geometry
+		FROM segments WHERE id = ?`, id).Scan(&segments).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve segment: %w", err)
+	}
+	if len(segments) == 0 {
+		return nil, nil
+	}
+	return &segments[0], nil
+}
+
+func (r *FarinRepository) Ring(ctx context.Context, id int64) (*entity.EvidenceRing, error) {
+	if r.DB.DB == nil {
+		return nil, ErrFarinUnavailable
+	}
+	var rings []entity.EvidenceRing
+	err := r.DB.DB.WithContext(ctx).Raw(`SELECT id, ring_code, ring_name FROM rings WHERE id = ?`, id).
+		Scan(&rings).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve ring: %w", err)
+	}
+	if len(rings) == 0 {
+		return nil, nil
+	}
+	return &rings[0], nil
+}
+
+// Driver finds the user of userID with its driver profile, the live profile
+// wins over deleted ones
+func (r *FarinRepository) Driver(ctx context.Context, userID string) (*entity.EvidenceDriver, error) {
+	if r.DB.DB == nil {
+		return nil, ErrFarinUnavailable
+	}
+	var drivers []entity.EvidenceDriver
+	err := r.DB.DB.WithContext(ctx).Raw(`SELECT u.id AS user_id, u.username, u.first_name, u.last_name,
+			coalesce(d.id::text, '') AS driver_id, coalesce(d.driver_type, '') AS driver_type,
+			coalesce(d.shift_type, '') AS shift_type
+		FROM users u LEFT JOIN drivers d ON d.user_id = u.id
+		WHERE u.id = ? ORDER BY d.deleted_at, d.created_at DESC LIMIT 1`, userID).Scan(&drivers).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve driver: %w", err)
+	}
+	if len(drivers) == 0 {
+		return nil, nil
+	}
+	return &drivers[0], nil
+}
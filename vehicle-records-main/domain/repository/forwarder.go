@@ -0,0 +1,207 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+
+	"git.abanppc.com/farin-project/vehicle-records/domain/entity"
+	"git.abanppc.com/farin-project/vehicle-records/infrastructure/godotenv"
+	"git.abanppc.com/farin-project/vehicle-records/infrastructure/resilience"
+)
+
+// TehranForwarderName is the name of the tehran.ir margin parking forwarder
+const TehranForwarderName = "tehran"
+
+// Errors of forwarders are wrapped in one of these to tell what went wrong
+var (
+	ErrForwardAuth      = errors.New("forwarder authentication failed")
+	ErrForwardRequest   = errors.New("forwarder request could not be built")
+	ErrForwardTransport = errors.New("forwarder request could not be sent")
+	ErrForwardStatus    = errors.New("forwarder rejected the record")
+	ErrForwardResponse  = errors.New("forwarder response is invalid")
+	// ErrForwardUnavailable is returned without calling the municipality,
+	// while it is considered down
+	ErrForwardUnavailable = errors.New("forwarder is unavailable")
+)
+
+// maxResponseExcerpt is the number of response body bytes kept of an attempt
+const maxResponseExcerpt = 2048
+
+// ForwardResult is what a municipality answered. RequestID and
+// PlateDetectionID identify the record on the municipality side and are empty
+// when the municipality does not return them, StatusCode is zero when no
+// response was received.
+type ForwardResult struct {
+	RequestID        string
+	PlateDetectionID int
+	StatusCode       int
+	ResponseBody     string
+}
+
+// Forwarder sends vehicle records to a municipality. The result is returned
+// with the error as well, as far as the request got.
+type Forwarder interface {
+	Name() string
+	SendVehicleRecord(ctx context.Context, record *entity.VehicleRecord) (*ForwardResult, error)
+}
+
+// GuardedForwarder is a forwarder whose calls go through a circuit breaker
+// and a rate limiter
+type GuardedForwarder interface {
+	Forwarder
+	Breaker() *resilience.CircuitBreaker
+	Limiter() *resilience.Limiter
+}
+
+// ForwardersConfig is the content of the FORWARDERS_CONFIG file. Records are
+// sent to the forwarder of the first route that lists their ring, then to the
+// first route whose city bounds contain their position and to Default
+// otherwise.
+type ForwardersConfig struct {
+	Default  string                   `json:"default"`
+	Routes   []ForwarderRoute         `json:"routes"`
+	Webhooks []WebhookForwarderConfig `json:"webhooks"`
+}
+
+// ForwarderRoute routes the records of the listed rings, or the records taken
+// within the bounds of City, to Forwarder
+type ForwarderRoute struct {
+	Forwarder string      `json:"forwarder"`
+	City      string      `json:"city"`
+	Bounds    *CityBounds `json:"bounds"`
+	RingIDs   []int64     `json:"ringIds"`
+}
+
+// CityBounds is the WGS 84 bounding box of a city
+type CityBounds struct {
+	MinLat float64 `json:"minLat"`
+	MinLon float64 `json:"minLon"`
+	MaxLat float64 `json:"maxLat"`
+	MaxLon float64 `json:"maxLon"`
+}
+
+func (b *CityBounds) contains(lat, lon float64) bool {
+	return lat >= b.MinLat && lat <= b.MaxLat && lon >= b.MinLon && lon <= b.MaxLon
+}
+
+type cityRoute struct {
+	city      string
+	bounds    *CityBounds
+	forwarder Forwarder
+}
+
+// ForwarderRegistry selects the forwarder of a vehicle record
+type ForwarderRegistry struct {
+	forwarders map[string]Forwarder
+	rings      map[int64]Forwarder
+	cities     []cityRoute
+	def        Forwarder
+}
+
+// NewForwarderRegistry registers the Tehran forwarder and the webhook
+// forwarders of the FORWARDERS_CONFIG file. Without a config file every
+// record goes to Tehran.
+func NewForwarderRegistry(env *godotenv.Env, logger *slog.Logger, tehran *TehranSiteRecordRepository) (
+	*ForwarderRegistry, error) {
+	cfg := &ForwardersConfig{Default: TehranForwarderName}
+	if env.ForwardersConfig != "" {
+		b, err := os.ReadFile(env.ForwardersConfig)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read forwarders config: %w", err)
+		}
+		if err := json.Unmarshal(b, cfg); err != nil {
+			return nil, fmt.Errorf("failed to parse forwarders config: %w", err)
+		}
+	}
+
+	reg := &ForwarderRegistry{
+		forwarders: map[string]Forwarder{TehranForwarderName: tehran},
+		rings:      map[int64]Forwarder{},
+	}
+	for _, wc := range cfg.Webhooks {
+		if _, ok := reg.forwarders[wc.Name]; ok {
+			return nil, fmt.Errorf("duplicate forwarder %q", wc.Name)
+		}
+		wf, err := NewWebhookForwarder(wc, logger)
+		if err != nil {
+			return nil, err
+		}
+		reg.forwarders[wc.Name] = wf
+	}
+
+	def, ok := reg.forwarders[cfg.Default]
+	if !ok {
+		return nil, fmt.Errorf("unknown default forwarder %q", cfg.Default)
+	}
+	reg.def = def
+	for _, route := range cfg.Routes {
+		f, ok := reg.forwarders[route.Forwarder]
+		if !ok {
+			return nil, fmt.Errorf("unknown forwarder %q in routes", route.Forwarder)
+		}
+		for _, ringID := range route.RingIDs {
+			if _, ok := reg.rings[ringID]; !ok {
+				reg.rings[ringID] = f
+			}
+		}
+		if route.Bounds != nil {
+			b := route.Bounds
+			if b.MinLat >= b.MaxLat || b.MinLon >= b.MaxLon {
+				return nil, fmt.Errorf("invalid bounds of city %q in routes", route.City)
+			}
+			reg.cities = append(reg.cities, cityRoute{city: route.City, bounds: b, forwarder: f})
+		}
+	}
+
+	return reg, nil
+}
+
+// For returns the forwarder of the record ring, or of the city the record was
+// taken in. The RTK position is used when there is one.
+func (r *ForwarderRegistry) For(record *entity.VehicleRecord) Forwarder {
+	if f, ok := r.rings[record.RingID]; ok {
+		return f
+	}
+	lat, lon := record.LPRVehicleRTKLatitude, record.LPRVehicleRTKLongitude
+	if lat == 0 && lon == 0 {
+		lat, lon = record.LPRVehicleGPSLatitude, record.LPRVehicleGPSLongitude
+	}
+	if lat != 0 || lon != 0 {
+		for _, c := range r.cities {
+			if c.bounds.contains(lat, lon) {
+				return c.forwarder
+			}
+		}
+	}
+	return r.def
+}
+
+// Get returns a forwarder by name
+func (r *ForwarderRegistry) Get(name string) (Forwarder, bool) {
+	f, ok := r.forwarders[name]
+	return f, ok
+}
+
+// Guarded returns the forwarders that have a circuit breaker
+func (r *ForwarderRegistry) Guarded() []GuardedForwarder {
+	var guarded []GuardedForwarder
+	for _, f := range r.forwarders {
+		if g, ok := f.(GuardedForwarder); ok {
+			guarded = append(guarded, g)
+		}
+	}
+	return guarded
+}
+
+// responseExcerpt keeps the start of a response body for the attempts ledger
+func responseExcerpt(b []byte) string {
+	if len(b) > maxResponseExcerpt {
+		b = b[:maxResponseExcerpt]
+	}
+	return strings.ToValidUTF8(string(b), "")
+}
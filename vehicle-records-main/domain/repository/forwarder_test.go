@@ -0,0 +1,78 @@
+package repository
+
+import (
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"git.abanppc.com/farin-project/vehicle-records/domain/entity"
+	"git.abanppc.com/farin-project/vehicle-records/infrastructure/godotenv"
+)
+
+func TestForwarderRegistryFor(t *testing.T) {
+	config := `{
+		"default": "tehran",
+		"routes": [
+			{"forwarder": "karaj", "city": "karaj", "ringIds": [501],
+				"bounds": {"minLat": 35.72, "minLon": 50.85, "maxLat": 35.88, "maxLon": 51.10}},
+			{"forwarder": "tehran", "ringIds": [601]}
+		],
+		"webhooks": [{"name": "karaj", "url": "http://karaj.example", "fields": {"id": "RecordId"}}]
+	}`
+	path := filepath.Join(t.TempDir(), "forwarders.json")
+	if err := os.WriteFile(path, []byte(config), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	reg, err := NewForwarderRegistry(&godotenv.Env{ForwardersConfig: path}, logger, &TehranSiteRecordRepository{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		record entity.VehicleRecord
+		want   string
+	}{
+		{"ring route", entity.VehicleRecord{RingID: 501}, "karaj"},
+		{"ring route wins over city",
+			entity.VehicleRecord{RingID: 601, LPRVehicleGPSLatitude: 35.8, LPRVehicleGPSLongitude: 51}, "tehran"},
+		{"city by gps", entity.VehicleRecord{LPRVehicleGPSLatitude: 35.8, LPRVehicleGPSLongitude: 51}, "karaj"},
+		{"city by rtk", entity.VehicleRecord{
+			LPRVehicleRTKLatitude: 35.8, LPRVehicleRTKLongitude: 51,
+			LPRVehicleGPSLatitude: 35.7, LPRVehicleGPSLongitude: 51.4}, "karaj"},
+		{"outside every city", entity.VehicleRecord{LPRVehicleGPSLatitude: 35.7, LPRVehicleGPSLongitude: 51.4}, "tehran"},
+		{"no ring and no position", entity.VehicleRecord{}, "tehran"},
+	}
+	for _, tt := range tests {
+		if got := reg.For(&tt.record).Name(); got != tt.want {
+			t.Errorf("%s: For() = %s, want %s", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestForwarderRegistryInvalidConfig(t *testing.T) {
+	tests := []struct {
+		name   string
+		config string
+	}{
+		{"unknown default", `{"default": "karaj"}`},
+		{"unknown route forwarder", `{"default": "tehran", "routes": [{"forwarder": "karaj", "ringIds": [1]}]}`},
+		{"empty bounds", `{"default": "tehran", "routes": [{"forwarder": "tehran", "bounds": {}}]}`},
+		{"duplicate forwarder", `{"default": "tehran",
+			"webhooks": [{"name": "tehran", "url": "http://x.example", "fields": {"id": "RecordId"}}]}`},
+	}
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	for _, tt := range tests {
+		path := filepath.Join(t.TempDir(), "forwarders.json")
+		if err := os.WriteFile(path, []byte(tt.config), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := NewForwarderRegistry(&godotenv.Env{ForwardersConfig: path}, logger,
+			&TehranSiteRecordRepository{}); err == nil {
+			t.Errorf("%s: NewForwarderRegistry() returned no error", tt.name)
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"git.abanppc.com/farin-project/vehicle-records/domain/entity"
+	gormdb "git.abanppc.com/farin-project/vehicle-records/infrastructure/gorm"
+	"gorm.io/gorm"
+)
+
+type ForwardingAttemptRepository struct {
+	DB *gormdb.GORMDB
+}
+
+func NewForwardingAttemptRepository(db *gormdb.GORMDB) *ForwardingAttemptRepository {
+	return &ForwardingAttemptRepository{DB: db}
+}
+
+// Create adds an attempt to the ledger
+func (r *ForwardingAttemptRepository) Create(ctx context.Context, attempt *entity.ForwardingAttempt) error {
+	if err := r.DB.DB.WithContext(ctx).Create(attempt).Error; err != nil {
+		return fmt.Errorf("failed to create forwarding attempt: %w", err)
+	}
+	return nil
+}
+
+// ListByRecord lists the attempts of a record, newest first
+func (r *ForwardingAttemptRepository) ListByRecord(ctx context.Context, recordID string, page, pageSize int) (
+	[]entity.ForwardingAttempt, int64, error) {
+	query := r.DB.DB.WithContext(ctx).Model(&entity.ForwardingAttempt{}).Where("record_id = ?", recordID)
+	return r.list(query, page, pageSize)
+}
+
+// ListFailures lists failed attempts with filtering and pagination, newest
+// first. from and to filter on the attempt time when they are not zero.
+func (r *ForwardingAttemptRepository) ListFailures(
+	ctx context.Context,
+	filters map[string]interface{},
+	from,
+	to int64,
+	page,
+	pageSize int,
+) ([]entity.ForwardingAttempt, int64, error) {
+	query := r.DB.DB.WithContext(ctx).Model(&entity.ForwardingAttempt{}).Where("success = ?", false)
+
+	// Apply filters
+	for key, value := range filters {
+		query = query.Where(fmt.Sprintf("%s = ?", key), value)
+	}
+	if from > 0 {
+		query = query.Where("attempted_at >= ?", from)
+	}
+	if to > 0 {
+		query = query.Where("attempted_at < ?", to)
+	}
+	return r.list(query, page, pageSize)
+}
+
+func (r *ForwardingAttemptRepository) list(query *gorm.DB, page, pageSize int) (
+	[]entity.ForwardingAttempt, int64, error) {
+	var attempts []entity.ForwardingAttempt
+	var total int64
+
+	// Count total attempts
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to count forwarding attempts: %w", err)
+	}
+
+	// Apply pagination
+	if page > 0 && pageSize > 0 {
+		query = query.Offset((page - 1) * pageSize).Limit(pageSize)
+	}
+
+	if err := query.Order("attempted_at desc").Find(&attempts).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to fetch forwarding attempts: %w", err)
+	}
+
+	return attempts, total, nil
+}
@@ -0,0 +1,46 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"git.abanppc.com/farin-project/vehicle-records/domain/entity"
+	"git.abanppc.com/farin-project/vehicle-records/infrastructure/godotenv"
+	"git.abanppc.com/farin-project/vehicle-records/infrastructure/rabbit"
+	"github.com/rabbitmq/amqp091-go"
+)
+
+// HotlistAlertRoutingKey is the routing key of hotlist alerts on the
+// internal exchange
+const HotlistAlertRoutingKey = "farin.alerts.hotlist"
+
+type HotlistAlertRabbitMQ struct {
+	rbt *rabbit.Rabbit
+	env *godotenv.Env
+}
+
+func NewHotlistAlertRabbitMQ(rbt *rabbit.Rabbit, env *godotenv.Env) *HotlistAlertRabbitMQ {
+	return &HotlistAlertRabbitMQ{rbt: rbt, env: env}
+}
+
+// Publish sends an alert as a persistent message and waits for rabbitmq to
+// confirm it
+func (r *HotlistAlertRabbitMQ) Publish(ctx context.Context, alert *entity.HotlistAlert) error {
+	b, err := json.Marshal(alert)
+	if err != nil {
+		return fmt.Errorf("failed to marshal hotlist alert: %w", err)
+	}
+
+	err = r.rbt.Publish(ctx, r.env.RabbitMQInternalExchange, HotlistAlertRoutingKey,
+		amqp091.Publishing{
+			ContentType:  "application/json",
+			DeliveryMode: amqp091.Persistent,
+			MessageId:    alert.MatchID,
+			Body:         b,
+		})
+	if err != nil {
+		return fmt.Errorf("failed to publish hotlist alert: %w", err)
+	}
+	return nil
+}
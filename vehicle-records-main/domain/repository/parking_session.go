@@ -0,0 +1,168 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"git.abanppc.com/farin-project/vehicle-records/domain/entity"
+	gormdb "git.abanppc.com/farin-project/vehicle-records/infrastructure/gorm"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrParkingSessionNotFound = errors.New("parking session not found")
+	ErrParkingSessionExists   = errors.New("parking session already open")
+)
+
+type ParkingSessionRepository struct {
+	DB *gormdb.GORMDB
+}
+
+func NewParkingSessionRepository(db *gormdb.GORMDB) *ParkingSessionRepository {
+	return &ParkingSessionRepository{DB: db}
+}
+
+// Create a new parking session, ErrParkingSessionExists is returned when the
+// plate already has an open session in the segment/parking lot
+func (r *ParkingSessionRepository) Create(ctx context.Context, session *entity.ParkingSession) (*entity.ParkingSession, error) {
+	result := r.DB.DB.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(session)
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to create parking session: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return nil, ErrParkingSessionExists
+	}
+	return session, nil
+}
+
+// FindOpen finds the open session of a plate in a segment/parking lot
+func (r *ParkingSessionRepository) FindOpen(ctx context.Context, plateNumeric int, segmentID, parkingLotID int64) (
+	*entity.ParkingSession, error) {
+	var session entity.ParkingSession
+
+	err := r.DB.DB.WithContext(ctx).
+		Where("citizen_plate_number_numeric = ?", plateNumeric).
+		Where("segment_id = ? AND parking_lot_id = ?", segmentID, parkingLotID).
+		Where("status = ?", entity.ParkingSessionOpen).
+		First(&session).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrParkingSessionNotFound
+		}
+		return nil, fmt.Errorf("failed to find open parking session: %w", err)
+	}
+
+	return &session, nil
+}
+
+// Sighted extends a session with a later sighting, sightings older than the
+// session last seen time only increase the sightings count
+func (r *ParkingSessionRepository) Sighted(ctx context.Context, id string, recordID string, seenAt int64) error {
+	err := r.DB.DB.WithContext(ctx).Exec(`UPDATE parking_sessions SET sightings = sightings + 1,
+		last_record_id = CASE WHEN last_seen_time < ? THEN ? ELSE last_record_id END,
+		last_seen_time = GREATEST(last_seen_time, ?),
+		updated_at = (extract(epoch from now()) * 1000)::bigint
+		WHERE id = ?`, seenAt, recordID, seenAt, id).Error
+	if err != nil {
+		return fmt.Errorf("failed to update parking session: %w", err)
+	}
+	return nil
+}
+
+// CloseMissed closes open sessions whose segment/parking lot was passed
+// again more than passGap after the session was last seen without the plate
+// being sighted. Passes newer than settledBefore are ignored, because the
+// plate may still show up later in the same pass. The end time of a closed
+// session is the first record of the pass that missed the plate.
+func (r *ParkingSessionRepository) CloseMissed(ctx context.Context, passGap int64, settledBefore int64) (int64, error) {
+	missedPass := `SELECT MIN(vr.record_store_time) FROM vehicle_records vr
+		WHERE vr.segment_id = ps.segment_id AND vr.parking_lot_id = ps.parking_lot_id
+		AND COALESCE(vr.deleted_at, 0) = 0
+		AND vr.record_store_time > ps.last_seen_time + ? AND vr.record_store_time < ?`
+
+	result := r.DB.DB.WithContext(ctx).Exec(`UPDATE parking_sessions ps SET status = ?,
+		end_time = (`+missedPass+`),
+		updated_at = (extract(epoch from now()) * 1000)::bigint
+		WHERE ps.status = ? AND ps.deleted_at = 0 AND ps.last_seen_time < ?
+		AND EXISTS (`+missedPass+`)`,
+		entity.ParkingSessionClosed,
+		passGap, settledBefore,
+		entity.ParkingSessionOpen, settledBefore-passGap,
+		passGap, settledBefore)
+	if result.Error != nil {
+		return 0, fmt.Errorf("failed to close parking sessions: %w", result.Error)
+	}
+	return result.RowsAffected, nil
+}
+
+// ExpireStale closes open sessions that were last seen before lastSeenBefore,
+// their end time is the last time the plate was seen
+func (r *ParkingSessionRepository) ExpireStale(ctx context.Context, lastSeenBefore int64) (int64, error) {
+	result := r.DB.DB.WithContext(ctx).Exec(`UPDATE parking_sessions SET status = ?, end_time = last_seen_time,
+		updated_at = (extract(epoch from now()) * 1000)::bigint
+		WHERE status = ? AND deleted_at = 0 AND last_seen_time < ?`,
+		entity.ParkingSessionExpired, entity.ParkingSessionOpen, lastSeenBefore)
+	if result.Error != nil {
+		return 0, fmt.Errorf("failed to expire parking sessions: %w", result.Error)
+	}
+	return result.RowsAffected, nil
+}
+
+// List parking sessions with filtering and pagination, newest first.
+// from and to filter on the session start time when they are not zero.
+func (r *ParkingSessionRepository) List(
+	ctx context.Context,
+	filters map[string]interface{},
+	from,
+	to int64,
+	page,
+	pageSize int,
+) ([]entity.ParkingSession, int64, error) {
+	var sessions []entity.ParkingSession
+	var total int64
+
+	query := r.DB.DB.WithContext(ctx).Model(&entity.ParkingSession{})
+
+	// Apply filters
+	for key, value := range filters {
+		query = query.Where(fmt.Sprintf("%s = ?", key), value)
+	}
+	if from > 0 {
+		query = query.Where("start_time >= ?", from)
+	}
+	if to > 0 {
+		query = query.Where("start_time < ?", to)
+	}
+
+	// Count total sessions
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to count parking sessions: %w", err)
+	}
+
+	// Apply pagination
+	if page > 0 && pageSize > 0 {
+		query = query.Offset((page - 1) * pageSize).Limit(pageSize)
+	}
+
+	if err := query.Order("start_time desc").Find(&sessions).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to fetch parking sessions: %w", err)
+	}
+
+	return sessions, total, nil
+}
+
+// GetByID gets a parking session by ID
+func (r *ParkingSessionRepository) GetByID(ctx context.Context, id string) (*entity.ParkingSession, error) {
+	var session entity.ParkingSession
+
+	if err := r.DB.DB.WithContext(ctx).Where("id = ?", id).First(&session).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrParkingSessionNotFound
+		}
+		return nil, fmt.Errorf("failed to retrieve parking session: %w", err)
+	}
+
+	return &session, nil
+}
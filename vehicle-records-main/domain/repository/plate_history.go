@@ -0,0 +1,103 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"git.abanppc.com/farin-project/vehicle-records/domain/entity"
+	"gorm.io/gorm"
+)
+
+// PlateHistoryFilter selects the sightings of a set of plates by their
+// numeric encoding, records stored in [From, To) and in the given ring,
+// segment and street. Zero values do not filter.
+type PlateHistoryFilter struct {
+	Numerics  []int
+	From      int64
+	To        int64
+	RingID    int64
+	SegmentID int64
+	StreetID  int64
+}
+
+// PlateHistoryCursor is the last sighting of a page, the next page starts
+// after it
+type PlateHistoryCursor struct {
+	StoreTime int64
+	RecordID  string
+}
+
+type PlateDayCount struct {
+	Day   string `json:"day"`
+	Count int64  `json:"count"`
+}
+
+type PlateSegmentCount struct {
+	SegmentID int64 `json:"segmentId"`
+	Count     int64 `json:"count"`
+}
+
+func (r *VehicleRecordRepository) plateHistory(ctx context.Context, f PlateHistoryFilter) *gorm.DB {
+	query := r.DB.DB.WithContext(ctx).Model(&entity.VehicleRecord{}).
+		Where("citizen_plate_number_numeric IN ? AND duplicate_of IS NULL", f.Numerics)
+	if f.From > 0 {
+		query = query.Where("record_store_time >= ?", f.From)
+	}
+	if f.To > 0 {
+		query = query.Where("record_store_time < ?", f.To)
+	}
+	if f.RingID > 0 {
+		query = query.Where("ring_id = ?", f.RingID)
+	}
+	if f.SegmentID > 0 {
+		query = query.Where("segment_id = ?", f.SegmentID)
+	}
+	if f.StreetID > 0 {
+		query = query.Where("street_id = ?", f.StreetID)
+	}
+	return query
+}
+
+// PlateHistory lists up to limit sightings newest first, after the cursor
+// when one is given. Merged duplicates are left out, their survivor counts
+// them.
+func (r *VehicleRecordRepository) PlateHistory(ctx context.Context, f PlateHistoryFilter,
+	after *PlateHistoryCursor, limit int) ([]entity.VehicleRecord, error) {
+	var records []entity.VehicleRecord
+
+	query := r.plateHistory(ctx, f)
+	if after != nil {
+		query = query.Where("(record_store_time, record_id) < (?, ?)", after.StoreTime, after.RecordID)
+	}
+	err := query.Order("record_store_time DESC, record_id DESC").Limit(limit).Find(&records).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch plate history: %w", err)
+	}
+	return records, nil
+}
+
+// PlateHistoryPerDay counts the sightings of every day in Tehran time
+func (r *VehicleRecordRepository) PlateHistoryPerDay(ctx context.Context, f PlateHistoryFilter) (
+	[]PlateDayCount, error) {
+	var counts []PlateDayCount
+	day := `to_char(to_timestamp(record_store_time / 1000.0) AT TIME ZONE 'Asia/Tehran', 'YYYY-MM-DD')`
+	err := r.plateHistory(ctx, f).Select(day + " AS day, count(*) AS count").
+		Group("day").Order("day").Scan(&counts).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to count plate history per day: %w", err)
+	}
+	return counts, nil
+}
+
+// PlateHistoryPerSegment counts the sightings of every segment, sightings
+// outside segments count under segment zero
+func (r *VehicleRecordRepository) PlateHistoryPerSegment(ctx context.Context, f PlateHistoryFilter) (
+	[]PlateSegmentCount, error) {
+	var counts []PlateSegmentCount
+	err := r.plateHistory(ctx, f).Select("coalesce(segment_id, 0) AS segment_id, count(*) AS count").
+		Group("coalesce(segment_id, 0)").Order("count DESC, segment_id").Scan(&counts).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to count plate history per segment: %w", err)
+	}
+	return counts, nil
+}
@@ -35,12 +35,242 @@ func (r *VehicleRecordRepository) Create(ctx context.Context, record *entity.Veh
 	}
 	return record, nil
 }
+
+// CreateWithOutbox creates a record without its photos and the outbox entry
+// that delivers it in one transaction
+func (r *VehicleRecordRepository) CreateWithOutbox(ctx context.Context, record *entity.VehicleRecord,
+	outbox *entity.RecordOutbox) (*entity.VehicleRecord, error) {
+	err := r.DB.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var c int64
+		if err := tx.Model(record).Where("record_id = ?", record.RecordID).Count(&c).Error; err != nil {
+			return fmt.Errorf("failed to check existing record: %w", err)
+		}
+		if c > 0 {
+			return ErrDuplicateRecord
+		}
+		if err := tx.Omit("VehiclePhotos").Create(record).Error; err != nil {
+			return fmt.Errorf("failed to create vehicle record: %w", err)
+		}
+		if err := tx.Create(outbox).Error; err != nil {
+			return fmt.Errorf("failed to create record outbox: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return record, nil
+}
+
+// DedupOutcome tells what CreateWithDedup did with a record
+type DedupOutcome int
+
+const (
+	// DedupNone means the record is the first sighting and is delivered
+	DedupNone DedupOutcome = iota
+	// DedupSuppressed means the record is linked to an earlier sighting that
+	// keeps its own photos
+	DedupSuppressed
+	// DedupPhotosReplaced means the record is linked to an earlier sighting
+	// that takes over its better photos
+	DedupPhotosReplaced
+)
+
+func (o DedupOutcome) String() string {
+	switch o {
+	case DedupSuppressed:
+		return "suppressed"
+	case DedupPhotosReplaced:
+		return "photos_replaced"
+	}
+	return "none"
+}
+
+// DedupWindow is how close two sightings of a plate have to be to be merged,
+// Window in milliseconds of record store time and Distance in meters. A zero
+// Window turns deduplication off.
+type DedupWindow struct {
+	Window   int64
+	Distance float64
+}
+
+// dedupLockSpace keeps the advisory locks of plates apart from other locks
+const dedupLockSpace = 9
+
+// CreateWithDedup creates a record like CreateWithOutbox unless a sighting of
+// the same plate, stored at most the window before it and within the distance
+// of it, was created before. The record is then kept without an outbox entry and linked to that
+// survivor. When the record has a better OCR accuracy and the photos of the
+// survivor are not uploaded yet, the survivor takes over the record photos.
+// Sightings of a plate are serialized with an advisory lock so concurrent
+// cameras do not both become survivors. A review, when given, is created
+// with the outbox entry of a first sighting.
+func (r *VehicleRecordRepository) CreateWithDedup(ctx context.Context, record *entity.VehicleRecord,
+	outbox *entity.RecordOutbox, review *entity.RecordReview, w DedupWindow) (*entity.VehicleRecord, DedupOutcome,
+	error) {
+	outcome := DedupNone
+	err := r.DB.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var c int64
+		if err := tx.Model(record).Where("record_id = ?", record.RecordID).Count(&c).Error; err != nil {
+			return fmt.Errorf("failed to check existing record: %w", err)
+		}
+		if c > 0 {
+			return ErrDuplicateRecord
+		}
+
+		var survivor *entity.VehicleRecord
+		if w.Window > 0 {
+			if err := tx.Exec("SELECT pg_advisory_xact_lock(?, ?)", dedupLockSpace,
+				record.CitizenPlateNumberNumeric).Error; err != nil {
+				return fmt.Errorf("failed to lock plate: %w", err)
+			}
+			var err error
+			survivor, err = findSurvivor(tx, record, w)
+			if err != nil {
+				return err
+			}
+		}
+
+		if survivor == nil {
+			if err := tx.Omit("VehiclePhotos").Create(record).Error; err != nil {
+				return fmt.Errorf("failed to create vehicle record: %w", err)
+			}
+			if err := tx.Create(outbox).Error; err != nil {
+				return fmt.Errorf("failed to create record outbox: %w", err)
+			}
+			if review != nil {
+				if err := tx.Create(review).Error; err != nil {
+					return fmt.Errorf("failed to create record review: %w", err)
+				}
+			}
+			return nil
+		}
+
+		record.DuplicateOf = &survivor.RecordID
+		if err := tx.Omit("VehiclePhotos").Create(record).Error; err != nil {
+			return fmt.Errorf("failed to create vehicle record: %w", err)
+		}
+		if err := tx.Exec("UPDATE vehicle_records SET duplicates = duplicates + 1 WHERE record_id = ?",
+			survivor.RecordID).Error; err != nil {
+			return fmt.Errorf("failed to update vehicle record: %w", err)
+		}
+		outcome = DedupSuppressed
+		if record.OCRAccuracy <= survivor.OCRAccuracy {
+			return nil
+		}
+
+		// the photos can only be swapped while no dispatcher works on the entry
+		result := tx.Exec(`UPDATE record_outbox SET photos = ?,
+			updated_at = (extract(epoch from now()) * 1000)::bigint
+			WHERE record_id = ? AND status = ? AND locked_until <= (extract(epoch from now()) * 1000)::bigint`,
+			outbox.Photos, survivor.RecordID, entity.RecordOutboxPendingUpload)
+		if result.Error != nil {
+			return fmt.Errorf("failed to update record outbox: %w", result.Error)
+		}
+		if result.RowsAffected == 0 {
+			return nil
+		}
+		if err := tx.Exec("UPDATE vehicle_records SET ocr_accuracy = ? WHERE record_id = ?",
+			record.OCRAccuracy, survivor.RecordID).Error; err != nil {
+			return fmt.Errorf("failed to update vehicle record: %w", err)
+		}
+		outcome = DedupPhotosReplaced
+		return nil
+	})
+	if err != nil {
+		return nil, DedupNone, err
+	}
+	return record, outcome, nil
+}
+
+// findSurvivor finds the earlier sighting of the record plate closest in time
+// within the window. Without a valid GPS fix only sightings of the same LPR
+// vehicle are considered.
+func findSurvivor(tx *gorm.DB, record *entity.VehicleRecord, w DedupWindow) (*entity.VehicleRecord, error) {
+	var survivors []entity.VehicleRecord
+	if err := survivorQuery(tx, record, w).Find(&survivors).Error; err != nil {
+		return nil, fmt.Errorf("failed to find earlier sighting: %w", err)
+	}
+	if len(survivors) == 0 {
+		return nil, nil
+	}
+	return &survivors[0], nil
+}
+
+// survivorQuery selects the latest sighting of the record plate stored at
+// most the window before the record. Later sightings are never survivors, so
+// a late arriving record does not merge into one taken after it.
+func survivorQuery(tx *gorm.DB, record *entity.VehicleRecord, w DedupWindow) *gorm.DB {
+	query := tx.Model(&entity.VehicleRecord{}).
+		Where("citizen_plate_number_numeric = ? AND duplicate_of IS NULL", record.CitizenPlateNumberNumeric).
+		Where("record_store_time BETWEEN ? AND ?", record.RecordStoreTime-w.Window, record.RecordStoreTime)
+	switch {
+	case !record.LPRVehicleIsGPSSignalValid:
+		query = query.Where("lpr_vehicle_id = ?", record.LPRVehicleID)
+	case w.Distance > 0:
+		query = query.Where(`lpr_vehicle_is_gps_signal_valid AND ST_DWithin(
+			ST_MakePoint(lpr_vehicle_gps_longitude, lpr_vehicle_gps_latitude)::geography,
+			ST_MakePoint(?, ?)::geography, ?)`,
+			record.LPRVehicleGPSLongitude, record.LPRVehicleGPSLatitude, w.Distance)
+	}
+	return query.Order("record_store_time DESC").Limit(1)
+}
+
+// DedupStats counts the records created in a time range. Suppressed records
+// were merged into one of the Survivors.
+type DedupStats struct {
+	Records    int64 `json:"records"`
+	Suppressed int64 `json:"suppressed"`
+	Survivors  int64 `json:"survivors"`
+}
+
+// DedupStats counts the records created in [from, to), a zero bound is open
+func (r *VehicleRecordRepository) DedupStats(ctx context.Context, from, to int64) (*DedupStats, error) {
+	stats := &DedupStats{}
+
+	query := r.DB.DB.WithContext(ctx).Model(&entity.VehicleRecord{}).
+		Select(`count(*) AS records,
+			count(*) FILTER (WHERE duplicate_of IS NOT NULL) AS suppressed,
+			count(*) FILTER (WHERE duplicates > 0) AS survivors`)
+	if from > 0 {
+		query = query.Where("created_at >= ?", from)
+	}
+	if to > 0 {
+		query = query.Where("created_at < ?", to)
+	}
+	if err := query.Scan(stats).Error; err != nil {
+		return nil, fmt.Errorf("failed to count vehicle records: %w", err)
+	}
+	return stats, nil
+}
+
+// ListDuplicates lists the sightings merged into a record, by store time
+func (r *VehicleRecordRepository) ListDuplicates(ctx context.Context, recordID string, page, pageSize int) (
+	[]entity.VehicleRecord, int64, error) {
+	var records []entity.VehicleRecord
+	var total int64
+
+	query := r.DB.DB.WithContext(ctx).Model(&entity.VehicleRecord{}).Where("duplicate_of = ?", recordID)
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to count vehicle records: %w", err)
+	}
+	if page > 0 && pageSize > 0 {
+		query = query.Offset((page - 1) * pageSize).Limit(pageSize)
+	}
+	if err := query.Order("record_store_time").Find(&records).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to fetch vehicle records: %w", err)
+	}
+	return records, total, nil
+}
+
 func (r *VehicleRecordRepository) GetNotSent(ctx context.Context, createAt int64, limit int) (
 	[]entity.VehicleRecord, error) {
 	var records []entity.VehicleRecord
 
 	err := r.DB.DB.WithContext(ctx).Model(&entity.VehicleRecord{}).Preload("VehiclePhotos").
-		Where("created_at > ?", createAt).Where("plate_detection_id=?", 0).Limit(limit).
+		Where("created_at > ?", createAt).Where("plate_detection_id=?", 0).Where("duplicate_of IS NULL").
+		Where(`NOT EXISTS (SELECT 1 FROM record_reviews rv WHERE rv.record_id = vehicle_records.record_id
+			AND rv.status != ?)`, entity.RecordReviewApproved).Limit(limit).
 		Find(&records).Error
 	if err != nil {
 		return nil, fmt.Errorf("failed to get records: %w", err)
@@ -49,6 +279,33 @@ func (r *VehicleRecordRepository) GetNotSent(ctx context.Context, createAt int64
 	return records, nil
 }
 
+// GetNotSentPage gets up to limit records that were not accepted by their
+// municipality, created in [from, to) after the (afterCreatedAt, afterID)
+// cursor, ordered by creation. Records the outbox is still delivering, held
+// for review, rejected or already delivered, records that failed before their
+// photos were stored, and suppressed duplicates are left out.
+func (r *VehicleRecordRepository) GetNotSentPage(ctx context.Context, from, to, afterCreatedAt int64, afterID string,
+	limit int) ([]entity.VehicleRecord, error) {
+	var records []entity.VehicleRecord
+
+	query := r.DB.DB.WithContext(ctx).Model(&entity.VehicleRecord{}).Preload("VehiclePhotos").
+		Where("sent = ? AND duplicate_of IS NULL", false).
+		Where("created_at >= ? AND created_at < ?", from, to).
+		Where(`NOT EXISTS (SELECT 1 FROM record_outbox o WHERE o.record_id = vehicle_records.record_id
+			AND (o.status IN (?, ?, ?, ?, ?) OR (o.status = ? AND o.photos IS NOT NULL)))`,
+			entity.RecordOutboxPendingUpload, entity.RecordOutboxPendingSend, entity.RecordOutboxPendingReview,
+			entity.RecordOutboxRejected, entity.RecordOutboxSent, entity.RecordOutboxFailed)
+	if afterID != "" {
+		query = query.Where("(created_at, record_id) > (?, ?)", afterCreatedAt, afterID)
+	}
+	err := query.Order("created_at, record_id").Limit(limit).Find(&records).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to get records: %w", err)
+	}
+
+	return records, nil
+}
+
 // List vehicle records with filtering, sorting, and pagination
 func (r *VehicleRecordRepository) List(
 	ctx context.Context,
@@ -91,9 +348,11 @@ func (r *VehicleRecordRepository) List(
 	return records, total, nil
 }
 
-// Update an existing vehicle record
+// Update an existing vehicle record. Dedup links and legal holds are changed
+// by their own methods and left alone, a stale record must not undo them.
 func (r *VehicleRecordRepository) Update(ctx context.Context, record *entity.VehicleRecord) (*entity.VehicleRecord, error) {
-	if err := r.DB.DB.WithContext(ctx).Omit("VehiclePhotos", "Retries").Updates(record).Error; err != nil {
+	if err := r.DB.DB.WithContext(ctx).Omit("VehiclePhotos", "Retries", "DuplicateOf", "Duplicates",
+		"LegalHold", "LegalHoldAt", "LegalHoldReason", "LegalHoldBy").Updates(record).Error; err != nil {
 		return nil, fmt.Errorf("failed to update vehicle record: %w", err)
 	}
 	return record, nil
@@ -107,6 +366,29 @@ func (r *VehicleRecordRepository) IncreaseRetry(ctx context.Context, record *ent
 	return record, nil
 }
 
+// SetLegalHold puts the photos of a record on legal hold, or releases them
+// when hold is false
+func (r *VehicleRecordRepository) SetLegalHold(ctx context.Context, id string, hold bool, reason, by string,
+	at int64) error {
+	values := map[string]interface{}{
+		"legal_hold":        hold,
+		"legal_hold_at":     at,
+		"legal_hold_reason": reason,
+		"legal_hold_by":     by,
+	}
+	if !hold {
+		values["legal_hold_at"] = 0
+	}
+	result := r.DB.DB.WithContext(ctx).Model(&entity.VehicleRecord{}).Where("record_id = ?", id).Updates(values)
+	if result.Error != nil {
+		return fmt.Errorf("failed to update legal hold: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrVehicleRecordNotFound
+	}
+	return nil
+}
+
 // Delete a vehicle record by ID
 func (r *VehicleRecordRepository) Delete(ctx context.Context, id string) error {
 	result := r.DB.DB.WithContext(ctx).Delete(&entity.VehicleRecord{}, id)
@@ -0,0 +1,142 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"git.abanppc.com/farin-project/vehicle-records/domain/entity"
+	gormdb "git.abanppc.com/farin-project/vehicle-records/infrastructure/gorm"
+	"gorm.io/gorm"
+)
+
+// ErrRecordOutboxConflict is returned when an outbox entry was moved by someone else
+var ErrRecordOutboxConflict = errors.New("record outbox entry is not in the expected status")
+
+type RecordOutboxRepository struct {
+	DB *gormdb.GORMDB
+}
+
+func NewRecordOutboxRepository(db *gormdb.GORMDB) *RecordOutboxRepository {
+	return &RecordOutboxRepository{DB: db}
+}
+
+// Enqueue adds an outbox entry. An entry the record already has is reset to
+// the status of outbox with no attempts when it failed or waits to be sent,
+// sent entries and entries waiting for their upload or review are left alone.
+// Nothing is enqueued for a suppressed duplicate, its sighting is forwarded as
+// the record it was merged into.
+func (r *RecordOutboxRepository) Enqueue(ctx context.Context, outbox *entity.RecordOutbox) error {
+	if err := enqueueQuery(r.DB.DB.WithContext(ctx), outbox).Error; err != nil {
+		return fmt.Errorf("failed to enqueue record outbox: %w", err)
+	}
+	return nil
+}
+
+// enqueueQuery inserts the outbox entry of a record that is not a duplicate,
+// resetting an entry that failed or waits to be sent
+func enqueueQuery(db *gorm.DB, outbox *entity.RecordOutbox) *gorm.DB {
+	return db.Exec(`INSERT INTO record_outbox
+		(record_id, status, photos, attempts, next_attempt_at, held, created_at, updated_at)
+		SELECT record_id, ?, ?, 0, ?, ?, (extract(epoch from now()) * 1000)::bigint,
+			(extract(epoch from now()) * 1000)::bigint
+		FROM vehicle_records WHERE record_id = ? AND duplicate_of IS NULL
+		ON CONFLICT (record_id) DO UPDATE SET status = EXCLUDED.status, attempts = 0,
+			next_attempt_at = EXCLUDED.next_attempt_at, last_error = '', updated_at = EXCLUDED.updated_at
+		WHERE record_outbox.status IN (?, ?)`,
+		outbox.Status, outbox.Photos, outbox.NextAttemptAt, outbox.Held, outbox.RecordID,
+		entity.RecordOutboxFailed, entity.RecordOutboxPendingSend)
+}
+
+// Claim locks up to limit due entries until lockedUntil, entries locked by
+// another dispatcher are skipped
+func (r *RecordOutboxRepository) Claim(ctx context.Context, now, lockedUntil int64, limit int) (
+	[]entity.RecordOutbox, error) {
+	var entries []entity.RecordOutbox
+
+	err := r.DB.DB.WithContext(ctx).Raw(`UPDATE record_outbox SET locked_until = ?,
+		updated_at = (extract(epoch from now()) * 1000)::bigint
+		WHERE id IN (
+			SELECT id FROM record_outbox
+			WHERE status IN (?, ?) AND next_attempt_at <= ? AND locked_until <= ? AND deleted_at = 0
+			ORDER BY next_attempt_at
+			LIMIT ? FOR UPDATE SKIP LOCKED
+		) RETURNING *`,
+		lockedUntil,
+		entity.RecordOutboxPendingUpload, entity.RecordOutboxPendingSend, now, now,
+		limit).Scan(&entries).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim record outbox: %w", err)
+	}
+	return entries, nil
+}
+
+// Renew extends the lease of a claimed entry to lockedUntil, as long as the
+// lease is still heldUntil. It fails with ErrRecordOutboxConflict when the
+// lease ran out and another dispatcher claimed the entry meanwhile.
+func (r *RecordOutboxRepository) Renew(ctx context.Context, id string, heldUntil, lockedUntil int64) error {
+	result := r.DB.DB.WithContext(ctx).Model(&entity.RecordOutbox{}).
+		Where("id = ? AND locked_until = ?", id, heldUntil).
+		Update("locked_until", lockedUntil)
+	if result.Error != nil {
+		return fmt.Errorf("failed to renew record outbox: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrRecordOutboxConflict
+	}
+	return nil
+}
+
+// Transition moves an entry from one status to another and applies updates,
+// it fails with ErrRecordOutboxConflict when the entry is no longer in from
+func (r *RecordOutboxRepository) Transition(ctx context.Context, id string, from, to entity.RecordOutboxStatus,
+	updates map[string]interface{}) error {
+	values := map[string]interface{}{"status": to}
+	for k, v := range updates {
+		values[k] = v
+	}
+
+	result := r.DB.DB.WithContext(ctx).Model(&entity.RecordOutbox{}).
+		Where("id = ? AND status = ?", id, from).
+		Updates(values)
+	if result.Error != nil {
+		return fmt.Errorf("failed to update record outbox: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrRecordOutboxConflict
+	}
+	return nil
+}
+
+// Uploaded moves an entry whose photos were stored to pending_send, or to
+// pending_review while it is held. It returns whether the entry is held and
+// fails with ErrRecordOutboxConflict when the entry is no longer uploading.
+func (r *RecordOutboxRepository) Uploaded(ctx context.Context, id string) (bool, error) {
+	var held []bool
+	err := r.DB.DB.WithContext(ctx).Raw(`UPDATE record_outbox SET photos = NULL, attempts = 0,
+		status = CASE WHEN held THEN ? ELSE ? END,
+		locked_until = CASE WHEN held THEN 0 ELSE locked_until END,
+		updated_at = (extract(epoch from now()) * 1000)::bigint
+		WHERE id = ? AND status = ? RETURNING held`,
+		entity.RecordOutboxPendingReview, entity.RecordOutboxPendingSend,
+		id, entity.RecordOutboxPendingUpload).Scan(&held).Error
+	if err != nil {
+		return false, fmt.Errorf("failed to update record outbox: %w", err)
+	}
+	if len(held) == 0 {
+		return false, ErrRecordOutboxConflict
+	}
+	return held[0], nil
+}
+
+// ResolveFailed marks the failed entry of a record as sent, once the record was
+// delivered some other way
+func (r *RecordOutboxRepository) ResolveFailed(ctx context.Context, recordID string) error {
+	err := r.DB.DB.WithContext(ctx).Model(&entity.RecordOutbox{}).
+		Where("record_id = ? AND status = ?", recordID, entity.RecordOutboxFailed).
+		Updates(map[string]interface{}{"status": entity.RecordOutboxSent, "last_error": ""}).Error
+	if err != nil {
+		return fmt.Errorf("failed to update record outbox: %w", err)
+	}
+	return nil
+}
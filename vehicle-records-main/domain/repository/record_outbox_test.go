@@ -0,0 +1,35 @@
+package repository
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"git.abanppc.com/farin-project/vehicle-records/domain/entity"
+)
+
+func TestEnqueueQuerySkipsDuplicates(t *testing.T) {
+	outbox := entity.RecordOutbox{
+		RecordID:      "record-1",
+		Status:        entity.RecordOutboxPendingSend,
+		NextAttemptAt: 1_000_000,
+	}
+	stmt := enqueueQuery(dryRunDB(t), &outbox).Statement
+	sql := stmt.SQL.String()
+
+	// the entry is only selected from a record that was not merged into another
+	for _, s := range []string{"FROM vehicle_records WHERE record_id = $5 AND duplicate_of IS NULL",
+		"WHERE record_outbox.status IN ($6, $7)"} {
+		if !strings.Contains(sql, s) {
+			t.Errorf("query %q does not contain %q", sql, s)
+		}
+	}
+	if strings.Contains(sql, "VALUES") {
+		t.Errorf("query %q inserts values regardless of the record", sql)
+	}
+	want := []interface{}{entity.RecordOutboxPendingSend, []byte(nil), int64(1_000_000), false, "record-1",
+		entity.RecordOutboxFailed, entity.RecordOutboxPendingSend}
+	if !reflect.DeepEqual(stmt.Vars, want) {
+		t.Errorf("vars = %#v, want %#v", stmt.Vars, want)
+	}
+}
@@ -0,0 +1,200 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"git.abanppc.com/farin-project/vehicle-records/domain/entity"
+	gormdb "git.abanppc.com/farin-project/vehicle-records/infrastructure/gorm"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrRecordReviewNotFound = errors.New("record review not found")
+	// ErrRecordReviewDecided is returned when a review was already approved or rejected
+	ErrRecordReviewDecided = errors.New("record review is already decided")
+)
+
+type RecordReviewRepository struct {
+	DB *gormdb.GORMDB
+}
+
+func NewRecordReviewRepository(db *gormdb.GORMDB) *RecordReviewRepository {
+	return &RecordReviewRepository{DB: db}
+}
+
+// List reviews in status with their records and photos, oldest first
+func (r *RecordReviewRepository) List(ctx context.Context, status entity.RecordReviewStatus, page, pageSize int) (
+	[]entity.RecordReview, int64, error) {
+	var reviews []entity.RecordReview
+	var total int64
+
+	query := r.DB.DB.WithContext(ctx).Model(&entity.RecordReview{}).Where("status = ?", status)
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to count record reviews: %w", err)
+	}
+	if page > 0 && pageSize > 0 {
+		query = query.Offset((page - 1) * pageSize).Limit(pageSize)
+	}
+	if err := query.Preload("VehicleRecord.VehiclePhotos").Order("created_at").Find(&reviews).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to fetch record reviews: %w", err)
+	}
+	return reviews, total, nil
+}
+
+// GetByID gets a review with its record, photos and audit trail
+func (r *RecordReviewRepository) GetByID(ctx context.Context, id string) (*entity.RecordReview, error) {
+	var review entity.RecordReview
+
+	err := r.DB.DB.WithContext(ctx).
+		Preload("VehicleRecord.VehiclePhotos").
+		Preload("Audits", func(db *gorm.DB) *gorm.DB { return db.Order("created_at") }).
+		Where("id = ?", id).First(&review).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrRecordReviewNotFound
+		}
+		return nil, fmt.Errorf("failed to retrieve record review: %w", err)
+	}
+	return &review, nil
+}
+
+// Correct replaces the plate of a pending review record and audits the old
+// and new plate
+func (r *RecordReviewRepository) Correct(ctx context.Context, id string, reviewer entity.Reviewer, plate string,
+	plateNumeric int, note string) error {
+	return r.DB.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		review, err := lockPending(tx, id)
+		if err != nil {
+			return err
+		}
+
+		var record entity.VehicleRecord
+		if err := tx.Select("citizen_plate_number").Where("record_id = ?", review.RecordID).
+			First(&record).Error; err != nil {
+			return fmt.Errorf("failed to retrieve vehicle record: %w", err)
+		}
+		if err := tx.Model(&entity.VehicleRecord{}).Where("record_id = ?", review.RecordID).
+			Updates(map[string]interface{}{
+				"citizen_plate_number":         plate,
+				"citizen_plate_number_numeric": plateNumeric,
+			}).Error; err != nil {
+			return fmt.Errorf("failed to update vehicle record: %w", err)
+		}
+
+		return createAudit(tx, &entity.RecordReviewAudit{
+			ReviewID: review.ID,
+			RecordID: review.RecordID,
+			Action:   entity.ReviewActionCorrect,
+			Reviewer: reviewer.Name,
+			ApiKeyID: reviewer.ApiKeyID,
+			OldPlate: record.CitizenPlateNumber,
+			NewPlate: plate,
+			Note:     note,
+		})
+	})
+}
+
+// Approve decides a pending review and releases its outbox entry to the
+// dispatchers, an entry still uploading is sent once the upload is done.
+// check is called with the plate of the record while the review is locked, so
+// no correction happens in between, an error of check is returned as is.
+func (r *RecordReviewRepository) Approve(ctx context.Context, id string, reviewer entity.Reviewer, note string,
+	now int64, check func(plate string) error) error {
+	return r.decide(ctx, id, reviewer, note, now, entity.RecordReviewApproved, func(tx *gorm.DB, recordID string) error {
+		var record entity.VehicleRecord
+		if err := tx.Select("citizen_plate_number").Where("record_id = ?", recordID).
+			First(&record).Error; err != nil {
+			return fmt.Errorf("failed to retrieve vehicle record: %w", err)
+		}
+		return check(record.CitizenPlateNumber)
+	}, func(tx *gorm.DB, recordID string) error {
+		return tx.Exec(`UPDATE record_outbox SET held = false, next_attempt_at = ?,
+			status = CASE WHEN status = ? THEN ? ELSE status END,
+			updated_at = (extract(epoch from now()) * 1000)::bigint
+			WHERE record_id = ? AND status IN (?, ?)`,
+			now, entity.RecordOutboxPendingReview, entity.RecordOutboxPendingSend,
+			recordID, entity.RecordOutboxPendingUpload, entity.RecordOutboxPendingReview).Error
+	})
+}
+
+// Reject decides a pending review, the record is never sent
+func (r *RecordReviewRepository) Reject(ctx context.Context, id string, reviewer entity.Reviewer, note string,
+	now int64) error {
+	return r.decide(ctx, id, reviewer, note, now, entity.RecordReviewRejected, nil, func(tx *gorm.DB,
+		recordID string) error {
+		return tx.Exec(`UPDATE record_outbox SET held = false, status = ?,
+			updated_at = (extract(epoch from now()) * 1000)::bigint
+			WHERE record_id = ? AND status IN (?, ?)`,
+			entity.RecordOutboxRejected,
+			recordID, entity.RecordOutboxPendingUpload, entity.RecordOutboxPendingReview).Error
+	})
+}
+
+// decide sets the status of a pending review, check, when given, may refuse the
+// decision after the review is locked
+func (r *RecordReviewRepository) decide(ctx context.Context, id string, reviewer entity.Reviewer, note string,
+	now int64, status entity.RecordReviewStatus, check, release func(tx *gorm.DB, recordID string) error) error {
+	return r.DB.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		review, err := lockPending(tx, id)
+		if err != nil {
+			return err
+		}
+		if check != nil {
+			if err := check(tx, review.RecordID); err != nil {
+				return err
+			}
+		}
+
+		if err := tx.Model(&entity.RecordReview{}).Where("id = ?", review.ID).
+			Updates(map[string]interface{}{
+				"status":      status,
+				"reviewer":    reviewer.Name,
+				"reviewed_at": now,
+				"note":        note,
+			}).Error; err != nil {
+			return fmt.Errorf("failed to update record review: %w", err)
+		}
+		if err := release(tx, review.RecordID); err != nil {
+			return fmt.Errorf("failed to update record outbox: %w", err)
+		}
+
+		action := entity.ReviewActionApprove
+		if status == entity.RecordReviewRejected {
+			action = entity.ReviewActionReject
+		}
+		return createAudit(tx, &entity.RecordReviewAudit{
+			ReviewID: review.ID,
+			RecordID: review.RecordID,
+			Action:   action,
+			Reviewer: reviewer.Name,
+			ApiKeyID: reviewer.ApiKeyID,
+			Note:     note,
+		})
+	})
+}
+
+// lockPending locks a review for the rest of the transaction, it fails with
+// ErrRecordReviewDecided when the review is no longer pending
+func lockPending(tx *gorm.DB, id string) (*entity.RecordReview, error) {
+	var review entity.RecordReview
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", id).First(&review).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrRecordReviewNotFound
+		}
+		return nil, fmt.Errorf("failed to retrieve record review: %w", err)
+	}
+	if review.Status != entity.RecordReviewPending {
+		return nil, ErrRecordReviewDecided
+	}
+	return &review, nil
+}
+
+func createAudit(tx *gorm.DB, audit *entity.RecordReviewAudit) error {
+	if err := tx.Create(audit).Error; err != nil {
+		return fmt.Errorf("failed to create record review audit: %w", err)
+	}
+	return nil
+}
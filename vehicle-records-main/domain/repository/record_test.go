@@ -0,0 +1,89 @@
+package repository
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"git.abanppc.com/farin-project/vehicle-records/domain/entity"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+// dryRunDB builds statements without a database
+func dryRunDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(postgres.New(postgres.Config{DSN: "host=127.0.0.1"}), &gorm.Config{
+		DryRun: true, SkipDefaultTransaction: true, DisableAutomaticPing: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func TestSurvivorQuery(t *testing.T) {
+	record := entity.VehicleRecord{
+		CitizenPlateNumberNumeric:  12345678,
+		RecordStoreTime:            1_000_000,
+		LPRVehicleID:               "lpr-1",
+		LPRVehicleIsGPSSignalValid: true,
+		LPRVehicleGPSLatitude:      35.7,
+		LPRVehicleGPSLongitude:     51.4,
+	}
+	noFix := record
+	noFix.LPRVehicleIsGPSSignalValid = false
+
+	tests := []struct {
+		name     string
+		record   entity.VehicleRecord
+		w        DedupWindow
+		want     []string
+		notWant  []string
+		wantVars []interface{}
+	}{
+		{
+			name:     "earlier sightings nearby",
+			record:   record,
+			w:        DedupWindow{Window: 30_000, Distance: 50},
+			want:     []string{"record_store_time BETWEEN $2 AND $3", "ST_DWithin", "ORDER BY record_store_time DESC"},
+			notWant:  []string{"lpr_vehicle_id"},
+			wantVars: []interface{}{12345678, int64(970_000), int64(1_000_000), 51.4, 35.7, 50.0},
+		},
+		{
+			name:     "without distance",
+			record:   record,
+			w:        DedupWindow{Window: 30_000},
+			notWant:  []string{"lpr_vehicle_id", "ST_DWithin"},
+			wantVars: []interface{}{12345678, int64(970_000), int64(1_000_000)},
+		},
+		{
+			name:     "without gps fix the same lpr vehicle",
+			record:   noFix,
+			w:        DedupWindow{Window: 30_000, Distance: 50},
+			want:     []string{"lpr_vehicle_id = $4"},
+			notWant:  []string{"ST_DWithin"},
+			wantVars: []interface{}{12345678, int64(970_000), int64(1_000_000), "lpr-1"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var survivors []entity.VehicleRecord
+			stmt := survivorQuery(dryRunDB(t), &tt.record, tt.w).Find(&survivors).Statement
+			sql := stmt.SQL.String()
+			for _, s := range tt.want {
+				if !strings.Contains(sql, s) {
+					t.Errorf("query %q does not contain %q", sql, s)
+				}
+			}
+			for _, s := range tt.notWant {
+				if strings.Contains(sql, s) {
+					t.Errorf("query %q contains %q", sql, s)
+				}
+			}
+			// the soft delete filter and the limit come last
+			if vars := stmt.Vars[:len(stmt.Vars)-2]; !reflect.DeepEqual(vars, tt.wantVars) {
+				t.Errorf("vars = %#v, want %#v", vars, tt.wantVars)
+			}
+		})
+	}
+}
@@ -0,0 +1,63 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"git.abanppc.com/farin-project/vehicle-records/domain/entity"
+	gormdb "git.abanppc.com/farin-project/vehicle-records/infrastructure/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrResendCheckpointLocked is returned when another scheduler holds the checkpoint
+var ErrResendCheckpointLocked = errors.New("resend checkpoint is locked")
+
+type ResendCheckpointRepository struct {
+	DB *gormdb.GORMDB
+}
+
+func NewResendCheckpointRepository(db *gormdb.GORMDB) *ResendCheckpointRepository {
+	return &ResendCheckpointRepository{DB: db}
+}
+
+// Acquire locks the checkpoint until lockedUntil and returns it, the
+// checkpoint is created on first use. It fails with ErrResendCheckpointLocked
+// while another scheduler holds it.
+func (r *ResendCheckpointRepository) Acquire(ctx context.Context, name string, now, lockedUntil int64) (
+	*entity.ResendCheckpoint, error) {
+	db := r.DB.DB.WithContext(ctx)
+	err := db.Clauses(clause.OnConflict{DoNothing: true}).Create(&entity.ResendCheckpoint{Name: name}).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to create resend checkpoint: %w", err)
+	}
+
+	var checkpoints []entity.ResendCheckpoint
+	err = db.Raw(`UPDATE resend_checkpoints SET locked_until = ?,
+		updated_at = (extract(epoch from now()) * 1000)::bigint
+		WHERE name = ? AND locked_until <= ? RETURNING *`, lockedUntil, name, now).
+		Scan(&checkpoints).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock resend checkpoint: %w", err)
+	}
+	if len(checkpoints) == 0 {
+		return nil, ErrResendCheckpointLocked
+	}
+	return &checkpoints[0], nil
+}
+
+// Save stores the progress of a checkpoint and its lease, as long as the lease
+// is still heldUntil. It fails with ErrResendCheckpointLocked when another
+// scheduler took the checkpoint over meanwhile.
+func (r *ResendCheckpointRepository) Save(ctx context.Context, checkpoint *entity.ResendCheckpoint,
+	heldUntil int64) error {
+	result := r.DB.DB.WithContext(ctx).Model(checkpoint).Where("locked_until = ?", heldUntil).
+		Select("*").Updates(checkpoint)
+	if result.Error != nil {
+		return fmt.Errorf("failed to save resend checkpoint: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrResendCheckpointLocked
+	}
+	return nil
+}
@@ -0,0 +1,207 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"git.abanppc.com/farin-project/vehicle-records/domain/entity"
+	gormdb "git.abanppc.com/farin-project/vehicle-records/infrastructure/gorm"
+	"gorm.io/gorm"
+)
+
+var ErrRetentionPolicyNotFound = errors.New("retention policy not found")
+
+type RetentionRepository struct {
+	DB *gormdb.GORMDB
+}
+
+func NewRetentionRepository(db *gormdb.GORMDB) *RetentionRepository {
+	return &RetentionRepository{DB: db}
+}
+
+// ListPolicies lists the retention policy of every record status
+func (r *RetentionRepository) ListPolicies(ctx context.Context) ([]entity.RetentionPolicy, error) {
+	var policies []entity.RetentionPolicy
+	if err := r.DB.DB.WithContext(ctx).Order("status").Find(&policies).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch retention policies: %w", err)
+	}
+	return policies, nil
+}
+
+// UpdatePolicy changes the retention of a record status
+func (r *RetentionRepository) UpdatePolicy(ctx context.Context, status string, retentionHours int,
+	updatedBy string) error {
+	result := r.DB.DB.WithContext(ctx).Model(&entity.RetentionPolicy{}).Where("status = ?", status).
+		Updates(map[string]interface{}{"retention_hours": retentionHours, "updated_by": updatedBy})
+	if result.Error != nil {
+		return fmt.Errorf("failed to update retention policy: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrRetentionPolicyNotFound
+	}
+	return nil
+}
+
+// expiredPhotos selects photos whose record status has a policy that expired
+// at @now, the status of a record is its legal hold, then its review, then
+// whether it was forwarded. Every record has a status.
+const expiredPhotos = `SELECT p.* FROM citizen_vehicle_photos p
+	JOIN vehicle_records r ON r.record_id = p.record_id
+	LEFT JOIN record_reviews rv ON rv.record_id = r.record_id
+	JOIN photo_retention_policies pol ON pol.status = CASE
+		WHEN r.legal_hold THEN @hold
+		WHEN rv.status = @reviewRejected THEN @rejected
+		WHEN rv.status = @reviewPending THEN @underReview
+		WHEN r.sent THEN @forwarded
+		ELSE @unforwarded
+	END
+	WHERE p.reaped_at = 0 AND pol.retention_hours > 0
+	AND CASE
+		WHEN r.legal_hold THEN r.legal_hold_at
+		WHEN rv.status = @reviewRejected THEN rv.reviewed_at
+		ELSE r.created_at
+	END + pol.retention_hours::bigint * 3600000 <= @now
+	LIMIT @limit
+	FOR UPDATE OF p, r SKIP LOCKED`
+
+// expiredPhotosArgs binds the parameters of expiredPhotos
+func expiredPhotosArgs(now int64, limit int) map[string]interface{} {
+	return map[string]interface{}{
+		"hold":           entity.RetentionEvidenceHold,
+		"rejected":       entity.RetentionRejected,
+		"underReview":    entity.RetentionUnderReview,
+		"forwarded":      entity.RetentionForwarded,
+		"unforwarded":    entity.RetentionUnforwarded,
+		"reviewRejected": entity.RecordReviewRejected,
+		"reviewPending":  entity.RecordReviewPending,
+		"now":            now,
+		"limit":          limit,
+	}
+}
+
+// Reap clears the paths of up to limit photos whose retention expired at now
+// and queues their objects for DeleteReaped. The photos and their records are
+// locked meanwhile, so a legal hold set during the pass waits for it. Objects
+// are never deleted inside the transaction, a failed commit leaves them
+// stored and referenced.
+func (r *RetentionRepository) Reap(ctx context.Context, now int64, limit int) ([]entity.CitizenVehiclePhoto, error) {
+	var photos []entity.CitizenVehiclePhoto
+	err := r.DB.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Raw(expiredPhotos, expiredPhotosArgs(now, limit)).Scan(&photos).Error; err != nil {
+			return fmt.Errorf("failed to fetch expired photos: %w", err)
+		}
+		if len(photos) == 0 {
+			return nil
+		}
+
+		ids := make([]string, len(photos))
+		objects := reapedObjects(photos, now)
+		for i := range photos {
+			ids[i] = photos[i].ID
+		}
+		if err := tx.Exec(`UPDATE citizen_vehicle_photos SET citizen_vehicle_photo = '',
+			citizen_vehicle_plate_crop_photo = '', reaped_at = ? WHERE id IN ?`, now, ids).Error; err != nil {
+			return fmt.Errorf("failed to update reaped photos: %w", err)
+		}
+		if len(objects) > 0 {
+			if err := tx.Create(&objects).Error; err != nil {
+				return fmt.Errorf("failed to queue reaped objects: %w", err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return photos, nil
+}
+
+// reapedObjects lists the stored objects of photos
+func reapedObjects(photos []entity.CitizenVehiclePhoto, now int64) []entity.ReapedObject {
+	var objects []entity.ReapedObject
+	for _, photo := range photos {
+		for _, path := range []string{photo.CitizenVehiclePhoto, photo.CitizenVehiclePlateCropPhoto} {
+			if path != "" {
+				objects = append(objects, entity.ReapedObject{Path: path, ReapedAt: now})
+			}
+		}
+	}
+	return objects
+}
+
+// claimReaped locks up to @limit queued objects that no pass holds at @now
+// until @until
+const claimReaped = `UPDATE reaped_objects SET locked_until = @until, updated_at = @now
+	WHERE id IN (SELECT id FROM reaped_objects WHERE locked_until <= @now AND deleted_at = 0
+		ORDER BY reaped_at LIMIT @limit FOR UPDATE SKIP LOCKED)
+	RETURNING *`
+
+// ClaimReaped returns up to limit queued objects and holds them until until,
+// an object a pass failed to delete is claimed again once its hold expired
+func (r *RetentionRepository) ClaimReaped(ctx context.Context, now, until int64, limit int) (
+	[]entity.ReapedObject, error) {
+	var objects []entity.ReapedObject
+	if err := r.DB.DB.WithContext(ctx).Raw(claimReaped, map[string]interface{}{
+		"now": now, "until": until, "limit": limit,
+	}).Scan(&objects).Error; err != nil {
+		return nil, fmt.Errorf("failed to claim reaped objects: %w", err)
+	}
+	return objects, nil
+}
+
+// DeleteReaped drops objects deleted from object storage from the queue
+func (r *RetentionRepository) DeleteReaped(ctx context.Context, ids []string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	if err := r.DB.DB.WithContext(ctx).Exec("DELETE FROM reaped_objects WHERE id IN ?", ids).Error; err != nil {
+		return fmt.Errorf("failed to delete reaped objects: %w", err)
+	}
+	return nil
+}
+
+// CreateRun adds a pass of the reaper to the report
+func (r *RetentionRepository) CreateRun(ctx context.Context, run *entity.RetentionRun) error {
+	if err := r.DB.DB.WithContext(ctx).Create(run).Error; err != nil {
+		return fmt.Errorf("failed to create retention run: %w", err)
+	}
+	return nil
+}
+
+// RetentionReport sums the reaper passes started in a time range
+type RetentionReport struct {
+	Runs    int64 `json:"runs"`
+	Photos  int64 `json:"photos"`
+	Objects int64 `json:"objects"`
+	Bytes   int64 `json:"bytes"`
+	Errors  int64 `json:"errors"`
+}
+
+// Report sums the reaper passes started in [from, to) and lists them newest
+// first, a zero bound is open
+func (r *RetentionRepository) Report(ctx context.Context, from, to int64, page, pageSize int) (
+	*RetentionReport, []entity.RetentionRun, error) {
+	report := &RetentionReport{}
+	var runs []entity.RetentionRun
+
+	query := r.DB.DB.WithContext(ctx).Model(&entity.RetentionRun{})
+	if from > 0 {
+		query = query.Where("started_at >= ?", from)
+	}
+	if to > 0 {
+		query = query.Where("started_at < ?", to)
+	}
+	if err := query.Session(&gorm.Session{}).Select(`count(*) AS runs, coalesce(sum(photos), 0) AS photos,
+		coalesce(sum(objects), 0) AS objects, coalesce(sum(bytes), 0) AS bytes,
+		coalesce(sum(errors), 0) AS errors`).Scan(report).Error; err != nil {
+		return nil, nil, fmt.Errorf("failed to sum retention runs: %w", err)
+	}
+	if page > 0 && pageSize > 0 {
+		query = query.Offset((page - 1) * pageSize).Limit(pageSize)
+	}
+	if err := query.Order("started_at desc").Find(&runs).Error; err != nil {
+		return nil, nil, fmt.Errorf("failed to fetch retention runs: %w", err)
+	}
+	return report, runs, nil
+}
@@ -0,0 +1,58 @@
+package repository
+
+import (
+	"reflect"
+	"regexp"
+	"testing"
+
+	"git.abanppc.com/farin-project/vehicle-records/domain/entity"
+)
+
+func TestExpiredPhotosArgs(t *testing.T) {
+	args := expiredPhotosArgs(1000, 10)
+	for _, name := range regexp.MustCompile(`@(\w+)`).FindAllStringSubmatch(expiredPhotos, -1) {
+		if _, ok := args[name[1]]; !ok {
+			t.Errorf("expiredPhotos parameter @%s is not bound", name[1])
+		}
+	}
+
+	// a record in no status would keep its photos forever
+	bound := map[interface{}]bool{}
+	for _, v := range args {
+		bound[v] = true
+	}
+	for _, status := range entity.RetentionStatuses {
+		if !bound[status] {
+			t.Errorf("retention status %s is never selected", status)
+		}
+	}
+}
+
+func TestReapedObjects(t *testing.T) {
+	photos := []entity.CitizenVehiclePhoto{
+		{CitizenVehiclePhoto: "records/a", CitizenVehiclePlateCropPhoto: "records/a_crop"},
+		{CitizenVehiclePhoto: "records/b"},
+		{},
+	}
+	want := []entity.ReapedObject{
+		{Path: "records/a", ReapedAt: 7},
+		{Path: "records/a_crop", ReapedAt: 7},
+		{Path: "records/b", ReapedAt: 7},
+	}
+	if got := reapedObjects(photos, 7); !reflect.DeepEqual(got, want) {
+		t.Errorf("reapedObjects() = %v, want %v", got, want)
+	}
+	if got := reapedObjects(nil, 7); got != nil {
+		t.Errorf("reapedObjects(nil) = %v, want nil", got)
+	}
+}
+
+func TestClaimReapedQuery(t *testing.T) {
+	var objects []entity.ReapedObject
+	stmt := dryRunDB(t).Raw(claimReaped, map[string]interface{}{"now": int64(10), "until": int64(20), "limit": 5}).
+		Scan(&objects).Statement
+	want := []interface{}{int64(20), int64(10), int64(10), 5}
+	if !reflect.DeepEqual(stmt.Vars, want) {
+		t.Errorf("claimReaped vars = %v, want %v", stmt.Vars, want)
+	}
+}
@@ -12,4 +12,16 @@ var ProviderSet = wire.NewSet(
 	NewVehicleRecordRepository,
 	NewCitizenVehiclePhotoRepository,
 	NewTehranSiteRecordRepository,
+	NewForwarderRegistry,
+	NewParkingSessionRepository,
+	NewRecordOutboxRepository,
+	NewForwardingAttemptRepository,
+	NewApiKeyRepository,
+	NewResendCheckpointRepository,
+	NewRecordReviewRepository,
+	NewRetentionRepository,
+	NewFarinRepository,
+	NewWatchlistRepository,
+	NewHotlistAlertRabbitMQ,
+	NewDeadLetterRabbitMQ,
 )
@@ -4,15 +4,18 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"git.abanppc.com/farin-project/vehicle-records/domain/entity"
 	"git.abanppc.com/farin-project/vehicle-records/infrastructure/godotenv"
+	"git.abanppc.com/farin-project/vehicle-records/infrastructure/resilience"
 	"io"
 	"log/slog"
 	"net/http"
 	"net/url"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 )
 
@@ -80,22 +83,100 @@ type MarginParkingResponse struct {
 type TehranSiteRecordRepository struct {
 	httpClient *http.Client
 	env        *godotenv.Env
+	mu         sync.Mutex // guards expire and token
 	expire     int64
 	token      string
+	authMu     sync.Mutex // one authentication at a time
+	breaker    *resilience.CircuitBreaker
+	limiter    *resilience.Limiter
+	sem        resilience.Semaphore
 	logger     *slog.Logger
 }
 
-// NewTehranSiteRecordRepository creates a new repository instance
+// NewTehranSiteRecordRepository creates a new repository instance. Calls to
+// tehran.ir go through a circuit breaker, an adaptive rate limiter and a cap
+// on concurrent requests so a slow tehran.ir does not pile up requests.
 func NewTehranSiteRecordRepository(env *godotenv.Env, logger *slog.Logger) *TehranSiteRecordRepository {
 	return &TehranSiteRecordRepository{
 		httpClient: &http.Client{
-			Timeout: 50 * time.Second,
+			Timeout: env.TehranTimeout,
 		},
-		env:    env,
-		logger: logger,
+		env:     env,
+		breaker: resilience.NewCircuitBreaker(env.TehranBreakerFailures, env.TehranBreakerOpen, env.TehranBreakerProbes),
+		limiter: resilience.NewLimiter(env.TehranRateMin, env.TehranRateMax, env.TehranBurst),
+		sem:     resilience.NewSemaphore(env.TehranConcurrency),
+		logger:  logger,
 	}
 }
 
+// Breaker returns the circuit breaker of tehran.ir calls
+func (r *TehranSiteRecordRepository) Breaker() *resilience.CircuitBreaker {
+	return r.breaker
+}
+
+// Limiter returns the rate limiter of tehran.ir calls
+func (r *TehranSiteRecordRepository) Limiter() *resilience.Limiter {
+	return r.limiter
+}
+
+// do sends a request to tehran.ir once the rate limiter, the concurrency cap
+// and the circuit breaker let it through. Transport errors, 5xx and 429
+// responses count as failures of tehran.ir.
+func (r *TehranSiteRecordRepository) do(ctx context.Context, req *http.Request) (*http.Response, error) {
+	if err := r.breaker.Allow(); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrForwardUnavailable, err)
+	}
+	if err := r.limiter.Wait(ctx); err != nil {
+		r.breaker.Ignore()
+		return nil, err
+	}
+	if err := r.sem.Acquire(ctx); err != nil {
+		r.breaker.Ignore()
+		return nil, err
+	}
+	defer r.sem.Release()
+
+	resp, err := r.httpClient.Do(req)
+	switch {
+	case err != nil && errors.Is(err, context.Canceled):
+		r.breaker.Ignore()
+	case err != nil, resp.StatusCode >= http.StatusInternalServerError,
+		resp.StatusCode == http.StatusTooManyRequests:
+		r.breaker.Failure()
+		r.limiter.Decrease()
+	default:
+		r.breaker.Success()
+		r.limiter.Increase()
+	}
+	return resp, err
+}
+
+// accessToken returns a valid token, authenticating when the current one is
+// about to expire
+func (r *TehranSiteRecordRepository) accessToken(ctx context.Context) (string, error) {
+	if token, ok := r.validToken(); ok {
+		return token, nil
+	}
+
+	r.authMu.Lock()
+	defer r.authMu.Unlock()
+	// another request may have authenticated while this one waited
+	if token, ok := r.validToken(); ok {
+		return token, nil
+	}
+	if err := r.Authenticate(ctx); err != nil {
+		return "", err
+	}
+	token, _ := r.validToken()
+	return token, nil
+}
+
+func (r *TehranSiteRecordRepository) validToken() (string, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.token, time.Now().Unix() <= r.expire-10
+}
+
 // Converter functions
 func convertToMarginParkingRequest(record *entity.VehicleRecord) *MarginParkingRequest {
 	return &MarginParkingRequest{
@@ -188,7 +269,7 @@ func (r *TehranSiteRecordRepository) Authenticate(ctx context.Context) error {
 	req.Header.Set("Authorization", "Basic "+r.env.TehranToken)
 
 	// Send request
-	resp, err := r.httpClient.Do(req)
+	resp, err := r.do(ctx, req)
 	if err != nil {
 		return fmt.Errorf("failed to send auth request: %w", err)
 	}
@@ -205,25 +286,33 @@ func (r *TehranSiteRecordRepository) Authenticate(ctx context.Context) error {
 	if err := json.NewDecoder(resp.Body).Decode(&tokenResponse); err != nil {
 		return fmt.Errorf("failed to decode auth response: %w", err)
 	}
+	r.mu.Lock()
 	r.expire = time.Now().Add(time.Second * time.Duration(tokenResponse.ExpiresIn)).Unix()
 	r.token = tokenResponse.AccessToken
+	r.mu.Unlock()
 	return nil
 }
 
+func (r *TehranSiteRecordRepository) Name() string {
+	return TehranForwarderName
+}
+
 // SendVehicleRecord sends the record to the external service
-func (r *TehranSiteRecordRepository) SendVehicleRecord(ctx context.Context, record *entity.VehicleRecord) (requestID string, plateDetctionID int, err error) {
+func (r *TehranSiteRecordRepository) SendVehicleRecord(ctx context.Context, record *entity.VehicleRecord) (*ForwardResult, error) {
 	lg := r.logger.With("method", "SendVehicleRecord")
-	if time.Now().Unix() > r.expire-10 {
-		if err = r.Authenticate(ctx); err != nil {
-			return
+	result := &ForwardResult{}
+	token, err := r.accessToken(ctx)
+	if err != nil {
+		if errors.Is(err, ErrForwardUnavailable) {
+			return result, err
 		}
+		return result, fmt.Errorf("%w: %w", ErrForwardAuth, err)
 	}
 	requestDTO := convertToMarginParkingRequest(record)
 
 	jsonData, err := json.Marshal(requestDTO)
 	if err != nil {
-		err = fmt.Errorf("failed to marshal request: %w", err)
-		return
+		return result, fmt.Errorf("%w: failed to marshal request: %w", ErrForwardRequest, err)
 	}
 
 	req, err := http.NewRequestWithContext(ctx,
@@ -231,42 +320,43 @@ func (r *TehranSiteRecordRepository) SendVehicleRecord(ctx context.Context, reco
 		r.env.TehranStoreRecordURL,
 		bytes.NewBuffer(jsonData))
 	if err != nil {
-		err = fmt.Errorf("failed to create request: %w", err)
-		return
+		return result, fmt.Errorf("%w: failed to create request: %w", ErrForwardRequest, err)
 	}
 	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", "Bearer "+r.token)
+	req.Header.Set("Authorization", "Bearer "+token)
 
 	//every 50 request log one shardari request
 	if time.Now().Unix()%50 == 0 {
 		lg.Info("shardari request", slog.String("req", string(jsonData)))
 	}
-	resp, err := r.httpClient.Do(req)
+	resp, err := r.do(ctx, req)
 	if err != nil {
-		err = fmt.Errorf("failed to send request: %w", err)
-		return
+		if errors.Is(err, ErrForwardUnavailable) {
+			return result, err
+		}
+		return result, fmt.Errorf("%w: failed to send request: %w", ErrForwardTransport, err)
 	}
 	defer resp.Body.Close()
 
+	result.StatusCode = resp.StatusCode
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return result, fmt.Errorf("%w: failed to read response body: %w", ErrForwardTransport, err)
+	}
+	result.ResponseBody = responseExcerpt(b)
+
 	if resp.StatusCode != http.StatusOK {
-		var b []byte
-		b, err = io.ReadAll(resp.Body)
-		if err != nil {
-			err = fmt.Errorf("failed to read response body: %w", err)
-			return
-		}
-		err = fmt.Errorf("unexpected status code: %d and resp:%s", resp.StatusCode, string(b), "Req", string(jsonData))
-		return
+		return result, fmt.Errorf("%w: unexpected status code: %d and resp:%s", ErrForwardStatus,
+			resp.StatusCode, result.ResponseBody)
 	}
 
 	// Parse response
 	var response MarginParkingResponse
-	if err = json.NewDecoder(resp.Body).Decode(&response); err != nil {
-		err = fmt.Errorf("failed to decode response: %w", err)
-		return
+	if err := json.Unmarshal(b, &response); err != nil {
+		return result, fmt.Errorf("%w: failed to decode response: %w", ErrForwardResponse, err)
 	}
-	requestID = response.RequestID
-	plateDetctionID = response.PlateDetectionID
+	result.RequestID = response.RequestID
+	result.PlateDetectionID = response.PlateDetectionID
 
-	return
+	return result, nil
 }
@@ -9,6 +9,7 @@ import (
 
 	"github.com/google/uuid"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 var ErrCitizenVehiclePhotoNotFound = errors.New("citizen vehicle photo not found")
@@ -134,6 +135,26 @@ func (r *CitizenVehiclePhotoRepository) GetByID(ctx context.Context, id uint) (*
 	return r.GetByField(ctx, "id", id)
 }
 
+// GetOfRecord gets a photo of a record by its ID
+func (r *CitizenVehiclePhotoRepository) GetOfRecord(ctx context.Context, recordID, id string) (
+	*entity.CitizenVehiclePhoto, error) {
+	var photo entity.CitizenVehiclePhoto
+
+	// malformed ids name no photo, postgres would fail on the uuid cast
+	if uuid.Validate(id) != nil || uuid.Validate(recordID) != nil {
+		return nil, ErrCitizenVehiclePhotoNotFound
+	}
+	if err := r.DB.DB.WithContext(ctx).Where("id = ? AND record_id = ?", id, recordID).
+		First(&photo).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrCitizenVehiclePhotoNotFound
+		}
+		return nil, fmt.Errorf("failed to retrieve citizen vehicle photo: %w", err)
+	}
+
+	return &photo, nil
+}
+
 // Find photos by record ID
 func (r *CitizenVehiclePhotoRepository) FindByRecordID(ctx context.Context, recordID uuid.UUID) ([]entity.CitizenVehiclePhoto, error) {
 	var photos []entity.CitizenVehiclePhoto
@@ -156,6 +177,15 @@ func (r *CitizenVehiclePhotoRepository) BulkCreate(ctx context.Context, photos [
 	return nil
 }
 
+// BulkCreateMissing creates the photos that do not exist yet, so a retried
+// upload does not fail on the photos stored by an earlier attempt
+func (r *CitizenVehiclePhotoRepository) BulkCreateMissing(ctx context.Context, photos []*entity.CitizenVehiclePhoto) error {
+	if err := r.DB.DB.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(photos).Error; err != nil {
+		return fmt.Errorf("failed to bulk create citizen vehicle photos: %w", err)
+	}
+	return nil
+}
+
 // Find photos by camera ID
 func (r *CitizenVehiclePhotoRepository) FindByCameraID(ctx context.Context, cameraID int) ([]entity.CitizenVehiclePhoto, error) {
 	var photos []entity.CitizenVehiclePhoto
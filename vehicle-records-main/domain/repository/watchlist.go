@@ -0,0 +1,277 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"git.abanppc.com/farin-project/vehicle-records/domain/entity"
+	gormdb "git.abanppc.com/farin-project/vehicle-records/infrastructure/gorm"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrWatchlistNotFound      = errors.New("watchlist not found")
+	ErrWatchlistEntryNotFound = errors.New("watchlist entry not found")
+	ErrWatchlistMatchNotFound = errors.New("watchlist match not found")
+	// ErrWatchlistMatchAcknowledged is returned when a match was already acknowledged
+	ErrWatchlistMatchAcknowledged = errors.New("watchlist match is already acknowledged")
+)
+
+type WatchlistRepository struct {
+	DB *gormdb.GORMDB
+}
+
+func NewWatchlistRepository(db *gormdb.GORMDB) *WatchlistRepository {
+	return &WatchlistRepository{DB: db}
+}
+
+// ListLists lists the watchlists by name
+func (r *WatchlistRepository) ListLists(ctx context.Context) ([]entity.Watchlist, error) {
+	var lists []entity.Watchlist
+	if err := r.DB.DB.WithContext(ctx).Order("name").Find(&lists).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch watchlists: %w", err)
+	}
+	return lists, nil
+}
+
+func (r *WatchlistRepository) GetList(ctx context.Context, id string) (*entity.Watchlist, error) {
+	var list entity.Watchlist
+	if err := r.DB.DB.WithContext(ctx).Where("id = ?", id).First(&list).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrWatchlistNotFound
+		}
+		return nil, fmt.Errorf("failed to retrieve watchlist: %w", err)
+	}
+	return &list, nil
+}
+
+func (r *WatchlistRepository) CreateList(ctx context.Context, list *entity.Watchlist) error {
+	if err := r.DB.DB.WithContext(ctx).Create(list).Error; err != nil {
+		return fmt.Errorf("failed to create watchlist: %w", err)
+	}
+	return nil
+}
+
+func (r *WatchlistRepository) UpdateList(ctx context.Context, list *entity.Watchlist) error {
+	result := r.DB.DB.WithContext(ctx).Model(&entity.Watchlist{}).Where("id = ?", list.ID).
+		Updates(map[string]interface{}{
+			"name":        list.Name,
+			"kind":        list.Kind,
+			"description": list.Description,
+			"active":      list.Active,
+		})
+	if result.Error != nil {
+		return fmt.Errorf("failed to update watchlist: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrWatchlistNotFound
+	}
+	return nil
+}
+
+// DeleteList deletes a watchlist with its entries, its matches are kept
+func (r *WatchlistRepository) DeleteList(ctx context.Context, id string) error {
+	return r.DB.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("id = ?", id).Delete(&entity.Watchlist{})
+		if result.Error != nil {
+			return fmt.Errorf("failed to delete watchlist: %w", result.Error)
+		}
+		if result.RowsAffected == 0 {
+			return ErrWatchlistNotFound
+		}
+		if err := tx.Where("watchlist_id = ?", id).Delete(&entity.WatchlistEntry{}).Error; err != nil {
+			return fmt.Errorf("failed to delete watchlist entries: %w", err)
+		}
+		return nil
+	})
+}
+
+// ListEntries lists the entries of a watchlist, newest first
+func (r *WatchlistRepository) ListEntries(ctx context.Context, listID string, page, pageSize int) (
+	[]entity.WatchlistEntry, int64, error) {
+	var entries []entity.WatchlistEntry
+	var total int64
+
+	query := r.DB.DB.WithContext(ctx).Model(&entity.WatchlistEntry{}).Where("watchlist_id = ?", listID)
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to count watchlist entries: %w", err)
+	}
+	if page > 0 && pageSize > 0 {
+		query = query.Offset((page - 1) * pageSize).Limit(pageSize)
+	}
+	if err := query.Order("created_at DESC").Find(&entries).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to fetch watchlist entries: %w", err)
+	}
+	return entries, total, nil
+}
+
+// CreateEntry adds an entry, it fails with ErrWatchlistNotFound when its
+// watchlist does not exist
+func (r *WatchlistRepository) CreateEntry(ctx context.Context, entry *entity.WatchlistEntry) error {
+	return r.DB.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var lists int64
+		if err := tx.Model(&entity.Watchlist{}).Where("id = ?", entry.WatchlistID).Count(&lists).Error; err != nil {
+			return fmt.Errorf("failed to retrieve watchlist: %w", err)
+		}
+		if lists == 0 {
+			return ErrWatchlistNotFound
+		}
+		if err := tx.Create(entry).Error; err != nil {
+			return fmt.Errorf("failed to create watchlist entry: %w", err)
+		}
+		return nil
+	})
+}
+
+func (r *WatchlistRepository) UpdateEntry(ctx context.Context, entry *entity.WatchlistEntry) error {
+	result := r.DB.DB.WithContext(ctx).Model(&entity.WatchlistEntry{}).
+		Where("id = ? AND watchlist_id = ?", entry.ID, entry.WatchlistID).
+		Updates(map[string]interface{}{
+			"pattern":     entry.Pattern,
+			"priority":    entry.Priority,
+			"valid_from":  entry.ValidFrom,
+			"valid_until": entry.ValidUntil,
+			"note":        entry.Note,
+		})
+	if result.Error != nil {
+		return fmt.Errorf("failed to update watchlist entry: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrWatchlistEntryNotFound
+	}
+	return nil
+}
+
+func (r *WatchlistRepository) DeleteEntry(ctx context.Context, listID, id string) error {
+	result := r.DB.DB.WithContext(ctx).Where("id = ? AND watchlist_id = ?", id, listID).
+		Delete(&entity.WatchlistEntry{})
+	if result.Error != nil {
+		return fmt.Errorf("failed to delete watchlist entry: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrWatchlistEntryNotFound
+	}
+	return nil
+}
+
+// ActiveEntries lists the entries of active watchlists that did not expire
+// at now, with their watchlists
+func (r *WatchlistRepository) ActiveEntries(ctx context.Context, now int64) ([]entity.WatchlistEntry, error) {
+	var entries []entity.WatchlistEntry
+	err := r.DB.DB.WithContext(ctx).Joins("Watchlist").
+		Where(`"Watchlist".active AND "Watchlist".deleted_at = 0`).
+		Where("watchlist_entries.valid_until = 0 OR watchlist_entries.valid_until > ?", now).
+		Find(&entries).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch watchlist entries: %w", err)
+	}
+	return entries, nil
+}
+
+// CreateMatches adds the matches of a record, matches the record already has
+// are left as they are. The matches that were added get their ids.
+func (r *WatchlistRepository) CreateMatches(ctx context.Context, matches []*entity.WatchlistMatch) (
+	[]*entity.WatchlistMatch, error) {
+	var created []*entity.WatchlistMatch
+	err := r.DB.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for _, m := range matches {
+			result := tx.Clauses(clause.OnConflict{
+				Columns:   []clause.Column{{Name: "entry_id"}, {Name: "record_id"}},
+				DoNothing: true,
+			}).Create(m)
+			if result.Error != nil {
+				return fmt.Errorf("failed to create watchlist match: %w", result.Error)
+			}
+			if result.RowsAffected > 0 {
+				created = append(created, m)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return created, nil
+}
+
+// MarkPublished records that the alert of a match was published at
+func (r *WatchlistRepository) MarkPublished(ctx context.Context, id string, at int64) error {
+	err := r.DB.DB.WithContext(ctx).Model(&entity.WatchlistMatch{}).Where("id = ?", id).
+		Update("published_at", at).Error
+	if err != nil {
+		return fmt.Errorf("failed to update watchlist match: %w", err)
+	}
+	return nil
+}
+
+// Unpublished lists up to limit matches created before createdBefore whose
+// alert was not published, with their watchlists, entries, records and photos
+func (r *WatchlistRepository) Unpublished(ctx context.Context, createdBefore int64, limit int) (
+	[]entity.WatchlistMatch, error) {
+	var matches []entity.WatchlistMatch
+	err := r.DB.DB.WithContext(ctx).
+		Preload("Watchlist", func(db *gorm.DB) *gorm.DB { return db.Unscoped() }).
+		Preload("Entry", func(db *gorm.DB) *gorm.DB { return db.Unscoped() }).
+		Preload("VehicleRecord.VehiclePhotos").
+		Where("published_at = 0 AND created_at < ?", createdBefore).
+		Order("created_at").Limit(limit).Find(&matches).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch unpublished watchlist matches: %w", err)
+	}
+	return matches, nil
+}
+
+// ListMatches lists the matches in status, highest priority first then oldest
+// first, with their watchlists, entries and records
+func (r *WatchlistRepository) ListMatches(ctx context.Context, status entity.WatchlistMatchStatus, page,
+	pageSize int) ([]entity.WatchlistMatch, int64, error) {
+	var matches []entity.WatchlistMatch
+	var total int64
+
+	query := r.DB.DB.WithContext(ctx).Model(&entity.WatchlistMatch{}).Where("status = ?", status)
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to count watchlist matches: %w", err)
+	}
+	if page > 0 && pageSize > 0 {
+		query = query.Offset((page - 1) * pageSize).Limit(pageSize)
+	}
+	err := query.
+		Preload("Watchlist", func(db *gorm.DB) *gorm.DB { return db.Unscoped() }).
+		Preload("Entry", func(db *gorm.DB) *gorm.DB { return db.Unscoped() }).
+		Preload("VehicleRecord.VehiclePhotos").
+		Order("priority DESC, created_at").Find(&matches).Error
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to fetch watchlist matches: %w", err)
+	}
+	return matches, total, nil
+}
+
+// Acknowledge marks a pending match as handled by an operator
+func (r *WatchlistRepository) Acknowledge(ctx context.Context, id, by, note string, at int64) error {
+	result := r.DB.DB.WithContext(ctx).Model(&entity.WatchlistMatch{}).
+		Where("id = ? AND status = ?", id, entity.WatchlistMatchPending).
+		Updates(map[string]interface{}{
+			"status":          entity.WatchlistMatchAcknowledged,
+			"acknowledged_by": by,
+			"acknowledged_at": at,
+			"note":            note,
+		})
+	if result.Error != nil {
+		return fmt.Errorf("failed to update watchlist match: %w", result.Error)
+	}
+	if result.RowsAffected > 0 {
+		return nil
+	}
+
+	var matches int64
+	if err := r.DB.DB.WithContext(ctx).Model(&entity.WatchlistMatch{}).Where("id = ?", id).
+		Count(&matches).Error; err != nil {
+		return fmt.Errorf("failed to retrieve watchlist match: %w", err)
+	}
+	if matches == 0 {
+		return ErrWatchlistMatchNotFound
+	}
+	return ErrWatchlistMatchAcknowledged
+}
@@ -0,0 +1,318 @@
+package repository
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"git.abanppc.com/farin-project/vehicle-records/domain/entity"
+)
+
+// WebhookForwarderConfig describes a municipality that accepts records as a
+// JSON document.
+//
+// Fields maps a dot separated path of the request body to a VehicleRecord
+// field, by its JSON name, optionally followed by a conversion:
+//
+//	"detection.plate":      "CitizenPlateNumber"
+//	"detection.storedAt":   "RecordStoreTime|time"   // milliseconds to RFC 3339
+//	"detection.ringId":     "RingId|string"
+//	"detection.distorted":  "IsCitizenVehicleDistorted|int"
+//	"photos":               "VehiclePhotos"
+//
+// PhotoFields maps the fields of every photo the same way when VehiclePhotos
+// is mapped. Constants are set as they are. Header values may reference
+// environment variables as $NAME so secrets stay out of the file.
+type WebhookForwarderConfig struct {
+	Name                  string                 `json:"name"`
+	URL                   string                 `json:"url"`
+	Method                string                 `json:"method"`
+	Headers               map[string]string      `json:"headers"`
+	Timeout               string                 `json:"timeout"`
+	TimeZone              string                 `json:"timeZone"`
+	Fields                map[string]string      `json:"fields"`
+	PhotoFields           map[string]string      `json:"photoFields"`
+	Constants             map[string]interface{} `json:"constants"`
+	RequestIDField        string                 `json:"requestIdField"`
+	PlateDetectionIDField string                 `json:"plateDetectionIdField"`
+}
+
+// WebhookForwarder sends records to a municipality as configured by a
+// WebhookForwarderConfig
+type WebhookForwarder struct {
+	cfg        WebhookForwarderConfig
+	httpClient *http.Client
+	loc        *time.Location
+	logger     *slog.Logger
+}
+
+func NewWebhookForwarder(cfg WebhookForwarderConfig, logger *slog.Logger) (*WebhookForwarder, error) {
+	if cfg.Name == "" || cfg.URL == "" {
+		return nil, errors.New("webhook forwarder needs a name and an url")
+	}
+	if len(cfg.Fields) == 0 {
+		return nil, fmt.Errorf("webhook forwarder %q has no fields", cfg.Name)
+	}
+	if cfg.Method == "" {
+		cfg.Method = http.MethodPost
+	}
+
+	timeout := 50 * time.Second
+	if cfg.Timeout != "" {
+		d, err := time.ParseDuration(cfg.Timeout)
+		if err != nil {
+			return nil, fmt.Errorf("invalid timeout of webhook forwarder %q: %w", cfg.Name, err)
+		}
+		timeout = d
+	}
+
+	loc := time.UTC
+	if cfg.TimeZone != "" {
+		l, err := time.LoadLocation(cfg.TimeZone)
+		if err != nil {
+			return nil, fmt.Errorf("invalid time zone of webhook forwarder %q: %w", cfg.Name, err)
+		}
+		loc = l
+	}
+
+	for _, spec := range cfg.Fields {
+		if _, err := parseFieldSpec(spec); err != nil {
+			return nil, fmt.Errorf("webhook forwarder %q: %w", cfg.Name, err)
+		}
+	}
+	for _, spec := range cfg.PhotoFields {
+		if _, err := parseFieldSpec(spec); err != nil {
+			return nil, fmt.Errorf("webhook forwarder %q: %w", cfg.Name, err)
+		}
+	}
+
+	return &WebhookForwarder{
+		cfg:        cfg,
+		httpClient: &http.Client{Timeout: timeout},
+		loc:        loc,
+		logger:     logger.With("layer", "WebhookForwarder", "forwarder", cfg.Name),
+	}, nil
+}
+
+func (w *WebhookForwarder) Name() string {
+	return w.cfg.Name
+}
+
+// SendVehicleRecord sends the mapped record, any 2xx status is a success
+func (w *WebhookForwarder) SendVehicleRecord(ctx context.Context, record *entity.VehicleRecord) (*ForwardResult, error) {
+	result := &ForwardResult{}
+	body, err := w.Body(record)
+	if err != nil {
+		return result, fmt.Errorf("%w: %w", ErrForwardRequest, err)
+	}
+	jsonData, err := json.Marshal(body)
+	if err != nil {
+		return result, fmt.Errorf("%w: failed to marshal request: %w", ErrForwardRequest, err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, w.cfg.Method, w.cfg.URL, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return result, fmt.Errorf("%w: failed to create request: %w", ErrForwardRequest, err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range w.cfg.Headers {
+		req.Header.Set(k, os.ExpandEnv(v))
+	}
+
+	resp, err := w.httpClient.Do(req)
+	if err != nil {
+		return result, fmt.Errorf("%w: failed to send request: %w", ErrForwardTransport, err)
+	}
+	defer resp.Body.Close()
+
+	result.StatusCode = resp.StatusCode
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return result, fmt.Errorf("%w: failed to read response body: %w", ErrForwardTransport, err)
+	}
+	result.ResponseBody = responseExcerpt(b)
+
+	if resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden {
+		return result, fmt.Errorf("%w: status code: %d and resp:%s", ErrForwardAuth, resp.StatusCode,
+			result.ResponseBody)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return result, fmt.Errorf("%w: unexpected status code: %d and resp:%s", ErrForwardStatus,
+			resp.StatusCode, result.ResponseBody)
+	}
+	if w.cfg.RequestIDField == "" && w.cfg.PlateDetectionIDField == "" {
+		return result, nil
+	}
+
+	var response map[string]interface{}
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	if err := dec.Decode(&response); err != nil {
+		return result, fmt.Errorf("%w: failed to decode response: %w", ErrForwardResponse, err)
+	}
+	if v, ok := getPath(response, w.cfg.RequestIDField); ok {
+		result.RequestID = fmt.Sprint(v)
+	}
+	if v, ok := getPath(response, w.cfg.PlateDetectionIDField); ok {
+		result.PlateDetectionID, err = strconv.Atoi(fmt.Sprint(v))
+		if err != nil {
+			return result, fmt.Errorf("%w: invalid plate detection id: %w", ErrForwardResponse, err)
+		}
+	}
+	return result, nil
+}
+
+// Body builds the request body of a record from the field mapping
+func (w *WebhookForwarder) Body(record *entity.VehicleRecord) (map[string]interface{}, error) {
+	src, err := toJSONMap(record)
+	if err != nil {
+		return nil, err
+	}
+
+	body := map[string]interface{}{}
+	for path, v := range w.cfg.Constants {
+		setPath(body, path, v)
+	}
+	for path, spec := range w.cfg.Fields {
+		fs, _ := parseFieldSpec(spec)
+		value := src[fs.name]
+		if fs.name == "VehiclePhotos" && len(w.cfg.PhotoFields) > 0 {
+			value, err = w.photos(record.VehiclePhotos)
+			if err != nil {
+				return nil, err
+			}
+		}
+		value, err = fs.convert(value, w.loc)
+		if err != nil {
+			return nil, fmt.Errorf("failed to map %s to %s: %w", fs.name, path, err)
+		}
+		setPath(body, path, value)
+	}
+	return body, nil
+}
+
+func (w *WebhookForwarder) photos(photos []*entity.CitizenVehiclePhoto) ([]interface{}, error) {
+	result := make([]interface{}, len(photos))
+	for i, photo := range photos {
+		src, err := toJSONMap(photo)
+		if err != nil {
+			return nil, err
+		}
+		out := map[string]interface{}{}
+		for path, spec := range w.cfg.PhotoFields {
+			fs, _ := parseFieldSpec(spec)
+			value, err := fs.convert(src[fs.name], w.loc)
+			if err != nil {
+				return nil, fmt.Errorf("failed to map photo %s to %s: %w", fs.name, path, err)
+			}
+			setPath(out, path, value)
+		}
+		result[i] = out
+	}
+	return result, nil
+}
+
+type fieldSpec struct {
+	name       string
+	conversion string
+}
+
+func parseFieldSpec(spec string) (fieldSpec, error) {
+	name, conversion, _ := strings.Cut(spec, "|")
+	fs := fieldSpec{name: strings.TrimSpace(name), conversion: strings.TrimSpace(conversion)}
+	if fs.name == "" {
+		return fs, fmt.Errorf("empty field in %q", spec)
+	}
+	switch fs.conversion {
+	case "", "string", "int", "time":
+	default:
+		return fs, fmt.Errorf("unknown conversion %q in %q", fs.conversion, spec)
+	}
+	return fs, nil
+}
+
+// convert applies the conversion of the spec, time turns unix milliseconds
+// into RFC 3339 in loc and int turns booleans into 0 or 1
+func (fs fieldSpec) convert(value interface{}, loc *time.Location) (interface{}, error) {
+	if value == nil {
+		return nil, nil
+	}
+	switch fs.conversion {
+	case "string":
+		return fmt.Sprint(value), nil
+	case "int":
+		switch v := value.(type) {
+		case bool:
+			if v {
+				return 1, nil
+			}
+			return 0, nil
+		case json.Number:
+			return v.Int64()
+		}
+		return nil, fmt.Errorf("%v is not a number", value)
+	case "time":
+		n, ok := value.(json.Number)
+		if !ok {
+			return nil, fmt.Errorf("%v is not a timestamp", value)
+		}
+		ms, err := n.Int64()
+		if err != nil {
+			return nil, err
+		}
+		return time.UnixMilli(ms).In(loc).Format(time.RFC3339), nil
+	}
+	return value, nil
+}
+
+func toJSONMap(v interface{}) (map[string]interface{}, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal record: %w", err)
+	}
+	m := map[string]interface{}{}
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	if err := dec.Decode(&m); err != nil {
+		return nil, fmt.Errorf("failed to decode record: %w", err)
+	}
+	return m, nil
+}
+
+func setPath(m map[string]interface{}, path string, value interface{}) {
+	keys := strings.Split(path, ".")
+	for _, k := range keys[:len(keys)-1] {
+		next, ok := m[k].(map[string]interface{})
+		if !ok {
+			next = map[string]interface{}{}
+			m[k] = next
+		}
+		m = next
+	}
+	m[keys[len(keys)-1]] = value
+}
+
+func getPath(m map[string]interface{}, path string) (interface{}, bool) {
+	if path == "" {
+		return nil, false
+	}
+	keys := strings.Split(path, ".")
+	for _, k := range keys[:len(keys)-1] {
+		next, ok := m[k].(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		m = next
+	}
+	v, ok := m[keys[len(keys)-1]]
+	return v, ok && v != nil
+}
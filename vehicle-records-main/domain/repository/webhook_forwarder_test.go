@@ -0,0 +1,148 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"git.abanppc.com/farin-project/vehicle-records/domain/entity"
+)
+
+func testWebhookForwarder(t *testing.T, url string) *WebhookForwarder {
+	t.Helper()
+	t.Setenv("WEBHOOK_TEST_TOKEN", "secret")
+	w, err := NewWebhookForwarder(WebhookForwarderConfig{
+		Name:     "karaj",
+		URL:      url,
+		Headers:  map[string]string{"Authorization": "Bearer $WEBHOOK_TEST_TOKEN"},
+		TimeZone: "UTC",
+		Fields: map[string]string{
+			"detection.id":         "RecordId",
+			"detection.detectedAt": "RecordStoreTime|time",
+			"detection.ringId":     "RingId|string",
+			"detection.distorted":  "IsCitizenVehicleDistorted|int",
+			"photos":               "VehiclePhotos",
+		},
+		PhotoFields:           map[string]string{"cameraId": "LPRVehicleCameraId", "image": "CitizenVehiclePhoto"},
+		Constants:             map[string]interface{}{"source": "farin"},
+		RequestIDField:        "tracking.id",
+		PlateDetectionIDField: "detectionId",
+	}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return w
+}
+
+func testWebhookRecord() *entity.VehicleRecord {
+	return &entity.VehicleRecord{
+		RecordID:                  "r1",
+		RecordStoreTime:           1717491600000, // 2024-06-04T09:00:00Z
+		RingID:                    501,
+		IsCitizenVehicleDistorted: true,
+		VehiclePhotos: []*entity.CitizenVehiclePhoto{
+			{LPRVehicleCameraID: 2, CitizenVehiclePhoto: "aGVsbG8="},
+		},
+	}
+}
+
+func TestWebhookForwarderBody(t *testing.T) {
+	w := testWebhookForwarder(t, "http://karaj.example")
+	body, err := w.Body(testWebhookRecord())
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := json.Marshal(body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got, want interface{}
+	_ = json.Unmarshal(b, &got)
+	_ = json.Unmarshal([]byte(`{
+		"source": "farin",
+		"detection": {"id": "r1", "detectedAt": "2024-06-04T09:00:00Z", "ringId": "501", "distorted": 1},
+		"photos": [{"cameraId": 2, "image": "aGVsbG8="}]
+	}`), &want)
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Body() = %s", b)
+	}
+}
+
+func TestNewWebhookForwarderInvalid(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	tests := []struct {
+		name string
+		cfg  WebhookForwarderConfig
+	}{
+		{"no url", WebhookForwarderConfig{Name: "x", Fields: map[string]string{"id": "RecordId"}}},
+		{"no fields", WebhookForwarderConfig{Name: "x", URL: "http://x.example"}},
+		{"unknown conversion", WebhookForwarderConfig{Name: "x", URL: "http://x.example",
+			Fields: map[string]string{"id": "RecordId|uuid"}}},
+		{"invalid timeout", WebhookForwarderConfig{Name: "x", URL: "http://x.example", Timeout: "soon",
+			Fields: map[string]string{"id": "RecordId"}}},
+		{"invalid time zone", WebhookForwarderConfig{Name: "x", URL: "http://x.example", TimeZone: "Mars/Olympus",
+			Fields: map[string]string{"id": "RecordId"}}},
+	}
+	for _, tt := range tests {
+		if _, err := NewWebhookForwarder(tt.cfg, logger); err == nil {
+			t.Errorf("%s: NewWebhookForwarder() returned no error", tt.name)
+		}
+	}
+}
+
+func TestWebhookForwarderSendVehicleRecord(t *testing.T) {
+	tests := []struct {
+		name       string
+		status     int
+		response   string
+		wantErr    error
+		wantResult ForwardResult
+	}{
+		{"accepted", http.StatusOK, `{"tracking": {"id": "t-1"}, "detectionId": 42}`, nil,
+			ForwardResult{RequestID: "t-1", PlateDetectionID: 42, StatusCode: http.StatusOK}},
+		{"unauthorized", http.StatusUnauthorized, `{}`, ErrForwardAuth,
+			ForwardResult{StatusCode: http.StatusUnauthorized}},
+		{"rejected", http.StatusBadRequest, `{"error": "bad plate"}`, ErrForwardStatus,
+			ForwardResult{StatusCode: http.StatusBadRequest}},
+		{"invalid response", http.StatusOK, `not json`, ErrForwardResponse,
+			ForwardResult{StatusCode: http.StatusOK}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var auth string
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				auth = r.Header.Get("Authorization")
+				w.WriteHeader(tt.status)
+				_, _ = w.Write([]byte(tt.response))
+			}))
+			defer srv.Close()
+
+			result, err := testWebhookForwarder(t, srv.URL).SendVehicleRecord(context.Background(), testWebhookRecord())
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("SendVehicleRecord() error = %v, want %v", err, tt.wantErr)
+			}
+			if auth != "Bearer secret" {
+				t.Errorf("Authorization = %q", auth)
+			}
+			tt.wantResult.ResponseBody = tt.response
+			if *result != tt.wantResult {
+				t.Errorf("SendVehicleRecord() = %+v, want %+v", *result, tt.wantResult)
+			}
+		})
+	}
+}
+
+func TestWebhookForwarderTransportError(t *testing.T) {
+	srv := httptest.NewServer(http.NotFoundHandler())
+	srv.Close()
+	_, err := testWebhookForwarder(t, srv.URL).SendVehicleRecord(context.Background(), testWebhookRecord())
+	if !errors.Is(err, ErrForwardTransport) {
+		t.Errorf("SendVehicleRecord() error = %v, want %v", err, ErrForwardTransport)
+	}
+}
@@ -0,0 +1,123 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"time"
+
+	"git.abanppc.com/farin-project/vehicle-records/domain/entity"
+	"git.abanppc.com/farin-project/vehicle-records/domain/repository"
+)
+
+var (
+	ErrInvalidApiKey = errors.New("invalid api key")
+	ErrApiKeyScope   = errors.New("api key lacks the required scope")
+)
+
+// apiKeyPrefix starts every key so leaked keys are easy to search for
+const apiKeyPrefix = "vr_"
+
+type ApiKeyService struct {
+	logger *slog.Logger
+	repo   *repository.ApiKeyRepository
+}
+
+func NewApiKeyService(logger *slog.Logger, repo *repository.ApiKeyRepository) *ApiKeyService {
+	return &ApiKeyService{
+		logger: logger.With("layer", "ApiKeyService"),
+		repo:   repo,
+	}
+}
+
+// Mint creates a key with the given scopes, expiresAt is zero for keys that
+// do not expire. The key is only returned here, the database keeps its hash.
+func (s *ApiKeyService) Mint(ctx context.Context, name string, scopes []string, expiresAt time.Time) (
+	string, *entity.ApiKey, error) {
+	if name == "" {
+		return "", nil, errors.New("api key needs a name")
+	}
+	if len(scopes) == 0 {
+		return "", nil, errors.New("api key needs at least one scope")
+	}
+	for _, scope := range scopes {
+		if !slices.Contains(entity.Scopes, scope) {
+			return "", nil, fmt.Errorf("unknown scope %q", scope)
+		}
+	}
+
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", nil, fmt.Errorf("failed to generate api key: %w", err)
+	}
+	plain := apiKeyPrefix + base64.RawURLEncoding.EncodeToString(secret)
+
+	key := &entity.ApiKey{
+		Name:    name,
+		Prefix:  plain[:len(apiKeyPrefix)+8],
+		KeyHash: hashApiKey(plain),
+		Scopes:  strings.Join(scopes, ","),
+	}
+	if !expiresAt.IsZero() {
+		key.ExpiresAt = expiresAt.UnixMilli()
+	}
+	key, err := s.repo.Create(ctx, key)
+	if err != nil {
+		return "", nil, err
+	}
+	return plain, key, nil
+}
+
+// Revoke stops a key from being accepted
+func (s *ApiKeyService) Revoke(ctx context.Context, id string) error {
+	return s.repo.Revoke(ctx, id, time.Now().UnixMilli())
+}
+
+func (s *ApiKeyService) List(ctx context.Context) ([]entity.ApiKey, error) {
+	return s.repo.List(ctx)
+}
+
+// Authenticate returns the key when it is active and was granted scope. It
+// fails with ErrInvalidApiKey for unknown, revoked or expired keys and with
+// ErrApiKeyScope for keys without the scope.
+func (s *ApiKeyService) Authenticate(ctx context.Context, plain string, scope string) (*entity.ApiKey, error) {
+	lg := s.logger.With("method", "Authenticate")
+	if !strings.HasPrefix(plain, apiKeyPrefix) {
+		return nil, ErrInvalidApiKey
+	}
+
+	key, err := s.repo.GetByHash(ctx, hashApiKey(plain))
+	if err != nil {
+		if errors.Is(err, repository.ErrApiKeyNotFound) {
+			return nil, ErrInvalidApiKey
+		}
+		lg.Error("failed to fetch api key", "error", err.Error())
+		return nil, err
+	}
+	now := time.Now().UnixMilli()
+	if !key.Active(now) {
+		return nil, ErrInvalidApiKey
+	}
+	if !key.HasScope(scope) {
+		return nil, ErrApiKeyScope
+	}
+
+	if err := s.repo.Used(ctx, key.ID, now); err != nil {
+		lg.Warn("failed to update api key last use", "error", err.Error(), "prefix", key.Prefix)
+	}
+	return key, nil
+}
+
+// hashApiKey hashes a key for storage. Keys are 256 random bits so a plain
+// SHA-256 is enough, there is nothing to brute force.
+func hashApiKey(plain string) string {
+	sum := sha256.Sum256([]byte(plain))
+	return hex.EncodeToString(sum[:])
+}
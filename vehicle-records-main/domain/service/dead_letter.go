@@ -0,0 +1,92 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+
+	"git.abanppc.com/farin-project/vehicle-records/domain/repository"
+	"git.abanppc.com/farin-project/vehicle-records/infrastructure/godotenv"
+	"git.abanppc.com/farin-project/vehicle-records/util/consumer"
+)
+
+// ErrUnknownQueue is returned for queues this service does not consume
+var ErrUnknownQueue = errors.New("unknown queue")
+
+// DeadLetterService inspects, replays and purges the messages the consumers
+// of this service gave up on
+type DeadLetterService struct {
+	logger         *slog.Logger
+	env            *godotenv.Env
+	deadLetterRepo *repository.DeadLetterRabbitMQ
+}
+
+func NewDeadLetterService(logger *slog.Logger, env *godotenv.Env,
+	deadLetterRepo *repository.DeadLetterRabbitMQ) *DeadLetterService {
+	return &DeadLetterService{
+		logger:         logger.With("layer", "DeadLetterService"),
+		env:            env,
+		deadLetterRepo: deadLetterRepo,
+	}
+}
+
+// Queues returns the queues whose dead letters the service manages
+func (s *DeadLetterService) Queues() []string {
+	return []string{s.env.RabbitMQRecordsQueue, s.env.RabbitMQRetryQueue}
+}
+
+func (s *DeadLetterService) checkQueue(queue string) error {
+	for _, q := range s.Queues() {
+		if q == queue {
+			return nil
+		}
+	}
+	return ErrUnknownQueue
+}
+
+// List returns up to limit dead letters of queue, oldest first, and the
+// number of dead letters it has
+func (s *DeadLetterService) List(_ context.Context, queue string, limit int) ([]consumer.DeadLetter, int, error) {
+	lg := s.logger.With("method", "List")
+	if err := s.checkQueue(queue); err != nil {
+		return nil, 0, err
+	}
+	letters, total, err := s.deadLetterRepo.Inspect(queue, limit)
+	if err != nil {
+		lg.Error("failed to inspect dead letters", "error", err, "queue", queue)
+		return nil, 0, err
+	}
+	return letters, total, nil
+}
+
+// Replay sends the dead letters with the given ids, or the first limit of
+// them without ids, back to queue
+func (s *DeadLetterService) Replay(ctx context.Context, queue string, ids []string, limit int, by string) (int, error) {
+	lg := s.logger.With("method", "Replay")
+	if err := s.checkQueue(queue); err != nil {
+		return 0, err
+	}
+	n, err := s.deadLetterRepo.Replay(ctx, queue, ids, limit)
+	if err != nil {
+		lg.Error("failed to replay dead letters", "error", err, "queue", queue, "replayed", n)
+		return n, err
+	}
+	lg.Info("dead letters replayed", "queue", queue, "replayed", n, "by", by)
+	return n, nil
+}
+
+// Purge deletes the dead letters with the given ids, or all of them
+// without ids
+func (s *DeadLetterService) Purge(_ context.Context, queue string, ids []string, by string) (int, error) {
+	lg := s.logger.With("method", "Purge")
+	if err := s.checkQueue(queue); err != nil {
+		return 0, err
+	}
+	n, err := s.deadLetterRepo.Purge(queue, ids)
+	if err != nil {
+		lg.Error("failed to purge dead letters", "error", err, "queue", queue, "purged", n)
+		return n, err
+	}
+	lg.Info("dead letters purged", "queue", queue, "purged", n, "by", by)
+	return n, nil
+}
@@ -0,0 +1,299 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/jpeg" // tiles of some servers are jpeg
+	_ "image/png"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"git.abanppc.com/farin-project/vehicle-records/domain/entity"
+	"git.abanppc.com/farin-project/vehicle-records/domain/repository"
+	"git.abanppc.com/farin-project/vehicle-records/infrastructure/godotenv"
+	"git.abanppc.com/farin-project/vehicle-records/util/evidence"
+	"git.abanppc.com/farin-project/vehicle-records/util/plate"
+	"git.abanppc.com/farin-project/vehicle-records/util/staticmap"
+	"github.com/mahdimehrabi/uploader/minio"
+)
+
+// ErrEvidenceUnsigned is returned while no evidence signing key is configured
+var ErrEvidenceUnsigned = errors.New("evidence signing key is not configured")
+
+const (
+	evidenceMapWidth  = 640
+	evidenceMapHeight = 480
+)
+
+type EvidenceService struct {
+	logger     *slog.Logger
+	env        *godotenv.Env
+	recordRepo *repository.VehicleRecordRepository
+	farinRepo  *repository.FarinRepository
+	minio      *minio.Minio
+	key        ed25519.PrivateKey
+	httpClient *http.Client
+}
+
+// NewEvidenceService loads the EVIDENCE_SIGNING_KEY, packages cannot be
+// exported without it
+func NewEvidenceService(logger *slog.Logger, env *godotenv.Env, recordRepo *repository.VehicleRecordRepository,
+	farinRepo *repository.FarinRepository, minio *minio.Minio) (*EvidenceService, error) {
+	s := &EvidenceService{
+		logger:     logger.With("layer", "EvidenceService"),
+		env:        env,
+		recordRepo: recordRepo,
+		farinRepo:  farinRepo,
+		minio:      minio,
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+	}
+	if env.EvidenceSigningKey != "" {
+		key, err := evidence.LoadPrivateKey(env.EvidenceSigningKey)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load evidence signing key: %w", err)
+		}
+		s.key = key
+	}
+	return s, nil
+}
+
+// EvidenceMetadata is the metadata.json of an evidence package. Missing
+// lists what could not be included, such as reaped photos.
+type EvidenceMetadata struct {
+	Record     *entity.VehicleRecord   `json:"record"`
+	Plate      string                  `json:"plate,omitempty"`
+	Location   EvidenceLocation        `json:"location"`
+	Segment    *entity.EvidenceSegment `json:"segment,omitempty"`
+	Ring       *entity.EvidenceRing    `json:"ring,omitempty"`
+	Driver     *entity.EvidenceDriver  `json:"driver,omitempty"`
+	Photos     []EvidencePhoto         `json:"photos"`
+	Missing    []string                `json:"missing,omitempty"`
+	ExportedAt time.Time               `json:"exportedAt"`
+	ExportedBy string                  `json:"exportedBy"`
+}
+
+type EvidenceLocation struct {
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+	Valid     bool    `json:"valid"`
+	Error     int     `json:"error"`
+}
+
+// EvidencePhoto names the files of a photo in the package
+type EvidencePhoto struct {
+	ID        string `json:"id"`
+	Photo     string `json:"photo,omitempty"`
+	PlateCrop string `json:"plateCrop,omitempty"`
+}
+
+// PublicKey returns the key packages can be verified with and its id
+func (s *EvidenceService) PublicKey() (ed25519.PublicKey, string, error) {
+	if s.key == nil {
+		return nil, "", ErrEvidenceUnsigned
+	}
+	pub := s.key.Public().(ed25519.PublicKey)
+	return pub, evidence.KeyID(pub), nil
+}
+
+// Export builds the signed evidence package of a record: its original photos
+// and plate crops, metadata.json with the segment, ring and driver of the
+// record and map.png of the GPS point over the segment. It fails with
+// repository.ErrVehicleRecordNotFound for unknown records.
+func (s *EvidenceService) Export(ctx context.Context, recordID, operator string) ([]byte, error) {
+	lg := s.logger.With("method", "Export")
+	if s.key == nil {
+		return nil, ErrEvidenceUnsigned
+	}
+	record, err := s.recordRepo.GetByID(ctx, recordID)
+	if err != nil {
+		if !errors.Is(err, repository.ErrVehicleRecordNotFound) {
+			lg.Error("failed to fetch record", "error", err.Error(), "recordID", recordID)
+		}
+		return nil, err
+	}
+
+	now := time.Now()
+	meta := &EvidenceMetadata{
+		Record: record,
+		Location: EvidenceLocation{
+			Latitude:  record.LPRVehicleGPSLatitude,
+			Longitude: record.LPRVehicleGPSLongitude,
+			Valid:     record.LPRVehicleIsGPSSignalValid,
+			Error:     record.LPRVehicleGPSError,
+		},
+		ExportedAt: now.UTC(),
+		ExportedBy: operator,
+	}
+	if p, err := plate.Parse(record.CitizenPlateNumber); err == nil {
+		meta.Plate = p.Latin()
+	}
+
+	var files []evidence.File
+	for _, photo := range record.VehiclePhotos {
+		ep := EvidencePhoto{ID: photo.ID}
+		for _, f := range []struct {
+			path string
+			name string
+			dst  *string
+		}{
+			{photo.CitizenVehiclePhoto, "photos/" + photo.ID, &ep.Photo},
+			{photo.CitizenVehiclePlateCropPhoto, "photos/" + photo.ID + "_plate", &ep.PlateCrop},
+		} {
+			if f.path == "" {
+				meta.Missing = append(meta.Missing, f.name+": reaped by the retention policy")
+				continue
+			}
+			b, err := download(ctx, s.minio, f.path)
+			if err != nil {
+				lg.Error("failed to download photo", "error", err.Error(), "recordID", recordID, "path", f.path)
+				return nil, err
+			}
+			name := f.name + photoExt(b)
+			files = append(files, evidence.File{Name: name, Content: b})
+			*f.dst = name
+		}
+		meta.Photos = append(meta.Photos, ep)
+	}
+
+	if err := s.lookup(ctx, record, meta); err != nil {
+		lg.Error("failed to look up record context", "error", err.Error(), "recordID", recordID)
+		return nil, err
+	}
+
+	if record.LPRVehicleGPSLatitude != 0 || record.LPRVehicleGPSLongitude != 0 {
+		mapPNG, err := s.renderMap(ctx, record, meta.Segment)
+		if err != nil {
+			lg.Error("failed to render map", "error", err.Error(), "recordID", recordID)
+			return nil, err
+		}
+		files = append(files, evidence.File{Name: "map.png", Content: mapPNG})
+	} else {
+		meta.Missing = append(meta.Missing, "map.png: the record has no GPS point")
+	}
+
+	// photos of the record are in the package, not at their storage paths
+	record.VehiclePhotos = nil
+	mb, err := json.MarshalIndent(meta, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal evidence metadata: %w", err)
+	}
+	files = append(files, evidence.File{Name: "metadata.json", Content: mb})
+
+	var out bytes.Buffer
+	if err := evidence.Write(&out, recordID, files, s.key, now); err != nil {
+		lg.Error("failed to write evidence package", "error", err.Error(), "recordID", recordID)
+		return nil, err
+	}
+	lg.Info("evidence exported", "recordID", recordID, "operator", operator, "files", len(files),
+		"bytes", out.Len())
+	return out.Bytes(), nil
+}
+
+// photoExt is the file extension of a photo by its content, photos are
+// stored without a content type
+func photoExt(b []byte) string {
+	switch http.DetectContentType(b) {
+	case "image/jpeg":
+		return ".jpg"
+	case "image/png":
+		return ".png"
+	case "image/webp":
+		return ".webp"
+	case "image/bmp":
+		return ".bmp"
+	default:
+		return ".bin"
+	}
+}
+
+// lookup adds the segment, ring and driver of the record from the farin
+// database, they are listed as missing while it is not configured
+func (s *EvidenceService) lookup(ctx context.Context, record *entity.VehicleRecord, meta *EvidenceMetadata) error {
+	var err error
+	if record.SegmentID != 0 {
+		meta.Segment, err = s.farinRepo.Segment(ctx, record.SegmentID)
+		if errors.Is(err, repository.ErrFarinUnavailable) {
+			meta.Missing = append(meta.Missing, "segment, ring and driver: "+err.Error())
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+	if record.RingID != 0 {
+		meta.Ring, err = s.farinRepo.Ring(ctx, record.RingID)
+		if errors.Is(err, repository.ErrFarinUnavailable) {
+			meta.Missing = append(meta.Missing, "ring and driver: "+err.Error())
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+	if record.UserID != "" {
+		meta.Driver, err = s.farinRepo.Driver(ctx, record.UserID)
+		if errors.Is(err, repository.ErrFarinUnavailable) {
+			meta.Missing = append(meta.Missing, "driver: "+err.Error())
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// renderMap draws the GPS point of the record over the segment geometry
+func (s *EvidenceService) renderMap(ctx context.Context, record *entity.VehicleRecord,
+	segment *entity.EvidenceSegment) ([]byte, error) {
+	point := staticmap.Point{Lon: record.LPRVehicleGPSLongitude, Lat: record.LPRVehicleGPSLatitude}
+	var lines [][]staticmap.Point
+	if segment != nil && segment.Geometry != "" {
+		var err error
+		lines, err = staticmap.Lines(segment.Geometry)
+		if err != nil {
+			s.logger.Warn("failed to read segment geometry", "error", err.Error(), "segmentID", segment.ID)
+		}
+	}
+
+	var tiles staticmap.TileSource
+	if s.env.EvidenceTileURL != "" {
+		tiles = s.tile
+	}
+	m := staticmap.Map{Width: evidenceMapWidth, Height: evidenceMapHeight, Zoom: s.env.EvidenceMapZoom, Center: point}
+	return m.Render(ctx, tiles, lines, point)
+}
+
+// tile fetches a map tile from EvidenceTileURL
+func (s *EvidenceService) tile(ctx context.Context, z, x, y int) (image.Image, error) {
+	u := strings.NewReplacer("{z}", strconv.Itoa(z), "{x}", strconv.Itoa(x), "{y}", strconv.Itoa(y)).
+		Replace(s.env.EvidenceTileURL)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", "farin-vehicle-records")
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		s.logger.Warn("failed to fetch map tile", "error", err.Error(), "url", u)
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		s.logger.Warn("failed to fetch map tile", "status", resp.StatusCode, "url", u)
+		return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+	img, _, err := image.Decode(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode map tile: %w", err)
+	}
+	return img, nil
+}
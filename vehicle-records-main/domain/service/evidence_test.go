@@ -0,0 +1,36 @@
+package service
+
+import (
+	"bytes"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"testing"
+)
+
+func TestPhotoExt(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 4, 4))
+	var jb, pb bytes.Buffer
+	if err := jpeg.Encode(&jb, img, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := png.Encode(&pb, img); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		b    []byte
+		want string
+	}{
+		{"jpeg", jb.Bytes(), ".jpg"},
+		{"png", pb.Bytes(), ".png"},
+		{"unknown", []byte("not a photo"), ".bin"},
+		{"empty", nil, ".bin"},
+	}
+	for _, tt := range tests {
+		if got := photoExt(tt.b); got != tt.want {
+			t.Errorf("%s: photoExt() = %s, want %s", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,52 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+
+	"git.abanppc.com/farin-project/vehicle-records/domain/entity"
+	"git.abanppc.com/farin-project/vehicle-records/domain/repository"
+)
+
+type ForwardingAttemptService struct {
+	logger      *slog.Logger
+	attemptRepo *repository.ForwardingAttemptRepository
+	recordRepo  *repository.VehicleRecordRepository
+}
+
+func NewForwardingAttemptService(logger *slog.Logger, attemptRepo *repository.ForwardingAttemptRepository,
+	recordRepo *repository.VehicleRecordRepository) *ForwardingAttemptService {
+	return &ForwardingAttemptService{
+		logger:      logger.With("layer", "ForwardingAttemptService"),
+		attemptRepo: attemptRepo,
+		recordRepo:  recordRepo,
+	}
+}
+
+// Attempts lists the forwarding attempts of a record, it fails with
+// repository.ErrVehicleRecordNotFound for unknown records
+func (s *ForwardingAttemptService) Attempts(ctx context.Context, recordID string, page, pageSize int) (
+	[]entity.ForwardingAttempt, int64, error) {
+	lg := s.logger.With("method", "Attempts")
+	if _, err := s.recordRepo.GetByID(ctx, recordID); err != nil {
+		return nil, 0, err
+	}
+	attempts, total, err := s.attemptRepo.ListByRecord(ctx, recordID, page, pageSize)
+	if err != nil {
+		lg.Error("failed to list forwarding attempts", "error", err.Error(), "recordID", recordID)
+		return nil, 0, err
+	}
+	return attempts, total, nil
+}
+
+// Failures lists failed forwarding attempts of all records
+func (s *ForwardingAttemptService) Failures(ctx context.Context, filters map[string]interface{}, from, to int64,
+	page, pageSize int) ([]entity.ForwardingAttempt, int64, error) {
+	lg := s.logger.With("method", "Failures")
+	attempts, total, err := s.attemptRepo.ListFailures(ctx, filters, from, to, page, pageSize)
+	if err != nil {
+		lg.Error("failed to list forwarding failures", "error", err.Error())
+		return nil, 0, err
+	}
+	return attempts, total, nil
+}
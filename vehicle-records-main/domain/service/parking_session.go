@@ -0,0 +1,134 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"git.abanppc.com/farin-project/vehicle-records/domain/entity"
+	"git.abanppc.com/farin-project/vehicle-records/domain/repository"
+	"git.abanppc.com/farin-project/vehicle-records/infrastructure/godotenv"
+)
+
+type ParkingSessionService struct {
+	logger      *slog.Logger
+	sessionRepo *repository.ParkingSessionRepository
+	env         *godotenv.Env
+}
+
+func NewParkingSessionService(logger *slog.Logger, sessionRepo *repository.ParkingSessionRepository,
+	env *godotenv.Env) *ParkingSessionService {
+	return &ParkingSessionService{
+		logger:      logger.With("layer", "ParkingSessionService"),
+		sessionRepo: sessionRepo,
+		env:         env,
+	}
+}
+
+// Track adds a sighting to the open session of the plate in the record
+// segment/parking lot, or opens a new session. Records outside a segment are
+// ignored.
+func (s *ParkingSessionService) Track(ctx context.Context, record *entity.VehicleRecord) error {
+	lg := s.logger.With("method", "Track")
+	if record.SegmentID == 0 || record.CitizenPlateNumberNumeric == 0 {
+		return nil
+	}
+
+	err := s.sighted(ctx, record)
+	if !errors.Is(err, repository.ErrParkingSessionNotFound) {
+		return err
+	}
+
+	session := &entity.ParkingSession{
+		CitizenPlateNumber:        record.CitizenPlateNumber,
+		CitizenPlateNumberNumeric: record.CitizenPlateNumberNumeric,
+		RingID:                    record.RingID,
+		StreetID:                  record.StreetID,
+		SegmentID:                 record.SegmentID,
+		ParkingLotID:              record.ParkingLotID,
+		FirstRecordID:             record.RecordID,
+		LastRecordID:              record.RecordID,
+		Sightings:                 1,
+		StartTime:                 record.RecordStoreTime,
+		LastSeenTime:              record.RecordStoreTime,
+		Status:                    entity.ParkingSessionOpen,
+	}
+	if _, err := s.sessionRepo.Create(ctx, session); err != nil {
+		if errors.Is(err, repository.ErrParkingSessionExists) {
+			// another sighting of the plate opened the session first
+			return s.sighted(ctx, record)
+		}
+		lg.Error("failed to open parking session", "error", err.Error(), "recordID", record.RecordID)
+		return err
+	}
+	lg.Debug("parking session opened", "sessionID", session.ID, "recordID", record.RecordID)
+	return nil
+}
+
+// sighted adds the record to the open session of its plate in the record
+// segment/parking lot, ErrParkingSessionNotFound is returned when there is none
+func (s *ParkingSessionService) sighted(ctx context.Context, record *entity.VehicleRecord) error {
+	lg := s.logger.With("method", "sighted")
+	session, err := s.sessionRepo.FindOpen(ctx, record.CitizenPlateNumberNumeric, record.SegmentID, record.ParkingLotID)
+	if err != nil {
+		if !errors.Is(err, repository.ErrParkingSessionNotFound) {
+			lg.Error("failed to find open parking session", "error", err.Error(), "recordID", record.RecordID)
+		}
+		return err
+	}
+	if err := s.sessionRepo.Sighted(ctx, session.ID, record.RecordID, record.RecordStoreTime); err != nil {
+		lg.Error("failed to add sighting to parking session", "error", err.Error(),
+			"sessionID", session.ID, "recordID", record.RecordID)
+		return err
+	}
+	return nil
+}
+
+// CloseSessions closes sessions whose plate was missing on a later pass over
+// their segment and expires sessions whose segment was not passed again
+func (s *ParkingSessionService) CloseSessions(ctx context.Context, now time.Time) error {
+	lg := s.logger.With("method", "CloseSessions")
+
+	settledBefore := now.Add(-s.env.ParkingSessionSettle).UnixMilli()
+	closed, err := s.sessionRepo.CloseMissed(ctx, s.env.ParkingSessionPassGap.Milliseconds(), settledBefore)
+	if err != nil {
+		lg.Error("failed to close parking sessions", "error", err.Error())
+		return err
+	}
+
+	expired, err := s.sessionRepo.ExpireStale(ctx, now.Add(-s.env.ParkingSessionMaxOpen).UnixMilli())
+	if err != nil {
+		lg.Error("failed to expire parking sessions", "error", err.Error())
+		return err
+	}
+
+	if closed > 0 || expired > 0 {
+		lg.Info("parking sessions closed", "closed", closed, "expired", expired)
+	}
+	return nil
+}
+
+// RunCloser calls CloseSessions every ParkingSessionCloseInterval until ctx is done
+func (s *ParkingSessionService) RunCloser(ctx context.Context) {
+	ticker := time.NewTicker(s.env.ParkingSessionCloseInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			_ = s.CloseSessions(ctx, now)
+		}
+	}
+}
+
+func (s *ParkingSessionService) List(ctx context.Context, filters map[string]interface{}, from, to int64,
+	page, pageSize int) ([]entity.ParkingSession, int64, error) {
+	return s.sessionRepo.List(ctx, filters, from, to, page, pageSize)
+}
+
+func (s *ParkingSessionService) Detail(ctx context.Context, id string) (*entity.ParkingSession, error) {
+	return s.sessionRepo.GetByID(ctx, id)
+}
@@ -0,0 +1,254 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/url"
+	"strings"
+	"sync"
+
+	"git.abanppc.com/farin-project/vehicle-records/domain/entity"
+	"git.abanppc.com/farin-project/vehicle-records/domain/repository"
+	"git.abanppc.com/farin-project/vehicle-records/infrastructure/godotenv"
+	"git.abanppc.com/farin-project/vehicle-records/infrastructure/resilience"
+	"git.abanppc.com/farin-project/vehicle-records/util/thumbnail"
+	"github.com/mahdimehrabi/uploader/minio"
+	minio2 "github.com/minio/minio-go/v7"
+)
+
+// ErrInvalidPhotoPath is returned for stored photo paths that are not bucket/object
+var ErrInvalidPhotoPath = errors.New("invalid photo path")
+
+// PhotoMode is how the photos of a record are returned by the API
+type PhotoMode string
+
+const (
+	// PhotoModeURL returns presigned URLs valid for PhotoURLExpiry
+	PhotoModeURL PhotoMode = "url"
+	// PhotoModeBase64 returns the base64 contents as they are sent to tehran.ir
+	PhotoModeBase64 PhotoMode = "base64"
+)
+
+// photoHashMeta is the user metadata holding the SHA-256 of a stored photo
+const photoHashMeta = "Sha256"
+
+type PhotoService struct {
+	logger    *slog.Logger
+	env       *godotenv.Env
+	photoRepo *repository.CitizenVehiclePhotoRepository
+	minio     *minio.Minio
+	uploads   resilience.Semaphore
+	stored    *hashIndex
+}
+
+func NewPhotoService(logger *slog.Logger, env *godotenv.Env, photoRepo *repository.CitizenVehiclePhotoRepository,
+	minio *minio.Minio) *PhotoService {
+	return &PhotoService{
+		logger:    logger.With("layer", "PhotoService"),
+		env:       env,
+		photoRepo: photoRepo,
+		minio:     minio,
+		uploads:   resilience.NewSemaphore(env.UploadConcurrency),
+		stored:    newHashIndex(hashIndexSize),
+	}
+}
+
+// Upload stores base64 content as bucket/name and returns the SHA-256 of the
+// decoded bytes. The content is decoded as a stream twice, to hash it and to
+// upload it, so no decoded copy is kept in memory. Nothing is uploaded when
+// the object already holds the same bytes, as when an outbox entry is
+// retried after a partial upload, and bytes recently stored under another
+// name by this instance are copied inside object storage instead of being
+// uploaded again. Every record keeps its own objects so retention can delete
+// them independently. At most UploadConcurrency uploads run at once across
+// all records.
+func (s *PhotoService) Upload(ctx context.Context, bucket, name, content string) (string, error) {
+	h := sha256.New()
+	size, err := io.Copy(h, base64.NewDecoder(base64.StdEncoding, strings.NewReader(content)))
+	if err != nil {
+		return "", fmt.Errorf("failed to decode photo: %w", err)
+	}
+	sum := hex.EncodeToString(h.Sum(nil))
+
+	if err := s.uploads.Acquire(ctx); err != nil {
+		return "", err
+	}
+	defer s.uploads.Release()
+
+	if s.holds(ctx, bucket, name, sum) {
+		s.logger.Debug("photo already uploaded", "object", name)
+		return sum, nil
+	}
+	if src, ok := s.stored.get(sum); ok && src.Bucket == bucket && s.holds(ctx, src.Bucket, src.Object, sum) {
+		_, err := s.minio.M.CopyObject(ctx,
+			minio2.CopyDestOptions{Bucket: bucket, Object: name},
+			minio2.CopySrcOptions{Bucket: src.Bucket, Object: src.Object, MatchETag: src.ETag})
+		if err == nil {
+			s.logger.Debug("photo copied from identical object", "object", name, "source", src.Object)
+			return sum, nil
+		}
+		s.logger.Warn("failed to copy identical photo, uploading it", "error", err.Error(), "object", name)
+	}
+
+	info, err := s.minio.M.PutObject(ctx, bucket, name,
+		base64.NewDecoder(base64.StdEncoding, strings.NewReader(content)), size,
+		minio2.PutObjectOptions{
+			ContentType:  "application/octet-stream",
+			UserMetadata: map[string]string{photoHashMeta: sum},
+		})
+	if err != nil {
+		return "", fmt.Errorf("failed to upload photo: %w", err)
+	}
+	s.stored.put(sum, storedObject{Bucket: bucket, Object: name, ETag: info.ETag})
+	return sum, nil
+}
+
+// holds reports whether bucket/name exists with the SHA-256 sum
+func (s *PhotoService) holds(ctx context.Context, bucket, name, sum string) bool {
+	info, err := s.minio.M.StatObject(ctx, bucket, name, minio2.StatObjectOptions{})
+	return err == nil && info.UserMetadata[photoHashMeta] == sum
+}
+
+// hashIndexSize is the number of recent uploads Upload can copy from
+const hashIndexSize = 4096
+
+// storedObject is an uploaded photo, ETag pins the copy source to the
+// uploaded version
+type storedObject struct {
+	Bucket string
+	Object string
+	ETag   string
+}
+
+// hashIndex maps the SHA-256 of the last uploads to their objects, the oldest
+// entry is dropped when it is full
+type hashIndex struct {
+	mu      sync.Mutex
+	objects map[string]storedObject
+	order   []string
+	next    int
+}
+
+func newHashIndex(size int) *hashIndex {
+	return &hashIndex{objects: make(map[string]storedObject, size), order: make([]string, size)}
+}
+
+func (x *hashIndex) get(sum string) (storedObject, bool) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	o, ok := x.objects[sum]
+	return o, ok
+}
+
+func (x *hashIndex) put(sum string, o storedObject) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	if _, ok := x.objects[sum]; !ok {
+		delete(x.objects, x.order[x.next])
+		x.order[x.next] = sum
+		x.next = (x.next + 1) % len(x.order)
+	}
+	x.objects[sum] = o
+}
+
+// SignRecord replaces the object storage paths of the record photos with
+// presigned URLs
+func (s *PhotoService) SignRecord(ctx context.Context, record *entity.VehicleRecord) error {
+	for _, vf := range record.VehiclePhotos {
+		u, err := s.URL(ctx, vf.CitizenVehiclePhoto)
+		if err != nil {
+			return err
+		}
+		vf.CitizenVehiclePhoto = u
+
+		u, err = s.URL(ctx, vf.CitizenVehiclePlateCropPhoto)
+		if err != nil {
+			return err
+		}
+		vf.CitizenVehiclePlateCropPhoto = u
+	}
+	return nil
+}
+
+// URL signs a stored photo path for PhotoURLExpiry, the empty path of a
+// reaped photo stays empty
+func (s *PhotoService) URL(ctx context.Context, path string) (string, error) {
+	if path == "" {
+		return "", nil
+	}
+	bucket, object, err := splitPhotoPath(path)
+	if err != nil {
+		return "", err
+	}
+	u, err := s.minio.M.PresignedGetObject(ctx, bucket, object, s.env.PhotoURLExpiry, url.Values{})
+	if err != nil {
+		return "", fmt.Errorf("failed to sign photo url: %w", err)
+	}
+	return u.String(), nil
+}
+
+// Thumbnail returns a photo of a record, or its plate crop, as a JPEG at
+// most width pixels wide. It fails with
+// repository.ErrCitizenVehiclePhotoNotFound for unknown and reaped photos.
+func (s *PhotoService) Thumbnail(ctx context.Context, recordID, photoID string, crop bool, width int) (
+	[]byte, error) {
+	lg := s.logger.With("method", "Thumbnail")
+	photo, err := s.photoRepo.GetOfRecord(ctx, recordID, photoID)
+	if err != nil {
+		return nil, err
+	}
+	path := photo.CitizenVehiclePhoto
+	if crop {
+		path = photo.CitizenVehiclePlateCropPhoto
+	}
+	if path == "" {
+		return nil, repository.ErrCitizenVehiclePhotoNotFound
+	}
+
+	src, err := download(ctx, s.minio, path)
+	if err != nil {
+		lg.Error("failed to download photo", "error", err.Error(), "photoID", photoID)
+		return nil, err
+	}
+	thumb, err := thumbnail.Make(src, width)
+	if err != nil {
+		lg.Error("failed to make thumbnail", "error", err.Error(), "photoID", photoID)
+		return nil, err
+	}
+	return thumb, nil
+}
+
+// download reads a stored photo
+func download(ctx context.Context, m *minio.Minio, path string) ([]byte, error) {
+	bucket, object, err := splitPhotoPath(path)
+	if err != nil {
+		return nil, err
+	}
+	obj, err := m.M.GetObject(ctx, bucket, object, minio2.GetObjectOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get object: %w", err)
+	}
+	defer obj.Close()
+
+	bf := bytes.NewBuffer([]byte{})
+	if _, err := io.Copy(bf, obj); err != nil {
+		return nil, fmt.Errorf("failed to read object: %w", err)
+	}
+	return bf.Bytes(), nil
+}
+
+// splitPhotoPath splits a photo path as stored with the record, bucket/object
+func splitPhotoPath(path string) (string, string, error) {
+	bucket, object, ok := strings.Cut(path, "/")
+	if !ok || bucket == "" || object == "" {
+		return "", "", fmt.Errorf("%w: %q", ErrInvalidPhotoPath, path)
+	}
+	return bucket, object, nil
+}
@@ -0,0 +1,30 @@
+package service
+
+import "testing"
+
+func TestHashIndex(t *testing.T) {
+	x := newHashIndex(2)
+	x.put("a", storedObject{Object: "a1"})
+	x.put("b", storedObject{Object: "b1"})
+	// a newer object of a known hash replaces it without evicting
+	x.put("a", storedObject{Object: "a2"})
+	if o, ok := x.get("a"); !ok || o.Object != "a2" {
+		t.Errorf("get(a) = %v, %v, want a2, true", o, ok)
+	}
+	if _, ok := x.get("b"); !ok {
+		t.Errorf("get(b) = false, want true")
+	}
+
+	x.put("c", storedObject{Object: "c1"})
+	if _, ok := x.get("a"); ok {
+		t.Errorf("get(a) = true after eviction, want false")
+	}
+	for _, sum := range []string{"b", "c"} {
+		if _, ok := x.get(sum); !ok {
+			t.Errorf("get(%s) = false, want true", sum)
+		}
+	}
+	if _, ok := x.get(""); ok {
+		t.Errorf("get(\"\") = true, want false")
+	}
+}
@@ -0,0 +1,152 @@
+package service
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+
+	"git.abanppc.com/farin-project/vehicle-records/domain/entity"
+	"git.abanppc.com/farin-project/vehicle-records/domain/repository"
+	"git.abanppc.com/farin-project/vehicle-records/util/plate"
+	"github.com/google/uuid"
+)
+
+// ErrInvalidCursor is returned for cursors not made by PlateHistory
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+type PlateHistoryService struct {
+	logger     *slog.Logger
+	recordRepo *repository.VehicleRecordRepository
+}
+
+func NewPlateHistoryService(logger *slog.Logger, recordRepo *repository.VehicleRecordRepository) *PlateHistoryService {
+	return &PlateHistoryService{
+		logger:     logger.With("layer", "PlateHistoryService"),
+		recordRepo: recordRepo,
+	}
+}
+
+// PlateHistoryQuery asks for a page of the sightings of Plate. Fuzzy adds
+// the plates that differ by one character the cameras misread.
+type PlateHistoryQuery struct {
+	Plate     string
+	Fuzzy     bool
+	From      int64
+	To        int64
+	RingID    int64
+	SegmentID int64
+	StreetID  int64
+	Cursor    string
+	Limit     int
+}
+
+// PlateSighting is a record of the history, Exact is false for records of a
+// confusable plate
+type PlateSighting struct {
+	Exact  bool                 `json:"exact"`
+	Record entity.VehicleRecord `json:"record"`
+}
+
+// PlateHistory is a page of sightings, newest first. NextCursor is empty on
+// the last page. The counts cover every page and are only returned with the
+// first one.
+type PlateHistory struct {
+	Plate      string                         `json:"plate"`
+	Candidates []string                       `json:"candidates,omitempty"`
+	Items      []PlateSighting                `json:"items"`
+	NextCursor string                         `json:"nextCursor,omitempty"`
+	PerDay     []repository.PlateDayCount     `json:"perDay,omitempty"`
+	PerSegment []repository.PlateSegmentCount `json:"perSegment,omitempty"`
+}
+
+// History returns a page of the sightings of a plate. It fails with
+// plate.ErrInvalidPlate for unreadable plates and ErrInvalidCursor for
+// unknown cursors.
+func (s *PlateHistoryService) History(ctx context.Context, q PlateHistoryQuery) (*PlateHistory, error) {
+	lg := s.logger.With("method", "History")
+	p, err := plate.Parse(q.Plate)
+	if err != nil {
+		return nil, err
+	}
+	after, err := decodeCursor(q.Cursor)
+	if err != nil {
+		return nil, err
+	}
+
+	history := &PlateHistory{Plate: p.String(), Items: []PlateSighting{}}
+	filter := repository.PlateHistoryFilter{
+		Numerics:  []int{p.Numeric()},
+		From:      q.From,
+		To:        q.To,
+		RingID:    q.RingID,
+		SegmentID: q.SegmentID,
+		StreetID:  q.StreetID,
+	}
+	if q.Fuzzy {
+		for _, c := range plate.Confusable(p) {
+			history.Candidates = append(history.Candidates, c.String())
+			filter.Numerics = append(filter.Numerics, c.Numeric())
+		}
+	}
+
+	// one more record tells if there is a next page
+	records, err := s.recordRepo.PlateHistory(ctx, filter, after, q.Limit+1)
+	if err != nil {
+		lg.Error("failed to fetch plate history", "error", err.Error())
+		return nil, err
+	}
+	if len(records) > q.Limit {
+		records = records[:q.Limit]
+		last := records[len(records)-1]
+		history.NextCursor = encodeCursor(&repository.PlateHistoryCursor{
+			StoreTime: last.RecordStoreTime,
+			RecordID:  last.RecordID,
+		})
+	}
+	for _, record := range records {
+		history.Items = append(history.Items, PlateSighting{
+			Exact:  record.CitizenPlateNumberNumeric == p.Numeric(),
+			Record: record,
+		})
+	}
+
+	if after == nil {
+		if history.PerDay, err = s.recordRepo.PlateHistoryPerDay(ctx, filter); err != nil {
+			lg.Error("failed to count plate history per day", "error", err.Error())
+			return nil, err
+		}
+		if history.PerSegment, err = s.recordRepo.PlateHistoryPerSegment(ctx, filter); err != nil {
+			lg.Error("failed to count plate history per segment", "error", err.Error())
+			return nil, err
+		}
+	}
+	return history, nil
+}
+
+// encodeCursor keeps the store time and id of the last record of a page
+func encodeCursor(c *repository.PlateHistoryCursor) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(fmt.Sprintf("%d:%s", c.StoreTime, c.RecordID)))
+}
+
+func decodeCursor(s string) (*repository.PlateHistoryCursor, error) {
+	if s == "" {
+		return nil, nil
+	}
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	t, id, ok := strings.Cut(string(b), ":")
+	if !ok || uuid.Validate(id) != nil {
+		return nil, ErrInvalidCursor
+	}
+	storeTime, err := strconv.ParseInt(t, 10, 64)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	return &repository.PlateHistoryCursor{StoreTime: storeTime, RecordID: id}, nil
+}
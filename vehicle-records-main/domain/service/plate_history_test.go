@@ -0,0 +1,42 @@
+package service
+
+import (
+	"encoding/base64"
+	"errors"
+	"testing"
+
+	"git.abanppc.com/farin-project/vehicle-records/domain/repository"
+)
+
+func TestDecodeCursor(t *testing.T) {
+	const id = "0b6f1c3e-8a51-4d6f-9f0e-2f1d4c3b2a19"
+	raw := func(s string) string {
+		return base64.RawURLEncoding.EncodeToString([]byte(s))
+	}
+
+	tests := []struct {
+		name    string
+		cursor  string
+		want    *repository.PlateHistoryCursor
+		wantErr error
+	}{
+		{"first page", "", nil, nil},
+		{"made by encodeCursor", encodeCursor(&repository.PlateHistoryCursor{StoreTime: 1_700_000_000_000, RecordID: id}),
+			&repository.PlateHistoryCursor{StoreTime: 1_700_000_000_000, RecordID: id}, nil},
+		{"not base64", "not base64!", nil, ErrInvalidCursor},
+		{"without separator", raw("1700000000000"), nil, ErrInvalidCursor},
+		{"without id", raw("1700000000000:"), nil, ErrInvalidCursor},
+		{"malformed id", raw("1700000000000:not-a-uuid"), nil, ErrInvalidCursor},
+		{"malformed store time", raw("soon:" + id), nil, ErrInvalidCursor},
+	}
+	for _, tt := range tests {
+		got, err := decodeCursor(tt.cursor)
+		if !errors.Is(err, tt.wantErr) {
+			t.Errorf("%s: decodeCursor() error = %v, want %v", tt.name, err, tt.wantErr)
+			continue
+		}
+		if (got == nil) != (tt.want == nil) || got != nil && *got != *tt.want {
+			t.Errorf("%s: decodeCursor() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -1,15 +1,12 @@
 package service
 
 import (
-	"bytes"
 	"context"
 	"encoding/base64"
 	"encoding/json"
 	"errors"
-	"fmt"
-	"io"
 	"log/slog"
-	"strconv"
+	"net"
 	"strings"
 	"time"
 
@@ -17,13 +14,11 @@ import (
 	"git.abanppc.com/farin-project/vehicle-records/domain/opentelemetry"
 	"git.abanppc.com/farin-project/vehicle-records/domain/repository"
 	"git.abanppc.com/farin-project/vehicle-records/infrastructure/godotenv"
-	"git.abanppc.com/farin-project/vehicle-records/infrastructure/rabbit"
+	"git.abanppc.com/farin-project/vehicle-records/util/plate"
 	"github.com/google/uuid"
-	"github.com/mahdimehrabi/uploader"
 	"github.com/mahdimehrabi/uploader/minio"
-	minio2 "github.com/minio/minio-go/v7"
-	"github.com/rabbitmq/amqp091-go"
 	ptime "github.com/yaa110/go-persian-calendar"
+	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/metric"
 )
 
@@ -33,19 +28,26 @@ type VehicleRecordService struct {
 	logger           *slog.Logger
 	recordRepo       *repository.VehicleRecordRepository
 	photoRepo        *repository.CitizenVehiclePhotoRepository
-	fr               uploader.FileRepository
 	env              *godotenv.Env
-	tehranSiteRepo   *repository.TehranSiteRecordRepository
-	rbt              *rabbit.Rabbit
+	forwarders       *repository.ForwarderRegistry
+	outboxRepo       *repository.RecordOutboxRepository
+	attemptRepo      *repository.ForwardingAttemptRepository
+	checkpointRepo   *repository.ResendCheckpointRepository
 	osDuration       metric.Int64Histogram
 	shardariDuration metric.Int64Histogram
+	shardariRejected metric.Int64Counter
+	dedupCounter     metric.Int64Counter
 	processDuration  metric.Int64Histogram
 	minio            *minio.Minio
+	photos           *PhotoService
+	outboxWake       chan struct{}
 }
 
-func NewVehicleRecordService(logger *slog.Logger, ringRepo *repository.VehicleRecordRepository, fr uploader.FileRepository,
-	env *godotenv.Env, photoRepo *repository.CitizenVehiclePhotoRepository, tehranSiteRepo *repository.TehranSiteRecordRepository,
-	rbt *rabbit.Rabbit, telemetry *opentelemetry.OpenTelemetry, minio *minio.Minio) *VehicleRecordService {
+func NewVehicleRecordService(logger *slog.Logger, ringRepo *repository.VehicleRecordRepository,
+	env *godotenv.Env, photoRepo *repository.CitizenVehiclePhotoRepository, forwarders *repository.ForwarderRegistry,
+	outboxRepo *repository.RecordOutboxRepository, attemptRepo *repository.ForwardingAttemptRepository,
+	checkpointRepo *repository.ResendCheckpointRepository, telemetry *opentelemetry.OpenTelemetry, minio *minio.Minio,
+	photos *PhotoService) *VehicleRecordService {
 	meter := telemetry.Meter.Meter("farin,vehicle_record.RabbitMQVehicleRecordHandler")
 	osDuration, err := meter.Int64Histogram("vehicle_record.objectstorage.duration",
 		metric.WithDescription("time of vehicle record object storage upload"))
@@ -62,57 +64,111 @@ func NewVehicleRecordService(logger *slog.Logger, ringRepo *repository.VehicleRe
 	if err != nil {
 		panic(err)
 	}
+	shardariRejected, err := meter.Int64Counter("vehicle_record.shardari.breaker.rejected",
+		metric.WithDescription("number of records not sent to shardari because the circuit breaker is open"))
+	if err != nil {
+		panic(err)
+	}
+	dedupCounter, err := meter.Int64Counter("vehicle_record.dedup",
+		metric.WithDescription("number of records merged into an earlier sighting of the same vehicle"))
+	if err != nil {
+		panic(err)
+	}
+	breakerState, err := meter.Int64ObservableGauge("vehicle_record.shardari.breaker.state",
+		metric.WithDescription("state of the shardari circuit breaker, 0 closed, 1 half-open, 2 open"))
+	if err != nil {
+		panic(err)
+	}
+	rateLimit, err := meter.Float64ObservableGauge("vehicle_record.shardari.rate_limit",
+		metric.WithDescription("requests per second allowed to shardari"))
+	if err != nil {
+		panic(err)
+	}
+	_, err = meter.RegisterCallback(func(_ context.Context, o metric.Observer) error {
+		for _, f := range forwarders.Guarded() {
+			attrs := metric.WithAttributes(attribute.String("forwarder", f.Name()))
+			o.ObserveInt64(breakerState, int64(f.Breaker().State()), attrs)
+			o.ObserveFloat64(rateLimit, f.Limiter().Rate(), attrs)
+		}
+		return nil
+	}, breakerState, rateLimit)
+	if err != nil {
+		panic(err)
+	}
 
 	return &VehicleRecordService{
 		logger:           logger.With("layer", "VehicleRecordService"),
 		recordRepo:       ringRepo,
-		fr:               fr,
 		env:              env,
 		photoRepo:        photoRepo,
-		tehranSiteRepo:   tehranSiteRepo,
-		rbt:              rbt,
+		forwarders:       forwarders,
+		outboxRepo:       outboxRepo,
+		attemptRepo:      attemptRepo,
+		checkpointRepo:   checkpointRepo,
+		outboxWake:       make(chan struct{}, 1),
 		osDuration:       osDuration,
 		shardariDuration: shardariDuration,
+		shardariRejected: shardariRejected,
+		dedupCounter:     dedupCounter,
 		processDuration:  processDuration,
 		minio:            minio,
+		photos:           photos,
 	}
 }
 
 func (s *VehicleRecordService) CreateRecord(ctx context.Context, record *entity.VehicleRecord) (*entity.VehicleRecord, error) {
-	startProcessDuration := time.Now().Unix()
 	tStart := time.Now()
 	lg := s.logger.With("method", "CreateRecord")
 	defer func() {
 		lg.Info("create record", "duration", time.Since(tStart))
 	}()
-	vfs := make([]*entity.CitizenVehiclePhoto, len(record.VehiclePhotos))
-	bts := make([]*entity.CitizenVehiclePhoto, len(record.VehiclePhotos))
-	for i, vf := range record.VehiclePhotos {
+
+	// photo ids are set here so uploads retried by the outbox use the same object names
+	for _, vf := range record.VehiclePhotos {
 		vf.ID = uuid.NewString()
-		var record1, record2 entity.CitizenVehiclePhoto = *vf, *vf
-		vfs[i] = &record1
-		bts[i] = &record2
+		vf.RecordID = record.RecordID
+	}
+	photos, err := json.Marshal(record.VehiclePhotos)
+	if err != nil {
+		lg.Error("failed to marshal photos", "error", err, "recordID", record.RecordID)
+		return nil, err
 	}
 	record.VehiclePhotos = nil
 	record.Sent = false
 
-	// Keep track of uploaded files for cleanup
-	uploadedFiles := make([]string, 0)
-	var createdPhotoIDs []string // Track created photo IDs for cleanup
-
-	record.Sent = true
-	var err error
-	record.CitizenPlateNumberNumeric, err = plateReplaceAlphabets(record.CitizenPlateNumber)
-	if err != nil {
-		lg.Error("failed to convert plate string number to number", "error", err,
+	dedup := repository.DedupWindow{Window: s.env.DedupWindow.Milliseconds(), Distance: s.env.DedupDistance}
+	p, plateErr := plate.Parse(record.CitizenPlateNumber)
+	if plateErr != nil {
+		// the plate is left for a reviewer to correct and cannot be matched
+		lg.Warn("failed to parse plate", "error", plateErr,
 			"citizen plate number", record.CitizenPlateNumber, "record id ", record.RecordID)
-		return nil, err
+		dedup.Window = 0
+	} else {
+		record.CitizenPlateNumber = p.String()
+		record.CitizenPlateNumberNumeric = p.Numeric()
 	}
 
 	// Get a new instance of ptime.Time using time.Time
 	pt := ptime.Now()
 	record.ShamsiTime = pt.String()
-	createdRecord, err := s.recordRepo.Create(ctx, record)
+
+	outbox := &entity.RecordOutbox{
+		RecordID:      record.RecordID,
+		Status:        entity.RecordOutboxPendingUpload,
+		Photos:        photos,
+		NextAttemptAt: time.Now().UnixMilli(),
+	}
+	var review *entity.RecordReview
+	if reasons := s.reviewReasons(record, plateErr != nil); len(reasons) > 0 {
+		outbox.Held = true
+		review = &entity.RecordReview{
+			RecordID:      record.RecordID,
+			Status:        entity.RecordReviewPending,
+			Reasons:       strings.Join(reasons, ","),
+			OriginalPlate: record.CitizenPlateNumber,
+		}
+	}
+	createdRecord, outcome, err := s.recordRepo.CreateWithDedup(ctx, record, outbox, review, dedup)
 	if err != nil {
 		if errors.Is(err, repository.ErrDuplicateRecord) {
 			lg.Warn("record already exists", "record_id", record.RecordID)
@@ -121,173 +177,78 @@ func (s *VehicleRecordService) CreateRecord(ctx context.Context, record *entity.
 		lg.Error("failed to create record", "error", err.Error())
 		return nil, err
 	}
-
-	// Define cleanup function that handles all rollback operations
-	cleanup := func() {
-		// Delete the record
-		if err := s.recordRepo.Delete(ctx, record.RecordID); err != nil {
-			lg.Error("failed to delete record during rollback", "error", err.Error())
-		}
-
-		// Bulk delete all created photos
-		if len(createdPhotoIDs) > 0 {
-			if err := s.photoRepo.BulkDelete(ctx, createdPhotoIDs); err != nil {
-				lg.Error("failed to delete photos during rollback", "error", err.Error())
-			}
-		}
-
-		// Delete all uploaded files
-		for _, fname := range uploadedFiles {
-			if err := s.fr.DeleteFile(ctx, s.env.MinioVehicleRecordsBucket, fname); err != nil {
-				lg.Error("failed to delete file during rollback", "filename", fname, "error", err.Error())
-			}
+	if outcome == repository.DedupNone {
+		if review != nil {
+			lg.Info("record held for review", "recordID", record.RecordID, "reasons", review.Reasons)
 		}
+		s.wakeOutbox()
+	} else {
+		s.dedupCounter.Add(ctx, 1, metric.WithAttributes(attribute.String("outcome", outcome.String())))
+		lg.Info("record merged into an earlier sighting", "recordID", record.RecordID,
+			"survivorID", *createdRecord.DuplicateOf, "outcome", outcome.String())
 	}
 
-	startOSUploadTime := time.Now().UnixMicro()
-	for i, photo := range vfs {
-		// Upload main photo
-		mainFname := fmt.Sprintf("record_id_%s_photo_id_%s", record.RecordID, photo.ID)
-
-		// Decode the main photo
-		decodedLen := base64.StdEncoding.DecodedLen(len(photo.CitizenVehiclePhoto))
-		b := make([]byte, decodedLen)
-		n, err := base64.StdEncoding.Decode(b, []byte(photo.CitizenVehiclePhoto))
-		if err != nil {
-			lg.Error("failed to decode citizen photo", "error", err.Error())
-			return createdRecord, err
-		}
-		// Use only the actual decoded bytes
-		b = b[:n]
-
-		rs := bytes.NewReader(b)
-		err = s.fr.UploadPublicFile(ctx, s.env.MinioVehicleRecordsBucket,
-			mainFname,
-			"application/octet-stream", rs)
-		if err != nil {
-			cleanup()
-			lg.Error("failed to upload picture", slog.Any("error", err))
-			return nil, err
-		}
-		uploadedFiles = append(uploadedFiles, mainFname)
-		vfs[i].CitizenVehiclePhoto = fmt.Sprintf("%s/%s", s.env.MinioVehicleRecordsBucket, mainFname)
-
-		// Decode the crop photo
-		decodedLen = base64.StdEncoding.DecodedLen(len(photo.CitizenVehiclePlateCropPhoto))
-		c := make([]byte, decodedLen)
-		n, err = base64.StdEncoding.Decode(c, []byte(photo.CitizenVehiclePlateCropPhoto))
-		if err != nil {
-			lg.Error("failed to decode citizen photo", "error", err.Error())
-			return createdRecord, err
-		}
-		// Use only the actual decoded bytes
-		c = c[:n]
-
-		// Upload crop photo
-		cropFname := fmt.Sprintf("record_id_%s_crop_photo_id_%s", record.RecordID, photo.ID)
-		rs = bytes.NewReader(c)
-		err = s.fr.UploadPublicFile(ctx, s.env.MinioVehicleRecordsBucket,
-			cropFname,
-			"application/octet-stream", rs)
-		if err != nil {
-			cleanup()
-			lg.Error("failed to upload picture", slog.Any("error", err))
-			return nil, err
-		}
-		uploadedFiles = append(uploadedFiles, cropFname)
-		vfs[i].CitizenVehiclePlateCropPhoto = fmt.Sprintf("%s/%s", s.env.MinioVehicleRecordsBucket, cropFname)
-	}
-	lg.Info("uploaded files", "duration", time.Since(time.UnixMicro(startOSUploadTime)))
-	endOsUploadTime := time.Now().UnixMicro()
-	s.osDuration.Record(ctx, endOsUploadTime-startOSUploadTime)
-	if len(vfs) > 0 {
-		if err := s.photoRepo.BulkCreate(ctx, vfs); err != nil {
-			cleanup()
-			return nil, err
-		}
-		// Collect created photo IDs for potential cleanup
-		for _, photo := range vfs {
-			createdPhotoIDs = append(createdPhotoIDs, photo.ID)
-		}
-	}
-	vfs = nil
-	createdRecord.VehiclePhotos = bts
-
-	sherr := s.sendToShardari(ctx, createdRecord)
-	if sherr == nil || errors.Is(sherr, ErrFailedUpdate) {
-		lg.Info("record created", "recordID", record.RecordID)
-		return createdRecord, nil
-	}
-	lg.Warn("failed to send to shardari retrying...", "error", sherr.Error(), "recordID", createdRecord.RecordID)
-	if err := s.sendToRetryQueue(ctx, createdRecord, lg); err != nil {
-		return nil, err
-	}
-
-	lg.Info("record sent for retrying", "recordID", createdRecord.RecordID)
-	endProcessDuration := time.Now().UnixMicro()
-	s.osDuration.Record(ctx, endProcessDuration-startProcessDuration)
+	s.processDuration.Record(ctx, time.Since(tStart).Microseconds())
+	lg.Info("record created", "recordID", record.RecordID)
 	return createdRecord, nil
 }
 
-func (s *VehicleRecordService) Retry(ctx context.Context, createdRecord *entity.VehicleRecord) error {
-	lg := s.logger.With("method", "Retry")
-	// check if its not in the next day and it's not behind the back of time
-	now := time.Now()
-	if now.Day() < time.Unix(createdRecord.CreatedAt, 0).Day() {
-		return nil
+// reviewReasons lists the quality rules a record fails, a record failing any
+// rule is held for review instead of being forwarded
+func (s *VehicleRecordService) reviewReasons(record *entity.VehicleRecord, invalidPlate bool) []string {
+	var reasons []string
+	if invalidPlate {
+		reasons = append(reasons, entity.ReviewReasonInvalidPlate)
 	}
-	if now.Unix() < createdRecord.BackoffTime {
-		err := s.sendToRetryQueue(ctx, createdRecord, lg)
-		return err
+	if record.OCRAccuracy < s.env.ReviewMinOCRAccuracy {
+		reasons = append(reasons, entity.ReviewReasonLowOCRAccuracy)
 	}
-
-	createdRecord.Retries++
-	createdRecord.BackoffTime = now.Add(time.Duration(createdRecord.Retries) * (time.Second * 2)).Unix()
-
-	if _, err := s.recordRepo.IncreaseRetry(ctx, createdRecord); err != nil {
-		s.logger.Error("failed to increase retry", "error", err.Error())
-		return err
-	}
-	sherr := s.sendToShardari(ctx, createdRecord)
-	if sherr == nil || errors.Is(sherr, ErrFailedUpdate) {
-		return nil
+	if s.env.ReviewDistorted && record.IsCitizenVehicleDistorted {
+		reasons = append(reasons, entity.ReviewReasonDistorted)
 	}
-	if err := s.sendToRetryQueue(ctx, createdRecord, lg); err != nil {
-		return err
+	if s.env.ReviewPlateNotVisible && !record.IsCitizenVehiclePlateNumberVisible {
+		reasons = append(reasons, entity.ReviewReasonPlateNotVisible)
 	}
-	lg.Info("record sent for retrying", "recordID", createdRecord.RecordID,
-		"backoffTime", createdRecord.BackoffTime, "retry", createdRecord.Retries)
-	return nil
+	return reasons
 }
 
-func (s *VehicleRecordService) sendToRetryQueue(ctx context.Context, createdRecord *entity.VehicleRecord, lg *slog.Logger) error {
-	b, err := json.Marshal(&createdRecord)
-	if err != nil {
-		lg.Error("failed to marshal created record", "error", err)
-		return err
-	}
-	if err = s.rbt.RCH.PublishWithContext(ctx, s.env.RabbitMQInternalExchange, "farin.vehicles.drivers.event.retry",
-		false, false, amqp091.Publishing{
-			ContentType: "application/json",
-			Body:        b,
-		}); err != nil {
-		lg.Error("failed to send to rabbitmq retry queue", "error", err.Error())
+// Retry hands a record of the retry queue over to the outbox, the queue is
+// only drained since new records are retried from the outbox
+func (s *VehicleRecordService) Retry(ctx context.Context, createdRecord *entity.VehicleRecord) error {
+	lg := s.logger.With("method", "Retry")
+	if err := s.outboxRepo.Enqueue(ctx, &entity.RecordOutbox{
+		RecordID:      createdRecord.RecordID,
+		Status:        entity.RecordOutboxPendingSend,
+		NextAttemptAt: time.Now().UnixMilli(),
+	}); err != nil {
+		lg.Error("failed to add record to outbox", "error", err.Error(), "recordID", createdRecord.RecordID)
 		return err
 	}
+	s.wakeOutbox()
 	return nil
 }
 
-func (s *VehicleRecordService) sendToShardari(ctx context.Context, createdRecord *entity.VehicleRecord) error {
-	startTime := time.Now().UnixMicro()
-	lg := s.logger.With("method", "sendToShardari")
+// forward sends the record to the municipality forwarder of its ring and
+// adds the attempt to the forwarding ledger
+func (s *VehicleRecordService) forward(ctx context.Context, createdRecord *entity.VehicleRecord) error {
+	start := time.Now()
+	startTime := start.UnixMicro()
+	forwarder := s.forwarders.For(createdRecord)
+	lg := s.logger.With("method", "forward", "forwarder", forwarder.Name())
 	defer func() {
-		lg.Info("send to shardari", "duration", time.Since(time.UnixMicro(startTime)))
+		lg.Info("forward record", "duration", time.Since(time.UnixMicro(startTime)))
 	}()
 
-	requestID, plateDetectionID, err := s.tehranSiteRepo.SendVehicleRecord(ctx, createdRecord)
-	lg.Info("send to tehran.ir", "requestID", requestID, "plateDetectionID", plateDetectionID)
+	result, err := forwarder.SendVehicleRecord(ctx, createdRecord)
+	if errors.Is(err, repository.ErrForwardUnavailable) {
+		// nothing was sent, the record stays in the backlog
+		s.shardariRejected.Add(ctx, 1, metric.WithAttributes(attribute.String("forwarder", forwarder.Name())))
+		return err
+	}
+	s.recordAttempt(ctx, forwarder.Name(), createdRecord.RecordID, start, result, err)
+	lg.Info("record forwarded", "requestID", result.RequestID, "plateDetectionID", result.PlateDetectionID)
 	if err != nil {
-		lg.Error("failed to send vehicle record to tehran.ir", "error", err.Error())
+		lg.Error("failed to forward vehicle record", "error", err.Error())
 		createdRecord.Sent = false
 		if _, err := s.recordRepo.Update(ctx, createdRecord); err != nil {
 			lg.Error("failed to update record", "error", err.Error())
@@ -296,8 +257,8 @@ func (s *VehicleRecordService) sendToShardari(ctx context.Context, createdRecord
 		return err
 	} else {
 		createdRecord.Sent = true
-		createdRecord.TehranRequestID = requestID
-		createdRecord.PlateDetectionID = plateDetectionID
+		createdRecord.TehranRequestID = result.RequestID
+		createdRecord.PlateDetectionID = result.PlateDetectionID
 		if _, err := s.recordRepo.Update(ctx, createdRecord); err != nil {
 			lg.Error("failed to update record", "error", err.Error())
 			return ErrFailedUpdate
@@ -309,6 +270,51 @@ func (s *VehicleRecordService) sendToShardari(ctx context.Context, createdRecord
 	return nil
 }
 
+// recordAttempt adds a forwarding attempt to the ledger, the ledger is only
+// for operations so failing to write it does not fail the delivery
+func (s *VehicleRecordService) recordAttempt(ctx context.Context, forwarder, recordID string, start time.Time,
+	result *repository.ForwardResult, sendErr error) {
+	attempt := &entity.ForwardingAttempt{
+		RecordID:         recordID,
+		Forwarder:        forwarder,
+		AttemptedAt:      start.UnixMilli(),
+		StatusCode:       result.StatusCode,
+		ResponseBody:     result.ResponseBody,
+		LatencyMs:        time.Since(start).Milliseconds(),
+		Success:          sendErr == nil,
+		RequestID:        result.RequestID,
+		PlateDetectionID: result.PlateDetectionID,
+	}
+	if sendErr != nil {
+		attempt.ErrorClass = forwardingErrorClass(sendErr)
+		attempt.Error = sendErr.Error()
+	}
+	if err := s.attemptRepo.Create(context.WithoutCancel(ctx), attempt); err != nil {
+		s.logger.Error("failed to record forwarding attempt", "error", err.Error(), "recordID", recordID)
+	}
+}
+
+// forwardingErrorClass tells why a forwarder failed, timeouts are told apart
+// from other transport errors
+func forwardingErrorClass(err error) string {
+	var netErr net.Error
+	switch {
+	case errors.Is(err, context.DeadlineExceeded), errors.As(err, &netErr) && netErr.Timeout():
+		return entity.ForwardingErrorTimeout
+	case errors.Is(err, repository.ErrForwardAuth):
+		return entity.ForwardingErrorAuth
+	case errors.Is(err, repository.ErrForwardRequest):
+		return entity.ForwardingErrorRequest
+	case errors.Is(err, repository.ErrForwardTransport):
+		return entity.ForwardingErrorTransport
+	case errors.Is(err, repository.ErrForwardStatus):
+		return entity.ForwardingErrorStatus
+	case errors.Is(err, repository.ErrForwardResponse):
+		return entity.ForwardingErrorResponse
+	}
+	return entity.ForwardingErrorUnknown
+}
+
 func (s *VehicleRecordService) FindResend(ctx context.Context, fromDate int64, limit int) error {
 	lg := s.logger.With("method", "FindResend")
 	records, err := s.recordRepo.GetNotSent(ctx, fromDate, limit)
@@ -317,134 +323,79 @@ func (s *VehicleRecordService) FindResend(ctx context.Context, fromDate int64, l
 		return nil
 	}
 
-	for i, record := range records {
-		for k, vf := range record.VehiclePhotos {
-			strs := strings.Split(vf.CitizenVehiclePhoto, "/")
-			bt, err := downloadDecode(ctx, s, strs, lg)
-			if err != nil {
-				lg.Warn("failed to download decode for CitizenVehiclePhoto", "error", err.Error())
-				break
-			}
-			records[i].VehiclePhotos[k].CitizenVehiclePhoto = string(bt)
-
-			strs = strings.Split(vf.CitizenVehiclePlateCropPhoto, "/")
-			bt, err = downloadDecode(ctx, s, strs, lg)
-			if err != nil {
-				lg.Warn("failed to download decode for CitizenVehiclePlateCropPhoto", "error", err.Error())
-				break
-			}
-			records[i].VehiclePhotos[k].CitizenVehiclePlateCropPhoto = string(bt)
+	for i := range records {
+		// tehran.ir takes the photos as base64
+		if err := s.loadPhotos(ctx, &records[i]); err != nil {
+			lg.Warn("failed to load photos", "error", err.Error(), "recordID", records[i].RecordID)
+			continue
 		}
-		if err := s.sendToShardari(ctx, &record); err != nil {
-			lg.Warn("failed to send to shardari", "error", err.Error())
+		if err := s.forward(ctx, &records[i]); err != nil {
+			lg.Warn("failed to forward record", "error", err.Error())
 			continue
 		}
 	}
 	return nil
 }
 
-func (s *VehicleRecordService) Detail(ctx context.Context, id string) (*entity.VehicleRecord, error) {
-	lg := s.logger.With("method", "Detail")
-	record, err := s.recordRepo.GetByID(ctx, id)
+// DedupStats counts the records created in [from, to) and how many of them
+// were merged into an earlier sighting
+func (s *VehicleRecordService) DedupStats(ctx context.Context, from, to int64) (*repository.DedupStats, error) {
+	lg := s.logger.With("method", "DedupStats")
+	stats, err := s.recordRepo.DedupStats(ctx, from, to)
 	if err != nil {
-		lg.Error("failed to fetch records", "error", err.Error())
+		lg.Error("failed to count records", "error", err.Error())
 		return nil, err
 	}
+	return stats, nil
+}
 
-	for k, vf := range record.VehiclePhotos {
-		strs := strings.Split(vf.CitizenVehiclePhoto, "/")
-		bt, err := downloadDecode(ctx, s, strs, lg)
-		if err != nil {
-			return nil, err
-		}
-		record.VehiclePhotos[k].CitizenVehiclePhoto = string(bt)
-
-		strs = strings.Split(vf.CitizenVehiclePlateCropPhoto, "/")
-		bt, err = downloadDecode(ctx, s, strs, lg)
-		if err != nil {
-			return nil, err
-		}
-		record.VehiclePhotos[k].CitizenVehiclePlateCropPhoto = string(bt)
+// Duplicates lists the sightings merged into a record, it fails with
+// repository.ErrVehicleRecordNotFound for unknown records
+func (s *VehicleRecordService) Duplicates(ctx context.Context, recordID string, page, pageSize int) (
+	[]entity.VehicleRecord, int64, error) {
+	lg := s.logger.With("method", "Duplicates")
+	if _, err := s.recordRepo.GetByID(ctx, recordID); err != nil {
+		return nil, 0, err
 	}
-
-	return record, nil
+	records, total, err := s.recordRepo.ListDuplicates(ctx, recordID, page, pageSize)
+	if err != nil {
+		lg.Error("failed to list duplicates", "error", err.Error(), "recordID", recordID)
+		return nil, 0, err
+	}
+	return records, total, nil
 }
 
-func downloadDecode(ctx context.Context, s *VehicleRecordService, strs []string, lg *slog.Logger) ([]byte, error) {
-	obj, err := s.minio.M.GetObject(ctx, strs[0], strs[1], minio2.GetObjectOptions{})
+// Detail gets a record with its photos as base64 contents or as presigned
+// URLs, as mode tells
+func (s *VehicleRecordService) Detail(ctx context.Context, id string, mode PhotoMode) (*entity.VehicleRecord, error) {
+	lg := s.logger.With("method", "Detail")
+	record, err := s.recordRepo.GetByID(ctx, id)
 	if err != nil {
-		lg.Error("Failed to get object", "error", err.Error())
+		lg.Error("failed to fetch records", "error", err.Error())
 		return nil, err
 	}
-	bf := bytes.NewBuffer([]byte{})
-	_, err = io.Copy(bf, obj)
+
+	if mode == PhotoModeURL {
+		err = s.photos.SignRecord(ctx, record)
+	} else {
+		err = s.loadPhotos(ctx, record)
+	}
 	if err != nil {
-		lg.Error("Failed to read object", "error", err.Error())
+		lg.Error("failed to load photos", "error", err.Error(), "recordID", id)
 		return nil, err
 	}
-	// Calculate the maximum decoded size
-	decodedData := bf.Bytes()
-	encodedSize := base64.StdEncoding.EncodedLen(len(decodedData))
-
-	// Allocate the destination buffer with the correct size
-	encodedData := make([]byte, encodedSize)
-
-	// Decode and get the actual size
-	base64.StdEncoding.Encode(encodedData, decodedData)
-
-	return encodedData, nil
+	return record, nil
 }
 
-func plateReplaceAlphabets(input string) (int, error) {
-	// Define a map of Persian characters to their corresponding numbers
-	replacements := map[string]string{
-		"ب":   "02",
-		"ج":   "06",
-		"ح":   "08",
-		"خ":   "09",
-		"د":   "10",
-		"ذ":   "11",
-		"ر":   "12",
-		"س":   "15",
-		"ص":   "17",
-		"ض":   "18",
-		"ط":   "19",
-		"ظ":   "20",
-		"غ":   "22",
-		"ق":   "24",
-		"ل":   "27",
-		"م":   "28",
-		"ن":   "29",
-		"و":   "30",
-		"هـ":  "31",
-		"ه":   "31",
-		"ی":   "32",
-		"الف": "01",
-		"ا":   "01",
-		"ژ":   "14",
-		"چ":   "07",
-		"پ":   "03",
-		"ت":   "04",
-		"ث":   "05",
-		"ز":   "13",
-		"ش":   "16",
-		"ع":   "21",
-		"ف":   "23",
-		"ک":   "25",
-		"گ":   "26",
-		"D":   "33",
-		"S":   "34",
-	}
-
-	result := input
-
-	// For each Persian character in the map, replace it with its corresponding number
-	for persianChar, number := range replacements {
-		if strings.Contains(result, persianChar) {
-			result = strings.ReplaceAll(result, persianChar, "")
-			result = result[0:2] + number + result[2:]
-		}
+// downloadDecode reads a stored photo as base64
+func downloadDecode(ctx context.Context, s *VehicleRecordService, path string, lg *slog.Logger) ([]byte, error) {
+	decodedData, err := download(ctx, s.minio, path)
+	if err != nil {
+		lg.Error("Failed to get object", "error", err.Error(), "path", path)
+		return nil, err
 	}
+	encodedData := make([]byte, base64.StdEncoding.EncodedLen(len(decodedData)))
+	base64.StdEncoding.Encode(encodedData, decodedData)
 
-	return strconv.Atoi(result)
+	return encodedData, nil
 }
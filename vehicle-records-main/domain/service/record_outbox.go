@@ -0,0 +1,285 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"git.abanppc.com/farin-project/vehicle-records/domain/entity"
+	"git.abanppc.com/farin-project/vehicle-records/domain/repository"
+	"golang.org/x/sync/errgroup"
+)
+
+// RunOutbox starts OutboxWorkers dispatchers that deliver the outbox until
+// ctx is done
+func (s *VehicleRecordService) RunOutbox(ctx context.Context) {
+	for i := 0; i < s.env.OutboxWorkers; i++ {
+		go s.runOutboxWorker(ctx)
+	}
+}
+
+func (s *VehicleRecordService) runOutboxWorker(ctx context.Context) {
+	ticker := time.NewTicker(s.env.OutboxPollInterval)
+	defer ticker.Stop()
+
+	for {
+		// a full batch means more entries may be due
+		if s.DispatchOutbox(ctx, time.Now()) == s.env.OutboxBatchSize {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-s.outboxWake:
+		}
+	}
+}
+
+// wakeOutbox lets an idle dispatcher pick up a new entry without waiting for
+// the next poll
+func (s *VehicleRecordService) wakeOutbox() {
+	select {
+	case s.outboxWake <- struct{}{}:
+	default:
+	}
+}
+
+// DispatchOutbox claims a batch of due outbox entries and delivers them,
+// it returns the number of claimed entries
+func (s *VehicleRecordService) DispatchOutbox(ctx context.Context, now time.Time) int {
+	lg := s.logger.With("method", "DispatchOutbox")
+
+	entries, err := s.outboxRepo.Claim(ctx, now.UnixMilli(), now.Add(s.outboxLease()).UnixMilli(),
+		s.env.OutboxBatchSize)
+	if err != nil {
+		lg.Error("failed to claim outbox entries", "error", err.Error())
+		return 0
+	}
+
+	s.dispatchLeased(ctx, entries, s.outboxRepo.Renew, s.dispatch)
+	return len(entries)
+}
+
+// dispatchLeased dispatches the claimed entries one by one, renewing the lease
+// of every entry right before it so the batch never outlives its claim. An
+// entry whose lease ran out and was claimed by another dispatcher is skipped.
+func (s *VehicleRecordService) dispatchLeased(ctx context.Context, entries []entity.RecordOutbox,
+	renew func(ctx context.Context, id string, heldUntil, lockedUntil int64) error,
+	dispatch func(ctx context.Context, entry *entity.RecordOutbox) error) {
+	lg := s.logger.With("method", "DispatchOutbox")
+
+	for i := range entries {
+		entry := &entries[i]
+		lockedUntil := time.Now().Add(s.outboxLease()).UnixMilli()
+		if err := renew(ctx, entry.ID, entry.LockedUntil, lockedUntil); err != nil {
+			if errors.Is(err, repository.ErrRecordOutboxConflict) {
+				lg.Warn("outbox entry was taken over", "recordID", entry.RecordID, "lockedUntil", entry.LockedUntil)
+			} else {
+				lg.Error("failed to renew outbox lease", "error", err.Error(), "recordID", entry.RecordID)
+			}
+			continue
+		}
+		entry.LockedUntil = lockedUntil
+
+		if err := dispatch(ctx, entry); err != nil {
+			lg.Warn("failed to dispatch outbox entry", "error", err.Error(), "recordID", entry.RecordID,
+				"status", entry.Status, "attempts", entry.Attempts)
+		}
+	}
+}
+
+// outboxLease is OutboxLease, but at least twice the time one forward may take
+func (s *VehicleRecordService) outboxLease() time.Duration {
+	return max(s.env.OutboxLease, 2*s.env.TehranTimeout)
+}
+
+// dispatch moves an entry forward. Every step can be repeated: photos are
+// uploaded under names fixed at creation and only missing photo rows are
+// inserted, status changes only apply to an entry still in the expected
+// status.
+func (s *VehicleRecordService) dispatch(ctx context.Context, entry *entity.RecordOutbox) error {
+	record, err := s.recordRepo.GetByID(ctx, entry.RecordID)
+	if err != nil {
+		if errors.Is(err, repository.ErrVehicleRecordNotFound) {
+			return s.outboxFailed(ctx, entry, err, true)
+		}
+		return s.outboxFailed(ctx, entry, err, false)
+	}
+	if record.DuplicateOf != nil {
+		// the sighting is forwarded as the record it was merged into
+		return s.outboxRepo.Transition(ctx, entry.ID, entry.Status, entity.RecordOutboxSuppressed,
+			map[string]interface{}{"photos": nil, "locked_until": 0, "last_error": ""})
+	}
+
+	switch entry.Status {
+	case entity.RecordOutboxPendingUpload:
+		var photos []*entity.CitizenVehiclePhoto
+		if err := json.Unmarshal(entry.Photos, &photos); err != nil {
+			return s.outboxFailed(ctx, entry, fmt.Errorf("invalid outbox photos: %w", err), true)
+		}
+		if err := s.uploadPhotos(ctx, record.RecordID, photos); err != nil {
+			return s.outboxFailed(ctx, entry, err, false)
+		}
+		held, err := s.outboxRepo.Uploaded(ctx, entry.ID)
+		if err != nil {
+			return err
+		}
+		if held {
+			// the review of the record releases the entry
+			return nil
+		}
+		entry.Status = entity.RecordOutboxPendingSend
+		entry.Attempts = 0
+		record.VehiclePhotos = photos
+	case entity.RecordOutboxPendingSend:
+		if err := s.loadPhotos(ctx, record); err != nil {
+			return s.outboxFailed(ctx, entry, err, false)
+		}
+	default:
+		return nil
+	}
+
+	sherr := s.forward(ctx, record)
+	if errors.Is(sherr, repository.ErrForwardUnavailable) {
+		return s.outboxDeferred(ctx, entry, sherr)
+	}
+	if sherr != nil && !errors.Is(sherr, ErrFailedUpdate) {
+		return s.outboxFailed(ctx, entry, sherr, false)
+	}
+	return s.outboxRepo.Transition(ctx, entry.ID, entity.RecordOutboxPendingSend, entity.RecordOutboxSent,
+		map[string]interface{}{"locked_until": 0, "last_error": ""})
+}
+
+// uploadPhotos stores the photos and their plate crops in object storage and
+// inserts the photo rows, the base64 contents of photos are kept for sending.
+// Up to UploadRecordConcurrency objects of the record are uploaded at once,
+// the first failure cancels the others.
+func (s *VehicleRecordService) uploadPhotos(ctx context.Context, recordID string,
+	photos []*entity.CitizenVehiclePhoto) error {
+	lg := s.logger.With("method", "uploadPhotos")
+	startOSUploadTime := time.Now()
+	bucket := s.env.MinioVehicleRecordsBucket
+
+	g, gctx := errgroup.WithContext(ctx)
+	g.SetLimit(s.env.UploadRecordConcurrency)
+	upload := func(fname, content string) {
+		g.Go(func() error {
+			_, err := s.photos.Upload(gctx, bucket, fname, content)
+			return err
+		})
+	}
+
+	stored := make([]*entity.CitizenVehiclePhoto, len(photos))
+	for i, photo := range photos {
+		mainFname, cropFname := photoObjects(recordID, photo.ID)
+		upload(mainFname, photo.CitizenVehiclePhoto)
+		upload(cropFname, photo.CitizenVehiclePlateCropPhoto)
+
+		sp := *photo
+		sp.RecordID = recordID
+		sp.CitizenVehiclePhoto = fmt.Sprintf("%s/%s", bucket, mainFname)
+		sp.CitizenVehiclePlateCropPhoto = fmt.Sprintf("%s/%s", bucket, cropFname)
+		stored[i] = &sp
+	}
+	if err := g.Wait(); err != nil {
+		lg.Error("failed to upload picture", "error", err.Error(), "recordID", recordID)
+		return err
+	}
+	lg.Info("uploaded files", "duration", time.Since(startOSUploadTime))
+	s.osDuration.Record(ctx, time.Since(startOSUploadTime).Microseconds())
+
+	if len(stored) > 0 {
+		if err := s.photoRepo.BulkCreateMissing(ctx, stored); err != nil {
+			lg.Error("failed to create photos", "error", err.Error(), "recordID", recordID)
+			return err
+		}
+	}
+	return nil
+}
+
+// photoObjects returns the object names a photo and its plate crop are
+// uploaded under
+func photoObjects(recordID, photoID string) (string, string) {
+	return fmt.Sprintf("record_id_%s_photo_id_%s", recordID, photoID),
+		fmt.Sprintf("record_id_%s_crop_photo_id_%s", recordID, photoID)
+}
+
+// loadPhotos replaces the object storage paths of the record photos with
+// their base64 contents, reaped photos are left empty
+func (s *VehicleRecordService) loadPhotos(ctx context.Context, record *entity.VehicleRecord) error {
+	lg := s.logger.With("method", "loadPhotos")
+	for _, vf := range record.VehiclePhotos {
+		if vf.CitizenVehiclePhoto == "" {
+			continue
+		}
+		bt, err := downloadDecode(ctx, s, vf.CitizenVehiclePhoto, lg)
+		if err != nil {
+			return err
+		}
+		vf.CitizenVehiclePhoto = string(bt)
+
+		bt, err = downloadDecode(ctx, s, vf.CitizenVehiclePlateCropPhoto, lg)
+		if err != nil {
+			return err
+		}
+		vf.CitizenVehiclePlateCropPhoto = string(bt)
+	}
+	return nil
+}
+
+// outboxFailed schedules the next attempt of an entry with an exponential
+// backoff, the entry fails for good when permanent is set or it ran out of
+// attempts
+func (s *VehicleRecordService) outboxFailed(ctx context.Context, entry *entity.RecordOutbox, cause error,
+	permanent bool) error {
+	attempts := entry.Attempts + 1
+	to := entry.Status
+	if permanent || attempts >= s.env.OutboxMaxAttempts {
+		to = entity.RecordOutboxFailed
+	}
+
+	err := s.outboxRepo.Transition(ctx, entry.ID, entry.Status, to, map[string]interface{}{
+		"attempts":        attempts,
+		"next_attempt_at": time.Now().Add(outboxBackoff(attempts, s.env.OutboxRetryBackoff, s.env.OutboxRetryMax)).UnixMilli(),
+		"locked_until":    0,
+		"last_error":      cause.Error(),
+	})
+	if err != nil {
+		return errors.Join(cause, err)
+	}
+	if to == entity.RecordOutboxFailed {
+		s.logger.Error("outbox entry failed", "error", cause.Error(), "recordID", entry.RecordID,
+			"attempts", attempts)
+	}
+	return cause
+}
+
+// outboxDeferred puts an entry back until the forwarder is expected to be
+// available again, without counting an attempt
+func (s *VehicleRecordService) outboxDeferred(ctx context.Context, entry *entity.RecordOutbox, cause error) error {
+	err := s.outboxRepo.Transition(ctx, entry.ID, entry.Status, entry.Status, map[string]interface{}{
+		"next_attempt_at": time.Now().Add(s.env.TehranBreakerOpen).UnixMilli(),
+		"locked_until":    0,
+		"last_error":      cause.Error(),
+	})
+	if err != nil {
+		return errors.Join(cause, err)
+	}
+	return nil
+}
+
+// outboxBackoff doubles base for every attempt after the first, up to max
+func outboxBackoff(attempts int, base, max time.Duration) time.Duration {
+	d := base
+	for i := 1; i < attempts && d < max; i++ {
+		d *= 2
+	}
+	if d > max {
+		return max
+	}
+	return d
+}
@@ -0,0 +1,65 @@
+package service
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"reflect"
+	"testing"
+	"time"
+
+	"git.abanppc.com/farin-project/vehicle-records/domain/entity"
+	"git.abanppc.com/farin-project/vehicle-records/domain/repository"
+	"git.abanppc.com/farin-project/vehicle-records/infrastructure/godotenv"
+)
+
+func TestDispatchLeasedSkipsTakenOverEntries(t *testing.T) {
+	s := &VehicleRecordService{
+		logger: slog.New(slog.NewTextHandler(io.Discard, nil)),
+		env:    &godotenv.Env{OutboxLease: time.Minute, TehranTimeout: 50 * time.Second},
+	}
+	claimed := time.Now().Add(time.Minute).UnixMilli()
+	entries := []entity.RecordOutbox{
+		{Base: entity.Base{ID: "a"}, RecordID: "record-a", LockedUntil: claimed},
+		{Base: entity.Base{ID: "b"}, RecordID: "record-b", LockedUntil: claimed},
+		{Base: entity.Base{ID: "c"}, RecordID: "record-c", LockedUntil: claimed},
+	}
+
+	// leases holds locked_until of the entries as the database has it
+	leases := map[string]int64{"a": claimed, "b": claimed, "c": claimed}
+	renew := func(_ context.Context, id string, heldUntil, lockedUntil int64) error {
+		if leases[id] != heldUntil {
+			return repository.ErrRecordOutboxConflict
+		}
+		leases[id] = lockedUntil
+		return nil
+	}
+
+	var dispatched []string
+	var start time.Time
+	dispatch := func(_ context.Context, entry *entity.RecordOutbox) error {
+		dispatched = append(dispatched, entry.ID)
+		if leases[entry.ID] != entry.LockedUntil {
+			t.Errorf("entry %s dispatched with lease %d, database has %d", entry.ID, entry.LockedUntil,
+				leases[entry.ID])
+		}
+		if entry.LockedUntil < start.Add(100*time.Second).UnixMilli() {
+			t.Errorf("entry %s leased until %d, want at least twice the tehran timeout", entry.ID, entry.LockedUntil)
+		}
+		if entry.ID == "a" {
+			// the forward of a outlasted the claim, another dispatcher
+			// claimed b after its lease expired
+			leases["b"] = claimed + time.Hour.Milliseconds()
+		}
+		return nil
+	}
+
+	start = time.Now()
+	s.dispatchLeased(context.Background(), entries, renew, dispatch)
+	if want := []string{"a", "c"}; !reflect.DeepEqual(dispatched, want) {
+		t.Errorf("dispatched %v, want %v", dispatched, want)
+	}
+	if leases["b"] != claimed+time.Hour.Milliseconds() {
+		t.Errorf("lease of the taken over entry changed to %d", leases["b"])
+	}
+}
@@ -0,0 +1,123 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"git.abanppc.com/farin-project/vehicle-records/domain/entity"
+	"git.abanppc.com/farin-project/vehicle-records/domain/repository"
+	"git.abanppc.com/farin-project/vehicle-records/util/plate"
+)
+
+// ErrReviewPlateInvalid is returned when a record is approved before its plate
+// was corrected into a valid plate
+var ErrReviewPlateInvalid = errors.New("plate of the record is invalid")
+
+type RecordReviewService struct {
+	logger     *slog.Logger
+	reviewRepo *repository.RecordReviewRepository
+	records    *VehicleRecordService
+	photos     *PhotoService
+}
+
+func NewRecordReviewService(logger *slog.Logger, reviewRepo *repository.RecordReviewRepository,
+	records *VehicleRecordService, photos *PhotoService) *RecordReviewService {
+	return &RecordReviewService{
+		logger:     logger.With("layer", "RecordReviewService"),
+		reviewRepo: reviewRepo,
+		records:    records,
+		photos:     photos,
+	}
+}
+
+// List lists the reviews in status, the photos of their records are replaced
+// with temporary URLs
+func (s *RecordReviewService) List(ctx context.Context, status entity.RecordReviewStatus, page, pageSize int) (
+	[]entity.RecordReview, int64, error) {
+	lg := s.logger.With("method", "List")
+	reviews, total, err := s.reviewRepo.List(ctx, status, page, pageSize)
+	if err != nil {
+		lg.Error("failed to list record reviews", "error", err.Error())
+		return nil, 0, err
+	}
+	for i := range reviews {
+		if err := s.photoURLs(ctx, &reviews[i]); err != nil {
+			lg.Error("failed to sign photo urls", "error", err.Error(), "reviewID", reviews[i].ID)
+			return nil, 0, err
+		}
+	}
+	return reviews, total, nil
+}
+
+// Get gets a review with its audit trail, the photos of its record are
+// replaced with temporary URLs
+func (s *RecordReviewService) Get(ctx context.Context, id string) (*entity.RecordReview, error) {
+	lg := s.logger.With("method", "Get")
+	review, err := s.reviewRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.photoURLs(ctx, review); err != nil {
+		lg.Error("failed to sign photo urls", "error", err.Error(), "reviewID", id)
+		return nil, err
+	}
+	return review, nil
+}
+
+// Correct replaces the plate of the record under review, it fails with
+// plate.ErrInvalidPlate when the new plate is invalid
+func (s *RecordReviewService) Correct(ctx context.Context, id string, reviewer entity.Reviewer, plateNumber,
+	note string) error {
+	lg := s.logger.With("method", "Correct")
+	p, err := plate.Parse(plateNumber)
+	if err != nil {
+		return err
+	}
+	if err := s.reviewRepo.Correct(ctx, id, reviewer, p.String(), p.Numeric(), note); err != nil {
+		lg.Warn("failed to correct plate", "error", err.Error(), "reviewID", id, "reviewer", reviewer.Name)
+		return err
+	}
+	lg.Info("plate corrected", "reviewID", id, "reviewer", reviewer.Name, "plate", p.String())
+	return nil
+}
+
+// Approve releases the record under review into the forwarding path, it
+// fails with ErrReviewPlateInvalid while the plate of the record is invalid
+func (s *RecordReviewService) Approve(ctx context.Context, id string, reviewer entity.Reviewer, note string) error {
+	lg := s.logger.With("method", "Approve")
+	err := s.reviewRepo.Approve(ctx, id, reviewer, note, time.Now().UnixMilli(), func(plateNumber string) error {
+		if _, err := plate.Parse(plateNumber); err != nil {
+			return ErrReviewPlateInvalid
+		}
+		return nil
+	})
+	if err != nil {
+		lg.Warn("failed to approve record", "error", err.Error(), "reviewID", id, "reviewer", reviewer.Name)
+		return err
+	}
+	s.records.wakeOutbox()
+	lg.Info("record approved", "reviewID", id, "reviewer", reviewer.Name)
+	return nil
+}
+
+// Reject drops the record under review, it is never forwarded
+func (s *RecordReviewService) Reject(ctx context.Context, id string, reviewer entity.Reviewer, note string) error {
+	lg := s.logger.With("method", "Reject")
+	if err := s.reviewRepo.Reject(ctx, id, reviewer, note, time.Now().UnixMilli()); err != nil {
+		lg.Warn("failed to reject record", "error", err.Error(), "reviewID", id, "reviewer", reviewer.Name)
+		return err
+	}
+	lg.Info("record rejected", "reviewID", id, "reviewer", reviewer.Name)
+	return nil
+}
+
+// photoURLs replaces the photos of the review record with presigned URLs,
+// photos still waiting for upload are not listed yet
+func (s *RecordReviewService) photoURLs(ctx context.Context, review *entity.RecordReview) error {
+	if review.VehicleRecord == nil {
+		return nil
+	}
+	return s.photos.SignRecord(ctx, review.VehicleRecord)
+}
@@ -0,0 +1,144 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"git.abanppc.com/farin-project/vehicle-records/domain/entity"
+	"git.abanppc.com/farin-project/vehicle-records/domain/repository"
+)
+
+// resendCheckpointName is the checkpoint of the scheduled resend
+const resendCheckpointName = "scheduled_resend"
+
+// RunResend resends unsent records every ResendInterval until ctx is done.
+// Records that the outbox gave up on or that predate it are picked up here.
+func (s *VehicleRecordService) RunResend(ctx context.Context) {
+	ticker := time.NewTicker(s.env.ResendInterval)
+	defer ticker.Stop()
+
+	for {
+		s.ResendPass(ctx, time.Now())
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// ResendPass scans the unsent records created in the resend window page by
+// page and forwards them, at most ResendRate a second. The position is saved
+// after every page so a restarted scheduler resumes the pass where it stopped.
+func (s *VehicleRecordService) ResendPass(ctx context.Context, now time.Time) {
+	lg := s.logger.With("method", "ResendPass")
+
+	checkpoint, err := s.checkpointRepo.Acquire(ctx, resendCheckpointName, now.UnixMilli(),
+		now.Add(s.resendLease()).UnixMilli())
+	if err != nil {
+		if !errors.Is(err, repository.ErrResendCheckpointLocked) {
+			lg.Error("failed to acquire resend checkpoint", "error", err.Error())
+		}
+		return
+	}
+
+	from := now.Add(-s.env.ResendWindow).UnixMilli()
+	to := now.Add(-s.env.ResendMinAge).UnixMilli()
+	if checkpoint.CursorRecordID == "" {
+		checkpoint.PassStartedAt = now.UnixMilli()
+		lg.Info("resend pass started")
+	} else {
+		lg.Info("resend pass resumed", "cursorCreatedAt", checkpoint.CursorCreatedAt,
+			"cursorRecordID", checkpoint.CursorRecordID)
+	}
+
+	limiter := time.NewTicker(time.Second / time.Duration(s.env.ResendRate))
+	defer limiter.Stop()
+
+	var sent, failed int
+	for {
+		records, err := s.recordRepo.GetNotSentPage(ctx, from, to, checkpoint.CursorCreatedAt,
+			checkpoint.CursorRecordID, s.env.ResendPageSize)
+		if err != nil {
+			lg.Error("failed to fetch unsent records", "error", err.Error())
+			s.saveCheckpoint(ctx, checkpoint, 0)
+			return
+		}
+
+		for i := range records {
+			select {
+			case <-ctx.Done():
+				s.saveCheckpoint(context.WithoutCancel(ctx), checkpoint, 0)
+				return
+			case <-limiter.C:
+			}
+
+			// renew the lease along with the progress, so it outlasts the forward
+			if err := s.saveCheckpoint(ctx, checkpoint, time.Now().Add(s.resendLease()).UnixMilli()); err != nil {
+				return
+			}
+
+			if err := s.resend(ctx, &records[i]); err != nil {
+				if errors.Is(err, repository.ErrForwardUnavailable) {
+					// keep the record for the next pass, resuming from it
+					lg.Warn("resend pass paused", "error", err.Error(), "sent", sent, "failed", failed)
+					s.saveCheckpoint(ctx, checkpoint, 0)
+					return
+				}
+				lg.Warn("failed to resend record", "error", err.Error(), "recordID", records[i].RecordID)
+				failed++
+			} else {
+				sent++
+			}
+			checkpoint.CursorCreatedAt = records[i].CreatedAt
+			checkpoint.CursorRecordID = records[i].RecordID
+		}
+
+		if len(records) < s.env.ResendPageSize {
+			break
+		}
+	}
+
+	lg.Info("resend pass finished", "sent", sent, "failed", failed,
+		"duration", time.Since(time.UnixMilli(checkpoint.PassStartedAt)))
+	checkpoint.CursorCreatedAt = 0
+	checkpoint.CursorRecordID = ""
+	s.saveCheckpoint(ctx, checkpoint, 0)
+}
+
+// saveCheckpoint stores the checkpoint with a lease until lockedUntil, zero
+// releases it. An error means the pass no longer owns the checkpoint.
+func (s *VehicleRecordService) saveCheckpoint(ctx context.Context, checkpoint *entity.ResendCheckpoint,
+	lockedUntil int64) error {
+	heldUntil := checkpoint.LockedUntil
+	checkpoint.LockedUntil = lockedUntil
+	if err := s.checkpointRepo.Save(ctx, checkpoint, heldUntil); err != nil {
+		if errors.Is(err, repository.ErrResendCheckpointLocked) {
+			s.logger.Warn("resend checkpoint was taken over", "lockedUntil", heldUntil)
+		} else {
+			s.logger.Error("failed to save resend checkpoint", "error", err.Error())
+		}
+		checkpoint.LockedUntil = heldUntil
+		return err
+	}
+	return nil
+}
+
+// resendLease is ResendLease, but at least twice the time one forward may take
+func (s *VehicleRecordService) resendLease() time.Duration {
+	return max(s.env.ResendLease, 2*s.env.TehranTimeout)
+}
+
+// resend forwards a stored record, a failed outbox entry of the record is
+// resolved once it is delivered
+func (s *VehicleRecordService) resend(ctx context.Context, record *entity.VehicleRecord) error {
+	if err := s.loadPhotos(ctx, record); err != nil {
+		return err
+	}
+	if err := s.forward(ctx, record); err != nil && !errors.Is(err, ErrFailedUpdate) {
+		return err
+	}
+	return s.outboxRepo.ResolveFailed(ctx, record.RecordID)
+}
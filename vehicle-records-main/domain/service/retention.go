@@ -0,0 +1,200 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"git.abanppc.com/farin-project/vehicle-records/domain/entity"
+	"git.abanppc.com/farin-project/vehicle-records/domain/repository"
+	"git.abanppc.com/farin-project/vehicle-records/infrastructure/godotenv"
+	"github.com/mahdimehrabi/uploader/minio"
+	minio2 "github.com/minio/minio-go/v7"
+)
+
+type RetentionService struct {
+	logger        *slog.Logger
+	env           *godotenv.Env
+	retentionRepo *repository.RetentionRepository
+	recordRepo    *repository.VehicleRecordRepository
+	minio         *minio.Minio
+}
+
+func NewRetentionService(logger *slog.Logger, env *godotenv.Env, retentionRepo *repository.RetentionRepository,
+	recordRepo *repository.VehicleRecordRepository, minio *minio.Minio) *RetentionService {
+	return &RetentionService{
+		logger:        logger.With("layer", "RetentionService"),
+		env:           env,
+		retentionRepo: retentionRepo,
+		recordRepo:    recordRepo,
+		minio:         minio,
+	}
+}
+
+// RunReaper deletes expired photos every RetentionInterval until ctx is done
+func (s *RetentionService) RunReaper(ctx context.Context) {
+	ticker := time.NewTicker(s.env.RetentionInterval)
+	defer ticker.Stop()
+
+	for {
+		s.ReapPass(ctx, time.Now())
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// ReapPass clears the paths of every photo whose retention expired at now,
+// RetentionBatchSize photos at a time, then deletes the objects of reaped
+// photos and adds the pass to the report. Objects are deleted outside the
+// reaping transactions, one that fails to be deleted is retried by the next
+// pass.
+func (s *RetentionService) ReapPass(ctx context.Context, now time.Time) {
+	lg := s.logger.With("method", "ReapPass")
+	run := &entity.RetentionRun{StartedAt: time.Now().UnixMilli()}
+
+	for ctx.Err() == nil {
+		photos, err := s.retentionRepo.Reap(ctx, now.UnixMilli(), s.env.RetentionBatchSize)
+		if err != nil {
+			lg.Error("failed to reap photos", "error", err.Error())
+			run.Errors++
+			break
+		}
+		if len(photos) == 0 {
+			break
+		}
+		run.Photos += len(photos)
+	}
+	s.sweep(ctx, run)
+
+	run.FinishedAt = time.Now().UnixMilli()
+	if run.Photos == 0 && run.Objects == 0 && run.Errors == 0 {
+		return
+	}
+	lg.Info("reaped photos", "photos", run.Photos, "objects", run.Objects, "bytes", run.Bytes,
+		"errors", run.Errors)
+	if err := s.retentionRepo.CreateRun(context.WithoutCancel(ctx), run); err != nil {
+		lg.Error("failed to create retention run", "error", err.Error())
+	}
+}
+
+// sweep deletes the queued objects of reaped photos and counts them in run,
+// an object is held for RetentionInterval so a failed one waits for the next
+// pass
+func (s *RetentionService) sweep(ctx context.Context, run *entity.RetentionRun) {
+	lg := s.logger.With("method", "sweep")
+	for ctx.Err() == nil {
+		now := time.Now()
+		objects, err := s.retentionRepo.ClaimReaped(ctx, now.UnixMilli(),
+			now.Add(s.env.RetentionInterval).UnixMilli(), s.env.RetentionBatchSize)
+		if err != nil {
+			lg.Error("failed to claim reaped objects", "error", err.Error())
+			run.Errors++
+			return
+		}
+		if len(objects) == 0 {
+			return
+		}
+
+		removed := make([]string, 0, len(objects))
+		for _, object := range objects {
+			size, err := s.remove(ctx, object.Path)
+			if err != nil {
+				lg.Error("failed to remove reaped object", "error", err.Error(), "path", object.Path)
+				run.Errors++
+				continue
+			}
+			removed = append(removed, object.ID)
+			run.Objects++
+			run.Bytes += size
+		}
+		if err := s.retentionRepo.DeleteReaped(ctx, removed); err != nil {
+			lg.Error("failed to dequeue reaped objects", "error", err.Error())
+			run.Errors++
+			return
+		}
+	}
+}
+
+// remove deletes a stored photo and returns its size, a photo that is already
+// gone counts as removed
+func (s *RetentionService) remove(ctx context.Context, path string) (int64, error) {
+	bucket, object, err := splitPhotoPath(path)
+	if err != nil {
+		// nothing can be stored under an invalid path, the path is nulled all the same
+		return 0, nil
+	}
+	var size int64
+	info, err := s.minio.M.StatObject(ctx, bucket, object, minio2.StatObjectOptions{})
+	if err == nil {
+		size = info.Size
+	}
+	if err := s.minio.M.RemoveObject(ctx, bucket, object, minio2.RemoveObjectOptions{}); err != nil {
+		return 0, err
+	}
+	return size, nil
+}
+
+// Policies lists the retention policy of every record status
+func (s *RetentionService) Policies(ctx context.Context) ([]entity.RetentionPolicy, error) {
+	lg := s.logger.With("method", "Policies")
+	policies, err := s.retentionRepo.ListPolicies(ctx)
+	if err != nil {
+		lg.Error("failed to list retention policies", "error", err.Error())
+		return nil, err
+	}
+	return policies, nil
+}
+
+// UpdatePolicy changes how long photos of records in status are kept, zero
+// hours keeps them forever
+func (s *RetentionService) UpdatePolicy(ctx context.Context, status string, retentionHours int,
+	operator string) error {
+	lg := s.logger.With("method", "UpdatePolicy")
+	if err := s.retentionRepo.UpdatePolicy(ctx, status, retentionHours, operator); err != nil {
+		lg.Warn("failed to update retention policy", "error", err.Error(), "status", status)
+		return err
+	}
+	lg.Info("retention policy updated", "status", status, "retentionHours", retentionHours,
+		"operator", operator)
+	return nil
+}
+
+// Hold puts the photos of a record on legal hold, photos already reaped
+// cannot be brought back
+func (s *RetentionService) Hold(ctx context.Context, recordID, reason, operator string) error {
+	lg := s.logger.With("method", "Hold")
+	if err := s.recordRepo.SetLegalHold(ctx, recordID, true, reason, operator, time.Now().UnixMilli()); err != nil {
+		lg.Warn("failed to set legal hold", "error", err.Error(), "recordID", recordID)
+		return err
+	}
+	lg.Info("legal hold set", "recordID", recordID, "operator", operator)
+	return nil
+}
+
+// Release lifts the legal hold of a record, its photos fall back to the
+// policy of its status
+func (s *RetentionService) Release(ctx context.Context, recordID, reason, operator string) error {
+	lg := s.logger.With("method", "Release")
+	if err := s.recordRepo.SetLegalHold(ctx, recordID, false, reason, operator, 0); err != nil {
+		lg.Warn("failed to release legal hold", "error", err.Error(), "recordID", recordID)
+		return err
+	}
+	lg.Info("legal hold released", "recordID", recordID, "operator", operator)
+	return nil
+}
+
+// Report sums the bytes reclaimed by the reaper passes started in [from, to)
+func (s *RetentionService) Report(ctx context.Context, from, to int64, page, pageSize int) (
+	*repository.RetentionReport, []entity.RetentionRun, error) {
+	lg := s.logger.With("method", "Report")
+	report, runs, err := s.retentionRepo.Report(ctx, from, to, page, pageSize)
+	if err != nil {
+		lg.Error("failed to report retention runs", "error", err.Error())
+		return nil, nil, err
+	}
+	return report, runs, nil
+}
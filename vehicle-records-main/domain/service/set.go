@@ -6,4 +6,14 @@ import (
 
 var ProviderSet = wire.NewSet(
 	NewVehicleRecordService,
+	NewParkingSessionService,
+	NewForwardingAttemptService,
+	NewApiKeyService,
+	NewRecordReviewService,
+	NewPhotoService,
+	NewRetentionService,
+	NewEvidenceService,
+	NewPlateHistoryService,
+	NewWatchlistService,
+	NewDeadLetterService,
 )
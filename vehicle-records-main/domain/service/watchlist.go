@@ -0,0 +1,369 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync/atomic"
+	"time"
+
+	"git.abanppc.com/farin-project/vehicle-records/domain/entity"
+	"git.abanppc.com/farin-project/vehicle-records/domain/repository"
+	"git.abanppc.com/farin-project/vehicle-records/infrastructure/godotenv"
+	"git.abanppc.com/farin-project/vehicle-records/util/plate"
+)
+
+var (
+	ErrInvalidWatchlistKind = errors.New("invalid watchlist kind")
+	// ErrInvalidValidity is returned for entries that expire before they start
+	ErrInvalidValidity = errors.New("validUntil must be after validFrom")
+)
+
+// hotlistRepublishBatch is the number of unpublished alerts sent again in one pass
+const hotlistRepublishBatch = 100
+
+type WatchlistService struct {
+	logger        *slog.Logger
+	env           *godotenv.Env
+	watchlistRepo *repository.WatchlistRepository
+	alerts        *repository.HotlistAlertRabbitMQ
+	photos        *PhotoService
+	matcher       atomic.Pointer[watchlistMatcher]
+}
+
+func NewWatchlistService(logger *slog.Logger, env *godotenv.Env, watchlistRepo *repository.WatchlistRepository,
+	alerts *repository.HotlistAlertRabbitMQ, photos *PhotoService) *WatchlistService {
+	s := &WatchlistService{
+		logger:        logger.With("layer", "WatchlistService"),
+		env:           env,
+		watchlistRepo: watchlistRepo,
+		alerts:        alerts,
+		photos:        photos,
+	}
+	s.matcher.Store(&watchlistMatcher{})
+	return s
+}
+
+// watchlistMatcher holds the active entries in memory, exact plates by their
+// numeric encoding and wildcard patterns in a list
+type watchlistMatcher struct {
+	exact    map[int][]*entity.WatchlistEntry
+	patterns []watchlistPattern
+}
+
+type watchlistPattern struct {
+	pattern plate.Pattern
+	entry   *entity.WatchlistEntry
+}
+
+// match returns the entries that match a plate stored at t
+func (m *watchlistMatcher) match(p plate.Plate, t int64) []*entity.WatchlistEntry {
+	var entries []*entity.WatchlistEntry
+	for _, e := range m.exact[p.Numeric()] {
+		if e.Active(t) {
+			entries = append(entries, e)
+		}
+	}
+	for _, wp := range m.patterns {
+		if wp.entry.Active(t) && wp.pattern.Match(p) {
+			entries = append(entries, wp.entry)
+		}
+	}
+	return entries
+}
+
+// RunRefresher reloads the matcher every WatchlistRefreshInterval until ctx
+// is done, and publishes the alerts that failed to publish. The first load is
+// a Refresh before the consumers start.
+func (s *WatchlistService) RunRefresher(ctx context.Context) {
+	ticker := time.NewTicker(s.env.WatchlistRefreshInterval)
+	defer ticker.Stop()
+
+	for {
+		s.Republish(ctx, time.Now())
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if err := s.Refresh(ctx); err != nil {
+			s.logger.Error("failed to refresh watchlists", "error", err.Error())
+		}
+	}
+}
+
+// Refresh loads the entries of the active watchlists into the matcher
+func (s *WatchlistService) Refresh(ctx context.Context) error {
+	lg := s.logger.With("method", "Refresh")
+	entries, err := s.watchlistRepo.ActiveEntries(ctx, time.Now().UnixMilli())
+	if err != nil {
+		return err
+	}
+
+	m := &watchlistMatcher{exact: map[int][]*entity.WatchlistEntry{}}
+	for i := range entries {
+		e := &entries[i]
+		pattern, err := plate.ParsePattern(e.Pattern)
+		if err != nil {
+			lg.Warn("skipped watchlist entry", "error", err.Error(), "entryID", e.ID)
+			continue
+		}
+		if n, ok := pattern.Exact(); ok {
+			m.exact[n] = append(m.exact[n], e)
+			continue
+		}
+		m.patterns = append(m.patterns, watchlistPattern{pattern: pattern, entry: e})
+	}
+	s.matcher.Store(m)
+	lg.Debug("watchlists refreshed", "exact", len(m.exact), "patterns", len(m.patterns))
+	return nil
+}
+
+// Check matches a new record against the watchlists and publishes an alert
+// for every entry it matches. photos are the photos of the record as it was
+// received, their objects are named as the outbox uploads them. Merged
+// sightings and records whose plate could not be read are not matched.
+func (s *WatchlistService) Check(ctx context.Context, record *entity.VehicleRecord,
+	photos []*entity.CitizenVehiclePhoto) error {
+	lg := s.logger.With("method", "Check")
+	if record.DuplicateOf != nil {
+		return nil
+	}
+	p, err := plate.Parse(record.CitizenPlateNumber)
+	if err != nil {
+		return nil
+	}
+	entries := s.matcher.Load().match(p, record.RecordStoreTime)
+	if len(entries) == 0 {
+		return nil
+	}
+
+	matches := make([]*entity.WatchlistMatch, len(entries))
+	for i, e := range entries {
+		matches[i] = &entity.WatchlistMatch{
+			WatchlistID: e.WatchlistID,
+			EntryID:     e.ID,
+			RecordID:    record.RecordID,
+			Plate:       p.String(),
+			Priority:    e.Priority,
+			Status:      entity.WatchlistMatchPending,
+		}
+	}
+	created, err := s.watchlistRepo.CreateMatches(ctx, matches)
+	if err != nil {
+		lg.Error("failed to create watchlist matches", "error", err.Error(), "recordID", record.RecordID)
+		return err
+	}
+
+	bucket := s.env.MinioVehicleRecordsBucket
+	stored := make([]*entity.CitizenVehiclePhoto, len(photos))
+	for i, photo := range photos {
+		mainFname, cropFname := photoObjects(record.RecordID, photo.ID)
+		stored[i] = &entity.CitizenVehiclePhoto{
+			Base:                         entity.Base{ID: photo.ID},
+			CitizenVehiclePhoto:          fmt.Sprintf("%s/%s", bucket, mainFname),
+			CitizenVehiclePlateCropPhoto: fmt.Sprintf("%s/%s", bucket, cropFname),
+		}
+	}
+
+	var errs []error
+	for _, m := range created {
+		for _, e := range entries {
+			if e.ID == m.EntryID {
+				m.Watchlist, m.Entry = e.Watchlist, e
+			}
+		}
+		lg.Info("record matched a watchlist", "recordID", record.RecordID, "watchlistID", m.WatchlistID,
+			"entryID", m.EntryID, "priority", m.Priority)
+		if err := s.publish(ctx, m, record, stored); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Republish publishes the alerts of matches that were not published
+// HotlistRepublishAfter after they were found
+func (s *WatchlistService) Republish(ctx context.Context, now time.Time) {
+	lg := s.logger.With("method", "Republish")
+	matches, err := s.watchlistRepo.Unpublished(ctx, now.Add(-s.env.HotlistRepublishAfter).UnixMilli(),
+		hotlistRepublishBatch)
+	if err != nil {
+		lg.Error("failed to fetch unpublished matches", "error", err.Error())
+		return
+	}
+	for i := range matches {
+		m := &matches[i]
+		if m.VehicleRecord == nil || m.Watchlist == nil || m.Entry == nil {
+			continue
+		}
+		record := *m.VehicleRecord
+		record.VehiclePhotos = nil
+		if err := s.publish(ctx, m, &record, m.VehicleRecord.VehiclePhotos); err != nil {
+			lg.Warn("failed to republish hotlist alert", "error", err.Error(), "matchID", m.ID)
+		}
+	}
+}
+
+// publish sends the alert of a match and marks it published
+func (s *WatchlistService) publish(ctx context.Context, m *entity.WatchlistMatch, record *entity.VehicleRecord,
+	photos []*entity.CitizenVehiclePhoto) error {
+	alert := &entity.HotlistAlert{
+		MatchID:   m.ID,
+		MatchedAt: m.CreatedAt,
+		Watchlist: entity.HotlistAlertList{ID: m.Watchlist.ID, Name: m.Watchlist.Name, Kind: m.Watchlist.Kind},
+		Entry: entity.HotlistAlertEntry{ID: m.Entry.ID, Pattern: m.Entry.Pattern, Priority: m.Entry.Priority,
+			Note: m.Entry.Note},
+		Plate: m.Plate,
+		Location: entity.HotlistLocation{
+			Latitude:  record.LPRVehicleGPSLatitude,
+			Longitude: record.LPRVehicleGPSLongitude,
+			RingID:    record.RingID,
+			SegmentID: record.SegmentID,
+			StreetID:  record.StreetID,
+		},
+		Photos: make([]entity.HotlistPhoto, 0, len(photos)),
+		Record: record,
+	}
+	for _, photo := range photos {
+		alert.Photos = append(alert.Photos, entity.HotlistPhoto{ID: photo.ID, Object: photo.CitizenVehiclePhoto,
+			PlateCropObject: photo.CitizenVehiclePlateCropPhoto})
+	}
+
+	if err := s.alerts.Publish(ctx, alert); err != nil {
+		return err
+	}
+	return s.watchlistRepo.MarkPublished(ctx, m.ID, time.Now().UnixMilli())
+}
+
+// refresh reloads the matcher after a change of the watchlists, a failure
+// is only logged as the next refresh picks the change up
+func (s *WatchlistService) refresh(ctx context.Context) {
+	if err := s.Refresh(ctx); err != nil {
+		s.logger.Error("failed to refresh watchlists", "error", err.Error())
+	}
+}
+
+func (s *WatchlistService) Lists(ctx context.Context) ([]entity.Watchlist, error) {
+	return s.watchlistRepo.ListLists(ctx)
+}
+
+func (s *WatchlistService) GetList(ctx context.Context, id string) (*entity.Watchlist, error) {
+	return s.watchlistRepo.GetList(ctx, id)
+}
+
+func (s *WatchlistService) CreateList(ctx context.Context, list *entity.Watchlist) error {
+	if err := validWatchlistKind(list.Kind); err != nil {
+		return err
+	}
+	if err := s.watchlistRepo.CreateList(ctx, list); err != nil {
+		return err
+	}
+	s.refresh(ctx)
+	return nil
+}
+
+func (s *WatchlistService) UpdateList(ctx context.Context, list *entity.Watchlist) error {
+	if err := validWatchlistKind(list.Kind); err != nil {
+		return err
+	}
+	if err := s.watchlistRepo.UpdateList(ctx, list); err != nil {
+		return err
+	}
+	s.refresh(ctx)
+	return nil
+}
+
+func (s *WatchlistService) DeleteList(ctx context.Context, id string) error {
+	if err := s.watchlistRepo.DeleteList(ctx, id); err != nil {
+		return err
+	}
+	s.refresh(ctx)
+	return nil
+}
+
+func (s *WatchlistService) Entries(ctx context.Context, listID string, page, pageSize int) (
+	[]entity.WatchlistEntry, int64, error) {
+	if _, err := s.watchlistRepo.GetList(ctx, listID); err != nil {
+		return nil, 0, err
+	}
+	return s.watchlistRepo.ListEntries(ctx, listID, page, pageSize)
+}
+
+// CreateEntry adds an entry with its pattern normalized, it fails with
+// plate.ErrInvalidPlate for invalid patterns
+func (s *WatchlistService) CreateEntry(ctx context.Context, entry *entity.WatchlistEntry) error {
+	if err := normalizeEntry(entry); err != nil {
+		return err
+	}
+	if err := s.watchlistRepo.CreateEntry(ctx, entry); err != nil {
+		return err
+	}
+	s.refresh(ctx)
+	return nil
+}
+
+func (s *WatchlistService) UpdateEntry(ctx context.Context, entry *entity.WatchlistEntry) error {
+	if err := normalizeEntry(entry); err != nil {
+		return err
+	}
+	if err := s.watchlistRepo.UpdateEntry(ctx, entry); err != nil {
+		return err
+	}
+	s.refresh(ctx)
+	return nil
+}
+
+func (s *WatchlistService) DeleteEntry(ctx context.Context, listID, id string) error {
+	if err := s.watchlistRepo.DeleteEntry(ctx, listID, id); err != nil {
+		return err
+	}
+	s.refresh(ctx)
+	return nil
+}
+
+// ListMatches lists the matches in a status with the photos of their records
+// signed
+func (s *WatchlistService) ListMatches(ctx context.Context, status entity.WatchlistMatchStatus, page,
+	pageSize int) ([]entity.WatchlistMatch, int64, error) {
+	matches, total, err := s.watchlistRepo.ListMatches(ctx, status, page, pageSize)
+	if err != nil {
+		return nil, 0, err
+	}
+	for i := range matches {
+		if matches[i].VehicleRecord == nil {
+			continue
+		}
+		if err := s.photos.SignRecord(ctx, matches[i].VehicleRecord); err != nil {
+			return nil, 0, err
+		}
+	}
+	return matches, total, nil
+}
+
+// Acknowledge records that an operator handled the alert of a match
+func (s *WatchlistService) Acknowledge(ctx context.Context, id, by, note string) error {
+	return s.watchlistRepo.Acknowledge(ctx, id, by, note, time.Now().UnixMilli())
+}
+
+func validWatchlistKind(kind entity.WatchlistKind) error {
+	switch kind {
+	case entity.WatchlistStolen, entity.WatchlistUnpaidFines, entity.WatchlistPolice, entity.WatchlistOther:
+		return nil
+	}
+	return fmt.Errorf("%w: %q", ErrInvalidWatchlistKind, kind)
+}
+
+func normalizeEntry(entry *entity.WatchlistEntry) error {
+	pattern, err := plate.ParsePattern(entry.Pattern)
+	if err != nil {
+		return err
+	}
+	if entry.ValidUntil != 0 && entry.ValidUntil <= entry.ValidFrom {
+		return ErrInvalidValidity
+	}
+	entry.Pattern = pattern.String()
+	return nil
+}
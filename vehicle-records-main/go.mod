@@ -3,6 +3,8 @@ module git.abanppc.com/farin-project/vehicle-records
 go 1.23.2
 
 require (
+	git.abanppc.com/farin-project/vehicle-records/util/consumer v0.1.0
+	git.abanppc.com/farin-project/vehicle-records/util/plate v0.1.0
 	github.com/gin-gonic/gin v1.10.0
 	github.com/golang-jwt/jwt v3.2.2+incompatible
 	github.com/google/uuid v1.6.0
@@ -28,6 +30,7 @@ require (
 	go.opentelemetry.io/otel/sdk v1.34.0
 	go.opentelemetry.io/otel/sdk/log v0.10.0
 	go.opentelemetry.io/otel/sdk/metric v1.34.0
+	golang.org/x/sync v0.10.0
 	gorm.io/driver/postgres v1.5.11
 	gorm.io/gorm v1.25.12
 	gorm.io/plugin/soft_delete v1.2.1
@@ -90,7 +93,6 @@ require (
 	golang.org/x/arch v0.8.0 // indirect
 	golang.org/x/crypto v0.32.0 // indirect
 	golang.org/x/net v0.34.0 // indirect
-	golang.org/x/sync v0.10.0 // indirect
 	golang.org/x/sys v0.29.0 // indirect
 	golang.org/x/text v0.21.0 // indirect
 	golang.org/x/tools v0.27.0 // indirect
@@ -100,3 +102,11 @@ require (
 	google.golang.org/protobuf v1.36.3 // indirect
 	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
+
+// util/consumer and util/plate are modules of their own so the other services
+// can require them without the rest of vehicle-records, they are tagged
+// util/consumer/vX.Y.Z and util/plate/vX.Y.Z
+replace (
+	git.abanppc.com/farin-project/vehicle-records/util/consumer => ./util/consumer
+	git.abanppc.com/farin-project/vehicle-records/util/plate => ./util/plate
+)
@@ -1,8 +1,11 @@
 package godotenv
 
 import (
+	"git.abanppc.com/farin-project/vehicle-records/util/consumer"
 	"github.com/joho/godotenv"
 	"os"
+	"strconv"
+	"time"
 )
 
 type Env struct {
@@ -25,9 +28,71 @@ type Env struct {
 	TehranToken          string
 	TehranLoginURL       string
 	TehranStoreRecordURL string
+	ForwardersConfig     string //path of the municipal forwarders json file, records go to tehran without it
+
+	TehranTimeout         time.Duration
+	TehranBreakerFailures int           //consecutive failures that open the circuit breaker
+	TehranBreakerOpen     time.Duration //time the breaker stays open before probing
+	TehranBreakerProbes   int           //concurrent probes while half-open
+	TehranRateMax         float64       //requests per second while tehran.ir is healthy
+	TehranRateMin         float64       //requests per second the limiter backs off to
+	TehranBurst           int
+	TehranConcurrency     int //concurrent requests at most
 
 	OpenTelemetryMetricExporter string
 	OpenTelemetryLogExporter    string
+
+	ParkingSessionPassGap       time.Duration //minimum time between two passes over a segment
+	ParkingSessionSettle        time.Duration //time to wait for a pass over a segment to finish
+	ParkingSessionMaxOpen       time.Duration //open sessions not seen for this long are expired
+	ParkingSessionCloseInterval time.Duration
+
+	OutboxWorkers      int
+	OutboxBatchSize    int
+	OutboxPollInterval time.Duration
+	OutboxLease        time.Duration //time a dispatcher owns a claimed entry
+	OutboxRetryBackoff time.Duration //first retry delay, doubled on every failed attempt
+	OutboxRetryMax     time.Duration
+	OutboxMaxAttempts  int
+
+	ResendInterval time.Duration //time between two scans for unsent records
+	ResendWindow   time.Duration //only records created within this window are resent
+	ResendMinAge   time.Duration //younger records are left to the outbox
+	ResendPageSize int
+	ResendRate     int           //records sent per second at most
+	ResendLease    time.Duration //time a scheduler owns the checkpoint, renewed every record and at least twice TEHRAN_TIMEOUT
+
+	DedupWindow   time.Duration //sightings of a plate stored this close are merged, 0 turns dedup off
+	DedupDistance float64       //meters between merged sightings at most
+
+	ReviewMinOCRAccuracy  float64 //records read with a lower accuracy are held for review
+	ReviewDistorted       bool    //hold records of distorted vehicles for review
+	ReviewPlateNotVisible bool    //hold records whose plate is not visible for review
+	PhotoURLExpiry        time.Duration
+
+	UploadRecordConcurrency int //concurrent photo uploads of one record
+	UploadConcurrency       int //concurrent photo uploads of all records
+
+	RetentionInterval  time.Duration //time between two passes of the photo reaper
+	RetentionBatchSize int           //photos deleted in one transaction
+
+	FarinDatabaseHost  string //read only dsn of the farin database, for the segment, ring and driver of records
+	EvidenceSigningKey string //path of the ed25519 PEM key evidence packages are signed with
+	EvidenceTileURL    string //map tile url with {z}, {x} and {y}, evidence maps have no tiles without it
+	EvidenceMapZoom    int
+
+	WatchlistRefreshInterval time.Duration //time between two reloads of the watchlist matcher
+	HotlistRepublishAfter    time.Duration //alerts not published this long after their match are published again
+
+	RabbitMQDeadLetterExchange string        //exchange messages that failed every attempt are published on
+	ConsumerMaxAttempts        int           //attempts of a message before it is dead-lettered
+	ConsumerRetryDelay         time.Duration //delay of the first retry of a message, doubled on every next one
+	ConsumerRetryMax           time.Duration
+
+	ShutdownTimeout time.Duration //time the consumers have to drain on SIGTERM, within the grace period of the pod
+
+	RecordsConsumer consumer.Settings //RECORDS_CONSUMER_*, see getSettings
+	RetryConsumer   consumer.Settings //RETRY_CONSUMER_*
 }
 
 func NewEnv() *Env {
@@ -46,6 +111,15 @@ func (e *Env) Load() {
 	e.TehranToken = os.Getenv("TEHRAN_TOKEN")
 	e.TehranLoginURL = os.Getenv("TEHRAN_LOGIN_URL")
 	e.TehranStoreRecordURL = os.Getenv("TEHRAN_STORE_RECORD_URL")
+	e.ForwardersConfig = os.Getenv("FORWARDERS_CONFIG")
+	e.TehranTimeout = getDuration("TEHRAN_TIMEOUT", 50*time.Second)
+	e.TehranBreakerFailures = getInt("TEHRAN_BREAKER_FAILURES", 5)
+	e.TehranBreakerOpen = getDuration("TEHRAN_BREAKER_OPEN", 30*time.Second)
+	e.TehranBreakerProbes = getInt("TEHRAN_BREAKER_PROBES", 1)
+	e.TehranRateMax = getFloat("TEHRAN_RATE_MAX", 20)
+	e.TehranRateMin = getFloat("TEHRAN_RATE_MIN", 1)
+	e.TehranBurst = getInt("TEHRAN_BURST", 10)
+	e.TehranConcurrency = getInt("TEHRAN_CONCURRENCY", 8)
 	e.BaseURL = os.Getenv("BASE_URL")
 	e.RabbitMQHost = os.Getenv("RABBITMQ_HOST")
 	e.RabbitMQEventExchange = os.Getenv("RABBITMQ_EVENT_EXCHANGE")
@@ -58,4 +132,108 @@ func (e *Env) Load() {
 	e.MinioVehicleRecordsBucket = os.Getenv("MINIO_VEHICLE_RECORDS_BUCKET")
 	e.OpenTelemetryMetricExporter = os.Getenv("OPEN_TELEMETRY_METRIC_EXPORTER")
 	e.OpenTelemetryLogExporter = os.Getenv("OPEN_TELEMETRY_LOG_EXPORTER")
+	e.ParkingSessionPassGap = getDuration("PARKING_SESSION_PASS_GAP", 10*time.Minute)
+	e.ParkingSessionSettle = getDuration("PARKING_SESSION_SETTLE", 5*time.Minute)
+	e.ParkingSessionMaxOpen = getDuration("PARKING_SESSION_MAX_OPEN", 24*time.Hour)
+	e.ParkingSessionCloseInterval = getDuration("PARKING_SESSION_CLOSE_INTERVAL", time.Minute)
+	e.OutboxWorkers = getInt("OUTBOX_WORKERS", 4)
+	e.OutboxBatchSize = getInt("OUTBOX_BATCH_SIZE", 10)
+	e.OutboxPollInterval = getDuration("OUTBOX_POLL_INTERVAL", 5*time.Second)
+	e.OutboxLease = getDuration("OUTBOX_LEASE", 2*time.Minute)
+	e.OutboxRetryBackoff = getDuration("OUTBOX_RETRY_BACKOFF", 10*time.Second)
+	e.OutboxRetryMax = getDuration("OUTBOX_RETRY_MAX", 30*time.Minute)
+	e.OutboxMaxAttempts = getInt("OUTBOX_MAX_ATTEMPTS", 50)
+	e.ResendInterval = getDuration("RESEND_INTERVAL", 10*time.Minute)
+	e.ResendWindow = getDuration("RESEND_WINDOW", 7*24*time.Hour)
+	e.ResendMinAge = getDuration("RESEND_MIN_AGE", time.Hour)
+	e.ResendPageSize = getInt("RESEND_PAGE_SIZE", 50)
+	e.ResendRate = getInt("RESEND_RATE", 2)
+	e.ResendLease = getDuration("RESEND_LEASE", 5*time.Minute)
+	e.DedupWindow = getDuration("DEDUP_WINDOW", 30*time.Second)
+	if d, err := time.ParseDuration(os.Getenv("DEDUP_WINDOW")); err == nil && d == 0 {
+		e.DedupWindow = 0
+	}
+	e.DedupDistance = getFloat("DEDUP_DISTANCE", 50)
+	e.ReviewMinOCRAccuracy = getFloat("REVIEW_MIN_OCR_ACCURACY", 0.6)
+	e.ReviewDistorted = getBool("REVIEW_DISTORTED", true)
+	e.ReviewPlateNotVisible = getBool("REVIEW_PLATE_NOT_VISIBLE", true)
+	e.PhotoURLExpiry = getDuration("PHOTO_URL_EXPIRY", 15*time.Minute)
+	e.UploadRecordConcurrency = getInt("UPLOAD_RECORD_CONCURRENCY", 4)
+	e.UploadConcurrency = getInt("UPLOAD_CONCURRENCY", 16)
+	e.RetentionInterval = getDuration("RETENTION_INTERVAL", time.Hour)
+	e.RetentionBatchSize = getInt("RETENTION_BATCH_SIZE", 100)
+	e.FarinDatabaseHost = os.Getenv("FARIN_DATABASE_HOST")
+	e.EvidenceSigningKey = os.Getenv("EVIDENCE_SIGNING_KEY")
+	e.EvidenceTileURL = os.Getenv("EVIDENCE_TILE_URL")
+	e.EvidenceMapZoom = getInt("EVIDENCE_MAP_ZOOM", 17)
+	e.WatchlistRefreshInterval = getDuration("WATCHLIST_REFRESH_INTERVAL", 30*time.Second)
+	e.HotlistRepublishAfter = getDuration("HOTLIST_REPUBLISH_AFTER", time.Minute)
+	e.RabbitMQDeadLetterExchange = os.Getenv("RABBITMQ_DEAD_LETTER_EXCHANGE")
+	if e.RabbitMQDeadLetterExchange == "" {
+		e.RabbitMQDeadLetterExchange = "farin.dead-letter"
+	}
+	e.ConsumerMaxAttempts = getInt("CONSUMER_MAX_ATTEMPTS", 5)
+	e.ConsumerRetryDelay = getDuration("CONSUMER_RETRY_DELAY", 5*time.Second)
+	e.ConsumerRetryMax = getDuration("CONSUMER_RETRY_MAX", 5*time.Minute)
+	e.ShutdownTimeout = getDuration("SHUTDOWN_TIMEOUT", 25*time.Second)
+	e.RecordsConsumer = getSettings("RECORDS_CONSUMER", consumer.Settings{
+		Prefetch: 8, Workers: 3, MaxWorkers: 8, Buffer: 8,
+	})
+	e.RetryConsumer = getSettings("RETRY_CONSUMER", consumer.Settings{
+		Prefetch: 8, Workers: 3, MaxWorkers: 6, Buffer: 8, QueueTTL: 18 * time.Hour,
+	})
+}
+
+// getInt reads a positive number from the environment, def is used when the
+// variable is missing or malformed
+func getInt(key string, def int) int {
+	i, err := strconv.Atoi(os.Getenv(key))
+	if err != nil || i <= 0 {
+		return def
+	}
+	return i
+}
+
+// getFloat reads a positive number from the environment, def is used when
+// the variable is missing or malformed
+func getFloat(key string, def float64) float64 {
+	f, err := strconv.ParseFloat(os.Getenv(key), 64)
+	if err != nil || f <= 0 {
+		return def
+	}
+	return f
+}
+
+// getBool reads true or false from the environment, def is used when the
+// variable is missing or malformed
+func getBool(key string, def bool) bool {
+	b, err := strconv.ParseBool(os.Getenv(key))
+	if err != nil {
+		return def
+	}
+	return b
+}
+
+// getDuration reads a duration such as "10m" from the environment, def is
+// used when the variable is missing or malformed
+func getDuration(key string, def time.Duration) time.Duration {
+	d, err := time.ParseDuration(os.Getenv(key))
+	if err != nil || d <= 0 {
+		return def
+	}
+	return d
+}
+
+// getSettings reads the settings of a consumer from the variables starting
+// with prefix, such as RECORDS_CONSUMER_WORKERS, def holds the defaults
+func getSettings(prefix string, def consumer.Settings) consumer.Settings {
+	return consumer.Settings{
+		Prefetch:       getInt(prefix+"_PREFETCH", def.Prefetch),
+		Workers:        getInt(prefix+"_WORKERS", def.Workers),
+		MaxWorkers:     getInt(prefix+"_MAX_WORKERS", def.MaxWorkers),
+		Buffer:         getInt(prefix+"_BUFFER", def.Buffer),
+		Timeout:        getDuration(prefix+"_TIMEOUT", def.Timeout),
+		QueueTTL:       getDuration(prefix+"_QUEUE_TTL", def.QueueTTL),
+		QueueMaxLength: getInt(prefix+"_QUEUE_MAX_LENGTH", def.QueueMaxLength),
+	}
 }
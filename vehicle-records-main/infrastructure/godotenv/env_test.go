@@ -0,0 +1,29 @@
+package godotenv
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDedupWindow(t *testing.T) {
+	tests := []struct {
+		value string
+		want  time.Duration
+	}{
+		{"", 30 * time.Second},
+		{"45s", 45 * time.Second},
+		{"0", 0},
+		{"0s", 0},
+		{"0m", 0},
+		{"-5s", 30 * time.Second},
+		{"soon", 30 * time.Second},
+	}
+	for _, tt := range tests {
+		t.Setenv("DEDUP_WINDOW", tt.value)
+		e := &Env{}
+		e.Load()
+		if e.DedupWindow != tt.want {
+			t.Errorf("DEDUP_WINDOW=%q: DedupWindow = %v, want %v", tt.value, e.DedupWindow, tt.want)
+		}
+	}
+}
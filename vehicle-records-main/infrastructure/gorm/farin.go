@@ -0,0 +1,63 @@
+package gormdb
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"git.abanppc.com/farin-project/vehicle-records/infrastructure/godotenv"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// FarinGORMDB is a read only connection to the farin database, where the
+// segments, rings and drivers of records are kept. DB stays nil while
+// FARIN_DATABASE_HOST is not set.
+type FarinGORMDB struct {
+	DB  *gorm.DB
+	env *godotenv.Env
+}
+
+func NewFarinGORMDB(env *godotenv.Env) *FarinGORMDB {
+	return &FarinGORMDB{
+		env: env,
+	}
+}
+
+func (g *FarinGORMDB) Setup(ctx context.Context) error {
+	if g.env.FarinDatabaseHost == "" {
+		return nil
+	}
+
+	newLogger := logger.New(
+		log.New(os.Stdout, "\r\n", log.LstdFlags),
+		logger.Config{
+			SlowThreshold: time.Second,
+			LogLevel:      logger.Warn,
+			Colorful:      true,
+		},
+	)
+	db, err := gorm.Open(postgres.Open(g.env.FarinDatabaseHost), &gorm.Config{
+		Logger: newLogger,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to connect to farin database: %w", err)
+	}
+
+	sqlDB, err := db.DB()
+	if err != nil {
+		return fmt.Errorf("failed to get sqlDB from gorm: %w", err)
+	}
+	sqlDB.SetMaxIdleConns(1)
+	sqlDB.SetMaxOpenConns(3)
+	sqlDB.SetConnMaxLifetime(time.Hour)
+
+	if err := sqlDB.PingContext(ctx); err != nil {
+		return fmt.Errorf("failed to ping farin database: %w", err)
+	}
+	g.DB = db
+	return nil
+}
@@ -1,9 +1,12 @@
 package rabbit
 
 import (
+	"context"
 	"errors"
+	"fmt"
 	"git.abanppc.com/farin-project/vehicle-records/app/rabbit/consumers"
 	"git.abanppc.com/farin-project/vehicle-records/infrastructure/godotenv"
+	"git.abanppc.com/farin-project/vehicle-records/util/consumer"
 	"log/slog"
 	"sync"
 	"time"
@@ -31,6 +34,21 @@ func (cr *ConsumerRunner) RunInnerWorkers() {
 	}
 }
 
+// Shutdown drains the consumers at once, until ctx is done
+func (cr *ConsumerRunner) Shutdown(ctx context.Context) error {
+	errs := make([]error, len(cr.consumers))
+	var wg sync.WaitGroup
+	for i, c := range cr.consumers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = c.Shutdown(ctx)
+		}()
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
+
 func (cr *ConsumerRunner) Setup(ch *amqp091.Channel, rch *amqp091.Channel) error {
 	cr.channel = ch
 	cr.retryChannel = rch
@@ -62,6 +80,7 @@ type Rabbit struct {
 
 	lg        *slog.Logger
 	connected bool
+	closing   bool // set by Close, the connection is not reopened
 	Mutex     sync.RWMutex
 }
 
@@ -77,6 +96,9 @@ func (r *Rabbit) Setup(consumerRunner *ConsumerRunner) error {
 	r.Mutex.Lock()
 	defer r.Mutex.Unlock()
 
+	if r.closing {
+		return errors.New("rabbitmq is closed")
+	}
 	if err := r.cleanup(); err != nil {
 		return err
 	}
@@ -104,20 +126,26 @@ func (r *Rabbit) Setup(consumerRunner *ConsumerRunner) error {
 	}
 
 	r.connected = true
-	go r.handleDisconnect(consumerRunner)
+	go r.handleDisconnect(consumerRunner,
+		r.conn.NotifyClose(make(chan *amqp091.Error, 1)),
+		r.CH.NotifyClose(make(chan *amqp091.Error, 1)),
+		r.RCH.NotifyClose(make(chan *amqp091.Error, 1)))
 
 	return nil
 }
 
 func (r *Rabbit) cleanup() error {
-	if r.conn != nil {
-		if err := r.conn.Close(); err != nil {
-			r.lg.Error("failed to close connection", "err", err)
+	r.connected = false
+	for _, ch := range []*amqp091.Channel{r.CH, r.RCH} {
+		if ch != nil && !ch.IsClosed() {
+			if err := ch.Close(); err != nil {
+				r.lg.Error("failed to close channel", "err", err)
+			}
 		}
 	}
-	if r.CH != nil {
-		if err := r.CH.Close(); err != nil {
-			r.lg.Error("failed to close channel", "err", err)
+	if r.conn != nil && !r.conn.IsClosed() {
+		if err := r.conn.Close(); err != nil {
+			r.lg.Error("failed to close connection", "err", err)
 		}
 	}
 	return nil
@@ -139,7 +167,9 @@ func (r *Rabbit) setupChannel() error {
 	}
 	r.CH = ch
 
-	if err := r.CH.Qos(8, 0, false); err != nil {
+	// the prefetch is set by every consumer, publishes wait for the broker to
+	// store them, see Publish
+	if err := r.CH.Confirm(false); err != nil {
 		return err
 	}
 
@@ -153,27 +183,39 @@ func (r *Rabbit) setupRetryChannel() error {
 	}
 	r.RCH = ch
 
-	if err := r.RCH.Qos(8, 0, false); err != nil {
+	if err := r.RCH.Confirm(false); err != nil {
 		return err
 	}
 
 	return nil
 }
 
-func (r *Rabbit) handleDisconnect(consumerRunner *ConsumerRunner) {
-	closeChan := make(chan *amqp091.Error)
-	r.conn.NotifyClose(closeChan)
-
-	err := <-closeChan
-	r.connected = false
+// handleDisconnect waits for the connection or one of the channels to close
+// with an error, such as on a broker restart, then sets up the connection,
+// the exchanges, the queues and the consumers again, backing off between
+// failed attempts. Closes without an error are made by Setup or Close.
+func (r *Rabbit) handleDisconnect(consumerRunner *ConsumerRunner, connClosed, chClosed, rchClosed <-chan *amqp091.Error) {
+	var err *amqp091.Error
+	select {
+	case err = <-connClosed:
+	case err = <-chClosed:
+	case err = <-rchClosed:
+	}
+	if err == nil || r.isClosing() {
+		r.lg.Info("RabbitMQ connection closed")
+		return
+	}
 	r.lg.Error("RabbitMQ connection closed", "err", err)
 
 	backoff := 1 * time.Second
 	maxBackoff := 30 * time.Second
 
-	for !r.connected {
+	for {
 		r.lg.Info("attempting to reconnect to RabbitMQ", "backoff", backoff)
 		time.Sleep(backoff)
+		if r.isClosing() {
+			return
+		}
 
 		if err := r.Setup(consumerRunner); err != nil {
 			r.lg.Error("failed to reconnect", "err", err)
@@ -189,6 +231,59 @@ func (r *Rabbit) handleDisconnect(consumerRunner *ConsumerRunner) {
 	}
 }
 
+func (r *Rabbit) isClosing() bool {
+	r.Mutex.RLock()
+	defer r.Mutex.RUnlock()
+	return r.closing
+}
+
+// Publish publishes msg on the main channel and waits for the broker to
+// confirm it, a message is only known to be stored once Publish returns nil
+func (r *Rabbit) Publish(ctx context.Context, exchange, key string, msg amqp091.Publishing) error {
+	r.Mutex.RLock()
+	ch := r.CH
+	r.Mutex.RUnlock()
+	if ch == nil || ch.IsClosed() {
+		return errors.New("rabbitmq channel is not open")
+	}
+	return consumer.Publish(ctx, ch, exchange, key, msg)
+}
+
+// Close closes the channels, which returns their unacked deliveries to the
+// queues, then the connection. The consumers are drained before it.
+func (r *Rabbit) Close() error {
+	r.Mutex.Lock()
+	defer r.Mutex.Unlock()
+
+	r.closing = true
+	var errs []error
+	for _, ch := range []*amqp091.Channel{r.CH, r.RCH} {
+		if ch != nil && !ch.IsClosed() {
+			if err := ch.Close(); err != nil {
+				errs = append(errs, fmt.Errorf("failed to close channel: %w", err))
+			}
+		}
+	}
+	if r.conn != nil && !r.conn.IsClosed() {
+		if err := r.conn.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("failed to close connection: %w", err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Channel opens a channel of its own on the connection, for work that must
+// not run on the consumer channels
+func (r *Rabbit) Channel() (*amqp091.Channel, error) {
+	r.Mutex.RLock()
+	defer r.Mutex.RUnlock()
+
+	if r.conn == nil || r.conn.IsClosed() {
+		return nil, errors.New("rabbitmq connection is not open")
+	}
+	return r.conn.Channel()
+}
+
 func (r *Rabbit) HealthCheck() error {
 	r.Mutex.RLock()
 	defer r.Mutex.RUnlock()
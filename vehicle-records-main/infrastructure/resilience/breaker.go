@@ -0,0 +1,122 @@
+package resilience
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen is returned by Allow while the breaker rejects calls
+var ErrCircuitOpen = errors.New("circuit breaker is open")
+
+type State int
+
+const (
+	StateClosed State = iota
+	StateHalfOpen
+	StateOpen
+)
+
+func (s State) String() string {
+	switch s {
+	case StateClosed:
+		return "closed"
+	case StateHalfOpen:
+		return "half-open"
+	}
+	return "open"
+}
+
+// CircuitBreaker stops calls to a failing service. It opens after threshold
+// consecutive failures and rejects calls for openFor, then lets up to probes
+// calls through at once. A successful probe closes it, a failed one opens it
+// again.
+type CircuitBreaker struct {
+	mu        sync.Mutex
+	state     State
+	failures  int
+	inFlight  int
+	openedAt  time.Time
+	threshold int
+	openFor   time.Duration
+	probes    int
+}
+
+func NewCircuitBreaker(threshold int, openFor time.Duration, probes int) *CircuitBreaker {
+	return &CircuitBreaker{threshold: threshold, openFor: openFor, probes: probes}
+}
+
+// Allow tells if a call may go through, every allowed call must be followed
+// by Success or Failure
+func (b *CircuitBreaker) Allow() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state == StateOpen && time.Since(b.openedAt) >= b.openFor {
+		b.state = StateHalfOpen
+		b.inFlight = 0
+	}
+	switch b.state {
+	case StateOpen:
+		return ErrCircuitOpen
+	case StateHalfOpen:
+		if b.inFlight >= b.probes {
+			return ErrCircuitOpen
+		}
+		b.inFlight++
+	}
+	return nil
+}
+
+// Success reports an allowed call that reached a healthy service
+func (b *CircuitBreaker) Success() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.failures = 0
+	if b.state == StateHalfOpen {
+		b.state = StateClosed
+		b.inFlight = 0
+	}
+}
+
+// Failure reports an allowed call that failed because of the service
+func (b *CircuitBreaker) Failure() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.failures++
+	if b.state == StateHalfOpen || (b.state == StateClosed && b.failures >= b.threshold) {
+		b.state = StateOpen
+		b.openedAt = time.Now()
+		b.inFlight = 0
+	}
+}
+
+func (b *CircuitBreaker) State() State {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.state
+}
+
+// RetryAt is the time the breaker lets probes through, it is zero unless the
+// breaker is open
+func (b *CircuitBreaker) RetryAt() time.Time {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.state != StateOpen {
+		return time.Time{}
+	}
+	return b.openedAt.Add(b.openFor)
+}
+
+// Ignore ends an allowed call that says nothing about the service, such as
+// one canceled by the caller
+func (b *CircuitBreaker) Ignore() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state == StateHalfOpen && b.inFlight > 0 {
+		b.inFlight--
+	}
+}
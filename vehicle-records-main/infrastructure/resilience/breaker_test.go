@@ -0,0 +1,97 @@
+package resilience
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestCircuitBreakerOpensAfterThreshold(t *testing.T) {
+	b := NewCircuitBreaker(3, time.Hour, 1)
+	for i := 0; i < 2; i++ {
+		if err := b.Allow(); err != nil {
+			t.Fatalf("Allow() after %d failures = %v", i, err)
+		}
+		b.Failure()
+	}
+	if b.State() != StateClosed {
+		t.Fatalf("state after 2 failures = %s, want closed", b.State())
+	}
+
+	// a success in between resets the consecutive failures
+	b.Success()
+	for i := 0; i < 3; i++ {
+		if err := b.Allow(); err != nil {
+			t.Fatalf("Allow() = %v", err)
+		}
+		b.Failure()
+	}
+	if b.State() != StateOpen {
+		t.Fatalf("state after 3 failures = %s, want open", b.State())
+	}
+	if err := b.Allow(); !errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("Allow() while open = %v, want %v", err, ErrCircuitOpen)
+	}
+	if b.RetryAt().IsZero() {
+		t.Error("RetryAt() is zero while open")
+	}
+}
+
+func TestCircuitBreakerHalfOpen(t *testing.T) {
+	tests := []struct {
+		name  string
+		probe func(b *CircuitBreaker)
+		want  State
+	}{
+		{"successful probe closes", (*CircuitBreaker).Success, StateClosed},
+		{"failed probe opens", (*CircuitBreaker).Failure, StateOpen},
+		{"ignored probe stays half-open", (*CircuitBreaker).Ignore, StateHalfOpen},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := NewCircuitBreaker(1, 10*time.Millisecond, 2)
+			_ = b.Allow()
+			b.Failure()
+			if err := b.Allow(); !errors.Is(err, ErrCircuitOpen) {
+				t.Fatalf("Allow() while open = %v", err)
+			}
+			time.Sleep(15 * time.Millisecond)
+
+			// up to probes calls are let through at once
+			for i := 0; i < 2; i++ {
+				if err := b.Allow(); err != nil {
+					t.Fatalf("probe %d: Allow() = %v", i, err)
+				}
+			}
+			if b.State() != StateHalfOpen {
+				t.Fatalf("state = %s, want half-open", b.State())
+			}
+			if err := b.Allow(); !errors.Is(err, ErrCircuitOpen) {
+				t.Fatalf("Allow() beyond probes = %v, want %v", err, ErrCircuitOpen)
+			}
+
+			tt.probe(b)
+			if b.State() != tt.want {
+				t.Errorf("state = %s, want %s", b.State(), tt.want)
+			}
+		})
+	}
+}
+
+func TestCircuitBreakerIgnoreFreesProbe(t *testing.T) {
+	b := NewCircuitBreaker(1, time.Millisecond, 1)
+	_ = b.Allow()
+	b.Failure()
+	time.Sleep(2 * time.Millisecond)
+
+	if err := b.Allow(); err != nil {
+		t.Fatalf("probe: Allow() = %v", err)
+	}
+	if err := b.Allow(); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("second probe: Allow() = %v, want %v", err, ErrCircuitOpen)
+	}
+	b.Ignore()
+	if err := b.Allow(); err != nil {
+		t.Errorf("Allow() after an ignored probe = %v", err)
+	}
+}
@@ -0,0 +1,88 @@
+package resilience
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Limiter is a token bucket whose rate adapts to the service: every success
+// adds a step to the rate up to max, every failure halves it down to min.
+type Limiter struct {
+	mu     sync.Mutex
+	rate   float64 // tokens per second
+	min    float64
+	max    float64
+	step   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+func NewLimiter(min, max float64, burst int) *Limiter {
+	return &Limiter{
+		rate:   max,
+		min:    min,
+		max:    max,
+		step:   max / 20,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   time.Now(),
+	}
+}
+
+// Wait blocks until a token is available or ctx is done
+func (l *Limiter) Wait(ctx context.Context) error {
+	l.mu.Lock()
+	now := time.Now()
+	l.refill(now)
+	l.tokens--
+	var wait time.Duration
+	if l.tokens < 0 {
+		wait = time.Duration(-l.tokens / l.rate * float64(time.Second))
+	}
+	l.mu.Unlock()
+
+	if wait == 0 {
+		return nil
+	}
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		l.mu.Lock()
+		l.tokens++
+		l.mu.Unlock()
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// Increase raises the rate by a step after a success
+func (l *Limiter) Increase() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.refill(time.Now())
+	l.rate = min(l.max, l.rate+l.step)
+}
+
+// Decrease halves the rate after a failure
+func (l *Limiter) Decrease() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.refill(time.Now())
+	l.rate = max(l.min, l.rate/2)
+}
+
+// Rate returns the current tokens per second
+func (l *Limiter) Rate() float64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.rate
+}
+
+func (l *Limiter) refill(now time.Time) {
+	l.tokens = min(l.burst, l.tokens+now.Sub(l.last).Seconds()*l.rate)
+	l.last = now
+}
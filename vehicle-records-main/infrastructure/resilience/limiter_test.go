@@ -0,0 +1,77 @@
+package resilience
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestLimiterBackOffAndRecovery(t *testing.T) {
+	l := NewLimiter(1, 20, 1)
+	if l.Rate() != 20 {
+		t.Fatalf("initial rate = %v, want 20", l.Rate())
+	}
+
+	for _, want := range []float64{10, 5, 2.5, 1.25, 1, 1} {
+		l.Decrease()
+		if l.Rate() != want {
+			t.Fatalf("rate after failure = %v, want %v", l.Rate(), want)
+		}
+	}
+
+	// every success adds a twentieth of max
+	l.Increase()
+	if l.Rate() != 2 {
+		t.Fatalf("rate after success = %v, want 2", l.Rate())
+	}
+	for i := 0; i < 30; i++ {
+		l.Increase()
+	}
+	if l.Rate() != 20 {
+		t.Errorf("rate after recovery = %v, want 20", l.Rate())
+	}
+}
+
+func TestLimiterWait(t *testing.T) {
+	l := NewLimiter(1, 50, 2)
+	ctx := context.Background()
+
+	start := time.Now()
+	for i := 0; i < 2; i++ {
+		if err := l.Wait(ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if d := time.Since(start); d > 10*time.Millisecond {
+		t.Errorf("burst took %v", d)
+	}
+
+	// the bucket is empty, the next token comes at 50 a second
+	start = time.Now()
+	if err := l.Wait(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if d := time.Since(start); d < 10*time.Millisecond {
+		t.Errorf("Wait() on an empty bucket returned after %v", d)
+	}
+}
+
+func TestLimiterWaitCanceled(t *testing.T) {
+	l := NewLimiter(0.1, 0.1, 1)
+	_ = l.Wait(context.Background())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Millisecond)
+	defer cancel()
+	if err := l.Wait(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Wait() = %v, want %v", err, context.DeadlineExceeded)
+	}
+
+	// the canceled wait gave its token back
+	l.mu.Lock()
+	tokens := l.tokens
+	l.mu.Unlock()
+	if tokens < -0.01 || tokens > 0.01 {
+		t.Errorf("tokens after canceled wait = %v, want 0", tokens)
+	}
+}
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_vehicle_records_segment_store_time;
+DROP TABLE IF EXISTS parking_sessions;
@@ -0,0 +1,31 @@
+CREATE TABLE parking_sessions
+(
+    id                           UUID DEFAULT uuid_generate_v4() PRIMARY KEY,
+    citizen_plate_number         TEXT        NOT NULL,
+    citizen_plate_number_numeric BIGINT      NOT NULL,
+
+    ring_id                      BIGINT,
+    street_id                    BIGINT,
+    segment_id                   BIGINT      NOT NULL,
+    parking_lot_id               BIGINT      NOT NULL,
+
+    first_record_id              UUID        NOT NULL REFERENCES vehicle_records (record_id) ON DELETE CASCADE,
+    last_record_id               UUID        NOT NULL REFERENCES vehicle_records (record_id) ON DELETE CASCADE,
+    sightings                    INTEGER     NOT NULL DEFAULT 1,
+
+    start_time                   BIGINT      NOT NULL,
+    last_seen_time               BIGINT      NOT NULL,
+    end_time                     BIGINT,
+    status                       VARCHAR(20) NOT NULL,
+
+    created_at                   BIGINT      NOT NULL,
+    updated_at                   BIGINT      NOT NULL,
+    deleted_at                   BIGINT
+);
+
+CREATE INDEX idx_parking_sessions_plate ON parking_sessions (citizen_plate_number_numeric, start_time);
+CREATE INDEX idx_parking_sessions_open ON parking_sessions (segment_id, parking_lot_id, last_seen_time) WHERE status = 'open';
+CREATE UNIQUE INDEX uq_parking_sessions_open_plate ON parking_sessions (citizen_plate_number_numeric, segment_id, parking_lot_id)
+    WHERE status = 'open' AND deleted_at = 0;
+
+CREATE INDEX idx_vehicle_records_segment_store_time ON vehicle_records (segment_id, parking_lot_id, record_store_time);
@@ -0,0 +1,18 @@
+CREATE TABLE record_outbox
+(
+    id              UUID DEFAULT uuid_generate_v4() PRIMARY KEY,
+    record_id       UUID        NOT NULL UNIQUE REFERENCES vehicle_records (record_id) ON DELETE CASCADE,
+    status          VARCHAR(20) NOT NULL,
+    photos          BYTEA, -- photos waiting for upload, cleared once they are uploaded
+    attempts        INTEGER     NOT NULL DEFAULT 0,
+    next_attempt_at BIGINT      NOT NULL,
+    locked_until    BIGINT      NOT NULL DEFAULT 0,
+    last_error      TEXT,
+
+    created_at      BIGINT      NOT NULL,
+    updated_at      BIGINT      NOT NULL,
+    deleted_at      BIGINT      NOT NULL DEFAULT 0
+);
+
+CREATE INDEX idx_record_outbox_due ON record_outbox (next_attempt_at)
+    WHERE status IN ('pending_upload', 'pending_send');
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS forwarding_attempts;
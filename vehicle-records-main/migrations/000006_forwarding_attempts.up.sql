@@ -0,0 +1,23 @@
+CREATE TABLE forwarding_attempts
+(
+    id                 UUID DEFAULT uuid_generate_v4() PRIMARY KEY,
+    record_id          UUID        NOT NULL REFERENCES vehicle_records (record_id) ON DELETE CASCADE,
+    forwarder          VARCHAR(50) NOT NULL,
+    attempted_at       BIGINT      NOT NULL,
+    status_code        INTEGER     NOT NULL DEFAULT 0, -- 0 when no response was received
+    response_body      TEXT,                           -- first 2KB of the response
+    latency_ms         BIGINT      NOT NULL,
+    success            BOOLEAN     NOT NULL,
+    error_class        VARCHAR(20),
+    error              TEXT,
+    request_id         VARCHAR(250),
+    plate_detection_id INTEGER,
+
+    created_at         BIGINT      NOT NULL,
+    updated_at         BIGINT      NOT NULL,
+    deleted_at         BIGINT      NOT NULL DEFAULT 0
+);
+
+CREATE INDEX idx_forwarding_attempts_record ON forwarding_attempts (record_id, attempted_at);
+CREATE INDEX idx_forwarding_attempts_failures ON forwarding_attempts (attempted_at)
+    WHERE success = false;
@@ -0,0 +1,15 @@
+CREATE TABLE api_keys
+(
+    id           UUID DEFAULT uuid_generate_v4() PRIMARY KEY,
+    name         VARCHAR(100) NOT NULL,
+    prefix       VARCHAR(20)  NOT NULL,
+    key_hash     CHAR(64)     NOT NULL UNIQUE, -- hex SHA-256 of the key, the key itself is never stored
+    scopes       TEXT         NOT NULL,        -- comma separated
+    expires_at   BIGINT       NOT NULL DEFAULT 0,
+    revoked_at   BIGINT       NOT NULL DEFAULT 0,
+    last_used_at BIGINT       NOT NULL DEFAULT 0,
+
+    created_at   BIGINT       NOT NULL,
+    updated_at   BIGINT       NOT NULL,
+    deleted_at   BIGINT       NOT NULL DEFAULT 0
+);
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_vehicle_records_not_sent;
+DROP TABLE IF EXISTS resend_checkpoints;
@@ -0,0 +1,12 @@
+CREATE TABLE resend_checkpoints
+(
+    name              VARCHAR(50) PRIMARY KEY,
+    cursor_created_at BIGINT      NOT NULL DEFAULT 0,
+    cursor_record_id  VARCHAR(36) NOT NULL DEFAULT '', -- empty when no pass is in progress
+    pass_started_at   BIGINT      NOT NULL DEFAULT 0,
+    locked_until      BIGINT      NOT NULL DEFAULT 0,
+    updated_at        BIGINT      NOT NULL DEFAULT 0
+);
+
+CREATE INDEX idx_vehicle_records_not_sent ON vehicle_records (created_at, record_id)
+    WHERE sent = false;
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_vehicle_records_duplicate_of;
+DROP INDEX IF EXISTS idx_vehicle_records_dedup;
+ALTER TABLE vehicle_records
+    DROP COLUMN IF EXISTS duplicates,
+    DROP COLUMN IF EXISTS duplicate_of;
@@ -0,0 +1,8 @@
+ALTER TABLE vehicle_records
+    ADD COLUMN duplicate_of UUID REFERENCES vehicle_records (record_id) ON DELETE SET NULL, -- survivor of a suppressed sighting
+    ADD COLUMN duplicates   INTEGER NOT NULL DEFAULT 0;                                     -- sightings suppressed in favour of this record
+
+CREATE INDEX idx_vehicle_records_dedup ON vehicle_records (citizen_plate_number_numeric, record_store_time)
+    WHERE duplicate_of IS NULL;
+CREATE INDEX idx_vehicle_records_duplicate_of ON vehicle_records (duplicate_of)
+    WHERE duplicate_of IS NOT NULL;
@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS record_review_audits;
+DROP TABLE IF EXISTS record_reviews;
+ALTER TABLE record_outbox
+    DROP COLUMN IF EXISTS held;
@@ -0,0 +1,38 @@
+ALTER TABLE record_outbox
+    ADD COLUMN held BOOLEAN NOT NULL DEFAULT false; -- waits in pending_review once uploaded
+
+CREATE TABLE record_reviews
+(
+    id             UUID DEFAULT uuid_generate_v4() PRIMARY KEY,
+    record_id      UUID         NOT NULL UNIQUE REFERENCES vehicle_records (record_id) ON DELETE CASCADE,
+    status         VARCHAR(20)  NOT NULL,
+    reasons        TEXT         NOT NULL, -- comma separated quality rules the record failed
+    original_plate TEXT         NOT NULL,
+    reviewer       VARCHAR(100),
+    reviewed_at    BIGINT       NOT NULL DEFAULT 0,
+    note           TEXT,
+
+    created_at     BIGINT       NOT NULL,
+    updated_at     BIGINT       NOT NULL,
+    deleted_at     BIGINT       NOT NULL DEFAULT 0
+);
+
+CREATE INDEX idx_record_reviews_status ON record_reviews (status, created_at);
+
+CREATE TABLE record_review_audits
+(
+    id         UUID DEFAULT uuid_generate_v4() PRIMARY KEY,
+    review_id  UUID         NOT NULL REFERENCES record_reviews (id) ON DELETE CASCADE,
+    record_id  UUID         NOT NULL,
+    action     VARCHAR(20)  NOT NULL,
+    reviewer   VARCHAR(100) NOT NULL,
+    old_plate  TEXT,
+    new_plate  TEXT,
+    note       TEXT,
+
+    created_at BIGINT       NOT NULL,
+    updated_at BIGINT       NOT NULL,
+    deleted_at BIGINT       NOT NULL DEFAULT 0
+);
+
+CREATE INDEX idx_record_review_audits_review ON record_review_audits (review_id, created_at);
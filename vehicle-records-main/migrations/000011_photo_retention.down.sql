@@ -0,0 +1,10 @@
+DROP INDEX IF EXISTS idx_citizen_vehicle_photos_not_reaped;
+DROP TABLE IF EXISTS retention_runs;
+DROP TABLE IF EXISTS photo_retention_policies;
+ALTER TABLE citizen_vehicle_photos
+    DROP COLUMN IF EXISTS reaped_at;
+ALTER TABLE vehicle_records
+    DROP COLUMN IF EXISTS legal_hold_by,
+    DROP COLUMN IF EXISTS legal_hold_reason,
+    DROP COLUMN IF EXISTS legal_hold_at,
+    DROP COLUMN IF EXISTS legal_hold;
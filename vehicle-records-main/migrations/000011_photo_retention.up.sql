@@ -0,0 +1,41 @@
+ALTER TABLE vehicle_records
+    ADD COLUMN legal_hold        BOOLEAN NOT NULL DEFAULT false, -- photos are kept as evidence
+    ADD COLUMN legal_hold_at     BIGINT  NOT NULL DEFAULT 0,
+    ADD COLUMN legal_hold_reason TEXT,
+    ADD COLUMN legal_hold_by     VARCHAR(100);
+
+ALTER TABLE citizen_vehicle_photos
+    ADD COLUMN reaped_at BIGINT NOT NULL DEFAULT 0; -- objects were deleted and paths nulled at
+
+CREATE TABLE photo_retention_policies
+(
+    status          VARCHAR(20) PRIMARY KEY,
+    retention_hours INTEGER     NOT NULL, -- 0 keeps the photos forever
+    updated_by      VARCHAR(100),
+    updated_at      BIGINT      NOT NULL DEFAULT 0
+);
+
+INSERT INTO photo_retention_policies (status, retention_hours)
+VALUES ('forwarded', 2160),
+       ('rejected', 720),
+       ('under_review', 0),
+       ('evidence_hold', 0);
+
+CREATE TABLE retention_runs
+(
+    id          UUID DEFAULT uuid_generate_v4() PRIMARY KEY,
+    started_at  BIGINT NOT NULL,
+    finished_at BIGINT NOT NULL,
+    photos      INTEGER NOT NULL,
+    objects     INTEGER NOT NULL,
+    bytes       BIGINT NOT NULL, -- bytes reclaimed from object storage
+    errors      INTEGER NOT NULL,
+
+    created_at  BIGINT NOT NULL,
+    updated_at  BIGINT NOT NULL,
+    deleted_at  BIGINT NOT NULL DEFAULT 0
+);
+
+CREATE INDEX idx_retention_runs_started_at ON retention_runs (started_at);
+CREATE INDEX idx_citizen_vehicle_photos_not_reaped ON citizen_vehicle_photos (record_id)
+    WHERE reaped_at = 0;
@@ -0,0 +1 @@
+DROP INDEX IF EXISTS idx_vehicle_records_plate_history;
@@ -0,0 +1,4 @@
+-- plate history pages through the sightings of a plate newest first, record_id breaks ties of the cursor
+CREATE INDEX idx_vehicle_records_plate_history
+    ON vehicle_records (citizen_plate_number_numeric, record_store_time DESC, record_id DESC)
+    WHERE duplicate_of IS NULL;
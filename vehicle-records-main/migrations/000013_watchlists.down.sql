@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS watchlist_matches;
+DROP TABLE IF EXISTS watchlist_entries;
+DROP TABLE IF EXISTS watchlists;
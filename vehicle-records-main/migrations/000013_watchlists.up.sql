@@ -0,0 +1,54 @@
+CREATE TABLE watchlists
+(
+    id          UUID DEFAULT uuid_generate_v4() PRIMARY KEY,
+    name        VARCHAR(100) NOT NULL,
+    kind        VARCHAR(20)  NOT NULL, -- stolen, unpaid_fines, police or other
+    description TEXT,
+    active      BOOLEAN      NOT NULL DEFAULT true,
+
+    created_at  BIGINT       NOT NULL,
+    updated_at  BIGINT       NOT NULL,
+    deleted_at  BIGINT       NOT NULL DEFAULT 0
+);
+
+CREATE TABLE watchlist_entries
+(
+    id           UUID DEFAULT uuid_generate_v4() PRIMARY KEY,
+    watchlist_id UUID         NOT NULL REFERENCES watchlists (id) ON DELETE CASCADE,
+    pattern      VARCHAR(50)  NOT NULL, -- a plate, or a plate with * and ? wildcards
+    priority     INTEGER      NOT NULL DEFAULT 0,
+    valid_from   BIGINT       NOT NULL DEFAULT 0,
+    valid_until  BIGINT       NOT NULL DEFAULT 0, -- 0 never expires
+    note         TEXT,
+    created_by   VARCHAR(100) NOT NULL,
+
+    created_at   BIGINT       NOT NULL,
+    updated_at   BIGINT       NOT NULL,
+    deleted_at   BIGINT       NOT NULL DEFAULT 0
+);
+
+CREATE INDEX idx_watchlist_entries_watchlist ON watchlist_entries (watchlist_id, created_at);
+
+CREATE TABLE watchlist_matches
+(
+    id              UUID DEFAULT uuid_generate_v4() PRIMARY KEY,
+    watchlist_id    UUID         NOT NULL REFERENCES watchlists (id) ON DELETE CASCADE,
+    entry_id        UUID         NOT NULL REFERENCES watchlist_entries (id) ON DELETE CASCADE,
+    record_id       UUID         NOT NULL REFERENCES vehicle_records (record_id) ON DELETE CASCADE,
+    plate           TEXT         NOT NULL,
+    priority        INTEGER      NOT NULL,
+    status          VARCHAR(20)  NOT NULL,
+    published_at    BIGINT       NOT NULL DEFAULT 0, -- 0 until the alert was published
+    acknowledged_by VARCHAR(100),
+    acknowledged_at BIGINT       NOT NULL DEFAULT 0,
+    note            TEXT,
+
+    created_at      BIGINT       NOT NULL,
+    updated_at      BIGINT       NOT NULL,
+    deleted_at      BIGINT       NOT NULL DEFAULT 0,
+    UNIQUE (entry_id, record_id)
+);
+
+CREATE INDEX idx_watchlist_matches_status ON watchlist_matches (status, priority DESC, created_at);
+CREATE INDEX idx_watchlist_matches_unpublished ON watchlist_matches (created_at)
+    WHERE published_at = 0;
@@ -0,0 +1,2 @@
+ALTER TABLE vehicle_records
+    DROP COLUMN IF EXISTS match_confidence;
@@ -0,0 +1,2 @@
+ALTER TABLE vehicle_records
+    ADD COLUMN match_confidence DOUBLE PRECISION NOT NULL DEFAULT 0; -- 0 when no segment was matched
@@ -0,0 +1,2 @@
+ALTER TABLE record_review_audits
+    DROP COLUMN IF EXISTS api_key_id;
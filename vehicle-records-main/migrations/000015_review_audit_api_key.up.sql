@@ -0,0 +1,2 @@
+ALTER TABLE record_review_audits
+    ADD COLUMN api_key_id UUID; -- api key the reviewer acted with
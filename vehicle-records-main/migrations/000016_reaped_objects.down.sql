@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_reaped_objects_locked_until;
+DROP TABLE IF EXISTS reaped_objects;
+DELETE FROM photo_retention_policies WHERE status = 'unforwarded';
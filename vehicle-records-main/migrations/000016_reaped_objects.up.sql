@@ -0,0 +1,16 @@
+INSERT INTO photo_retention_policies (status, retention_hours)
+VALUES ('unforwarded', 2160); -- records neither forwarded nor under review, as failed ones
+
+CREATE TABLE reaped_objects
+(
+    id           UUID DEFAULT uuid_generate_v4() PRIMARY KEY,
+    path         TEXT   NOT NULL, -- bucket/object of a reaped photo, deleted after the reaping commit
+    reaped_at    BIGINT NOT NULL,
+    locked_until BIGINT NOT NULL DEFAULT 0, -- held by a pass deleting it
+
+    created_at   BIGINT NOT NULL,
+    updated_at   BIGINT NOT NULL,
+    deleted_at   BIGINT NOT NULL DEFAULT 0
+);
+
+CREATE INDEX idx_reaped_objects_locked_until ON reaped_objects (locked_until);
@@ -0,0 +1,186 @@
+package consumer
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/rabbitmq/amqp091-go"
+)
+
+var ErrDeadQueueNotFound = errors.New("dead-letter queue not found")
+
+// DeadLetter is a message in a dead-letter queue
+type DeadLetter struct {
+	ID          string `json:"id"`
+	RoutingKey  string `json:"routingKey"`
+	Error       string `json:"error"`
+	Attempts    int    `json:"attempts"`
+	DeadAt      int64  `json:"deadAt"` // unix milliseconds
+	ContentType string `json:"contentType"`
+	Body        string `json:"body"`
+}
+
+// Admin inspects, replays and purges the dead-letter queues. Every call runs
+// on its own channel, messages it reads and leaves are returned to their
+// queue, in order, when the channel is closed.
+type Admin struct {
+	channel func() (*amqp091.Channel, error)
+}
+
+// NewAdmin returns an Admin opening its channels with channel
+func NewAdmin(channel func() (*amqp091.Channel, error)) *Admin {
+	return &Admin{channel: channel}
+}
+
+// Inspect returns up to limit dead letters of queue, oldest first, and the
+// number of messages in its dead-letter queue
+func (a *Admin) Inspect(queue string, limit int) ([]DeadLetter, int, error) {
+	var letters []DeadLetter
+	total, err := a.walk(queue, func(_ *amqp091.Channel, d amqp091.Delivery) (bool, error) {
+		letters = append(letters, deadLetter(d))
+		return len(letters) < limit, nil
+	})
+	return letters, total, err
+}
+
+// Replay publishes the dead letters of queue with the given ids, or up to
+// limit of them without ids, back to queue with a fresh attempt count. It
+// returns the number of replayed messages.
+func (a *Admin) Replay(ctx context.Context, queue string, ids []string, limit int) (int, error) {
+	replayed := 0
+	confirming := false
+	_, err := a.walk(queue, func(ch *amqp091.Channel, d amqp091.Delivery) (bool, error) {
+		if !selected(d, ids) {
+			return true, nil
+		}
+		// a dead letter is acked once its copy is confirmed
+		if !confirming {
+			if err := ch.Confirm(false); err != nil {
+				return false, fmt.Errorf("failed to put channel in confirm mode: %w", err)
+			}
+			confirming = true
+		}
+		headers := forwardHeaders(d.Headers)
+		delete(headers, HeaderRetries)
+		delete(headers, HeaderError)
+		delete(headers, HeaderDeadAt)
+		if _, ok := headers[HeaderRoutingKey]; !ok {
+			headers[HeaderRoutingKey] = d.RoutingKey
+		}
+		if err := Publish(ctx, ch, "", queue, republishing(d, headers)); err != nil {
+			return false, fmt.Errorf("failed to replay dead letter: %w", err)
+		}
+		if err := d.Ack(false); err != nil {
+			return false, fmt.Errorf("failed to ack dead letter: %w", err)
+		}
+		replayed++
+		return len(ids) > 0 || replayed < limit, nil
+	})
+	return replayed, err
+}
+
+// Purge deletes the dead letters of queue with the given ids, or all of them
+// without ids. It returns the number of deleted messages.
+func (a *Admin) Purge(queue string, ids []string) (int, error) {
+	if len(ids) == 0 {
+		ch, err := a.channel()
+		if err != nil {
+			return 0, err
+		}
+		defer ch.Close()
+		if _, err := ch.QueueDeclarePassive(DeadQueue(queue), true, false, false, false, nil); err != nil {
+			return 0, ErrDeadQueueNotFound
+		}
+		n, err := ch.QueuePurge(DeadQueue(queue), false)
+		if err != nil {
+			return 0, fmt.Errorf("failed to purge dead letters: %w", err)
+		}
+		return n, nil
+	}
+
+	purged := 0
+	_, err := a.walk(queue, func(_ *amqp091.Channel, d amqp091.Delivery) (bool, error) {
+		if !selected(d, ids) {
+			return true, nil
+		}
+		if err := d.Ack(false); err != nil {
+			return false, fmt.Errorf("failed to ack dead letter: %w", err)
+		}
+		purged++
+		return true, nil
+	})
+	return purged, err
+}
+
+// walk reads the messages of the dead-letter queue of queue once each, until
+// fn returns false. It returns the number of messages the queue had.
+func (a *Admin) walk(queue string, fn func(*amqp091.Channel, amqp091.Delivery) (bool, error)) (int, error) {
+	ch, err := a.channel()
+	if err != nil {
+		return 0, err
+	}
+	// closing the channel requeues the messages that were not acked
+	defer ch.Close()
+
+	q, err := ch.QueueDeclarePassive(DeadQueue(queue), true, false, false, false, nil)
+	if err != nil {
+		return 0, ErrDeadQueueNotFound
+	}
+	for i := 0; i < q.Messages; i++ {
+		d, ok, err := ch.Get(q.Name, false)
+		if err != nil {
+			return q.Messages, fmt.Errorf("failed to get dead letter: %w", err)
+		}
+		if !ok {
+			break
+		}
+		next, err := fn(ch, d)
+		if err != nil || !next {
+			return q.Messages, err
+		}
+	}
+	return q.Messages, nil
+}
+
+func deadLetter(d amqp091.Delivery) DeadLetter {
+	key, _ := d.Headers[HeaderRoutingKey].(string)
+	cause, _ := d.Headers[HeaderError].(string)
+	deadAt := int64(0)
+	if t, ok := d.Headers[HeaderDeadAt].(int64); ok {
+		deadAt = t
+	}
+	return DeadLetter{
+		ID:          d.MessageId,
+		RoutingKey:  key,
+		Error:       cause,
+		Attempts:    retries(d) + 1,
+		DeadAt:      deadAt,
+		ContentType: d.ContentType,
+		Body:        string(d.Body),
+	}
+}
+
+func selected(d amqp091.Delivery, ids []string) bool {
+	if len(ids) == 0 {
+		return true
+	}
+	for _, id := range ids {
+		if id == d.MessageId {
+			return true
+		}
+	}
+	return false
+}
+
+// newID returns a random id for dead letters published without a message id
+func newID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return fmt.Sprintf("%d", time.Now().UnixNano())
+	}
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,97 @@
+package consumer
+
+import (
+	"time"
+)
+
+// scaleInterval is how often the autoscaler looks at the load
+const scaleInterval = 10 * time.Second
+
+// Load of the workers over one scaleInterval
+type load struct {
+	workers int
+	backlog int           // messages ready in the queue
+	busy    float64       // share of the interval the workers spent in handlers
+	latency time.Duration // average handler call
+}
+
+// autoscale adds a worker while messages wait in the queue and the workers
+// are busy, and removes one when the queue is empty and they are mostly
+// idle, between Workers and MaxWorkers. It stops when a shutdown starts.
+func (r *Runtime) autoscale() {
+	lg := r.logger.With("method", "autoscale")
+	ticker := time.NewTicker(scaleInterval)
+	defer ticker.Stop()
+
+	last := time.Now()
+	for range ticker.C {
+		if r.Draining() {
+			return
+		}
+		now := time.Now()
+		l := r.load(now.Sub(last))
+		last = now
+
+		switch scale(l, r.cfg.Settings) {
+		case 1:
+			r.startWorker()
+			lg.Info("worker added", "workers", l.workers+1, "backlog", l.backlog, "busy", l.busy, "latency", l.latency)
+		case -1:
+			select {
+			case r.retire <- struct{}{}:
+				lg.Info("worker removed", "workers", l.workers-1, "backlog", l.backlog, "busy", l.busy)
+			default:
+			}
+		}
+	}
+}
+
+// load returns the load of the workers since the last call, interval ago
+func (r *Runtime) load(interval time.Duration) load {
+	workers := int(r.workers.Load())
+	handled := r.handled.Swap(0)
+	busy := time.Duration(r.busy.Swap(0))
+
+	l := load{workers: workers, backlog: r.backlog()}
+	if workers > 0 && interval > 0 {
+		l.busy = float64(busy) / float64(time.Duration(workers)*interval)
+	}
+	if handled > 0 {
+		l.latency = busy / time.Duration(handled)
+	}
+	return l
+}
+
+// backlog returns the messages ready in the queue and in the buffer, the
+// queue is skipped while the channel is closed
+func (r *Runtime) backlog() int {
+	n := len(r.buffer)
+	r.mu.RLock()
+	ch := r.ch
+	r.mu.RUnlock()
+	if ch == nil || ch.IsClosed() {
+		return n
+	}
+	q, err := ch.QueueDeclarePassive(r.cfg.Queue, true, false, false, false, nil)
+	if err != nil {
+		r.logger.Warn("failed to inspect queue", "error", err)
+		return n
+	}
+	return n + q.Messages
+}
+
+// scale returns 1 to add a worker, -1 to remove one or 0. No worker is added
+// while handlers take more than half their timeout, a slow dependency is not
+// helped by more concurrent calls.
+func scale(l load, s Settings) int {
+	switch {
+	case l.backlog > 0 && l.busy >= 0.8 && l.workers < s.MaxWorkers:
+		if s.Timeout > 0 && l.latency > s.Timeout/2 {
+			return 0
+		}
+		return 1
+	case l.backlog == 0 && l.busy < 0.3 && l.workers > max(s.Workers, 1):
+		return -1
+	}
+	return 0
+}
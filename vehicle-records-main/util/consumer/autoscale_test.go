@@ -0,0 +1,45 @@
+package consumer
+
+import (
+	"testing"
+	"time"
+)
+
+func TestScale(t *testing.T) {
+	s := Settings{Workers: 2, MaxWorkers: 4, Timeout: 10 * time.Second}
+	tests := []struct {
+		name string
+		load load
+		want int
+	}{
+		{"backlog and busy", load{workers: 2, backlog: 10, busy: 0.9, latency: time.Second}, 1},
+		{"at max workers", load{workers: 4, backlog: 10, busy: 0.9, latency: time.Second}, 0},
+		{"slow handlers", load{workers: 2, backlog: 10, busy: 0.9, latency: 6 * time.Second}, 0},
+		{"backlog but idle", load{workers: 2, backlog: 10, busy: 0.5}, 0},
+		{"empty and idle", load{workers: 3, busy: 0.1}, -1},
+		{"at min workers", load{workers: 2, busy: 0.1}, 0},
+		{"empty but busy", load{workers: 3, busy: 0.5}, 0},
+	}
+	for _, tt := range tests {
+		if got := scale(tt.load, s); got != tt.want {
+			t.Errorf("%s: scale() = %d, want %d", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestQueueArgs(t *testing.T) {
+	s := Settings{QueueTTL: 18 * time.Hour, QueueMaxLength: 1000}
+	args := s.QueueArgs(map[string]interface{}{"x-queue-type": "quorum"})
+	if args["x-queue-type"] != "quorum" {
+		t.Errorf("x-queue-type = %v, want quorum", args["x-queue-type"])
+	}
+	if args["x-message-ttl"] != 64800000 {
+		t.Errorf("x-message-ttl = %v, want 64800000", args["x-message-ttl"])
+	}
+	if args["x-max-length"] != 1000 || args["x-overflow"] != "reject-publish" {
+		t.Errorf("max length args = %v, %v", args["x-max-length"], args["x-overflow"])
+	}
+	if _, ok := (Settings{}).QueueArgs(nil)["x-message-ttl"]; ok {
+		t.Error("x-message-ttl set without a QueueTTL")
+	}
+}
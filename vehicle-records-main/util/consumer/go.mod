@@ -0,0 +1,5 @@
+module git.abanppc.com/farin-project/vehicle-records/util/consumer
+
+go 1.22
+
+require github.com/rabbitmq/amqp091-go v1.10.0
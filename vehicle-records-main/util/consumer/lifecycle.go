@@ -0,0 +1,122 @@
+package consumer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/rabbitmq/amqp091-go"
+)
+
+// ErrDraining is returned by Consume once Shutdown started
+var ErrDraining = errors.New("consumer is draining")
+
+// Consume starts consuming the queue on ch under the consumer tag of the
+// runtime, the deliveries are passed to Feed
+func (r *Runtime) Consume(ch *amqp091.Channel) (<-chan amqp091.Delivery, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if r.draining {
+		return nil, ErrDraining
+	}
+	// applies to the consumers started on ch after it
+	if err := ch.Qos(r.cfg.Prefetch, 0, false); err != nil {
+		return nil, fmt.Errorf("failed to set prefetch: %w", err)
+	}
+	return ch.Consume(r.cfg.Queue, r.tag, false, false, false, false, nil)
+}
+
+// Feed copies deliveries into the buffer of the workers until the channel
+// of deliveries is closed, by a reconnect or by Shutdown
+func (r *Runtime) Feed(deliveries <-chan amqp091.Delivery) {
+	r.mu.Lock()
+	if r.draining {
+		r.mu.Unlock()
+		return
+	}
+	r.feeding.Add(1)
+	r.mu.Unlock()
+	defer r.feeding.Done()
+
+	for msg := range deliveries {
+		r.buffer <- msg
+	}
+}
+
+// RunWorkers starts the workers handling the buffered deliveries, they return
+// once Shutdown drained the buffer. With MaxWorkers over Workers the number
+// of workers follows the load, see autoscale.
+func (r *Runtime) RunWorkers() {
+	for i := 0; i < max(r.cfg.Workers, 1); i++ {
+		r.startWorker()
+	}
+	if r.cfg.MaxWorkers > r.cfg.Workers {
+		go r.autoscale()
+	}
+}
+
+func (r *Runtime) startWorker() {
+	r.workers.Add(1)
+	r.working.Add(1)
+	go func() {
+		defer r.working.Done()
+		for {
+			select {
+			case <-r.retire:
+				r.workers.Add(-1)
+				return
+			case msg, ok := <-r.buffer:
+				if !ok {
+					r.workers.Add(-1)
+					return
+				}
+				r.Process(msg)
+			}
+		}
+	}()
+}
+
+// Draining tells if Shutdown started
+func (r *Runtime) Draining() bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.draining
+}
+
+// Shutdown stops consuming, lets the workers handle the buffered deliveries
+// and waits for them until ctx is done. Handlers still running then are
+// cancelled and their messages requeued, the deliveries left in the buffer
+// are requeued by the broker when the channel is closed, which must happen
+// after Shutdown returns.
+func (r *Runtime) Shutdown(ctx context.Context) error {
+	r.mu.Lock()
+	r.draining = true
+	ch := r.ch
+	r.mu.Unlock()
+
+	lg := r.logger.With("method", "Shutdown")
+	lg.Info("draining consumer", "buffered", len(r.buffer))
+	// the broker stops sending, deliveries already sent are still fed
+	if ch != nil && !ch.IsClosed() {
+		if err := ch.Cancel(r.tag, false); err != nil {
+			lg.Warn("failed to cancel consumer", "error", err)
+		}
+	}
+
+	fed := make(chan struct{})
+	go func() {
+		r.feeding.Wait()
+		r.closeBuffer.Do(func() { close(r.buffer) })
+		r.working.Wait()
+		close(fed)
+	}()
+	select {
+	case <-fed:
+		lg.Info("consumer drained")
+		return nil
+	case <-ctx.Done():
+		r.cancel()
+		left := len(r.buffer)
+		return fmt.Errorf("consumer of %s not drained, %d buffered deliveries left: %w", r.cfg.Queue, left, ctx.Err())
+	}
+}
@@ -0,0 +1,34 @@
+package consumer
+
+import (
+	"context"
+	"errors"
+
+	"github.com/rabbitmq/amqp091-go"
+)
+
+// ErrNotConfirmed is returned when the broker nacked a published message or
+// the channel closed before confirming it
+var ErrNotConfirmed = errors.New("message not confirmed by rabbitmq")
+
+// Publish publishes msg on ch and, when ch is in confirm mode, waits for the
+// broker to confirm it, so the message is stored before the delivery it came
+// from is acked
+func Publish(ctx context.Context, ch *amqp091.Channel, exchange, key string, msg amqp091.Publishing) error {
+	dc, err := ch.PublishWithDeferredConfirmWithContext(ctx, exchange, key, false, false, msg)
+	if err != nil {
+		return err
+	}
+	if dc == nil {
+		// ch is not in confirm mode
+		return nil
+	}
+	ok, err := dc.WaitContext(ctx)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrNotConfirmed
+	}
+	return nil
+}
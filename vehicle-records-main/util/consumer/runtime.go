@@ -0,0 +1,428 @@
+// Package consumer is the runtime the RabbitMQ consumers of the farin
+// services share. It dispatches deliveries to handlers by routing key, retries
+// failed messages through delay queues with an exponential backoff and moves
+// them to a dead-letter queue after the last attempt, so a message that
+// cannot be handled never loops on a worker.
+//
+// Every queue Q gets these queues next to it:
+//   - Q.delay.<duration>, one per retry delay, whose expired messages go back
+//     to Q through the default exchange
+//   - Q.dead, bound to the dead-letter exchange with Q as the routing key
+package consumer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/rabbitmq/amqp091-go"
+)
+
+// Headers the runtime sets on retried and dead-lettered messages
+const (
+	HeaderRoutingKey = "x-routing-key"      // routing key the message was first delivered with
+	HeaderRetries    = "x-retries"          // times the message went through a delay queue
+	HeaderError      = "x-last-error"       // error of the last attempt
+	HeaderDeadAt     = "x-dead-lettered-at" // unix milliseconds
+	// headerDeliveryCount is set by quorum queues on redeliveries
+	headerDeliveryCount = "x-delivery-count"
+)
+
+type HandlerFunc func(context.Context, []byte) error
+
+// Config of the runtime of one queue
+type Config struct {
+	Queue              string
+	DeadLetterExchange string        // direct exchange dead letters are published on, with Queue as routing key
+	MaxAttempts        int           // attempts of a message before it is dead-lettered
+	RetryDelay         time.Duration // delay of the first retry, doubled on every next one
+	RetryMax           time.Duration
+	Settings
+}
+
+// Settings are the tunables of one consumer, the services read them from
+// their environment
+type Settings struct {
+	Prefetch   int           // unacked deliveries the broker sends the consumer, zero for no limit
+	Workers    int           // goroutines handling messages, the least the autoscaler keeps
+	MaxWorkers int           // most goroutines the autoscaler starts, at most Workers disables it
+	Buffer     int           // deliveries waiting for a worker
+	Timeout    time.Duration // of a handler call, zero for none
+	// queue arguments, a queue declared with other arguments must be deleted
+	// before they change
+	QueueTTL       time.Duration // x-message-ttl, zero for none
+	QueueMaxLength int           // x-max-length, publishes over it are rejected, zero for none
+}
+
+// QueueArgs returns args with the queue arguments of s added
+func (s Settings) QueueArgs(args amqp091.Table) amqp091.Table {
+	out := amqp091.Table{}
+	for k, v := range args {
+		out[k] = v
+	}
+	// ints are sent as 32-bit values, as the queues were first declared with,
+	// rabbitmq compares the types of the arguments of a redeclared queue
+	if s.QueueTTL > 0 {
+		out["x-message-ttl"] = int(s.QueueTTL.Milliseconds())
+	}
+	if s.QueueMaxLength > 0 {
+		out["x-max-length"] = s.QueueMaxLength
+		out["x-overflow"] = "reject-publish"
+	}
+	return out
+}
+
+// Delay returns the delay before attempt, attempts start from 1
+func (c Config) Delay(attempt int) time.Duration {
+	d := c.RetryDelay
+	for i := 2; i < attempt && d < c.RetryMax; i++ {
+		d *= 2
+	}
+	return min(d, c.RetryMax)
+}
+
+// DeadQueue returns the name of the dead-letter queue of a queue
+func DeadQueue(queue string) string {
+	return queue + ".dead"
+}
+
+// DelayQueue returns the name of the queue messages of queue wait d in
+func DelayQueue(queue string, d time.Duration) string {
+	return queue + ".delay." + d.String()
+}
+
+// permanentError is an error retrying does not fix
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+// Permanent marks err as one retrying does not fix, such as a malformed
+// payload, the message is dead-lettered without further attempts
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &permanentError{err: err}
+}
+
+type route struct {
+	pattern []string
+	handler HandlerFunc
+}
+
+// Runtime handles the deliveries of one queue
+type Runtime struct {
+	cfg    Config
+	logger *slog.Logger
+	routes []route
+	mu     sync.RWMutex
+	ch     *amqp091.Channel
+
+	tag    string // consumer tag, to cancel the consumer on shutdown
+	buffer chan amqp091.Delivery
+	// ctx is the parent of the handler contexts, it is cancelled when a
+	// shutdown runs out of time
+	ctx         context.Context
+	cancel      context.CancelFunc
+	draining    bool
+	feeding     sync.WaitGroup
+	working     sync.WaitGroup
+	closeBuffer sync.Once
+
+	// read by the autoscaler
+	workers atomic.Int32
+	retire  chan struct{} // a worker receiving from it stops
+	handled atomic.Int64
+	busy    atomic.Int64 // nanoseconds spent in handlers
+}
+
+func New(logger *slog.Logger, cfg Config) *Runtime {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Runtime{
+		cfg:    cfg,
+		logger: logger.With("layer", "ConsumerRuntime", "queue", cfg.Queue),
+		tag:    cfg.Queue + "-" + newID(),
+		buffer: make(chan amqp091.Delivery, cfg.Buffer),
+		ctx:    ctx,
+		cancel: cancel,
+		retire: make(chan struct{}),
+	}
+}
+
+// RegisterHandler routes the messages whose routing key matches pattern to
+// handler. Patterns are topic binding keys, * matches one word and # any
+// number of words.
+func (r *Runtime) RegisterHandler(pattern string, handler HandlerFunc) {
+	r.routes = append(r.routes, route{pattern: strings.Split(pattern, "."), handler: handler})
+}
+
+// Declare declares the delay and dead-letter queues of the queue on ch, which
+// retries and dead letters are then published on. It is called again with
+// the new channel after a reconnect.
+func (r *Runtime) Declare(ch *amqp091.Channel) error {
+	if err := ch.ExchangeDeclare(r.cfg.DeadLetterExchange, "direct", true, false, false, false, nil); err != nil {
+		return fmt.Errorf("failed to declare dead-letter exchange: %w", err)
+	}
+	dead, err := ch.QueueDeclare(DeadQueue(r.cfg.Queue), true, false, false, false, nil)
+	if err != nil {
+		return fmt.Errorf("failed to declare dead-letter queue: %w", err)
+	}
+	if err := ch.QueueBind(dead.Name, r.cfg.Queue, r.cfg.DeadLetterExchange, false, nil); err != nil {
+		return fmt.Errorf("failed to bind dead-letter queue: %w", err)
+	}
+
+	declared := map[time.Duration]bool{}
+	for attempt := 2; attempt <= r.cfg.MaxAttempts; attempt++ {
+		d := r.cfg.Delay(attempt)
+		if declared[d] {
+			continue
+		}
+		declared[d] = true
+		_, err := ch.QueueDeclare(DelayQueue(r.cfg.Queue, d), true, false, false, false, amqp091.Table{
+			"x-message-ttl":             d.Milliseconds(),
+			"x-dead-letter-exchange":    "",
+			"x-dead-letter-routing-key": r.cfg.Queue,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to declare delay queue: %w", err)
+		}
+	}
+
+	r.mu.Lock()
+	r.ch = ch
+	r.mu.Unlock()
+	return nil
+}
+
+// Process handles a delivery and acks it. A failed message is published to
+// the delay queue of its next attempt, or to the dead-letter exchange after
+// the last attempt or on a permanent error. Messages without a handler are
+// dropped.
+func (r *Runtime) Process(msg amqp091.Delivery) {
+	key := RoutingKey(msg)
+	lg := r.logger.With("routingKey", key)
+	lg.Info("rabbit message received in msg queue go channel")
+
+	handler := r.handler(key)
+	if handler == nil {
+		lg.Warn("no handler found for routingKey")
+		r.ack(lg, msg)
+		return
+	}
+
+	attempt := Attempt(msg)
+	if attempt > r.cfg.MaxAttempts {
+		// the message was redelivered after crashing the consumer
+		r.deadLetter(lg, msg, key, attempt-1, errors.New("delivery limit exceeded"))
+		return
+	}
+
+	start := time.Now()
+	err := r.call(handler, msg.Body)
+	r.handled.Add(1)
+	r.busy.Add(int64(time.Since(start)))
+	if err == nil {
+		r.ack(lg, msg)
+		lg.Info("rabbit message acked")
+		return
+	}
+	if r.ctx.Err() != nil {
+		// cut off by a shutdown, the message is left to the next consumer
+		lg.Warn("rabbit message requeued on shutdown", "error", err)
+		r.nack(lg, msg)
+		return
+	}
+
+	var permanent *permanentError
+	if errors.As(err, &permanent) || attempt >= r.cfg.MaxAttempts {
+		r.deadLetter(lg, msg, key, attempt, err)
+		return
+	}
+	r.retry(lg, msg, key, attempt, err)
+}
+
+func (r *Runtime) handler(key string) HandlerFunc {
+	words := strings.Split(key, ".")
+	for _, rt := range r.routes {
+		if match(rt.pattern, words) {
+			return rt.handler
+		}
+	}
+	return nil
+}
+
+// call runs handler, a panic is a permanent error
+func (r *Runtime) call(handler HandlerFunc, body []byte) (err error) {
+	ctx := r.ctx
+	if r.cfg.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, r.cfg.Timeout)
+		defer cancel()
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			err = Permanent(fmt.Errorf("handler panicked: %v", p))
+		}
+	}()
+	return handler(ctx, body)
+}
+
+func (r *Runtime) retry(lg *slog.Logger, msg amqp091.Delivery, key string, attempt int, cause error) {
+	delay := r.cfg.Delay(attempt + 1)
+	headers := forwardHeaders(msg.Headers)
+	headers[HeaderRoutingKey] = key
+	headers[HeaderRetries] = int64(retries(msg) + 1)
+	headers[HeaderError] = cause.Error()
+	if err := r.publish(msg, "", DelayQueue(r.cfg.Queue, delay), headers); err != nil {
+		lg.Error("failed to publish message for retry", "error", err)
+		r.nack(lg, msg)
+		return
+	}
+	r.ack(lg, msg)
+	lg.Warn("rabbit message scheduled for retry", "error", cause, "attempt", attempt, "delay", delay)
+}
+
+func (r *Runtime) deadLetter(lg *slog.Logger, msg amqp091.Delivery, key string, attempts int, cause error) {
+	headers := forwardHeaders(msg.Headers)
+	headers[HeaderRoutingKey] = key
+	headers[HeaderRetries] = int64(attempts - 1)
+	headers[HeaderError] = cause.Error()
+	headers[HeaderDeadAt] = time.Now().UnixMilli()
+	if msg.MessageId == "" {
+		msg.MessageId = newID()
+	}
+	if err := r.publish(msg, r.cfg.DeadLetterExchange, r.cfg.Queue, headers); err != nil {
+		lg.Error("failed to publish dead letter", "error", err)
+		r.nack(lg, msg)
+		return
+	}
+	r.ack(lg, msg)
+	lg.Error("rabbit message dead-lettered", "error", cause, "attempts", attempts, "messageID", msg.MessageId)
+}
+
+// publish waits for the broker to confirm the copy of msg when the channel is
+// in confirm mode, msg is acked only after it
+func (r *Runtime) publish(msg amqp091.Delivery, exchange, key string, headers amqp091.Table) error {
+	r.mu.RLock()
+	ch := r.ch
+	r.mu.RUnlock()
+	if ch == nil || ch.IsClosed() {
+		return errors.New("rabbitmq channel is not open")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	return Publish(ctx, ch, exchange, key, republishing(msg, headers))
+}
+
+func (r *Runtime) ack(lg *slog.Logger, msg amqp091.Delivery) {
+	if err := msg.Ack(false); err != nil {
+		lg.Error("failed to ack message", "error", err)
+	}
+}
+
+// nack requeues a message whose retry could not be published or whose
+// handler was cut off by a shutdown, it is counted as an attempt by the
+// x-delivery-count of quorum queues
+func (r *Runtime) nack(lg *slog.Logger, msg amqp091.Delivery) {
+	if err := msg.Nack(false, true); err != nil {
+		lg.Error("failed to nack message", "error", err)
+	}
+}
+
+// RoutingKey returns the routing key a message was first delivered with,
+// retried messages come back from their delay queue with the queue name
+func RoutingKey(msg amqp091.Delivery) string {
+	if key, ok := msg.Headers[HeaderRoutingKey].(string); ok && key != "" {
+		return key
+	}
+	return msg.RoutingKey
+}
+
+// Attempt returns the attempt a delivery is, from 1. It counts the trips
+// through delay queues and the redeliveries counted by quorum queues.
+func Attempt(msg amqp091.Delivery) int {
+	return 1 + retries(msg) + headerInt(msg.Headers, headerDeliveryCount)
+}
+
+func retries(msg amqp091.Delivery) int {
+	return headerInt(msg.Headers, HeaderRetries)
+}
+
+func headerInt(headers amqp091.Table, key string) int {
+	switch v := headers[key].(type) {
+	case int64:
+		return int(v)
+	case int32:
+		return int(v)
+	case int16:
+		return int(v)
+	case int8:
+		return int(v)
+	case int:
+		return v
+	case uint8:
+		return int(v)
+	case uint16:
+		return int(v)
+	case uint32:
+		return int(v)
+	}
+	return 0
+}
+
+// forwardHeaders copies the headers of a message to publish it again, the
+// headers the broker sets are left out
+func forwardHeaders(headers amqp091.Table) amqp091.Table {
+	t := amqp091.Table{}
+	for k, v := range headers {
+		if k == headerDeliveryCount || k == "x-death" || strings.HasPrefix(k, "x-first-death-") ||
+			strings.HasPrefix(k, "x-last-death-") {
+			continue
+		}
+		t[k] = v
+	}
+	return t
+}
+
+func republishing(msg amqp091.Delivery, headers amqp091.Table) amqp091.Publishing {
+	return amqp091.Publishing{
+		Headers:         headers,
+		ContentType:     msg.ContentType,
+		ContentEncoding: msg.ContentEncoding,
+		DeliveryMode:    amqp091.Persistent,
+		Priority:        msg.Priority,
+		CorrelationId:   msg.CorrelationId,
+		MessageId:       msg.MessageId,
+		Timestamp:       msg.Timestamp,
+		Type:            msg.Type,
+		AppId:           msg.AppId,
+		Body:            msg.Body,
+	}
+}
+
+// match tells if the words of a routing key match a topic pattern
+func match(pattern, words []string) bool {
+	if len(pattern) == 0 {
+		return len(words) == 0
+	}
+	switch pattern[0] {
+	case "#":
+		for i := 0; i <= len(words); i++ {
+			if match(pattern[1:], words[i:]) {
+				return true
+			}
+		}
+		return false
+	case "*":
+		return len(words) > 0 && match(pattern[1:], words[1:])
+	}
+	return len(words) > 0 && pattern[0] == words[0] && match(pattern[1:], words[1:])
+}
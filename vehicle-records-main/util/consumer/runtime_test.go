@@ -0,0 +1,149 @@
+package consumer
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/rabbitmq/amqp091-go"
+)
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		pattern string
+		key     string
+		want    bool
+	}{
+		{"farin.vehicles.drivers.event", "farin.vehicles.drivers.event", true},
+		{"farin.vehicles.drivers.event", "farin.vehicles.drivers.state", false},
+		{"farin.vehicles.*.drivers.*.event", "farin.vehicles.12.drivers.34.event", true},
+		{"farin.vehicles.*.drivers.*.event", "farin.vehicles.12.drivers.event", false},
+		{"farin.#", "farin", true},
+		{"farin.#", "farin.vehicles.drivers.event", true},
+		{"#.event", "farin.vehicles.drivers.event", true},
+		{"#.event", "farin.vehicles.drivers.state", false},
+		{"*", "farin.vehicles", false},
+	}
+	for _, tt := range tests {
+		if got := match(strings.Split(tt.pattern, "."), strings.Split(tt.key, ".")); got != tt.want {
+			t.Errorf("match(%q, %q) = %v, want %v", tt.pattern, tt.key, got, tt.want)
+		}
+	}
+}
+
+func TestDelay(t *testing.T) {
+	cfg := Config{RetryDelay: 5 * time.Second, RetryMax: time.Minute}
+	want := []time.Duration{5 * time.Second, 10 * time.Second, 20 * time.Second, 40 * time.Second, time.Minute, time.Minute}
+	for i, w := range want {
+		if got := cfg.Delay(i + 2); got != w {
+			t.Errorf("Delay(%d) = %s, want %s", i+2, got, w)
+		}
+	}
+}
+
+func TestAttempt(t *testing.T) {
+	tests := []struct {
+		name    string
+		headers amqp091.Table
+		want    int
+	}{
+		{"first", nil, 1},
+		{"redelivered", amqp091.Table{headerDeliveryCount: int64(2)}, 3},
+		{"retried", amqp091.Table{HeaderRetries: int64(2)}, 3},
+		{"retried and redelivered", amqp091.Table{HeaderRetries: int32(2), headerDeliveryCount: int64(1)}, 4},
+	}
+	for _, tt := range tests {
+		if got := Attempt(amqp091.Delivery{Headers: tt.headers}); got != tt.want {
+			t.Errorf("%s: Attempt() = %d, want %d", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestCall(t *testing.T) {
+	r := New(slog.New(slog.NewTextHandler(io.Discard, nil)), Config{Queue: "q", Settings: Settings{Timeout: time.Second}})
+
+	err := r.call(func(context.Context, []byte) error { panic("boom") }, nil)
+	var permanent *permanentError
+	if !errors.As(err, &permanent) {
+		t.Errorf("panic: got %v, want a permanent error", err)
+	}
+
+	cause := errors.New("malformed")
+	err = r.call(func(context.Context, []byte) error { return Permanent(cause) }, nil)
+	if !errors.As(err, &permanent) || !errors.Is(err, cause) {
+		t.Errorf("Permanent: got %v, want a permanent error wrapping the cause", err)
+	}
+
+	err = r.call(func(ctx context.Context, _ []byte) error {
+		if _, ok := ctx.Deadline(); !ok {
+			return errors.New("no deadline")
+		}
+		return nil
+	}, nil)
+	if err != nil {
+		t.Errorf("timeout: %v", err)
+	}
+}
+
+func TestShutdown(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	cfg := Config{Queue: "q", MaxAttempts: 1, Settings: Settings{Workers: 2, Buffer: 4}}
+
+	t.Run("drained", func(t *testing.T) {
+		r := New(logger, cfg)
+		var handled atomic.Int32
+		r.RegisterHandler("#", func(context.Context, []byte) error {
+			handled.Add(1)
+			return nil
+		})
+		deliveries := make(chan amqp091.Delivery, 3)
+		for i := 0; i < 3; i++ {
+			deliveries <- amqp091.Delivery{RoutingKey: "farin.event"}
+		}
+		close(deliveries)
+		r.RunWorkers()
+		r.Feed(deliveries)
+
+		if err := r.Shutdown(context.Background()); err != nil {
+			t.Fatalf("Shutdown: %v", err)
+		}
+		if got := handled.Load(); got != 3 {
+			t.Errorf("handled %d messages, want 3", got)
+		}
+		if !r.Draining() {
+			t.Error("Draining() = false after Shutdown")
+		}
+	})
+
+	t.Run("deadline", func(t *testing.T) {
+		r := New(logger, cfg)
+		started, cancelled := make(chan struct{}), make(chan struct{})
+		r.RegisterHandler("#", func(ctx context.Context, _ []byte) error {
+			close(started)
+			<-ctx.Done()
+			close(cancelled)
+			return ctx.Err()
+		})
+		deliveries := make(chan amqp091.Delivery, 1)
+		deliveries <- amqp091.Delivery{RoutingKey: "farin.event"}
+		r.RunWorkers()
+		go r.Feed(deliveries)
+		<-started
+
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		if err := r.Shutdown(ctx); !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("Shutdown: got %v, want a deadline error", err)
+		}
+		select {
+		case <-cancelled:
+		case <-time.After(time.Second):
+			t.Error("handler context was not cancelled")
+		}
+	})
+}
@@ -0,0 +1,221 @@
+// Package evidence writes and verifies tamper evident ZIP packages. Every
+// file of a package is listed with its SHA-256 in manifest.json and the
+// manifest is signed with an Ed25519 key in manifest.sig.
+package evidence
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+const (
+	ManifestName  = "manifest.json"
+	SignatureName = "manifest.sig"
+)
+
+// ErrTampered is returned by Verify when a package does not match its signed
+// manifest
+var ErrTampered = errors.New("evidence package was tampered with")
+
+// File is a file of a package
+type File struct {
+	Name    string
+	Content []byte
+}
+
+// Manifest lists the files of a package
+type Manifest struct {
+	RecordID  string         `json:"recordId"`
+	CreatedAt time.Time      `json:"createdAt"`
+	KeyID     string         `json:"keyId"`
+	Files     []ManifestFile `json:"files"`
+}
+
+type ManifestFile struct {
+	Name   string `json:"name"`
+	Size   int    `json:"size"`
+	SHA256 string `json:"sha256"`
+}
+
+// Write writes files, their manifest and its signature as a ZIP to w
+func Write(w io.Writer, recordID string, files []File, key ed25519.PrivateKey, now time.Time) error {
+	manifest := Manifest{
+		RecordID:  recordID,
+		CreatedAt: now.UTC(),
+		KeyID:     KeyID(key.Public().(ed25519.PublicKey)),
+		Files:     make([]ManifestFile, len(files)),
+	}
+	for i, f := range files {
+		sum := sha256.Sum256(f.Content)
+		manifest.Files[i] = ManifestFile{Name: f.Name, Size: len(f.Content), SHA256: hex.EncodeToString(sum[:])}
+	}
+	mb, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal manifest: %w", err)
+	}
+	sig := base64.StdEncoding.EncodeToString(ed25519.Sign(key, mb))
+
+	zw := zip.NewWriter(w)
+	files = append(files, File{Name: ManifestName, Content: mb}, File{Name: SignatureName, Content: []byte(sig)})
+	for _, f := range files {
+		fw, err := zw.CreateHeader(&zip.FileHeader{Name: f.Name, Method: zip.Deflate, Modified: now})
+		if err != nil {
+			return fmt.Errorf("failed to add %s: %w", f.Name, err)
+		}
+		if _, err := fw.Write(f.Content); err != nil {
+			return fmt.Errorf("failed to write %s: %w", f.Name, err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return fmt.Errorf("failed to close package: %w", err)
+	}
+	return nil
+}
+
+// Verify checks the manifest signature of a package with pub and the hash of
+// every file. It fails with ErrTampered when a file was changed, removed or
+// added.
+func Verify(r io.ReaderAt, size int64, pub ed25519.PublicKey) (*Manifest, error) {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open package: %w", err)
+	}
+	contents := map[string][]byte{}
+	for _, zf := range zr.File {
+		if _, ok := contents[zf.Name]; ok {
+			return nil, fmt.Errorf("%w: %s is there twice", ErrTampered, zf.Name)
+		}
+		rc, err := zf.Open()
+		if err != nil {
+			return nil, fmt.Errorf("failed to open %s: %w", zf.Name, err)
+		}
+		b, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", zf.Name, err)
+		}
+		contents[zf.Name] = b
+	}
+
+	mb, sb := contents[ManifestName], contents[SignatureName]
+	if mb == nil || sb == nil {
+		return nil, fmt.Errorf("%w: manifest or signature is missing", ErrTampered)
+	}
+	sig, err := base64.StdEncoding.DecodeString(string(bytes.TrimSpace(sb)))
+	if err != nil || !ed25519.Verify(pub, mb, sig) {
+		return nil, fmt.Errorf("%w: manifest signature is invalid", ErrTampered)
+	}
+	var manifest Manifest
+	if err := json.Unmarshal(mb, &manifest); err != nil {
+		return nil, fmt.Errorf("failed to decode manifest: %w", err)
+	}
+
+	delete(contents, ManifestName)
+	delete(contents, SignatureName)
+	for _, f := range manifest.Files {
+		b, ok := contents[f.Name]
+		if !ok {
+			return nil, fmt.Errorf("%w: %s is missing", ErrTampered, f.Name)
+		}
+		sum := sha256.Sum256(b)
+		if hex.EncodeToString(sum[:]) != f.SHA256 {
+			return nil, fmt.Errorf("%w: %s does not match its hash", ErrTampered, f.Name)
+		}
+		delete(contents, f.Name)
+	}
+	for name := range contents {
+		return nil, fmt.Errorf("%w: %s is not in the manifest", ErrTampered, name)
+	}
+	return &manifest, nil
+}
+
+// KeyID identifies a public key by the start of its SHA-256
+func KeyID(pub ed25519.PublicKey) string {
+	sum := sha256.Sum256(pub)
+	return hex.EncodeToString(sum[:8])
+}
+
+// GenerateKey returns a new private key and its public key as PEM
+func GenerateKey() (private, public []byte, err error) {
+	pub, key, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to generate key: %w", err)
+	}
+	kb, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to marshal private key: %w", err)
+	}
+	pb, err := x509.MarshalPKIXPublicKey(pub)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to marshal public key: %w", err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: kb}),
+		pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: pb}), nil
+}
+
+// LoadPrivateKey reads a PKCS #8 Ed25519 private key from a PEM file
+func LoadPrivateKey(path string) (ed25519.PrivateKey, error) {
+	block, err := readPEM(path, "PRIVATE KEY")
+	if err != nil {
+		return nil, err
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse private key: %w", err)
+	}
+	ek, ok := key.(ed25519.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("%s is not an ed25519 key", path)
+	}
+	return ek, nil
+}
+
+// LoadPublicKey reads a PKIX Ed25519 public key from a PEM file
+func LoadPublicKey(path string) (ed25519.PublicKey, error) {
+	block, err := readPEM(path, "PUBLIC KEY")
+	if err != nil {
+		return nil, err
+	}
+	key, err := x509.ParsePKIXPublicKey(block)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse public key: %w", err)
+	}
+	ek, ok := key.(ed25519.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("%s is not an ed25519 key", path)
+	}
+	return ek, nil
+}
+
+// PublicKeyPEM encodes a public key as PEM
+func PublicKeyPEM(pub ed25519.PublicKey) ([]byte, error) {
+	pb, err := x509.MarshalPKIXPublicKey(pub)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal public key: %w", err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: pb}), nil
+}
+
+func readPEM(path, typ string) ([]byte, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read key: %w", err)
+	}
+	block, _ := pem.Decode(b)
+	if block == nil || block.Type != typ {
+		return nil, fmt.Errorf("%s holds no %s", path, typ)
+	}
+	return block.Bytes, nil
+}
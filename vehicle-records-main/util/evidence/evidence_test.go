@@ -0,0 +1,200 @@
+package evidence
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/ed25519"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func testKey(t *testing.T) ed25519.PrivateKey {
+	t.Helper()
+	_, key, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+var testFiles = []File{
+	{Name: "photos/1.jpg", Content: []byte("photo")},
+	{Name: "metadata.json", Content: []byte(`{"plate":"12B34567"}`)},
+}
+
+func writePackage(t *testing.T, key ed25519.PrivateKey) []byte {
+	t.Helper()
+	var b bytes.Buffer
+	if err := Write(&b, "record-1", testFiles, key, time.Unix(1700000000, 0)); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	return b.Bytes()
+}
+
+// rewrite copies a package through edit, edit returns the content of a file
+// and false to drop it
+func rewrite(t *testing.T, pkg []byte, edit func(name string, b []byte) ([]byte, bool), extra ...File) []byte {
+	t.Helper()
+	zr, err := zip.NewReader(bytes.NewReader(pkg), int64(len(pkg)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	zw := zip.NewWriter(&out)
+	add := func(name string, b []byte) {
+		fw, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := fw.Write(b); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, zf := range zr.File {
+		rc, err := zf.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if b, ok := edit(zf.Name, b); ok {
+			add(zf.Name, b)
+		}
+	}
+	for _, f := range extra {
+		add(f.Name, f.Content)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return out.Bytes()
+}
+
+func TestWriteVerify(t *testing.T) {
+	key := testKey(t)
+	pkg := writePackage(t, key)
+
+	m, err := Verify(bytes.NewReader(pkg), int64(len(pkg)), key.Public().(ed25519.PublicKey))
+	if err != nil {
+		t.Fatalf("Verify() error = %v", err)
+	}
+	if m.RecordID != "record-1" || m.KeyID != KeyID(key.Public().(ed25519.PublicKey)) {
+		t.Errorf("Verify() manifest = %+v", m)
+	}
+	if len(m.Files) != len(testFiles) {
+		t.Fatalf("Verify() files = %d, want %d", len(m.Files), len(testFiles))
+	}
+	for i, f := range m.Files {
+		if f.Name != testFiles[i].Name || f.Size != len(testFiles[i].Content) {
+			t.Errorf("Verify() file %d = %+v, want %s of %d bytes", i, f, testFiles[i].Name,
+				len(testFiles[i].Content))
+		}
+	}
+}
+
+func TestVerifyTampered(t *testing.T) {
+	key := testKey(t)
+	pub := key.Public().(ed25519.PublicKey)
+	pkg := writePackage(t, key)
+
+	tests := []struct {
+		name string
+		pkg  []byte
+		pub  ed25519.PublicKey
+	}{
+		{
+			name: "changed file",
+			pkg: rewrite(t, pkg, func(name string, b []byte) ([]byte, bool) {
+				if name == "photos/1.jpg" {
+					return []byte("other photo"), true
+				}
+				return b, true
+			}),
+		},
+		{
+			name: "removed file",
+			pkg: rewrite(t, pkg, func(name string, b []byte) ([]byte, bool) {
+				return b, name != "metadata.json"
+			}),
+		},
+		{
+			name: "added file",
+			pkg: rewrite(t, pkg, func(_ string, b []byte) ([]byte, bool) { return b, true },
+				File{Name: "photos/2.jpg", Content: []byte("planted")}),
+		},
+		{
+			name: "changed manifest",
+			pkg: rewrite(t, pkg, func(name string, b []byte) ([]byte, bool) {
+				if name == ManifestName {
+					return bytes.Replace(b, []byte("record-1"), []byte("record-2"), 1), true
+				}
+				return b, true
+			}),
+		},
+		{
+			name: "removed signature",
+			pkg: rewrite(t, pkg, func(name string, b []byte) ([]byte, bool) {
+				return b, name != SignatureName
+			}),
+		},
+		{
+			name: "duplicate file",
+			pkg: rewrite(t, pkg, func(_ string, b []byte) ([]byte, bool) { return b, true },
+				File{Name: "metadata.json", Content: []byte(`{"plate":"12B34567"}`)}),
+		},
+		{
+			name: "other key",
+			pkg:  pkg,
+			pub:  testKey(t).Public().(ed25519.PublicKey),
+		},
+	}
+	for _, tt := range tests {
+		p := tt.pub
+		if p == nil {
+			p = pub
+		}
+		if _, err := Verify(bytes.NewReader(tt.pkg), int64(len(tt.pkg)), p); !errors.Is(err, ErrTampered) {
+			t.Errorf("%s: Verify() error = %v, want %v", tt.name, err, ErrTampered)
+		}
+	}
+}
+
+func TestKeyFiles(t *testing.T) {
+	private, public, err := GenerateKey()
+	if err != nil {
+		t.Fatalf("GenerateKey() error = %v", err)
+	}
+	dir := t.TempDir()
+	kp, pp := filepath.Join(dir, "key.pem"), filepath.Join(dir, "key.pub.pem")
+	if err := os.WriteFile(kp, private, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(pp, public, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	key, err := LoadPrivateKey(kp)
+	if err != nil {
+		t.Fatalf("LoadPrivateKey() error = %v", err)
+	}
+	pub, err := LoadPublicKey(pp)
+	if err != nil {
+		t.Fatalf("LoadPublicKey() error = %v", err)
+	}
+	if !pub.Equal(key.Public()) {
+		t.Errorf("LoadPublicKey() does not match LoadPrivateKey()")
+	}
+	if pb, err := PublicKeyPEM(pub); err != nil || !bytes.Equal(pb, public) {
+		t.Errorf("PublicKeyPEM() = %s, %v, want %s", pb, err, public)
+	}
+	if _, err := LoadPrivateKey(pp); err == nil {
+		t.Errorf("LoadPrivateKey(public key) error = nil, want an error")
+	}
+}
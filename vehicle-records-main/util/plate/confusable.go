@@ -0,0 +1,75 @@
+package plate
+
+import "sort"
+
+// confusableDigits are digits the LPR cameras mistake for each other on
+// Persian plates
+var confusableDigits = map[rune][]rune{
+	'0': {'5'}, '5': {'0'},
+	'2': {'3'}, '3': {'2'},
+	'6': {'9'}, '9': {'6'},
+	'7': {'8'}, '8': {'7'},
+}
+
+// confusableLetters are groups of letters that only differ by their dots
+var confusableLetters = [][]Letter{
+	{"ب", "پ", "ت", "ث"},
+	{"ج", "چ", "ح", "خ"},
+	{"د", "ذ"},
+	{"ر", "ز", "ژ"},
+	{"س", "ش"},
+	{"ص", "ض"},
+	{"ط", "ظ"},
+	{"ع", "غ"},
+	{"ف", "ق"},
+	{"ک", "گ"},
+}
+
+var lettersConfusedWith = map[Letter][]Letter{}
+
+func init() {
+	for _, group := range confusableLetters {
+		for _, l := range group {
+			for _, other := range group {
+				if other != l {
+					lettersConfusedWith[l] = append(lettersConfusedWith[l], other)
+				}
+			}
+		}
+	}
+}
+
+// Confusable returns the valid plates that differ from p by one digit or
+// letter the cameras are known to misread, ordered by their numeric encoding
+func Confusable(p Plate) []Plate {
+	seen := map[int]bool{p.Numeric(): true}
+	var result []Plate
+	add := func(c Plate) {
+		if n := c.Numeric(); !seen[n] {
+			seen[n] = true
+			result = append(result, c)
+		}
+	}
+
+	runes := []rune(p.String())
+	for i, r := range runes {
+		for _, alt := range confusableDigits[r] {
+			variant := make([]rune, len(runes))
+			copy(variant, runes)
+			variant[i] = alt
+			if c, err := Parse(string(variant)); err == nil && c.Kind == p.Kind {
+				add(c)
+			}
+		}
+	}
+	if p.Kind == KindCar {
+		for _, l := range lettersConfusedWith[p.Letter] {
+			c := p
+			c.Letter = l
+			add(c)
+		}
+	}
+
+	sort.Slice(result, func(i, j int) bool { return result[i].Numeric() < result[j].Numeric() })
+	return result
+}
@@ -0,0 +1,3 @@
+module git.abanppc.com/farin-project/vehicle-records/util/plate
+
+go 1.22
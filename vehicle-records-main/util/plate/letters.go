@@ -0,0 +1,123 @@
+package plate
+
+// Letter is the letter of a car plate in its Persian form
+type Letter string
+
+type letterInfo struct {
+	latin string
+	code  int
+	typ   Type
+}
+
+// letters holds the Latin name, the two digit code of the numeric encoding
+// and the plate type of every letter. The codes are the ones stored in
+// citizen_plate_number_numeric and must not change.
+var letters = map[Letter]letterInfo{
+	"الف": {"ALEF", 1, TypeGovernment},
+	"ب":   {"BE", 2, TypePrivate},
+	"پ":   {"PE", 3, TypePolice},
+	"ت":   {"TE", 4, TypeTaxi},
+	"ث":   {"SE", 5, TypeMilitary},
+	"ج":   {"JIM", 6, TypePrivate},
+	"چ":   {"CHE", 7, TypePrivate},
+	"ح":   {"HE", 8, TypePrivate},
+	"خ":   {"KHE", 9, TypePrivate},
+	"د":   {"DAL", 10, TypePrivate},
+	"ذ":   {"ZAL", 11, TypePrivate},
+	"ر":   {"RE", 12, TypePrivate},
+	"ز":   {"ZE", 13, TypeMilitary},
+	"ژ":   {"ZHE", 14, TypeDisabled},
+	"س":   {"SIN", 15, TypePrivate},
+	"ش":   {"SHIN", 16, TypeMilitary},
+	"ص":   {"SAD", 17, TypePrivate},
+	"ض":   {"ZAD", 18, TypePrivate},
+	"ط":   {"TA", 19, TypePrivate},
+	"ظ":   {"ZA", 20, TypePrivate},
+	"ع":   {"EIN", 21, TypePublic},
+	"غ":   {"GHEIN", 22, TypePrivate},
+	"ف":   {"FE", 23, TypePrivate},
+	"ق":   {"GHAF", 24, TypePrivate},
+	"ک":   {"KAF", 25, TypeAgricultural},
+	"گ":   {"GAF", 26, TypePrivate},
+	"ل":   {"LAM", 27, TypePrivate},
+	"م":   {"MIM", 28, TypePrivate},
+	"ن":   {"NUN", 29, TypePrivate},
+	"و":   {"VAV", 30, TypePrivate},
+	"ه":   {"HEH", 31, TypePrivate},
+	"ی":   {"YE", 32, TypePrivate},
+	"D":   {"D", 33, TypeDiplomatic},
+	"S":   {"S", 34, TypeDiplomatic},
+}
+
+// letterAliases are other spellings of letters found in plate readings
+var letterAliases = map[string]Letter{
+	"ا": "الف",
+}
+
+var (
+	lettersByLatin = map[string]Letter{}
+	lettersByCode  = map[int]Letter{}
+)
+
+func init() {
+	for l, info := range letters {
+		lettersByLatin[info.latin] = l
+		lettersByCode[info.code] = l
+	}
+}
+
+// Latin returns the Latin name of the letter
+func (l Letter) Latin() string {
+	return letters[l].latin
+}
+
+// Code returns the two digit code of the letter in the numeric encoding
+func (l Letter) Code() int {
+	return letters[l].code
+}
+
+// Zone is the free zone of a free zone plate
+type Zone int
+
+const (
+	ZoneKish Zone = iota + 11
+	ZoneQeshm
+	ZoneAras
+	ZoneChabahar
+	ZoneArvand
+	ZoneAnzali
+	ZoneMaku
+)
+
+type zoneInfo struct {
+	persian string
+	latin   string
+}
+
+// zones are numbered from 11 so the numeric encoding keeps its width
+var zones = map[Zone]zoneInfo{
+	ZoneKish:     {"کیش", "KISH"},
+	ZoneQeshm:    {"قشم", "QESHM"},
+	ZoneAras:     {"ارس", "ARAS"},
+	ZoneChabahar: {"چابهار", "CHABAHAR"},
+	ZoneArvand:   {"اروند", "ARVAND"},
+	ZoneAnzali:   {"انزلی", "ANZALI"},
+	ZoneMaku:     {"ماکو", "MAKU"},
+}
+
+var zonesByName = map[string]Zone{}
+
+func init() {
+	for z, info := range zones {
+		zonesByName[info.persian] = z
+		zonesByName[info.latin] = z
+	}
+}
+
+func (z Zone) Persian() string {
+	return zones[z].persian
+}
+
+func (z Zone) Latin() string {
+	return zones[z].latin
+}
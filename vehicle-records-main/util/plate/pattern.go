@@ -0,0 +1,102 @@
+package plate
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+// Pattern is a plate, or a plate with wildcards as written by String with *
+// for any run of characters and ? for a single digit or letter, such as
+// 12ب345* for every region of 12ب345. Car letters and free zones are matched
+// in their Persian form, 12BE345* is read as 12ب345*.
+type Pattern struct {
+	text    string
+	exact   bool
+	numeric int
+}
+
+// ParsePattern reads a pattern, a pattern without wildcards must be a valid
+// plate
+func ParsePattern(s string) (Pattern, error) {
+	if !strings.ContainsAny(s, "*?") {
+		p, err := Parse(s)
+		if err != nil {
+			return Pattern{}, err
+		}
+		return Pattern{text: p.String(), exact: true, numeric: p.Numeric()}, nil
+	}
+
+	text := strings.Map(func(r rune) rune {
+		if r == ' ' || r == '-' || r == '_' {
+			return -1
+		}
+		return r
+	}, normalize(s))
+	if strings.Trim(text, "*?") == "" {
+		return Pattern{}, fmt.Errorf("%w: pattern %q matches every plate", ErrInvalidPlate, s)
+	}
+	if strings.ContainsAny(text, `[]\/`) {
+		return Pattern{}, fmt.Errorf("%w: pattern %q has characters other than * and ?", ErrInvalidPlate, s)
+	}
+	text, err := persianNames(text)
+	if err != nil {
+		return Pattern{}, fmt.Errorf("%w: pattern %q: %v", ErrInvalidPlate, s, err)
+	}
+	return Pattern{text: text}, nil
+}
+
+// persianNames writes the Latin names of letters and free zones in a pattern
+// in Persian, as plates are written by String, and drops the word Iran
+func persianNames(text string) (string, error) {
+	var b, run strings.Builder
+	flush := func() error {
+		name := run.String()
+		run.Reset()
+		switch {
+		case name == "" || name == "IR":
+		case lettersByLatin[name] != "":
+			b.WriteString(string(lettersByLatin[name]))
+		default:
+			z, ok := zonesByName[name]
+			if !ok {
+				return fmt.Errorf("unknown letter %q", name)
+			}
+			b.WriteString(z.Persian())
+		}
+		return nil
+	}
+	for _, r := range text {
+		if r >= 'A' && r <= 'Z' {
+			run.WriteRune(r)
+			continue
+		}
+		if err := flush(); err != nil {
+			return "", err
+		}
+		b.WriteRune(r)
+	}
+	if err := flush(); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
+
+// String returns the normalized pattern
+func (p Pattern) String() string {
+	return p.text
+}
+
+// Exact returns the numeric encoding of a pattern without wildcards
+func (p Pattern) Exact() (int, bool) {
+	return p.numeric, p.exact
+}
+
+// Match tells if the plate matches the pattern
+func (p Pattern) Match(plate Plate) bool {
+	if p.exact {
+		return plate.Numeric() == p.numeric
+	}
+	ok, _ := path.Match(p.text, plate.String())
+	return ok
+}
@@ -0,0 +1,328 @@
+// Package plate parses Iranian licence plates as read by the LPR cameras or
+// typed by operators, and formats them in Persian, Latin and the numeric
+// encoding stored next to the plates.
+//
+// Three layouts are known:
+//
+//	car         12 ب 345 ایران 67   two digits, a letter, three digits and the region
+//	motorcycle  123 45678           the three digit city code and five digits
+//	free zone   کیش 12345           the zone and five digits
+//
+// Diplomatic and service plates are car plates with the letters D and S.
+package plate
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+var ErrInvalidPlate = errors.New("invalid plate")
+
+// Kind is the layout of a plate
+type Kind int
+
+const (
+	KindCar Kind = iota
+	KindMotorcycle
+	KindFreeZone
+)
+
+func (k Kind) String() string {
+	switch k {
+	case KindCar:
+		return "car"
+	case KindMotorcycle:
+		return "motorcycle"
+	}
+	return "freeZone"
+}
+
+// Type is the use a plate is issued for
+type Type string
+
+const (
+	TypePrivate      Type = "private"
+	TypeGovernment   Type = "government"
+	TypeTaxi         Type = "taxi"
+	TypePublic       Type = "public"
+	TypeAgricultural Type = "agricultural"
+	TypePolice       Type = "police"
+	TypeMilitary     Type = "military"
+	TypeDisabled     Type = "disabled"
+	TypeDiplomatic   Type = "diplomatic"
+)
+
+// Colour is the background colour of a plate
+type Colour string
+
+const (
+	ColourWhite  Colour = "white"
+	ColourRed    Colour = "red"
+	ColourYellow Colour = "yellow"
+	ColourBlue   Colour = "blue"
+	ColourGreen  Colour = "green"
+	ColourKhaki  Colour = "khaki"
+)
+
+var colours = map[Type]Colour{
+	TypePrivate:      ColourWhite,
+	TypeGovernment:   ColourRed,
+	TypeTaxi:         ColourYellow,
+	TypePublic:       ColourYellow,
+	TypeAgricultural: ColourYellow,
+	TypePolice:       ColourGreen,
+	TypeMilitary:     ColourKhaki,
+	TypeDisabled:     ColourWhite,
+	TypeDiplomatic:   ColourBlue,
+}
+
+// Plate is a parsed plate. Prefix, Letter and Region are only set for car
+// plates, City for motorcycle plates and Zone for free zone plates. Body is
+// the three digits of car plates and the five digits of the others.
+type Plate struct {
+	Kind   Kind
+	Prefix int
+	Letter Letter
+	Body   int
+	Region int
+	City   int
+	Zone   Zone
+}
+
+// The numeric encoding keeps the kinds apart by range: motorcycles are
+// below 10^8 as CCCNNNNN, cars take nine digits as PPLLBBBRR with the letter
+// code in LL, free zones start from 10^9 as 10^9 + ZZNNNNN.
+const (
+	carNumericMin      = 100_000_000
+	freeZoneNumericMin = 1_000_000_000
+)
+
+// Parse reads a plate. Persian and Arabic digits and letters, the Latin
+// letter names, spaces, dashes and the word ایران or IR between the
+// body and the region are accepted.
+func Parse(s string) (Plate, error) {
+	tokens := tokenize(normalize(s))
+
+	switch {
+	case len(tokens) == 3 && tokens[0].digits && !tokens[1].digits && tokens[2].digits:
+		return parseCar(s, tokens[0].text, tokens[1].text, tokens[2].text)
+	case len(tokens) == 2 && !tokens[0].digits && tokens[1].digits:
+		return parseFreeZone(s, tokens[0].text, tokens[1].text)
+	case len(tokens) == 2 && tokens[0].digits && !tokens[1].digits:
+		return parseFreeZone(s, tokens[1].text, tokens[0].text)
+	case len(tokens) == 1 && tokens[0].digits:
+		return parseMotorcycle(s, tokens[0].text)
+	}
+	return Plate{}, fmt.Errorf("%w: %q", ErrInvalidPlate, s)
+}
+
+func parseCar(s, prefix, letter, rest string) (Plate, error) {
+	l, ok := lookupLetter(letter)
+	if !ok {
+		return Plate{}, fmt.Errorf("%w: unknown letter %q in %q", ErrInvalidPlate, letter, s)
+	}
+	if len(prefix) != 2 || prefix[0] == '0' {
+		return Plate{}, fmt.Errorf("%w: %q needs two digits before the letter", ErrInvalidPlate, s)
+	}
+	if len(rest) != 5 || rest[3] == '0' {
+		return Plate{}, fmt.Errorf("%w: %q needs three digits and a region after the letter", ErrInvalidPlate, s)
+	}
+	p := Plate{Kind: KindCar, Letter: l}
+	p.Prefix, _ = strconv.Atoi(prefix)
+	p.Body, _ = strconv.Atoi(rest[:3])
+	p.Region, _ = strconv.Atoi(rest[3:])
+	return p, nil
+}
+
+func parseFreeZone(s, name, number string) (Plate, error) {
+	zone, ok := zonesByName[name]
+	if !ok {
+		return Plate{}, fmt.Errorf("%w: unknown free zone %q in %q", ErrInvalidPlate, name, s)
+	}
+	if len(number) != 5 {
+		return Plate{}, fmt.Errorf("%w: %q needs five digits", ErrInvalidPlate, s)
+	}
+	p := Plate{Kind: KindFreeZone, Zone: zone}
+	p.Body, _ = strconv.Atoi(number)
+	return p, nil
+}
+
+func parseMotorcycle(s, digits string) (Plate, error) {
+	if len(digits) != 8 {
+		return Plate{}, fmt.Errorf("%w: %q needs a city code and five digits", ErrInvalidPlate, s)
+	}
+	p := Plate{Kind: KindMotorcycle}
+	p.City, _ = strconv.Atoi(digits[:3])
+	p.Body, _ = strconv.Atoi(digits[3:])
+	return p, nil
+}
+
+// FromNumeric decodes a plate of the numeric encoding
+func FromNumeric(n int) (Plate, error) {
+	switch {
+	case n <= 0:
+	case n < carNumericMin:
+		return Plate{Kind: KindMotorcycle, City: n / 100_000, Body: n % 100_000}, nil
+	case n < freeZoneNumericMin:
+		l, ok := lettersByCode[n/100_000%100]
+		if !ok {
+			break
+		}
+		return Plate{Kind: KindCar, Prefix: n / 10_000_000, Letter: l, Body: n / 100 % 1000, Region: n % 100}, nil
+	default:
+		zone := Zone((n - freeZoneNumericMin) / 100_000)
+		if _, ok := zones[zone]; !ok {
+			break
+		}
+		return Plate{Kind: KindFreeZone, Zone: zone, Body: n % 100_000}, nil
+	}
+	return Plate{}, fmt.Errorf("%w: numeric %d", ErrInvalidPlate, n)
+}
+
+// Numeric returns the numeric encoding of the plate
+func (p Plate) Numeric() int {
+	switch p.Kind {
+	case KindCar:
+		return p.Prefix*10_000_000 + p.Letter.Code()*100_000 + p.Body*100 + p.Region
+	case KindMotorcycle:
+		return p.City*100_000 + p.Body
+	}
+	return freeZoneNumericMin + int(p.Zone)*100_000 + p.Body
+}
+
+// Type returns the use the plate is issued for, motorcycle and free zone
+// plates are private
+func (p Plate) Type() Type {
+	if p.Kind != KindCar {
+		return TypePrivate
+	}
+	return letters[p.Letter].typ
+}
+
+func (p Plate) Colour() Colour {
+	return colours[p.Type()]
+}
+
+// String returns the plate as the LPR cameras send it, such as 12ب34567
+func (p Plate) String() string {
+	switch p.Kind {
+	case KindCar:
+		return fmt.Sprintf("%02d%s%03d%02d", p.Prefix, p.Letter, p.Body, p.Region)
+	case KindMotorcycle:
+		return fmt.Sprintf("%03d%05d", p.City, p.Body)
+	}
+	return fmt.Sprintf("%s%05d", p.Zone.Persian(), p.Body)
+}
+
+// Persian returns the plate as written on it, such as ۱۲ ب ۳۴۵ ایران ۶۷
+func (p Plate) Persian() string {
+	switch p.Kind {
+	case KindCar:
+		return persianDigits(fmt.Sprintf("%02d %s %03d ایران %02d", p.Prefix, p.Letter, p.Body, p.Region))
+	case KindMotorcycle:
+		return persianDigits(fmt.Sprintf("%03d %05d", p.City, p.Body))
+	}
+	return persianDigits(fmt.Sprintf("%s %05d", p.Zone.Persian(), p.Body))
+}
+
+// Latin returns the plate in Latin letters, such as 12 BE 345 IR 67
+func (p Plate) Latin() string {
+	switch p.Kind {
+	case KindCar:
+		return fmt.Sprintf("%02d %s %03d IR %02d", p.Prefix, p.Letter.Latin(), p.Body, p.Region)
+	case KindMotorcycle:
+		return fmt.Sprintf("%03d %05d", p.City, p.Body)
+	}
+	return fmt.Sprintf("%s %05d", p.Zone.Latin(), p.Body)
+}
+
+func lookupLetter(s string) (Letter, bool) {
+	if _, ok := letters[Letter(s)]; ok {
+		return Letter(s), true
+	}
+	if l, ok := letterAliases[s]; ok {
+		return l, true
+	}
+	l, ok := lettersByLatin[s]
+	return l, ok
+}
+
+var normalizer = strings.NewReplacer(
+	"ي", "ی", "ى", "ی", "ك", "ک", "ة", "ه", "ـ", "", "‌", "",
+	"ایران", " ", "IRAN", " ",
+)
+
+// normalize turns digits into ASCII, Arabic letters into Persian and Latin
+// letters into upper case, and drops the word Iran
+func normalize(s string) string {
+	s = strings.Map(func(r rune) rune {
+		switch {
+		case r >= '۰' && r <= '۹':
+			return '0' + r - '۰'
+		case r >= '٠' && r <= '٩':
+			return '0' + r - '٠'
+		}
+		return unicode.ToUpper(r)
+	}, s)
+	return normalizer.Replace(s)
+}
+
+type token struct {
+	text   string
+	digits bool
+}
+
+// tokenize splits s into runs of digits and of letters, anything else only
+// separates runs. A lone IR between two runs of digits is the word Iran.
+func tokenize(s string) []token {
+	var tokens []token
+	var cur strings.Builder
+	curDigits := false
+	flush := func() {
+		if cur.Len() > 0 {
+			tokens = append(tokens, token{text: cur.String(), digits: curDigits})
+			cur.Reset()
+		}
+	}
+	for _, r := range s {
+		isDigit := r >= '0' && r <= '9'
+		if !isDigit && !unicode.IsLetter(r) {
+			flush()
+			continue
+		}
+		if cur.Len() > 0 && isDigit != curDigits {
+			flush()
+		}
+		curDigits = isDigit
+		cur.WriteRune(r)
+	}
+	flush()
+
+	// merge runs of digits split by separators or the word Iran
+	var merged []token
+	for i := 0; i < len(tokens); i++ {
+		t := tokens[i]
+		if t.text == "IR" && len(merged) > 0 && merged[len(merged)-1].digits &&
+			i+1 < len(tokens) && tokens[i+1].digits {
+			continue
+		}
+		if len(merged) > 0 && t.digits && merged[len(merged)-1].digits {
+			merged[len(merged)-1].text += t.text
+			continue
+		}
+		merged = append(merged, t)
+	}
+	return merged
+}
+
+func persianDigits(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= '0' && r <= '9' {
+			return '۰' + r - '0'
+		}
+		return r
+	}, s)
+}
@@ -0,0 +1,231 @@
+package plate
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		in      string
+		want    Plate
+		numeric int
+	}{
+		{"camera", "12ت26511", Plate{Kind: KindCar, Prefix: 12, Letter: "ت", Body: 265, Region: 11}, 120426511},
+		{"camera vav", "68و52399", Plate{Kind: KindCar, Prefix: 68, Letter: "و", Body: 523, Region: 99}, 683052399},
+		{"persian digits", "۱۲ ب ۳۴۵ ایران ۶۷", Plate{Kind: KindCar, Prefix: 12, Letter: "ب", Body: 345, Region: 67}, 120234567},
+		{"arabic digits", "١٢ب٣٤٥٦٧", Plate{Kind: KindCar, Prefix: 12, Letter: "ب", Body: 345, Region: 67}, 120234567},
+		{"arabic yeh", "12ي34567", Plate{Kind: KindCar, Prefix: 12, Letter: "ی", Body: 345, Region: 67}, 123234567},
+		{"arabic kaf", "12ك34567", Plate{Kind: KindCar, Prefix: 12, Letter: "ک", Body: 345, Region: 67}, 122534567},
+		{"heh with tatweel", "12هـ34567", Plate{Kind: KindCar, Prefix: 12, Letter: "ه", Body: 345, Region: 67}, 123134567},
+		{"alef", "12الف34567", Plate{Kind: KindCar, Prefix: 12, Letter: "الف", Body: 345, Region: 67}, 120134567},
+		{"short alef", "12ا34567", Plate{Kind: KindCar, Prefix: 12, Letter: "الف", Body: 345, Region: 67}, 120134567},
+		{"dashes", "12-ب-345-67", Plate{Kind: KindCar, Prefix: 12, Letter: "ب", Body: 345, Region: 67}, 120234567},
+		{"latin", "12 BE 345 IR 67", Plate{Kind: KindCar, Prefix: 12, Letter: "ب", Body: 345, Region: 67}, 120234567},
+		{"latin compact", "12be34567", Plate{Kind: KindCar, Prefix: 12, Letter: "ب", Body: 345, Region: 67}, 120234567},
+		{"diplomatic", "12D34567", Plate{Kind: KindCar, Prefix: 12, Letter: "D", Body: 345, Region: 67}, 123334567},
+		{"service", "12 S 345 67", Plate{Kind: KindCar, Prefix: 12, Letter: "S", Body: 345, Region: 67}, 123434567},
+		{"motorcycle", "123 45678", Plate{Kind: KindMotorcycle, City: 123, Body: 45678}, 12345678},
+		{"motorcycle persian", "۱۲۳۴۵۶۷۸", Plate{Kind: KindMotorcycle, City: 123, Body: 45678}, 12345678},
+		{"free zone", "کیش 12345", Plate{Kind: KindFreeZone, Zone: ZoneKish, Body: 12345}, 1_001_112_345},
+		{"free zone number first", "12345 قشم", Plate{Kind: KindFreeZone, Zone: ZoneQeshm, Body: 12345}, 1_001_212_345},
+		{"free zone latin", "maku-00042", Plate{Kind: KindFreeZone, Zone: ZoneMaku, Body: 42}, 1_001_700_042},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse(tt.in)
+			if err != nil {
+				t.Fatalf("Parse(%q) error: %v", tt.in, err)
+			}
+			if got != tt.want {
+				t.Fatalf("Parse(%q) = %+v, want %+v", tt.in, got, tt.want)
+			}
+			if n := got.Numeric(); n != tt.numeric {
+				t.Fatalf("Parse(%q).Numeric() = %d, want %d", tt.in, n, tt.numeric)
+			}
+			back, err := FromNumeric(tt.numeric)
+			if err != nil {
+				t.Fatalf("FromNumeric(%d) error: %v", tt.numeric, err)
+			}
+			if back != tt.want {
+				t.Fatalf("FromNumeric(%d) = %+v, want %+v", tt.numeric, back, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+	}{
+		{"empty", ""},
+		{"short", "1"},
+		{"letter only", "ب"},
+		{"one digit prefix", "1ب34567"},
+		{"zero prefix", "02ب34567"},
+		{"short body", "12ب3456"},
+		{"long body", "12ب345678"},
+		{"zero region", "12ب34507"},
+		{"unknown letter", "12X34567"},
+		{"two letters", "12بب34567"},
+		{"motorcycle short", "1234567"},
+		{"unknown zone", "تهران 12345"},
+		{"free zone short", "کیش 1234"},
+		{"extra part", "12ب34567ب"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if p, err := Parse(tt.in); !errors.Is(err, ErrInvalidPlate) {
+				t.Fatalf("Parse(%q) = %+v, %v, want ErrInvalidPlate", tt.in, p, err)
+			}
+		})
+	}
+}
+
+func TestFromNumericInvalid(t *testing.T) {
+	for _, n := range []int{0, -5, 129934567, 1_009_912_345} {
+		if p, err := FromNumeric(n); !errors.Is(err, ErrInvalidPlate) {
+			t.Fatalf("FromNumeric(%d) = %+v, %v, want ErrInvalidPlate", n, p, err)
+		}
+	}
+}
+
+func TestFormat(t *testing.T) {
+	tests := []struct {
+		in      string
+		str     string
+		persian string
+		latin   string
+		typ     Type
+		colour  Colour
+	}{
+		{"12ب34567", "12ب34567", "۱۲ ب ۳۴۵ ایران ۶۷", "12 BE 345 IR 67", TypePrivate, ColourWhite},
+		{"12الف34567", "12الف34567", "۱۲ الف ۳۴۵ ایران ۶۷", "12 ALEF 345 IR 67", TypeGovernment, ColourRed},
+		{"45ت67811", "45ت67811", "۴۵ ت ۶۷۸ ایران ۱۱", "45 TE 678 IR 11", TypeTaxi, ColourYellow},
+		{"12ع34567", "12ع34567", "۱۲ ع ۳۴۵ ایران ۶۷", "12 EIN 345 IR 67", TypePublic, ColourYellow},
+		{"12پ34567", "12پ34567", "۱۲ پ ۳۴۵ ایران ۶۷", "12 PE 345 IR 67", TypePolice, ColourGreen},
+		{"12ش34567", "12ش34567", "۱۲ ش ۳۴۵ ایران ۶۷", "12 SHIN 345 IR 67", TypeMilitary, ColourKhaki},
+		{"12ژ34567", "12ژ34567", "۱۲ ژ ۳۴۵ ایران ۶۷", "12 ZHE 345 IR 67", TypeDisabled, ColourWhite},
+		{"12D34567", "12D34567", "۱۲ D ۳۴۵ ایران ۶۷", "12 D 345 IR 67", TypeDiplomatic, ColourBlue},
+		{"01245678", "01245678", "۰۱۲ ۴۵۶۷۸", "012 45678", TypePrivate, ColourWhite},
+		{"کیش 00012", "کیش00012", "کیش ۰۰۰۱۲", "KISH 00012", TypePrivate, ColourWhite},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			p, err := Parse(tt.in)
+			if err != nil {
+				t.Fatalf("Parse(%q) error: %v", tt.in, err)
+			}
+			if got := p.String(); got != tt.str {
+				t.Errorf("String() = %q, want %q", got, tt.str)
+			}
+			if got := p.Persian(); got != tt.persian {
+				t.Errorf("Persian() = %q, want %q", got, tt.persian)
+			}
+			if got := p.Latin(); got != tt.latin {
+				t.Errorf("Latin() = %q, want %q", got, tt.latin)
+			}
+			if got := p.Type(); got != tt.typ {
+				t.Errorf("Type() = %q, want %q", got, tt.typ)
+			}
+			if got := p.Colour(); got != tt.colour {
+				t.Errorf("Colour() = %q, want %q", got, tt.colour)
+			}
+			for _, formatted := range []string{p.String(), p.Persian(), p.Latin()} {
+				again, err := Parse(formatted)
+				if err != nil || again != p {
+					t.Errorf("Parse(%q) = %+v, %v, want %+v", formatted, again, err, p)
+				}
+			}
+		})
+	}
+}
+
+func TestConfusable(t *testing.T) {
+	tests := []struct {
+		in   string
+		want []string
+	}{
+		{"12ب34567", []string{"12ب34568", "12ب34597", "12ب34067", "12ب24567", "13ب34567", "12پ34567", "12ت34567",
+			"12ث34567"}},
+		// a region may not start with zero, so the five of region 50 is kept
+		{"11س14050", []string{"11س14055", "11س14550", "11ش14050"}},
+		{"کیش 00012", []string{"کیش00013", "کیش00512", "کیش05012", "کیش50012"}},
+		{"12345678", []string{"12345677", "12345688", "12345978", "12340678", "12245678", "13345678"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			p, err := Parse(tt.in)
+			if err != nil {
+				t.Fatalf("Parse(%q) error: %v", tt.in, err)
+			}
+			want := map[string]bool{}
+			for _, w := range tt.want {
+				want[w] = true
+			}
+			got := Confusable(p)
+			for _, c := range got {
+				if !want[c.String()] {
+					t.Errorf("Confusable(%q) has unexpected %q", tt.in, c.String())
+				}
+				delete(want, c.String())
+			}
+			for w := range want {
+				t.Errorf("Confusable(%q) misses %q", tt.in, w)
+			}
+			for i := 1; i < len(got); i++ {
+				if got[i-1].Numeric() >= got[i].Numeric() {
+					t.Fatalf("Confusable(%q) is not ordered by numeric", tt.in)
+				}
+			}
+		})
+	}
+}
+
+func TestPattern(t *testing.T) {
+	tests := []struct {
+		pattern string
+		text    string
+		match   []string
+		miss    []string
+	}{
+		{"12 ب 345 ایران 67", "12ب34567", []string{"12ب34567", "12 BE 345 IR 67"}, []string{"12ب34568"}},
+		{"12ب345*", "12ب345*", []string{"12ب34567", "12ب34511"}, []string{"12ج34567", "13ب34567"}},
+		{"۱۲ ب ۳۴۵ ایران ??", "12ب345??", []string{"12ب34567"}, []string{"12پ34567"}},
+		{"??ت*", "??ت*", []string{"12ت26511", "45ت67811"}, []string{"12ب34567", "12345678"}},
+		{"123*", "123*", []string{"12345678"}, []string{"12ب34567"}},
+		{"12 BE 345 IR *", "12ب345*", []string{"12ب34567", "12 BE 345 IR 11"}, []string{"12ج34567"}},
+		{"??be*", "??ب*", []string{"45ب67811"}, []string{"12ت26511"}},
+		{"??D*", "??D*", []string{"12D34567"}, []string{"12ب34567"}},
+		{"KISH 1234?", "کیش1234?", []string{"کیش 12345"}, []string{"قشم 12345"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.pattern, func(t *testing.T) {
+			p, err := ParsePattern(tt.pattern)
+			if err != nil {
+				t.Fatalf("ParsePattern(%q) error: %v", tt.pattern, err)
+			}
+			if p.String() != tt.text {
+				t.Errorf("ParsePattern(%q).String() = %q, want %q", tt.pattern, p.String(), tt.text)
+			}
+			for _, s := range tt.match {
+				if plate, _ := Parse(s); !p.Match(plate) {
+					t.Errorf("%q does not match %q", tt.pattern, s)
+				}
+			}
+			for _, s := range tt.miss {
+				if plate, _ := Parse(s); p.Match(plate) {
+					t.Errorf("%q matches %q", tt.pattern, s)
+				}
+			}
+		})
+	}
+
+	for _, s := range []string{"*", "??*", "12ب3456", "12[ب]*", "12XY345*"} {
+		if _, err := ParsePattern(s); !errors.Is(err, ErrInvalidPlate) {
+			t.Errorf("ParsePattern(%q) error = %v, want ErrInvalidPlate", s, err)
+		}
+	}
+}
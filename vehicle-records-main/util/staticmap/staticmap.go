@@ -0,0 +1,184 @@
+// Package staticmap renders a point and line geometries over web mercator
+// map tiles into a single image
+package staticmap
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"math"
+)
+
+// TileSize is the width and height of a map tile in pixels
+const TileSize = 256
+
+var (
+	background = color.RGBA{R: 0xe5, G: 0xe3, B: 0xdf, A: 0xff}
+	lineColour = color.RGBA{R: 0x1e, G: 0x63, B: 0xd6, A: 0xff}
+	markColour = color.RGBA{R: 0xd6, G: 0x1e, B: 0x1e, A: 0xff}
+	white      = color.RGBA{R: 0xff, G: 0xff, B: 0xff, A: 0xff}
+)
+
+// Point is a WGS 84 position
+type Point struct {
+	Lon float64
+	Lat float64
+}
+
+// TileSource returns the tile x, y at zoom z. Tiles it fails to return are
+// left blank.
+type TileSource func(ctx context.Context, z, x, y int) (image.Image, error)
+
+// Map is an image of width x height pixels centred on Center at Zoom
+type Map struct {
+	Width  int
+	Height int
+	Zoom   int
+	Center Point
+}
+
+// Render draws the tiles of the map, lines over them and a marker on mark,
+// then encodes the image as PNG. tiles may be nil for a plain background.
+func (m Map) Render(ctx context.Context, tiles TileSource, lines [][]Point, mark Point) ([]byte, error) {
+	img := image.NewRGBA(image.Rect(0, 0, m.Width, m.Height))
+	draw.Draw(img, img.Bounds(), &image.Uniform{C: background}, image.Point{}, draw.Src)
+
+	cx, cy := project(m.Center, m.Zoom)
+	left, top := cx-float64(m.Width)/2, cy-float64(m.Height)/2
+
+	if tiles != nil {
+		n := 1 << m.Zoom
+		for ty := int(math.Floor(top / TileSize)); float64(ty*TileSize) < top+float64(m.Height); ty++ {
+			if ty < 0 || ty >= n {
+				continue
+			}
+			for tx := int(math.Floor(left / TileSize)); float64(tx*TileSize) < left+float64(m.Width); tx++ {
+				tile, err := tiles(ctx, m.Zoom, ((tx%n)+n)%n, ty)
+				if err != nil || tile == nil {
+					continue
+				}
+				at := image.Pt(int(math.Round(float64(tx*TileSize)-left)), int(math.Round(float64(ty*TileSize)-top)))
+				draw.Draw(img, image.Rectangle{Min: at, Max: at.Add(image.Pt(TileSize, TileSize))},
+					tile, tile.Bounds().Min, draw.Src)
+			}
+		}
+	}
+
+	pixel := func(p Point) (float64, float64) {
+		x, y := project(p, m.Zoom)
+		return x - left, y - top
+	}
+	for _, line := range lines {
+		for i := 1; i < len(line); i++ {
+			x0, y0 := pixel(line[i-1])
+			x1, y1 := pixel(line[i])
+			drawLine(img, x0, y0, x1, y1, 2, lineColour)
+		}
+	}
+	mx, my := pixel(mark)
+	fillCircle(img, mx, my, 8, white)
+	fillCircle(img, mx, my, 6, markColour)
+
+	var out bytes.Buffer
+	if err := png.Encode(&out, img); err != nil {
+		return nil, fmt.Errorf("failed to encode map: %w", err)
+	}
+	return out.Bytes(), nil
+}
+
+// project returns the web mercator pixel position of p at zoom z
+func project(p Point, z int) (float64, float64) {
+	size := TileSize * math.Exp2(float64(z))
+	lat := math.Max(-85.05112878, math.Min(85.05112878, p.Lat)) * math.Pi / 180
+	x := (p.Lon + 180) / 360 * size
+	y := (1 - math.Log(math.Tan(lat)+1/math.Cos(lat))/math.Pi) / 2 * size
+	return x, y
+}
+
+// drawLine draws a segment width pixels around its centre line
+func drawLine(img *image.RGBA, x0, y0, x1, y1, width float64, c color.RGBA) {
+	steps := math.Max(math.Abs(x1-x0), math.Abs(y1-y0))
+	if steps > 4*float64(img.Bounds().Dx()+img.Bounds().Dy()) {
+		// far outside the map, nothing of it would be seen
+		return
+	}
+	for i := 0.0; i <= steps; i++ {
+		t := 0.0
+		if steps > 0 {
+			t = i / steps
+		}
+		fillCircle(img, x0+(x1-x0)*t, y0+(y1-y0)*t, width, c)
+	}
+}
+
+func fillCircle(img *image.RGBA, cx, cy, r float64, c color.RGBA) {
+	for y := int(cy - r); y <= int(cy+r); y++ {
+		for x := int(cx - r); x <= int(cx+r); x++ {
+			dx, dy := float64(x)-cx, float64(y)-cy
+			if dx*dx+dy*dy <= r*r && image.Pt(x, y).In(img.Bounds()) {
+				img.SetRGBA(x, y, c)
+			}
+		}
+	}
+}
+
+// Lines reads the lines of a GeoJSON geometry, polygons give their rings and
+// points give nothing
+func Lines(geoJSON string) ([][]Point, error) {
+	var g struct {
+		Type        string            `json:"type"`
+		Coordinates json.RawMessage   `json:"coordinates"`
+		Geometries  []json.RawMessage `json:"geometries"`
+	}
+	if err := json.Unmarshal([]byte(geoJSON), &g); err != nil {
+		return nil, fmt.Errorf("failed to decode geometry: %w", err)
+	}
+
+	var lines [][][2]float64
+	var err error
+	switch g.Type {
+	case "Point", "MultiPoint":
+		return nil, nil
+	case "LineString":
+		var line [][2]float64
+		err = json.Unmarshal(g.Coordinates, &line)
+		lines = [][][2]float64{line}
+	case "MultiLineString", "Polygon":
+		err = json.Unmarshal(g.Coordinates, &lines)
+	case "MultiPolygon":
+		var polygons [][][][2]float64
+		err = json.Unmarshal(g.Coordinates, &polygons)
+		for _, polygon := range polygons {
+			lines = append(lines, polygon...)
+		}
+	case "GeometryCollection":
+		var result [][]Point
+		for _, raw := range g.Geometries {
+			sub, err := Lines(string(raw))
+			if err != nil {
+				return nil, err
+			}
+			result = append(result, sub...)
+		}
+		return result, nil
+	default:
+		return nil, fmt.Errorf("unknown geometry type %q", g.Type)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode %s coordinates: %w", g.Type, err)
+	}
+
+	result := make([][]Point, len(lines))
+	for i, line := range lines {
+		result[i] = make([]Point, len(line))
+		for j, c := range line {
+			result[i][j] = Point{Lon: c[0], Lat: c[1]}
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,130 @@
+package staticmap
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestProject(t *testing.T) {
+	tests := []struct {
+		p      Point
+		z      int
+		wx, wy float64
+	}{
+		{Point{Lon: 0, Lat: 0}, 0, 128, 128},
+		{Point{Lon: -180, Lat: 0}, 0, 0, 128},
+		{Point{Lon: 180, Lat: 0}, 1, 512, 256},
+		{Point{Lon: 0, Lat: 85.05112878}, 0, 128, 0},
+		// clamped to the web mercator bounds
+		{Point{Lon: 0, Lat: -90}, 0, 128, 256},
+		// tile 10/658/403 of the OSM grid holds tehran
+		{Point{Lon: 51.389, Lat: 35.6892}, 10, 658.17 * TileSize, 403.21 * TileSize},
+	}
+	for _, tt := range tests {
+		x, y := project(tt.p, tt.z)
+		// within a hundredth of a tile
+		if math.Abs(x-tt.wx) > 2.56 || math.Abs(y-tt.wy) > 2.56 {
+			t.Errorf("project(%v, %d) = %.1f, %.1f, want %.1f, %.1f", tt.p, tt.z, x, y, tt.wx, tt.wy)
+		}
+	}
+}
+
+func TestLines(t *testing.T) {
+	tests := []struct {
+		name    string
+		geoJSON string
+		want    [][]Point
+		wantErr bool
+	}{
+		{"point", `{"type":"Point","coordinates":[51.4,35.7]}`, nil, false},
+		{"line", `{"type":"LineString","coordinates":[[51.4,35.7],[51.5,35.8]]}`,
+			[][]Point{{{51.4, 35.7}, {51.5, 35.8}}}, false},
+		{"multi line", `{"type":"MultiLineString","coordinates":[[[1,2],[3,4]],[[5,6],[7,8]]]}`,
+			[][]Point{{{1, 2}, {3, 4}}, {{5, 6}, {7, 8}}}, false},
+		{"polygon", `{"type":"Polygon","coordinates":[[[0,0],[1,0],[1,1],[0,0]]]}`,
+			[][]Point{{{0, 0}, {1, 0}, {1, 1}, {0, 0}}}, false},
+		{"multi polygon", `{"type":"MultiPolygon","coordinates":[[[[0,0],[1,1]]],[[[2,2],[3,3]]]]}`,
+			[][]Point{{{0, 0}, {1, 1}}, {{2, 2}, {3, 3}}}, false},
+		{"collection", `{"type":"GeometryCollection","geometries":[{"type":"Point","coordinates":[0,0]},` +
+			`{"type":"LineString","coordinates":[[1,2],[3,4]]}]}`, [][]Point{{{1, 2}, {3, 4}}}, false},
+		{"unknown type", `{"type":"Circle","coordinates":[0,0]}`, nil, true},
+		{"bad coordinates", `{"type":"LineString","coordinates":[1,2]}`, nil, true},
+		{"not json", `LINESTRING(1 2, 3 4)`, nil, true},
+	}
+	for _, tt := range tests {
+		got, err := Lines(tt.geoJSON)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: Lines() error = %v, wantErr %v", tt.name, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: Lines() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestRender(t *testing.T) {
+	m := Map{Width: 200, Height: 100, Zoom: 15, Center: Point{Lon: 51.389, Lat: 35.6892}}
+	green := color.RGBA{G: 0xff, A: 0xff}
+	var requested int
+	tiles := func(_ context.Context, z, x, y int) (image.Image, error) {
+		requested++
+		if z != m.Zoom {
+			return nil, errors.New("wrong zoom")
+		}
+		img := image.NewRGBA(image.Rect(0, 0, TileSize, TileSize))
+		draw.Draw(img, img.Bounds(), &image.Uniform{C: green}, image.Point{}, draw.Src)
+		return img, nil
+	}
+	// a line along the latitude of the centre, from the left edge to the right
+	line := []Point{{Lon: 51.385, Lat: 35.6892}, {Lon: 51.393, Lat: 35.6892}}
+
+	b, err := m.Render(context.Background(), tiles, [][]Point{line}, m.Center)
+	if err != nil {
+		t.Fatalf("Render() error = %v", err)
+	}
+	img, err := png.Decode(bytes.NewReader(b))
+	if err != nil {
+		t.Fatalf("Render() is no PNG: %v", err)
+	}
+	if img.Bounds().Dx() != 200 || img.Bounds().Dy() != 100 {
+		t.Errorf("Render() size = %v, want 200x100", img.Bounds())
+	}
+	if requested == 0 {
+		t.Errorf("Render() requested no tiles")
+	}
+
+	at := func(x, y int) color.RGBA {
+		r, g, b, a := img.At(x, y).RGBA()
+		return color.RGBA{R: uint8(r >> 8), G: uint8(g >> 8), B: uint8(b >> 8), A: uint8(a >> 8)}
+	}
+	if c := at(100, 50); c != markColour {
+		t.Errorf("centre = %v, want the marker %v", c, markColour)
+	}
+	if c := at(20, 50); c != lineColour {
+		t.Errorf("line = %v, want %v", c, lineColour)
+	}
+	if c := at(20, 10); c != green {
+		t.Errorf("corner = %v, want the tile %v", c, green)
+	}
+
+	// no tiles leave the background
+	b, err = m.Render(context.Background(), nil, nil, m.Center)
+	if err != nil {
+		t.Fatalf("Render(nil tiles) error = %v", err)
+	}
+	if img, err = png.Decode(bytes.NewReader(b)); err != nil {
+		t.Fatal(err)
+	}
+	if c := at(20, 10); c != background {
+		t.Errorf("corner without tiles = %v, want %v", c, background)
+	}
+}
@@ -0,0 +1,105 @@
+package thumbnail
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"testing"
+)
+
+func solid(w, h int, c color.Color) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			img.Set(x, y, c)
+		}
+	}
+	return img
+}
+
+func encodeJPEG(t *testing.T, img image.Image) []byte {
+	t.Helper()
+	var b bytes.Buffer
+	if err := jpeg.Encode(&b, img, nil); err != nil {
+		t.Fatalf("jpeg.Encode() error = %v", err)
+	}
+	return b.Bytes()
+}
+
+func encodePNG(t *testing.T, img image.Image) []byte {
+	t.Helper()
+	var b bytes.Buffer
+	if err := png.Encode(&b, img); err != nil {
+		t.Fatalf("png.Encode() error = %v", err)
+	}
+	return b.Bytes()
+}
+
+func TestMake(t *testing.T) {
+	gray := color.RGBA{R: 128, G: 128, B: 128, A: 255}
+	tests := []struct {
+		name       string
+		src        []byte
+		width      int
+		wantWidth  int
+		wantHeight int
+	}{
+		{"jpeg is scaled down", encodeJPEG(t, solid(640, 480, gray)), 320, 320, 240},
+		{"png is scaled down", encodePNG(t, solid(400, 100, gray)), 100, 100, 25},
+		{"smaller photo is not scaled up", encodeJPEG(t, solid(200, 150, gray)), 320, 200, 150},
+		{"equal width is kept", encodePNG(t, solid(320, 10, gray)), 320, 320, 10},
+		{"height is at least one pixel", encodePNG(t, solid(1000, 2, gray)), 10, 10, 1},
+	}
+	for _, tt := range tests {
+		got, err := Make(tt.src, tt.width)
+		if err != nil {
+			t.Errorf("%s: Make() error = %v", tt.name, err)
+			continue
+		}
+		cfg, format, err := image.DecodeConfig(bytes.NewReader(got))
+		if err != nil {
+			t.Errorf("%s: thumbnail does not decode: %v", tt.name, err)
+			continue
+		}
+		if format != "jpeg" {
+			t.Errorf("%s: format = %s, want jpeg", tt.name, format)
+		}
+		if cfg.Width != tt.wantWidth || cfg.Height != tt.wantHeight {
+			t.Errorf("%s: size = %dx%d, want %dx%d", tt.name, cfg.Width, cfg.Height, tt.wantWidth, tt.wantHeight)
+		}
+	}
+}
+
+func TestMakeInvalid(t *testing.T) {
+	for _, src := range [][]byte{nil, []byte("not a photo")} {
+		if _, err := Make(src, 320); err == nil {
+			t.Errorf("Make(%q) error = nil, want an error", src)
+		}
+	}
+}
+
+func TestScaleAverages(t *testing.T) {
+	// left half black, right half white, scaled to 2x1 the halves stay apart
+	// and scaled to 1x1 they average to mid gray
+	img := solid(4, 2, color.Black)
+	for y := 0; y < 2; y++ {
+		for x := 2; x < 4; x++ {
+			img.Set(x, y, color.White)
+		}
+	}
+
+	two := scale(img, 2, 1)
+	if r := two.RGBAAt(0, 0).R; r != 0 {
+		t.Errorf("scale(2x1) left = %d, want 0", r)
+	}
+	if r := two.RGBAAt(1, 0).R; r != 255 {
+		t.Errorf("scale(2x1) right = %d, want 255", r)
+	}
+
+	one := scale(img, 1, 1)
+	if c := one.RGBAAt(0, 0); c.R != 127 || c.G != 127 || c.B != 127 || c.A != 255 {
+		t.Errorf("scale(1x1) = %v, want {127 127 127 255}", c)
+	}
+}